@@ -2,22 +2,32 @@ package cli
 
 import (
 	"fmt"
+	"strings"
 
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
+// listHostNetworks is used during tests to mock netutils.ListHostNetworks.
+var listHostNetworks = netutils.ListHostNetworks
+
 func mustAddCIDRFlags(flagSet *pflag.FlagSet) {
-	flagSet.String("cidr", ecv1beta1.DefaultNetworkCIDR, "CIDR block of available private IP addresses (/16 or larger)")
+	flagSet.String("cidr", ecv1beta1.DefaultNetworkCIDR, "CIDR block of available private IP addresses (/16 or larger), or of unique local IPv6 addresses (between /48 and /56) for an IPv6 only cluster")
 
 	flagSet.String("pod-cidr", k0sv1beta1.DefaultNetwork().PodCIDR, "IP address range for Pods")
 	mustMarkFlagHidden(flagSet, "pod-cidr")
 
 	flagSet.String("service-cidr", k0sv1beta1.DefaultNetwork().ServiceCIDR, "IP address range for Services")
 	mustMarkFlagHidden(flagSet, "service-cidr")
+
+	flagSet.String("ipv6-cidr", "", "CIDR block of available unique local IPv6 addresses (between /48 and /56), enables dual-stack networking along with --cidr")
+
+	flagSet.StringSlice("cidr-candidates", newconfig.DefaultCIDRCandidates, "CIDR blocks tried in order when --cidr is not set, the first one that does not overlap with the networks of this host is used")
 }
 
 func validateCIDRFlags(cmd *cobra.Command) error {
@@ -34,14 +44,81 @@ func validateCIDRFlags(cmd *cobra.Command) error {
 		return err
 	}
 
+	candidates, err := cmd.Flags().GetStringSlice("cidr-candidates")
+	if err != nil {
+		return fmt.Errorf("unable to get cidr-candidates flag: %w", err)
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("--cidr-candidates must list at least one cidr")
+	}
+	for _, candidate := range candidates {
+		if netutils.IsIPv6CIDR(candidate) {
+			return fmt.Errorf("--cidr-candidates only accepts IPv4 cidrs, use --cidr for an IPv6 only cluster")
+		}
+		if err := newconfig.ValidateCIDR(candidate); err != nil {
+			return fmt.Errorf("invalid --cidr-candidates: %w", err)
+		}
+	}
+
+	if cmd.Flags().Changed("pod-cidr") || cmd.Flags().Changed("service-cidr") {
+		podCIDR, err := cmd.Flags().GetString("pod-cidr")
+		if err != nil {
+			return fmt.Errorf("unable to get pod-cidr flag: %w", err)
+		}
+		serviceCIDR, err := cmd.Flags().GetString("service-cidr")
+		if err != nil {
+			return fmt.Errorf("unable to get service-cidr flag: %w", err)
+		}
+		if netutils.IsIPv6CIDR(podCIDR) != netutils.IsIPv6CIDR(serviceCIDR) {
+			return fmt.Errorf("--pod-cidr and --service-cidr must be of the same IP family")
+		}
+		cidr = podCIDR
+	}
+
+	ipv6CIDR, err := cmd.Flags().GetString("ipv6-cidr")
+	if err != nil {
+		return fmt.Errorf("unable to get ipv6-cidr flag: %w", err)
+	}
+	if ipv6CIDR != "" {
+		if netutils.IsIPv6CIDR(cidr) {
+			return fmt.Errorf("--ipv6-cidr can't be used with IPv6 CIDRs, they install an IPv6 only cluster")
+		}
+		if err := newconfig.ValidateIPv6CIDR(ipv6CIDR); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // getCIDRConfig determines, based on the command line flags,
 // what are the pod and service CIDRs to be used for the cluster. If either
 // of --pod-cidr or --service-cidr have been set, they are used. Otherwise,
-// the cidr flag is split into pod and service CIDRs.
+// the cidr flag is split into pod and service CIDRs, the first candidate free
+// on this host is used if it is not set. The ipv6-cidr flag is split into the
+// IPv6 pod and service CIDRs of a dual-stack cluster.
 func getCIDRConfig(cmd *cobra.Command) (*newconfig.CIDRConfig, error) {
+	cidrCfg, err := getPrimaryCIDRConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	ipv6CIDR, err := cmd.Flags().GetString("ipv6-cidr")
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ipv6-cidr flag: %w", err)
+	}
+	if ipv6CIDR != "" {
+		cidrCfg.IPv6PodCIDR, cidrCfg.IPv6ServiceCIDR, err = newconfig.SplitCIDR(ipv6CIDR)
+		if err != nil {
+			return nil, fmt.Errorf("unable to split ipv6-cidr flag: %w", err)
+		}
+	}
+	return cidrCfg, nil
+}
+
+// getPrimaryCIDRConfig returns the pod and service CIDRs of the primary IP family of the
+// cluster, IPv4 unless the cluster is IPv6 only.
+func getPrimaryCIDRConfig(cmd *cobra.Command) (*newconfig.CIDRConfig, error) {
 	if cmd.Flags().Changed("pod-cidr") || cmd.Flags().Changed("service-cidr") {
 		podCIDR, err := cmd.Flags().GetString("pod-cidr")
 		if err != nil {
@@ -61,6 +138,11 @@ func getCIDRConfig(cmd *cobra.Command) (*newconfig.CIDRConfig, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to get cidr flag: %w", err)
 	}
+	if !cmd.Flags().Changed("cidr") {
+		if globalCIDR, err = selectFreeCIDR(cmd, globalCIDR); err != nil {
+			return nil, err
+		}
+	}
 	podCIDR, serviceCIDR, err := newconfig.SplitCIDR(globalCIDR)
 	if err != nil {
 		return nil, fmt.Errorf("unable to split cidr flag: %w", err)
@@ -71,3 +153,74 @@ func getCIDRConfig(cmd *cobra.Command) (*newconfig.CIDRConfig, error) {
 		GlobalCIDR:  &globalCIDR,
 	}, nil
 }
+
+// selectFreeCIDR returns the first of the candidate cidrs that does not overlap with the
+// networks of this host. The default cidr is returned when the networks can't be listed, the
+// host preflights check it later.
+func selectFreeCIDR(cmd *cobra.Command, defaultCIDR string) (string, error) {
+	candidates, err := cmd.Flags().GetStringSlice("cidr-candidates")
+	if err != nil {
+		return "", fmt.Errorf("unable to get cidr-candidates flag: %w", err)
+	}
+	if len(candidates) == 0 {
+		return defaultCIDR, nil
+	}
+
+	networks, err := listHostNetworks()
+	if err != nil {
+		logrus.Debugf("unable to list the networks of this host, using cidr %s: %v", candidates[0], err)
+		return candidates[0], nil
+	}
+
+	cidr, err := netutils.SelectFreeCIDR(candidates, networks)
+	if err != nil {
+		return "", fmt.Errorf("%w, use --cidr to set a range that is free on this network", err)
+	}
+	if cidr != candidates[0] {
+		conflicts, _ := netutils.CIDRConflicts(candidates[0], networks)
+		logrus.Infof("Using cidr %s, %s overlaps with %s.", cidr, candidates[0], describeHostNetworks(conflicts))
+	}
+	return cidr, nil
+}
+
+// checkCIDRConflicts returns an error describing the networks of this host the pod and service
+// cidrs overlap with. Traffic to the host networks would be sent to the pods and services
+// instead, or the other way around.
+func checkCIDRConflicts(cidrCfg *newconfig.CIDRConfig) error {
+	networks, err := listHostNetworks()
+	if err != nil {
+		logrus.Debugf("unable to list the networks of this host, skipping the cidr conflict check: %v", err)
+		return nil
+	}
+
+	for _, cidr := range []struct{ name, cidr string }{
+		{"pod", cidrCfg.PodCIDR},
+		{"service", cidrCfg.ServiceCIDR},
+		{"IPv6 pod", cidrCfg.IPv6PodCIDR},
+		{"IPv6 service", cidrCfg.IPv6ServiceCIDR},
+	} {
+		if cidr.cidr == "" {
+			continue
+		}
+		conflicts, err := netutils.CIDRConflicts(cidr.cidr, networks)
+		if err != nil {
+			return fmt.Errorf("unable to check the %s cidr: %w", cidr.name, err)
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf(
+				"the %s cidr %s overlaps with %s. Traffic between this host and these networks would be routed into the cluster. Choose a range that is free on this network with --cidr",
+				cidr.name, cidr.cidr, describeHostNetworks(conflicts),
+			)
+		}
+	}
+	return nil
+}
+
+// describeHostNetworks joins the descriptions of the networks.
+func describeHostNetworks(networks []netutils.HostNetwork) string {
+	descriptions := make([]string, len(networks))
+	for i, network := range networks {
+		descriptions[i] = network.String()
+	}
+	return strings.Join(descriptions, ", ")
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	nodeutil "k8s.io/component-helpers/node/util"
+)
+
+// DisableHACmd is the command for disabling HA mode.
+func DisableHACmd(ctx context.Context, appTitle string) *cobra.Command {
+	var rc runtimeconfig.RuntimeConfig
+	var assumeYes bool
+
+	cmd := &cobra.Command{
+		Use:   "disable-ha",
+		Short: fmt.Sprintf("Disable high availability for the %s cluster", appTitle),
+		Long: fmt.Sprintf(`Disable high availability for the %s cluster so it can be reduced to a single controller.
+
+In airgap installations, the registry data is moved back from SeaweedFS to a volume on the node
+this command runs on, and SeaweedFS is removed. The Admin Console is scaled back to a single
+replica. Run this command on the controller node you want to keep, then reset the other
+controller nodes one at a time.`, appTitle),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupOutput(cmd); err != nil {
+				return err
+			}
+
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("disable-ha command must be run as root")
+			}
+
+			rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+			_ = rc.SetEnv()
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runDisableHA(cmd.Context(), rc, assumeYes); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+	mustAddOutputFlag(cmd)
+
+	return cmd
+}
+
+func runDisableHA(ctx context.Context, rc runtimeconfig.RuntimeConfig, assumeYes bool) error {
+	status, err := k0s.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get k0s status, is this a cluster node?: %w", err)
+	}
+	if status.Role != "controller" {
+		return fmt.Errorf("disable-ha command must be run on a controller node")
+	}
+
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return fmt.Errorf("unable to get hostname: %w", err)
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to get kube client: %w", err)
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get latest installation: %w", err)
+	}
+
+	addOns, closeAddOns, err := newHAAddOns(kcli, rc, in)
+	if err != nil {
+		return err
+	}
+	defer closeAddOns()
+
+	canDisableHA, reason, err := addOns.CanDisableHA(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to check if HA can be disabled: %w", err)
+	}
+	if !canDisableHA {
+		logrus.Warnf("High availability cannot be disabled: %s", reason)
+		return NewErrorNothingElseToAdd(fmt.Errorf("high availability cannot be disabled: %s", reason))
+	}
+
+	if in.Spec.AirGap {
+		logrus.Infof("Registry data will be moved to node %s, which must be the %s node you keep.", hostname, config.GetControllerRoleName())
+	}
+	if !assumeYes {
+		confirmed, err := prompts.New().Confirm("Do you want to disable high availability?", false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			return fmt.Errorf("Aborting")
+		}
+		logrus.Info("")
+	}
+
+	loading := spinner.Start()
+
+	haOpts, err := newEnableHAOptions(ctx, kcli, rc, in)
+	if err != nil {
+		loading.Close()
+		return err
+	}
+	opts := addons.DisableHAOptions{
+		EnableHAOptions: haOpts,
+		RegistryNode:    hostname,
+	}
+
+	err = runPhase(phaseDisableHA, func() error {
+		return addOns.DisableHA(ctx, opts, loading)
+	})
+	loading.Close()
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("You can now reset the other %s nodes, one at a time.", config.GetControllerRoleName())
+
+	return nil
+}
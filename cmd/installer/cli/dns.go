@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// parseDNSUpstreams validates the --dns-upstream flag values. They end up as nameserver entries
+// in a resolv.conf so they must be IP addresses, without a port.
+func parseDNSUpstreams(upstreams []string) ([]string, error) {
+	if len(upstreams) > 3 {
+		return nil, fmt.Errorf("at most 3 dns upstreams can be set, the kubelet ignores the others")
+	}
+
+	var parsed []string
+	for _, upstream := range upstreams {
+		addr, err := netip.ParseAddr(upstream)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dns upstream %q: must be an IP address", upstream)
+		}
+		if addr.IsLoopback() {
+			return nil, fmt.Errorf("invalid dns upstream %s: loopback addresses are not reachable from CoreDNS", upstream)
+		}
+		parsed = append(parsed, addr.String())
+	}
+	return parsed, nil
+}
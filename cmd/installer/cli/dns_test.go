@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseDNSUpstreams(t *testing.T) {
+	tests := []struct {
+		name      string
+		upstreams []string
+		want      []string
+		wantErr   string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:      "ipv4 and ipv6",
+			upstreams: []string{"10.0.0.53", "2001:db8::0053"},
+			want:      []string{"10.0.0.53", "2001:db8::53"},
+		},
+		{
+			name:      "hostname",
+			upstreams: []string{"dns.example.com"},
+			wantErr:   `invalid dns upstream "dns.example.com": must be an IP address`,
+		},
+		{
+			name:      "with port",
+			upstreams: []string{"10.0.0.53:53"},
+			wantErr:   `invalid dns upstream "10.0.0.53:53": must be an IP address`,
+		},
+		{
+			name:      "systemd-resolved stub",
+			upstreams: []string{"127.0.0.53"},
+			wantErr:   "invalid dns upstream 127.0.0.53: loopback addresses are not reachable from CoreDNS",
+		},
+		{
+			name:      "too many",
+			upstreams: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+			wantErr:   "at most 3 dns upstreams can be set",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDNSUpstreams(tt.upstreams)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/domains"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons"
 	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
@@ -17,6 +18,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/versions"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // EnableHACmd is the command for enabling HA mode.
@@ -27,6 +29,10 @@ func EnableHACmd(ctx context.Context, appTitle string) *cobra.Command {
 		Use:   "enable-ha",
 		Short: fmt.Sprintf("Enable high availability for the %s cluster", appTitle),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupOutput(cmd); err != nil {
+				return err
+			}
+
 			// Skip root check if dryrun mode is enabled
 			if !dryrun.Enabled() && os.Getuid() != 0 {
 				return fmt.Errorf("enable-ha command must be run as root")
@@ -50,6 +56,8 @@ func EnableHACmd(ctx context.Context, appTitle string) *cobra.Command {
 		},
 	}
 
+	mustAddOutputFlag(cmd)
+
 	return cmd
 }
 
@@ -59,45 +67,16 @@ func runEnableHA(ctx context.Context, rc runtimeconfig.RuntimeConfig) error {
 		return fmt.Errorf("unable to get kube client: %w", err)
 	}
 
-	mcli, err := kubeutils.MetadataClient()
-	if err != nil {
-		return fmt.Errorf("unable to create metadata client: %w", err)
-	}
-
-	kclient, err := kubeutils.GetClientset()
-	if err != nil {
-		return fmt.Errorf("unable to create kubernetes client: %w", err)
-	}
-
 	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
 	if err != nil {
 		return fmt.Errorf("unable to get latest installation: %w", err)
 	}
 
-	airgapChartsPath := ""
-	if in.Spec.AirGap {
-		airgapChartsPath = rc.EmbeddedClusterChartsSubDir()
-	}
-
-	hcli, err := helm.NewClient(helm.HelmOptions{
-		HelmPath:              rc.PathToEmbeddedClusterBinary("helm"),
-		KubernetesEnvSettings: rc.GetKubernetesEnvSettings(),
-		K8sVersion:            versions.K0sVersion,
-		AirgapPath:            airgapChartsPath,
-	})
+	addOns, closeAddOns, err := newHAAddOns(kcli, rc, in)
 	if err != nil {
-		return fmt.Errorf("unable to create helm client: %w", err)
+		return err
 	}
-	defer hcli.Close()
-
-	addOns := addons.New(
-		addons.WithLogFunc(logrus.Debugf),
-		addons.WithKubernetesClient(kcli),
-		addons.WithKubernetesClientSet(kclient),
-		addons.WithMetadataClient(mcli),
-		addons.WithHelmClient(hcli),
-		addons.WithDomains(domains.GetDomains(in.Spec.Config, release.GetChannelRelease())),
-	)
+	defer closeAddOns()
 
 	canEnableHA, reason, err := addOns.CanEnableHA(ctx)
 	if err != nil {
@@ -111,12 +90,24 @@ func runEnableHA(ctx context.Context, rc runtimeconfig.RuntimeConfig) error {
 	loading := spinner.Start()
 	defer loading.Close()
 
+	opts, err := newEnableHAOptions(ctx, kcli, rc, in)
+	if err != nil {
+		return err
+	}
+
+	return runPhase(phaseEnableHA, func() error {
+		return addOns.EnableHA(ctx, opts, loading)
+	})
+}
+
+// newEnableHAOptions returns the options used to enable high availability for the installation.
+func newEnableHAOptions(ctx context.Context, kcli client.Client, rc runtimeconfig.RuntimeConfig, in *ecv1beta1.Installation) (addons.EnableHAOptions, error) {
 	kotsadmNamespace, err := runtimeconfig.KotsadmNamespace(ctx, kcli)
 	if err != nil {
-		return fmt.Errorf("get kotsadm namespace: %w", err)
+		return addons.EnableHAOptions{}, fmt.Errorf("get kotsadm namespace: %w", err)
 	}
 
-	opts := addons.EnableHAOptions{
+	return addons.EnableHAOptions{
 		ClusterID:          in.Spec.ClusterID,
 		AdminConsolePort:   rc.AdminConsolePort(),
 		IsAirgap:           in.Spec.AirGap,
@@ -130,7 +121,45 @@ func runEnableHA(ctx context.Context, rc runtimeconfig.RuntimeConfig) error {
 		SeaweedFSDataDir:   rc.EmbeddedClusterSeaweedFSSubDir(),
 		ServiceCIDR:        rc.ServiceCIDR(),
 		KotsadmNamespace:   kotsadmNamespace,
+	}, nil
+}
+
+// newHAAddOns returns the addons client used to manage high availability for the installation
+// and a function to release it.
+func newHAAddOns(kcli client.Client, rc runtimeconfig.RuntimeConfig, in *ecv1beta1.Installation) (*addons.AddOns, func(), error) {
+	mcli, err := kubeutils.MetadataClient()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create metadata client: %w", err)
+	}
+
+	kclient, err := kubeutils.GetClientset()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create kubernetes client: %w", err)
+	}
+
+	airgapChartsPath := ""
+	if in.Spec.AirGap {
+		airgapChartsPath = rc.EmbeddedClusterChartsSubDir()
+	}
+
+	hcli, err := helm.NewClient(helm.HelmOptions{
+		HelmPath:              rc.PathToEmbeddedClusterBinary("helm"),
+		KubernetesEnvSettings: rc.GetKubernetesEnvSettings(),
+		K8sVersion:            versions.K0sVersion,
+		AirgapPath:            airgapChartsPath,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create helm client: %w", err)
 	}
 
-	return addOns.EnableHA(ctx, opts, loading)
+	addOns := addons.New(
+		addons.WithLogFunc(logrus.Debugf),
+		addons.WithKubernetesClient(kcli),
+		addons.WithKubernetesClientSet(kclient),
+		addons.WithMetadataClient(mcli),
+		addons.WithHelmClient(hcli),
+		addons.WithDomains(domains.GetDomains(in.Spec.Config, release.GetChannelRelease())),
+	)
+
+	return addOns, func() { hcli.Close() }, nil
 }
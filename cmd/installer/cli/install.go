@@ -6,8 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -26,11 +28,13 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/kubernetesinstallation"
 	ecmetadata "github.com/replicatedhq/embedded-cluster/pkg-new/metadata"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	addontypes "github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
 	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/extensions"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
@@ -69,9 +73,19 @@ type installFlags struct {
 	ignoreHostPreflights              bool
 	ignoreAppPreflights               bool
 	disableFilesystemPerformanceCheck bool
+	ntpServers                        []string
+	dnsUpstreams                      []string
 	networkInterface                  string
+	controlPlaneInterface             string
+	podNetworkInterface               string
+	adminConsoleAddress               string
 	cidrConfig                        *newconfig.CIDRConfig
 	proxySpec                         *ecv1beta1.ProxySpec
+	resume                            bool
+	configFile                        string
+	plan                              bool
+	nodesFile                         string
+	wizard                            bool
 
 	// kubernetes flags
 	kubernetesEnvSettings *helmcli.EnvSettings
@@ -97,6 +111,8 @@ type installConfig struct {
 	tlsCertBytes       []byte
 	tlsKeyBytes        []byte
 	configValues       *kotsv1beta1.ConfigValues
+	checkpoint         *installCheckpoint
+	nodeInventory      *nodeinventory.NodeInventory
 }
 
 // webAssetsFS is the filesystem to be used by the web component. Defaults to nil allowing the web server to use the default assets embedded in the binary. Useful for testing.
@@ -115,11 +131,35 @@ func InstallCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 		Use:     "install",
 		Short:   fmt.Sprintf("Install %s", appTitle),
 		Example: installCmdExample(appSlug),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// with --plan, --output selects the format of the plan instead of the progress stream
+			if !flags.plan {
+				if err := setupOutput(cmd); err != nil {
+					return err
+				}
+			}
+
+			// values from the config file must be in place before cobra validates the required flags
+			return applyInstallConfigFile(cmd, &flags)
+		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			rc.Cleanup()
 			cancel() // Cancel context when command completes
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.wizard {
+				if err := validateWizardFlags(cmd, &flags); err != nil {
+					return err
+				}
+				if err := newInstallWizard(appTitle).Run(cmd); err != nil {
+					return err
+				}
+			}
+
+			if flags.plan {
+				return runInstallPlan(ctx, cmd, appSlug, &flags, rc, ki)
+			}
+
 			installCfg, err := preRunInstall(cmd, &flags, rc, ki)
 			if err != nil {
 				return err
@@ -130,7 +170,10 @@ func InstallCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 			}
 
 			metricsReporter := buildMetricsReporter(cmd, installCfg)
-			metricsReporter.ReportInstallationStarted(ctx)
+			// the interrupted install already reported that it started
+			if !flags.resume {
+				metricsReporter.ReportInstallationStarted(ctx)
+			}
 
 			_ = rc.SetEnv()
 
@@ -150,6 +193,12 @@ func InstallCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 			}
 			metricsReporter.ReportInstallationSucceeded(ctx)
 
+			if installCfg.nodeInventory != nil {
+				if err := runInstallNodeAdd(cmd.Context(), appSlug, rc, installCfg.nodeInventory); err != nil {
+					return fmt.Errorf("installation succeeded but not all nodes joined, run node add to retry: %w", err)
+				}
+			}
+
 			return nil
 		},
 	}
@@ -167,6 +216,22 @@ func InstallCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 	if err := addManagementConsoleFlags(cmd, &flags); err != nil {
 		panic(err)
 	}
+	if err := addResumeFlag(cmd, &flags); err != nil {
+		panic(err)
+	}
+	if err := addConfigFileFlag(cmd, &flags); err != nil {
+		panic(err)
+	}
+	if err := addPlanFlag(cmd, &flags); err != nil {
+		panic(err)
+	}
+	if err := addNodesFlag(cmd, &flags); err != nil {
+		panic(err)
+	}
+	if err := addWizardFlag(cmd, &flags); err != nil {
+		panic(err)
+	}
+	mustAddOutputFlag(cmd)
 
 	cmd.AddCommand(InstallRunPreflightsCmd(ctx, appSlug))
 
@@ -252,6 +317,9 @@ func newLinuxInstallFlags(flags *installFlags) *pflag.FlagSet {
 	flagSet.StringVar(&flags.dataDir, "data-dir", ecv1beta1.DefaultDataDir, "Path to the data directory")
 	flagSet.IntVar(&flags.localArtifactMirrorPort, "local-artifact-mirror-port", ecv1beta1.DefaultLocalArtifactMirrorPort, "Port on which the Local Artifact Mirror will be served")
 	flagSet.StringVar(&flags.networkInterface, "network-interface", "", "The network interface to use for the cluster")
+	flagSet.StringVar(&flags.controlPlaneInterface, "control-plane-interface", "", "The network interface the Kubernetes API and etcd advertise their address on (default: --network-interface)")
+	flagSet.StringVar(&flags.podNetworkInterface, "pod-network-interface", "", "Regular expression matching the network interfaces of the nodes that carry the pod traffic (default: autodetected by Calico)")
+	flagSet.StringVar(&flags.adminConsoleAddress, "admin-console-address", "", "IP address or hostname the Admin Console is advertised on (default: the public IP or the address of --network-interface)")
 
 	flagSet.StringSlice("private-ca", []string{}, "Path to a trusted private CA certificate file")
 	mustMarkFlagHidden(flagSet, "private-ca")
@@ -263,8 +331,10 @@ func newLinuxInstallFlags(flags *installFlags) *pflag.FlagSet {
 
 	flagSet.BoolVar(&flags.ignoreHostPreflights, "ignore-host-preflights", false, "Allow bypassing host preflight failures")
 	flagSet.BoolVar(&flags.ignoreAppPreflights, "ignore-app-preflights", false, "Allow bypassing app preflight failures")
-	flagSet.BoolVar(&flags.disableFilesystemPerformanceCheck, "disable-filesystem-performance-check", false, "Disable the filesystem write latency performance check")
+	flagSet.BoolVar(&flags.disableFilesystemPerformanceCheck, "disable-filesystem-performance-check", false, "Disable the filesystem write latency and storage performance checks")
 	mustMarkFlagHidden(flagSet, "disable-filesystem-performance-check")
+	flagSet.StringSliceVar(&flags.ntpServers, "ntp-servers", nil, "NTP servers to configure chrony or systemd-timesyncd with before the host preflights run")
+	flagSet.StringSliceVar(&flags.dnsUpstreams, "dns-upstream", nil, "Nameservers CoreDNS forwards to instead of the nameservers in /etc/resolv.conf (at most 3)")
 
 	mustAddCIDRFlags(flagSet)
 
@@ -322,6 +392,13 @@ func addManagementConsoleFlags(cmd *cobra.Command, flags *installFlags) error {
 	return nil
 }
 
+func addResumeFlag(cmd *cobra.Command, flags *installFlags) error {
+	cmd.Flags().BoolVar(&flags.resume, "resume", false, "Resume a previously interrupted installation from the first incomplete step")
+	mustSetFlagTargetLinux(cmd.Flags(), "resume")
+
+	return nil
+}
+
 // Hop: buildMetricsReporter builds the metrics reporter for installation tracking
 func buildMetricsReporter(cmd *cobra.Command, installCfg *installConfig) *installReporter {
 	return newInstallReporter(
@@ -346,9 +423,18 @@ func preRunInstall(cmd *cobra.Command, flags *installFlags, rc runtimeconfig.Run
 	if err := buildRuntimeConfig(flags, installCfg, rc); err != nil {
 		return nil, fmt.Errorf("build runtime config: %w", err)
 	}
-	if err := preRunInstallLinux(); err != nil {
-		return nil, fmt.Errorf("pre run install linux: %w", err)
+	// planning doesn't touch the host and can be done by any user
+	if !flags.plan {
+		if err := preRunInstallLinux(); err != nil {
+			return nil, fmt.Errorf("pre run install linux: %w", err)
+		}
+	}
+
+	checkpoint, err := buildInstallCheckpoint(flags, installCfg, rc)
+	if err != nil {
+		return nil, err
 	}
+	installCfg.checkpoint = checkpoint
 
 	return installCfg, nil
 }
@@ -363,6 +449,13 @@ func buildInstallFlags(cmd *cobra.Command, flags *installFlags) error {
 		return fmt.Errorf(`invalid --target (must be one of: "linux", "kubernetes")`)
 	}
 
+	if flags.plan && flags.resume {
+		return fmt.Errorf("--plan cannot be used with --resume")
+	}
+	if flags.plan && flags.nodesFile != "" {
+		return fmt.Errorf("--plan cannot be used with --nodes")
+	}
+
 	// If only one of cert or key is provided, return an error
 	if (flags.tlsCertFile != "" && flags.tlsKeyFile == "") || (flags.tlsCertFile == "" && flags.tlsKeyFile != "") {
 		return fmt.Errorf("both --tls-cert and --tls-key must be provided together")
@@ -391,6 +484,10 @@ func buildInstallFlags(cmd *cobra.Command, flags *installFlags) error {
 		// If error, leave empty and validation will catch it later
 	}
 
+	if err := validateInterfaceFlags(flags.podNetworkInterface, flags.adminConsoleAddress); err != nil {
+		return err
+	}
+
 	// Port conflict validations
 	if flags.managerPort != 0 && flags.adminConsolePort != 0 {
 		if flags.managerPort == flags.adminConsolePort {
@@ -405,14 +502,21 @@ func buildInstallFlags(cmd *cobra.Command, flags *installFlags) error {
 	}
 
 	// CIDR configuration
-	cidrCfg, err := cidrConfigFromCmd(cmd)
+	cidrCfg, err := installCIDRConfig(cmd, flags)
 	if err != nil {
 		return err
 	}
 	flags.cidrConfig = cidrCfg
 
+	// DNS upstreams
+	dnsUpstreams, err := parseDNSUpstreams(flags.dnsUpstreams)
+	if err != nil {
+		return err
+	}
+	flags.dnsUpstreams = dnsUpstreams
+
 	// Proxy configuration
-	proxy, err := parseProxyFlags(cmd, flags.networkInterface, flags.cidrConfig)
+	proxy, err := parseProxyFlags(cmd, flags.networkInterface, flags.controlPlaneInterface, flags.cidrConfig)
 	if err != nil {
 		return err
 	}
@@ -492,9 +596,66 @@ func buildInstallConfig(flags *installFlags) (*installConfig, error) {
 		return nil, fmt.Errorf("process TLS config: %w", err)
 	}
 
+	// Node inventory, parsed upfront so mistakes surface before installing
+	if flags.nodesFile != "" {
+		inv, err := nodeinventory.ParseFile(flags.nodesFile, config.GetControllerRoleName())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read node inventory: %w", err)
+		}
+		installCfg.nodeInventory = inv
+	}
+
 	return installCfg, nil
 }
 
+// installCIDRConfig returns the cidrs of the cluster. A resume reuses the cidrs of the
+// interrupted install: the host now routes them to the cluster, so they would conflict with the
+// host networks, and a free range selected again could differ from the one installed.
+func installCIDRConfig(cmd *cobra.Command, flags *installFlags) (*newconfig.CIDRConfig, error) {
+	if !flags.resume {
+		return cidrConfigFromCmd(cmd)
+	}
+
+	if err := validateCIDRFlags(cmd); err != nil {
+		return nil, err
+	}
+
+	// the cidrs set on the command line are used as is, the inputs digest refuses them if they
+	// are not the ones of the interrupted install
+	if cmd.Flags().Changed("cidr") || cmd.Flags().Changed("pod-cidr") || cmd.Flags().Changed("service-cidr") {
+		cidrCfg, err := getCIDRConfig(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine pod and service CIDRs: %w", err)
+		}
+		return cidrCfg, nil
+	}
+
+	recorded, err := resumedInstallCIDRs(flags.dataDir)
+	if err != nil {
+		return nil, err
+	}
+	// the checkpoints written before the cidrs were recorded don't have them
+	if recorded == nil {
+		return cidrConfigFromCmd(cmd)
+	}
+
+	cidrCfg := *recorded
+	if cmd.Flags().Changed("ipv6-cidr") {
+		ipv6CIDR, err := cmd.Flags().GetString("ipv6-cidr")
+		if err != nil {
+			return nil, fmt.Errorf("unable to get ipv6-cidr flag: %w", err)
+		}
+		cidrCfg.IPv6PodCIDR, cidrCfg.IPv6ServiceCIDR = "", ""
+		if ipv6CIDR != "" {
+			cidrCfg.IPv6PodCIDR, cidrCfg.IPv6ServiceCIDR, err = newconfig.SplitCIDR(ipv6CIDR)
+			if err != nil {
+				return nil, fmt.Errorf("unable to split ipv6-cidr flag: %w", err)
+			}
+		}
+	}
+	return &cidrCfg, nil
+}
+
 func cidrConfigFromCmd(cmd *cobra.Command) (*newconfig.CIDRConfig, error) {
 	if err := validateCIDRFlags(cmd); err != nil {
 		return nil, err
@@ -506,6 +667,11 @@ func cidrConfigFromCmd(cmd *cobra.Command) (*newconfig.CIDRConfig, error) {
 		return nil, fmt.Errorf("failed to determine pod and service CIDRs: %w", err)
 	}
 
+	// refuse the ranges the host already reaches, before anything is installed
+	if err := checkCIDRConflicts(cidrCfg); err != nil {
+		return nil, err
+	}
+
 	return cidrCfg, nil
 }
 
@@ -584,6 +750,10 @@ func buildNetworkSpec(flags *installFlags, installCfg *installConfig) (ecv1beta1
 	}
 	networkSpec := helpers.NetworkSpecFromK0sConfig(k0sCfg)
 	networkSpec.NetworkInterface = flags.networkInterface
+	networkSpec.ControlPlaneInterface = flags.controlPlaneInterface
+	networkSpec.PodNetworkInterface = flags.podNetworkInterface
+	networkSpec.AdminConsoleAddress = flags.adminConsoleAddress
+	networkSpec.DNSUpstreams = flags.dnsUpstreams
 	if flags.cidrConfig.GlobalCIDR != nil {
 		networkSpec.GlobalCIDR = *flags.cidrConfig.GlobalCIDR
 	}
@@ -632,20 +802,40 @@ func buildKubernetesInstallation(flags *installFlags, ki kubernetesinstallation.
 }
 
 func runInstall(ctx context.Context, flags installFlags, installCfg *installConfig, rc runtimeconfig.RuntimeConfig, metricsReporter *installReporter) (finalErr error) {
+	checkpoint := installCfg.checkpoint
+
+	defer func() {
+		if finalErr != nil && checkpoint.HasProgress() {
+			printResumeHint(runtimeconfig.AppSlug())
+		}
+	}()
+
 	logrus.Debug("initializing install")
-	if err := initializeInstall(ctx, flags, installCfg, rc); err != nil {
+	if err := checkpoint.Run(installStepInitialize, verifyInstallInitialized, func() error {
+		return initializeInstall(ctx, flags, installCfg, rc)
+	}); err != nil {
 		return fmt.Errorf("failed to initialize install: %w", err)
 	}
 
 	logrus.Debugf("running install preflights")
-	if err := runInstallPreflights(ctx, flags, installCfg, rc, metricsReporter.reporter); err != nil {
+	if err := checkpoint.Run(installStepHostPreflights, nil, func() error {
+		if flags.wizard {
+			return runInstallWizardPreflights(ctx, flags, installCfg, rc, metricsReporter.reporter, prompts.New())
+		}
+		return runInstallPreflights(ctx, flags, installCfg, rc, metricsReporter.reporter)
+	}); err != nil {
 		if errors.Is(err, preflights.ErrPreflightsHaveFail) {
 			return NewErrorNothingElseToAdd(err)
 		}
 		return fmt.Errorf("failed to run install preflights: %w", err)
 	}
 
-	if _, err := installAndStartCluster(ctx, flags, installCfg, rc, nil); err != nil {
+	if err := checkpoint.Run(installStepCluster, func() error {
+		return verifyClusterStarted(ctx)
+	}, func() error {
+		_, err := installAndStartCluster(ctx, flags, installCfg, rc, nil)
+		return err
+	}); err != nil {
 		return fmt.Errorf("failed to install cluster: %w", err)
 	}
 
@@ -662,30 +852,25 @@ func runInstall(ctx context.Context, flags installFlags, installCfg *installConf
 	errCh := kubeutils.WaitForKubernetes(ctx, kcli)
 	defer logKubernetesErrors(errCh)
 
-	in, err := kubeutils.RecordInstallation(ctx, kcli, buildRecordInstallationOptions(installCfg, rc))
-	if err != nil {
-		return fmt.Errorf("record installation: %w", err)
+	var in *ecv1beta1.Installation
+	if err := checkpoint.Run(installStepRecordInstallation, func() error {
+		in, err = verifyInstallationRecorded(ctx, kcli, installCfg.clusterID)
+		return err
+	}, func() error {
+		in, err = recordInstallation(ctx, kcli, installCfg, rc)
+		return err
+	}); err != nil {
+		return err
 	}
 
-	if err := ecmetadata.CreateVersionMetadataConfigmap(ctx, kcli); err != nil {
-		return fmt.Errorf("failed to create version metadata configmap: %w", err)
+	// from now on, every completed step is also reflected in the installation object
+	checkpoint.recordCheckpointsInInstallation(ctx, kcli, in)
+	if err := setInstallCheckpointCondition(ctx, kcli, in, installStepRecordInstallation); err != nil {
+		return err
 	}
 
 	// TODO (@salah): update installation status to reflect what's happening
 
-	// Only airgap installs use the in-cluster registry; online installs don't
-	// need the insecure-registry drop-in (and k0s 1.36+ rejects its legacy v1 format).
-	if installCfg.isAirgap {
-		logrus.Debugf("setup internal registry config for containerd to pull from the in-cluster registry")
-		registryIP, err := registry.GetRegistryClusterIP(rc.ServiceCIDR())
-		if err != nil {
-			return fmt.Errorf("failed to get registry cluster IP: %w", err)
-		}
-		if err := hostutils.AddInsecureRegistry(fmt.Sprintf("%s:5000", registryIP)); err != nil {
-			return fmt.Errorf("failed to add insecure registry: %w", err)
-		}
-	}
-
 	helmOpts := buildHelmClientOptions(installCfg, rc)
 
 	hcli, err := helm.NewClient(helmOpts)
@@ -695,12 +880,21 @@ func runInstall(ctx context.Context, flags installFlags, installCfg *installConf
 	defer hcli.Close()
 
 	logrus.Debugf("installing addons")
-	if err := installAddons(ctx, kcli, mcli, hcli, flags, installCfg, rc); err != nil {
+	if err := checkpoint.Run(installStepAddons, nil, func() error {
+		return installAddons(ctx, kcli, mcli, hcli, flags, installCfg, rc)
+	}); err != nil {
 		return err
 	}
 
 	logrus.Debugf("installing extensions")
-	if err := installExtensions(ctx, hcli, installCfg.isAirgap); err != nil {
+	if err := checkpoint.Run(installStepExtensions, nil, func() error {
+		if flags.resume {
+			if err := extensions.RemoveLeftovers(ctx, hcli); err != nil {
+				return fmt.Errorf("remove leftover extensions: %w", err)
+			}
+		}
+		return installExtensions(ctx, hcli, installCfg.isAirgap)
+	}); err != nil {
 		return fmt.Errorf("failed to install extensions: %w", err)
 	}
 
@@ -708,6 +902,11 @@ func runInstall(ctx context.Context, flags installFlags, installCfg *installConf
 		return fmt.Errorf("failed to update installation: %w", err)
 	}
 
+	// there is nothing left to resume
+	if err := checkpoint.Remove(); err != nil {
+		logrus.Warnf("failed to remove install checkpoint: %v", err)
+	}
+
 	if err = support.CreateHostSupportBundle(ctx, kcli); err != nil {
 		logrus.Warnf("failed to create host support bundle: %v", err)
 	}
@@ -717,9 +916,100 @@ func runInstall(ctx context.Context, flags installFlags, installCfg *installConf
 	return nil
 }
 
+// recordInstallation creates the installation object and the version metadata configmap, and
+// configures containerd to pull from the in-cluster registry on airgap installs.
+func recordInstallation(ctx context.Context, kcli client.Client, installCfg *installConfig, rc runtimeconfig.RuntimeConfig) (*ecv1beta1.Installation, error) {
+	in, err := kubeutils.RecordInstallation(ctx, kcli, buildRecordInstallationOptions(installCfg, rc))
+	if err != nil {
+		return nil, fmt.Errorf("record installation: %w", err)
+	}
+
+	if err := ecmetadata.CreateVersionMetadataConfigmap(ctx, kcli); err != nil {
+		return nil, fmt.Errorf("failed to create version metadata configmap: %w", err)
+	}
+
+	// Only airgap installs use the in-cluster registry; online installs don't
+	// need the insecure-registry drop-in (and k0s 1.36+ rejects its legacy v1 format).
+	if installCfg.isAirgap {
+		logrus.Debugf("setup internal registry config for containerd to pull from the in-cluster registry")
+		registryIP, err := registry.GetRegistryClusterIP(rc.ServiceCIDR())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get registry cluster IP: %w", err)
+		}
+		if err := hostutils.AddInsecureRegistry(fmt.Sprintf("%s:5000", registryIP)); err != nil {
+			return nil, fmt.Errorf("failed to add insecure registry: %w", err)
+		}
+	}
+
+	return in, nil
+}
+
+// verifyInstallInitialized makes sure the host was initialized by a previous run by checking
+// the runtime config has been written to disk.
+func verifyInstallInitialized() error {
+	if _, err := os.Stat(runtimeconfig.ECConfigPath); err != nil {
+		return fmt.Errorf("runtime config not found: %w", err)
+	}
+	return nil
+}
+
+// verifyClusterStarted makes sure k0s, installed by a previous run, is still running and the
+// node is ready.
+func verifyClusterStarted(ctx context.Context) error {
+	installed, err := k0s.IsInstalled()
+	if err != nil {
+		return err
+	}
+	if !installed {
+		return errors.New("k0s is not installed")
+	}
+
+	loading := spinner.Start()
+	loading.Infof("Waiting for node")
+	if err := k0s.WaitForK0s(); err != nil {
+		loading.ErrorClosef("Failed to install node")
+		return fmt.Errorf("wait for k0s: %w", err)
+	}
+	if err := waitForNode(ctx); err != nil {
+		loading.ErrorClosef("Node failed to become ready")
+		return fmt.Errorf("wait for node: %w", err)
+	}
+	loading.Closef("Node is ready")
+
+	return nil
+}
+
+// verifyInstallationRecorded returns the installation object recorded by a previous run,
+// making sure it belongs to the install being resumed.
+func verifyInstallationRecorded(ctx context.Context, kcli client.Client, clusterID string) (*ecv1beta1.Installation, error) {
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("get latest installation: %w", err)
+	}
+	if in.Spec.ClusterID != clusterID {
+		return nil, fmt.Errorf("installation %s belongs to cluster %s, expected %s", in.Name, in.Spec.ClusterID, clusterID)
+	}
+	return in, nil
+}
+
 // Hop: buildK0sConfig builds k0s cluster configuration from install flags and config
 func buildK0sConfig(flags *installFlags, installCfg *installConfig) (*k0sv1beta1.ClusterConfig, error) {
-	return k0s.NewK0sConfig(flags.networkInterface, installCfg.isAirgap, flags.cidrConfig.PodCIDR, flags.cidrConfig.ServiceCIDR, installCfg.endUserConfig, nil)
+	cidrCfg := flags.cidrConfig
+	mutate := func(cfg *k0sv1beta1.ClusterConfig) error {
+		if cidrCfg.IPv6PodCIDR != "" {
+			config.EnableDualStack(cfg, cidrCfg.IPv6PodCIDR, cidrCfg.IPv6ServiceCIDR)
+		}
+		if flags.podNetworkInterface != "" {
+			config.SetCalicoInterface(cfg, flags.podNetworkInterface)
+		}
+		return nil
+	}
+	// the api and etcd advertise the address of the control plane interface
+	controlPlaneInterface := flags.networkInterface
+	if flags.controlPlaneInterface != "" {
+		controlPlaneInterface = flags.controlPlaneInterface
+	}
+	return k0s.NewK0sConfig(controlPlaneInterface, installCfg.isAirgap, flags.cidrConfig.PodCIDR, flags.cidrConfig.ServiceCIDR, installCfg.endUserConfig, mutate)
 }
 
 // Hop: buildHelmClientOptions builds helm client options from install config and runtime config
@@ -792,13 +1082,15 @@ func buildKotsInstallOptions(installCfg *installConfig, flags installFlags, kots
 }
 
 func verifyAndPrompt(ctx context.Context, cmd *cobra.Command, appSlug string, flags *installFlags, installCfg *installConfig, prompt prompts.Prompt) error {
-	logrus.Debugf("checking if k0s is already installed")
-	err := verifyNoInstallation(appSlug, "reinstall")
-	if err != nil {
-		return err
+	// when resuming, an installation is expected to be (partially) present on this machine
+	if !flags.resume {
+		logrus.Debugf("checking if k0s is already installed")
+		if err := verifyNoInstallation(appSlug, "reinstall"); err != nil {
+			return err
+		}
 	}
 
-	err = verifyChannelRelease("installation", installCfg.isAirgap, flags.assumeYes)
+	err := verifyChannelRelease("installation", installCfg.isAirgap, flags.assumeYes)
 	if err != nil {
 		return err
 	}
@@ -1018,6 +1310,7 @@ func initializeInstall(ctx context.Context, flags installFlags, installCfg *inst
 	if err := hostutils.ConfigureHost(ctx, rc, release.GetChannelRelease(), hostutils.InitForInstallOptions{
 		License:      installCfg.licenseBytes,
 		AirgapBundle: flags.airgapBundle,
+		NTPServers:   flags.ntpServers,
 	}); err != nil {
 		spinner.ErrorClosef("Initialization failed")
 		return fmt.Errorf("configure host: %w", err)
@@ -1075,6 +1368,7 @@ func installAndStartCluster(ctx context.Context, flags installFlags, installCfg
 		loading.ErrorClosef("Node failed to become ready")
 		return nil, fmt.Errorf("wait for node: %w", err)
 	}
+	recordNodeFacts(ctx, flags.networkInterface)
 
 	loading.Closef("Node is ready")
 	return cfg, nil
@@ -1087,12 +1381,16 @@ func installAddons(ctx context.Context, kcli client.Client, mcli metadata.Interf
 	var loading *spinner.MessageWriter
 	go func() {
 		for progress := range progressChan {
+			emitAddOnProgress(progress)
 			switch progress.Status.State {
 			case apitypes.StateRunning:
 				loading = spinner.Start()
 				loading.Infof("Installing %s", progress.Name)
 			case apitypes.StateSucceeded:
 				loading.Closef("%s is ready", progress.Name)
+				if err := installCfg.checkpoint.AddOnInstalled(progress.Name); err != nil {
+					logrus.Warnf("failed to save install checkpoint: %v", err)
+				}
 			case apitypes.StateFailed:
 				loading.ErrorClosef("Failed to install %s", progress.Name)
 			}
@@ -1114,6 +1412,10 @@ func installAddons(ctx context.Context, kcli client.Client, mcli metadata.Interf
 	}
 
 	opts := buildAddonInstallOpts(flags, installCfg, rc, kotsadmNamespace, &loading)
+	if flags.resume {
+		opts.Resume = true
+		opts.InstalledAddOns = installCfg.checkpoint.AddOns()
+	}
 
 	if err := addOns.Install(ctx, *opts); err != nil {
 		return fmt.Errorf("install addons: %w", err)
@@ -1333,7 +1635,7 @@ func normalizeNoPromptToYes(f *pflag.FlagSet, name string) pflag.NormalizedName
 }
 
 func printSuccessMessage(license *kotsv1beta1.License, hostname string, networkInterface string, rc runtimeconfig.RuntimeConfig) {
-	adminConsoleURL := getAdminConsoleURL(hostname, networkInterface, rc.AdminConsolePort())
+	adminConsoleURL := getAdminConsoleURL(hostname, rc.AdminConsoleAddress(), networkInterface, rc.AdminConsolePort())
 
 	message := fmt.Sprintf("Visit the Admin Console to configure and install %s:", license.Spec.AppSlug)
 
@@ -1360,10 +1662,16 @@ func printSuccessMessage(license *kotsv1beta1.License, hostname string, networkI
 	logrus.Infof("%s%s%s\n", boldStart, divider, boldEnd)
 }
 
-func getAdminConsoleURL(hostname string, networkInterface string, port int) string {
+// getAdminConsoleURL returns the URL the admin console is reached on. The hostname and the
+// advertised address are used as given, the address is otherwise discovered from the cloud
+// provider or the network interface.
+func getAdminConsoleURL(hostname string, address string, networkInterface string, port int) string {
 	if hostname != "" {
 		return fmt.Sprintf("http://%s:%v", hostname, port)
 	}
+	if address != "" {
+		return fmt.Sprintf("http://%s", net.JoinHostPort(address, strconv.Itoa(port)))
+	}
 	ipaddr := cloudutils.TryDiscoverPublicIP()
 	if ipaddr == "" {
 		if addr := os.Getenv("EC_PUBLIC_ADDRESS"); addr != "" {
@@ -1371,13 +1679,17 @@ func getAdminConsoleURL(hostname string, networkInterface string, port int) stri
 		} else {
 			var err error
 			ipaddr, err = netutils.FirstValidAddress(networkInterface)
+			if err != nil {
+				// ipv6 only hosts
+				ipaddr, err = netutils.FirstValidIPv6Address(networkInterface)
+			}
 			if err != nil {
 				logrus.Errorf("failed to determine node IP address: %v", err)
 				ipaddr = "NODE-IP-ADDRESS"
 			}
 		}
 	}
-	return fmt.Sprintf("http://%s:%v", ipaddr, port)
+	return fmt.Sprintf("http://%s", net.JoinHostPort(ipaddr, strconv.Itoa(port)))
 }
 
 // logKubernetesErrors prints errors that may be related to k8s not coming up that manifest as
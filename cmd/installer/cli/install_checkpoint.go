@@ -0,0 +1,357 @@
+package cli
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// installCheckpointFile is the name of the file, relative to the data directory, where the
+// progress of an install is recorded so it can be resumed with `install --resume`.
+const installCheckpointFile = "install-checkpoint.json"
+
+// installStep identifies one of the sequential steps of an install.
+type installStep string
+
+const (
+	installStepInitialize         installStep = "initialize"
+	installStepHostPreflights     installStep = "host-preflights"
+	installStepCluster            installStep = "cluster"
+	installStepRecordInstallation installStep = "record-installation"
+	installStepAddons             installStep = "addons"
+	installStepExtensions         installStep = "extensions"
+)
+
+// installStepReasons maps each install step to the reason used in the install checkpoint
+// condition of the Installation object once the step is completed.
+var installStepReasons = map[installStep]string{
+	installStepInitialize:         "HostInitialized",
+	installStepHostPreflights:     "HostPreflightsPassed",
+	installStepCluster:            "ClusterReady",
+	installStepRecordInstallation: "InstallationRecorded",
+	installStepAddons:             "AddonsInstalled",
+	installStepExtensions:         "ExtensionsInstalled",
+}
+
+// installCheckpoint records which install steps have been completed. It is persisted in the
+// data directory after every step so an interrupted install can be resumed from the first
+// incomplete step.
+type installCheckpoint struct {
+	ClusterID string `json:"clusterID"`
+	// InputsDigest is the digest of the flags, license and configuration the install was
+	// started with. A resume is refused if the inputs are not the same.
+	InputsDigest    string        `json:"inputsDigest"`
+	CompletedSteps  []installStep `json:"completedSteps"`
+	InstalledAddOns []string      `json:"installedAddOns,omitempty"`
+	// CIDRs are the pod and service cidrs of the cluster. A resume reuses them instead of
+	// selecting the cidrs again, the host networks now include the routes of the cluster.
+	CIDRs     *newconfig.CIDRConfig `json:"cidrs,omitempty"`
+	UpdatedAt time.Time             `json:"updatedAt"`
+
+	path       string
+	onComplete func(step installStep) error
+	mu         sync.Mutex
+}
+
+func newInstallCheckpoint(rc runtimeconfig.RuntimeConfig, clusterID string, inputsDigest string) *installCheckpoint {
+	return &installCheckpoint{
+		ClusterID:    clusterID,
+		InputsDigest: inputsDigest,
+		path:         installCheckpointPath(rc),
+	}
+}
+
+func installCheckpointPath(rc runtimeconfig.RuntimeConfig) string {
+	return filepath.Join(rc.EmbeddedClusterHomeDirectory(), installCheckpointFile)
+}
+
+// readInstallCheckpoint reads the install checkpoint from the data directory. It returns an
+// error wrapping fs.ErrNotExist if no checkpoint has been recorded.
+func readInstallCheckpoint(rc runtimeconfig.RuntimeConfig) (*installCheckpoint, error) {
+	path := installCheckpointPath(rc)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read install checkpoint: %w", err)
+	}
+
+	cp := &installCheckpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("unmarshal install checkpoint: %w", err)
+	}
+	if cp.ClusterID == "" {
+		return nil, fmt.Errorf("install checkpoint %s has no cluster id", path)
+	}
+	cp.path = path
+
+	return cp, nil
+}
+
+// readResumedInstallCheckpoint reads the checkpoint of the interrupted install being resumed.
+func readResumedInstallCheckpoint(rc runtimeconfig.RuntimeConfig) (*installCheckpoint, error) {
+	cp, err := readInstallCheckpoint(rc)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no previous install found in %s, --resume requires the same --data-dir as the interrupted install", rc.EmbeddedClusterHomeDirectory())
+	}
+	return cp, err
+}
+
+// resumedInstallCIDRs returns the cidrs recorded by the interrupted install in the given data
+// directory. It returns nil if the checkpoint predates the recording of the cidrs.
+func resumedInstallCIDRs(dataDir string) (*newconfig.CIDRConfig, error) {
+	absoluteDataDir, err := filepath.Abs(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("construct path for directory: %w", err)
+	}
+	rc := runtimeconfig.New(nil)
+	rc.SetDataDir(absoluteDataDir)
+
+	cp, err := readResumedInstallCheckpoint(rc)
+	if err != nil {
+		return nil, err
+	}
+	return cp.CIDRs, nil
+}
+
+// buildInstallCheckpoint returns the checkpoint to use for this install. When resuming, the
+// checkpoint left behind by the interrupted install is loaded and its cluster id is reused,
+// otherwise a new empty checkpoint is returned. A resume is refused if the install inputs differ
+// from the ones of the interrupted install.
+func buildInstallCheckpoint(flags *installFlags, installCfg *installConfig, rc runtimeconfig.RuntimeConfig) (*installCheckpoint, error) {
+	digest, err := installInputsDigest(flags, installCfg)
+	if err != nil {
+		return nil, fmt.Errorf("compute install inputs digest: %w", err)
+	}
+
+	if !flags.resume {
+		cp := newInstallCheckpoint(rc, installCfg.clusterID, digest)
+		cp.CIDRs = flags.cidrConfig
+		return cp, nil
+	}
+
+	cp, err := readResumedInstallCheckpoint(rc)
+	if err != nil {
+		return nil, err
+	}
+	if cp.InputsDigest != digest {
+		return nil, fmt.Errorf("the flags, license or configuration differ from the ones of the interrupted install, --resume requires the same ones")
+	}
+	installCfg.clusterID = cp.ClusterID
+
+	return cp, nil
+}
+
+// installInputs holds the install inputs that shape the cluster. It is only used to compute
+// the digest that ties a checkpoint to the install that created it. The admin console password
+// is left out so it is not exposed to an offline guess from the digest.
+type installInputs struct {
+	License                 []byte                    `json:"license"`
+	AirgapBundle            string                    `json:"airgapBundle"`
+	Overrides               string                    `json:"overrides"`
+	ConfigValues            *kotsv1beta1.ConfigValues `json:"configValues"`
+	EndUserConfig           *ecv1beta1.Config         `json:"endUserConfig"`
+	DataDir                 string                    `json:"dataDir"`
+	AdminConsolePort        int                       `json:"adminConsolePort"`
+	LocalArtifactMirrorPort int                       `json:"localArtifactMirrorPort"`
+	NetworkInterface        string                    `json:"networkInterface"`
+	ControlPlaneInterface   string                    `json:"controlPlaneInterface"`
+	PodNetworkInterface     string                    `json:"podNetworkInterface"`
+	AdminConsoleAddress     string                    `json:"adminConsoleAddress"`
+	CIDRs                   *newconfig.CIDRConfig     `json:"cidrs"`
+	Proxy                   *ecv1beta1.ProxySpec      `json:"proxy"`
+	NTPServers              []string                  `json:"ntpServers"`
+	DNSUpstreams            []string                  `json:"dnsUpstreams"`
+	Hostname                string                    `json:"hostname"`
+	TLSCert                 []byte                    `json:"tlsCert"`
+}
+
+func installInputsDigest(flags *installFlags, installCfg *installConfig) (string, error) {
+	data, err := json.Marshal(installInputs{
+		License:                 installCfg.licenseBytes,
+		AirgapBundle:            flags.airgapBundle,
+		Overrides:               flags.overrides,
+		ConfigValues:            installCfg.configValues,
+		EndUserConfig:           installCfg.endUserConfig,
+		DataDir:                 flags.dataDir,
+		AdminConsolePort:        flags.adminConsolePort,
+		LocalArtifactMirrorPort: flags.localArtifactMirrorPort,
+		NetworkInterface:        flags.networkInterface,
+		ControlPlaneInterface:   flags.controlPlaneInterface,
+		PodNetworkInterface:     flags.podNetworkInterface,
+		AdminConsoleAddress:     flags.adminConsoleAddress,
+		CIDRs:                   flags.cidrConfig,
+		Proxy:                   flags.proxySpec,
+		NTPServers:              flags.ntpServers,
+		DNSUpstreams:            flags.dnsUpstreams,
+		Hostname:                flags.hostname,
+		TLSCert:                 installCfg.tlsCertBytes,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// IsCompleted returns true if the given step was completed by this or a previous run.
+func (c *installCheckpoint) IsCompleted(step installStep) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Contains(c.CompletedSteps, step)
+}
+
+// HasProgress returns true if at least one step has been completed.
+func (c *installCheckpoint) HasProgress() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.CompletedSteps) > 0
+}
+
+// Complete marks the given step as completed and persists the checkpoint.
+func (c *installCheckpoint) Complete(step installStep) error {
+	c.mu.Lock()
+	if !slices.Contains(c.CompletedSteps, step) {
+		c.CompletedSteps = append(c.CompletedSteps, step)
+	}
+	err := c.save()
+	onComplete := c.onComplete
+	c.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+	if onComplete != nil {
+		return onComplete(step)
+	}
+	return nil
+}
+
+// AddOnInstalled records that an addon was installed and persists the checkpoint.
+func (c *installCheckpoint) AddOnInstalled(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !slices.Contains(c.InstalledAddOns, name) {
+		c.InstalledAddOns = append(c.InstalledAddOns, name)
+	}
+	return c.save()
+}
+
+// AddOns returns the names of the addons installed so far.
+func (c *installCheckpoint) AddOns() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Clone(c.InstalledAddOns)
+}
+
+// Run runs fn unless the step was completed by a previous run, in which case verify is called
+// instead to make sure the outcome of the step is still in place. The step is marked as
+// completed once fn succeeds, and its progress is reported on the progress stream.
+func (c *installCheckpoint) Run(step installStep, verify func() error, fn func() error) error {
+	if c.IsCompleted(step) {
+		logrus.Debugf("install step %s already completed, verifying", step)
+		if verify != nil {
+			if err := verify(); err != nil {
+				err = fmt.Errorf("verify completed step %s: %w", step, err)
+				progress.Phase(string(step), progress.StatusFailed, err)
+				return err
+			}
+		}
+		progress.Phase(string(step), progress.StatusSkipped, nil)
+		return nil
+	}
+
+	if err := runPhase(string(step), fn); err != nil {
+		return err
+	}
+
+	if err := c.Complete(step); err != nil {
+		return fmt.Errorf("save install checkpoint: %w", err)
+	}
+	return nil
+}
+
+// save writes the checkpoint to disk. The caller must hold the lock.
+func (c *installCheckpoint) save() error {
+	c.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal install checkpoint: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create install checkpoint directory: %w", err)
+	}
+
+	// write to a temporary file first so an interruption never leaves a truncated checkpoint
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("write install checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("rename install checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint from the data directory once the install succeeded.
+func (c *installCheckpoint) Remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove install checkpoint: %w", err)
+	}
+	return nil
+}
+
+// recordCheckpointsInInstallation mirrors every step completed from now on in the install
+// checkpoint condition of the given Installation object.
+func (c *installCheckpoint) recordCheckpointsInInstallation(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.onComplete = func(step installStep) error {
+		return setInstallCheckpointCondition(ctx, kcli, in, step)
+	}
+}
+
+func setInstallCheckpointCondition(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, step installStep) error {
+	err := kubeutils.SetInstallationConditionStatus(ctx, kcli, in, metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeInstallCheckpoint,
+		Status:  metav1.ConditionTrue,
+		Reason:  installStepReasons[step],
+		Message: fmt.Sprintf("Install step %s completed", step),
+	})
+	if err != nil {
+		return fmt.Errorf("set install checkpoint condition: %w", err)
+	}
+	return nil
+}
+
+// printResumeHint tells the user how to continue an install that failed after making some
+// progress.
+func printResumeHint(appSlug string) {
+	logrus.Infof("\nThe installation can be continued from where it stopped by re-running the same command with --resume:")
+	logrus.Infof("\n  sudo ./%s install --resume <flags used for the failed install>\n", appSlug)
+}
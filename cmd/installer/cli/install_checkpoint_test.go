@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_buildInstallCheckpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		resume        bool
+		existing      string
+		wantClusterID string
+		wantSteps     []installStep
+		wantErr       string
+	}{
+		{
+			name:          "new install ignores existing checkpoint",
+			resume:        false,
+			existing:      `{"clusterID":"old-cluster-id","completedSteps":["initialize"]}`,
+			wantClusterID: "new-cluster-id",
+			wantSteps:     nil,
+		},
+		{
+			name:          "resume loads existing checkpoint",
+			resume:        true,
+			existing:      `{"clusterID":"old-cluster-id","inputsDigest":"$DIGEST","completedSteps":["initialize","host-preflights"]}`,
+			wantClusterID: "old-cluster-id",
+			wantSteps:     []installStep{installStepInitialize, installStepHostPreflights},
+		},
+		{
+			name:     "resume with different inputs",
+			resume:   true,
+			existing: `{"clusterID":"old-cluster-id","inputsDigest":"other","completedSteps":["initialize"]}`,
+			wantErr:  "--resume requires the same ones",
+		},
+		{
+			name:    "resume without checkpoint",
+			resume:  true,
+			wantErr: "no previous install found",
+		},
+		{
+			name:     "resume with checkpoint missing cluster id",
+			resume:   true,
+			existing: `{"completedSteps":["initialize"]}`,
+			wantErr:  "has no cluster id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			dataDir := t.TempDir()
+			rc := runtimeconfig.New(nil)
+			rc.SetDataDir(dataDir)
+
+			flags := &installFlags{resume: tt.resume, networkInterface: "eth0"}
+			installCfg := &installConfig{clusterID: "new-cluster-id", licenseBytes: []byte("license")}
+
+			if tt.existing != "" {
+				digest, err := installInputsDigest(flags, installCfg)
+				req.NoError(err)
+				existing := strings.ReplaceAll(tt.existing, "$DIGEST", digest)
+				err = os.WriteFile(filepath.Join(dataDir, installCheckpointFile), []byte(existing), 0600)
+				req.NoError(err)
+			}
+
+			cp, err := buildInstallCheckpoint(flags, installCfg, rc)
+			if tt.wantErr != "" {
+				req.ErrorContains(err, tt.wantErr)
+				return
+			}
+			req.NoError(err)
+
+			assert.Equal(t, tt.wantClusterID, cp.ClusterID)
+			assert.Equal(t, tt.wantClusterID, installCfg.clusterID)
+			assert.Equal(t, tt.wantSteps, cp.CompletedSteps)
+		})
+	}
+}
+
+func Test_installCheckpoint_Run(t *testing.T) {
+	tests := []struct {
+		name         string
+		completed    []installStep
+		verifyErr    error
+		fnErr        error
+		wantFnCalled bool
+		wantVerified bool
+		wantErr      bool
+		wantSteps    []installStep
+	}{
+		{
+			name:         "runs incomplete step and records it",
+			wantFnCalled: true,
+			wantSteps:    []installStep{installStepAddons},
+		},
+		{
+			name:         "does not record failed step",
+			fnErr:        errors.New("boom"),
+			wantFnCalled: true,
+			wantErr:      true,
+			wantSteps:    nil,
+		},
+		{
+			name:         "verifies completed step instead of running it",
+			completed:    []installStep{installStepAddons},
+			wantVerified: true,
+			wantSteps:    []installStep{installStepAddons},
+		},
+		{
+			name:         "fails when completed step cannot be verified",
+			completed:    []installStep{installStepAddons},
+			verifyErr:    errors.New("gone"),
+			wantVerified: true,
+			wantErr:      true,
+			wantSteps:    []installStep{installStepAddons},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			rc := runtimeconfig.New(nil)
+			rc.SetDataDir(t.TempDir())
+
+			cp := newInstallCheckpoint(rc, "cluster-id", "digest")
+			cp.CompletedSteps = tt.completed
+
+			var fnCalled, verified bool
+			err := cp.Run(installStepAddons, func() error {
+				verified = true
+				return tt.verifyErr
+			}, func() error {
+				fnCalled = true
+				return tt.fnErr
+			})
+			if tt.wantErr {
+				req.Error(err)
+			} else {
+				req.NoError(err)
+			}
+
+			assert.Equal(t, tt.wantFnCalled, fnCalled)
+			assert.Equal(t, tt.wantVerified, verified)
+			assert.Equal(t, tt.wantSteps, cp.CompletedSteps)
+
+			if tt.wantFnCalled && !tt.wantErr {
+				// the checkpoint must have been persisted so a later run can resume from it
+				loaded, err := readInstallCheckpoint(rc)
+				req.NoError(err)
+				assert.Equal(t, tt.wantSteps, loaded.CompletedSteps)
+				assert.Equal(t, "cluster-id", loaded.ClusterID)
+			}
+		})
+	}
+}
+
+func Test_installInputsDigest(t *testing.T) {
+	flags := &installFlags{networkInterface: "eth0", adminConsolePassword: "password"}
+	installCfg := &installConfig{licenseBytes: []byte("license")}
+
+	digest, err := installInputsDigest(flags, installCfg)
+	require.NoError(t, err)
+
+	// the password is not part of the inputs
+	flags.adminConsolePassword = "other"
+	same, err := installInputsDigest(flags, installCfg)
+	require.NoError(t, err)
+	assert.Equal(t, digest, same)
+
+	flags.networkInterface = "eth1"
+	changed, err := installInputsDigest(flags, installCfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+
+	flags.networkInterface = "eth0"
+	installCfg.licenseBytes = []byte("other license")
+	changed, err = installInputsDigest(flags, installCfg)
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, changed)
+}
+
+func Test_installCheckpoint_Remove(t *testing.T) {
+	rc := runtimeconfig.New(nil)
+	rc.SetDataDir(t.TempDir())
+
+	cp := newInstallCheckpoint(rc, "cluster-id", "digest")
+	require.NoError(t, cp.Complete(installStepInitialize))
+	require.FileExists(t, installCheckpointPath(rc))
+
+	require.NoError(t, cp.Remove())
+	assert.NoFileExists(t, installCheckpointPath(rc))
+
+	// removing it twice is not an error
+	require.NoError(t, cp.Remove())
+}
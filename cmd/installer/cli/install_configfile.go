@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/replicatedhq/embedded-cluster/pkg-new/installconfig"
+	"github.com/spf13/cobra"
+)
+
+func addConfigFileFlag(cmd *cobra.Command, flags *installFlags) error {
+	cmd.Flags().StringVar(&flags.configFile, "config-file", "", "Path to an InstallConfig file with the answers for the installation. Flags take precedence over values in the file.")
+	mustSetFlagTargetLinux(cmd.Flags(), "config-file")
+
+	return nil
+}
+
+// installConfigFlagValue maps a value from the InstallConfig file to the flag it stands for.
+type installConfigFlagValue struct {
+	flag  string
+	value string
+}
+
+// applyInstallConfigFile reads the InstallConfig file passed with --config-file, if any, and
+// sets every flag that was not explicitly provided on the command line to the value found in
+// the file. This must run before the required flags are validated.
+func applyInstallConfigFile(cmd *cobra.Command, flags *installFlags) error {
+	if flags.configFile == "" {
+		return nil
+	}
+
+	cfg, err := installconfig.ParseFile(flags.configFile)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range installConfigFlagValues(cmd, cfg.Spec) {
+		if v.value == "" || cmd.Flags().Lookup(v.flag) == nil || cmd.Flags().Changed(v.flag) {
+			continue
+		}
+		if err := cmd.Flags().Set(v.flag, v.value); err != nil {
+			return fmt.Errorf("unable to set --%s from config file: %w", v.flag, err)
+		}
+	}
+
+	return nil
+}
+
+// installConfigFlagValues returns the flag values held by the InstallConfig spec. Flags that
+// only make sense together (cidr ranges and the tls certificate and key) are taken either all
+// from the command line or all from the file.
+func installConfigFlagValues(cmd *cobra.Command, spec installconfig.InstallConfigSpec) []installConfigFlagValue {
+	values := []installConfigFlagValue{
+		{"license", spec.License},
+		{"airgap-bundle", spec.AirgapBundle},
+		{"config-values", spec.ConfigValues},
+		{"data-dir", spec.DataDir},
+		{"local-artifact-mirror-port", portFlagValue(spec.LocalArtifactMirrorPort)},
+		{"ignore-host-preflights", boolFlagValue(spec.IgnoreHostPreflights)},
+		{"ignore-app-preflights", boolFlagValue(spec.IgnoreAppPreflights)},
+		{"admin-console-port", portFlagValue(spec.AdminConsole.Port)},
+		{"admin-console-password", spec.AdminConsole.Password},
+		{"hostname", spec.AdminConsole.Hostname},
+		{"admin-console-address", spec.AdminConsole.Address},
+		{"network-interface", spec.Network.Interface},
+		{"control-plane-interface", spec.Network.ControlPlaneInterface},
+		{"pod-network-interface", spec.Network.PodNetworkInterface},
+		{"http-proxy", spec.Proxy.HTTPProxy},
+		{"https-proxy", spec.Proxy.HTTPSProxy},
+		{"no-proxy", spec.Proxy.NoProxy},
+	}
+
+	if !anyFlagChanged(cmd, "cidr", "pod-cidr", "service-cidr") {
+		values = append(values,
+			installConfigFlagValue{"cidr", spec.Network.CIDR},
+			installConfigFlagValue{"pod-cidr", spec.Network.PodCIDR},
+			installConfigFlagValue{"service-cidr", spec.Network.ServiceCIDR},
+		)
+	}
+	values = append(values, installConfigFlagValue{"ipv6-cidr", spec.Network.IPv6CIDR})
+
+	if !anyFlagChanged(cmd, "tls-cert", "tls-key") {
+		values = append(values,
+			installConfigFlagValue{"tls-cert", spec.AdminConsole.TLSCert},
+			installConfigFlagValue{"tls-key", spec.AdminConsole.TLSKey},
+		)
+	}
+
+	return values
+}
+
+func anyFlagChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func portFlagValue(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+func boolFlagValue(b bool) string {
+	if !b {
+		return ""
+	}
+	return "true"
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_applyInstallConfigFile(t *testing.T) {
+	configFile := `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: InstallConfig
+spec:
+  license: license.yaml
+  dataDir: /opt/from-file
+  ignoreHostPreflights: true
+  adminConsole:
+    port: 31000
+    password: file-password
+    tlsCert: tls.crt
+    tlsKey: tls.key
+  network:
+    podCIDR: 10.10.0.0/16
+    serviceCIDR: 10.20.0.0/16
+  proxy:
+    httpProxy: http://file-proxy:3128
+`
+
+	tests := []struct {
+		name   string
+		args   []string
+		assert func(t *testing.T, dir string, cmd *cobra.Command, flags *installFlags)
+	}{
+		{
+			name: "values from file fill unset flags",
+			assert: func(t *testing.T, dir string, cmd *cobra.Command, flags *installFlags) {
+				assert.Equal(t, filepath.Join(dir, "license.yaml"), flags.licenseFile)
+				assert.Equal(t, "/opt/from-file", flags.dataDir)
+				assert.True(t, flags.ignoreHostPreflights)
+				assert.Equal(t, 31000, flags.adminConsolePort)
+				assert.Equal(t, "file-password", flags.adminConsolePassword)
+				assert.Equal(t, filepath.Join(dir, "tls.crt"), flags.tlsCertFile)
+				assert.Equal(t, filepath.Join(dir, "tls.key"), flags.tlsKeyFile)
+				assert.Equal(t, "10.10.0.0/16", mustGetStringFlag(t, cmd, "pod-cidr"))
+				assert.Equal(t, "10.20.0.0/16", mustGetStringFlag(t, cmd, "service-cidr"))
+				assert.Equal(t, "http://file-proxy:3128", mustGetStringFlag(t, cmd, "http-proxy"))
+			},
+		},
+		{
+			name: "flags take precedence over the file",
+			args: []string{"--data-dir", "/opt/from-flag", "--admin-console-port", "32000"},
+			assert: func(t *testing.T, dir string, cmd *cobra.Command, flags *installFlags) {
+				assert.Equal(t, "/opt/from-flag", flags.dataDir)
+				assert.Equal(t, 32000, flags.adminConsolePort)
+				assert.Equal(t, filepath.Join(dir, "license.yaml"), flags.licenseFile)
+			},
+		},
+		{
+			name: "cidr flag replaces all network ranges from the file",
+			args: []string{"--cidr", "172.16.0.0/16"},
+			assert: func(t *testing.T, dir string, cmd *cobra.Command, flags *installFlags) {
+				assert.Equal(t, "172.16.0.0/16", mustGetStringFlag(t, cmd, "cidr"))
+				assert.False(t, cmd.Flags().Changed("pod-cidr"))
+				assert.False(t, cmd.Flags().Changed("service-cidr"))
+			},
+		},
+		{
+			name: "tls flags replace both tls values from the file",
+			args: []string{"--tls-cert", "/flag/tls.crt"},
+			assert: func(t *testing.T, dir string, cmd *cobra.Command, flags *installFlags) {
+				assert.Equal(t, "/flag/tls.crt", flags.tlsCertFile)
+				assert.Empty(t, flags.tlsKeyFile)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "install.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(configFile), 0600))
+
+			flags := &installFlags{}
+			cmd := &cobra.Command{}
+			mustAddInstallFlags(cmd, flags)
+			require.NoError(t, addInstallAdminConsoleFlags(cmd, flags))
+			require.NoError(t, addTLSFlags(cmd, flags))
+			require.NoError(t, addConfigFileFlag(cmd, flags))
+
+			require.NoError(t, cmd.ParseFlags(append(tt.args, "--config-file", path)))
+
+			require.NoError(t, applyInstallConfigFile(cmd, flags))
+			tt.assert(t, dir, cmd, flags)
+		})
+	}
+}
+
+func Test_applyInstallConfigFile_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "install.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("apiVersion: v1\nkind: InstallConfig\n"), 0600))
+
+	flags := &installFlags{configFile: path}
+	cmd := &cobra.Command{}
+	require.NoError(t, addConfigFileFlag(cmd, flags))
+	assert.Equal(t, []string{flagAnnotationTargetValueLinux}, cmd.Flags().Lookup("config-file").Annotations[flagAnnotationTarget])
+
+	err := applyInstallConfigFile(cmd, flags)
+	require.ErrorContains(t, err, "invalid install config")
+}
+
+func mustGetStringFlag(t *testing.T, cmd *cobra.Command, name string) string {
+	v, err := cmd.Flags().GetString(name)
+	require.NoError(t, err)
+	return v
+}
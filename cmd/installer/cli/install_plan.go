@@ -0,0 +1,254 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg-new/hostutils"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/kubernetesinstallation"
+	ecmetadata "github.com/replicatedhq/embedded-cluster/pkg-new/metadata"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	drtypes "github.com/replicatedhq/embedded-cluster/pkg/dryrun/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/extensions"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	nodeutil "k8s.io/component-helpers/node/util"
+	"sigs.k8s.io/yaml"
+)
+
+func addPlanFlag(cmd *cobra.Command, flags *installFlags) error {
+	cmd.Flags().BoolVar(&flags.plan, "plan", false, "Print the changes the installation would make to this host and the cluster without making them. Use --output json for a machine-readable plan.")
+	mustSetFlagTargetLinux(cmd.Flags(), "plan")
+
+	return nil
+}
+
+// planFileSections are the sections files are grouped in when the plan is printed as text.
+var planFileSections = []struct {
+	category drtypes.FileCategory
+	title    string
+}{
+	{drtypes.FileCategoryMaterialized, "Files materialized"},
+	{drtypes.FileCategorySystemd, "Systemd units"},
+	{drtypes.FileCategorySysctl, "Sysctl configuration"},
+	{drtypes.FileCategoryKernelModules, "Kernel modules"},
+	{drtypes.FileCategoryNetworkManager, "NetworkManager configuration"},
+	{drtypes.FileCategoryContainerd, "Containerd configuration"},
+	{drtypes.FileCategoryTimeSync, "Time synchronization"},
+	{drtypes.FileCategoryK0s, "k0s"},
+}
+
+// runInstallPlan prints the changes an install with the given flags would make. The install
+// runs with dry run enabled so the changes are recorded rather than made.
+func runInstallPlan(ctx context.Context, cmd *cobra.Command, appSlug string, flags *installFlags, rc runtimeconfig.RuntimeConfig, ki kubernetesinstallation.Installation) error {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("unable to get output flag: %w", err)
+	}
+	if format != outputFormatText && format != outputFormatJSON {
+		return fmt.Errorf("invalid output format %q, must be one of %s or %s", format, outputFormatText, outputFormatJSON)
+	}
+
+	// stdout is reserved for the plan
+	stdoutLoggerOutput = os.Stderr
+
+	installCfg, err := preRunInstall(cmd, flags, rc, ki)
+	if err != nil {
+		return err
+	}
+
+	// the checks must run before dry run is enabled as they inspect the host
+	if err := verifyNoInstallation(appSlug, "reinstall"); err != nil {
+		return err
+	}
+	verifiedLicense, err := verifyLicense(installCfg.license)
+	if err != nil {
+		return err
+	}
+	installCfg.license = verifiedLicense
+	if err := release.ValidateECConfig(); err != nil {
+		return err
+	}
+
+	dryrun.InitPlan()
+
+	plan, err := buildInstallPlan(ctx, *flags, installCfg, rc)
+	if err != nil {
+		return fmt.Errorf("unable to plan installation: %w", err)
+	}
+
+	return printInstallPlan(os.Stdout, format, plan)
+}
+
+// buildInstallPlan goes through the same steps as an install, recording the changes of each of
+// them. Waiting for the cluster is skipped as nothing is started.
+func buildInstallPlan(ctx context.Context, flags installFlags, installCfg *installConfig, rc runtimeconfig.RuntimeConfig) (*drtypes.Plan, error) {
+	if err := hostutils.ConfigureHost(ctx, rc, release.GetChannelRelease(), hostutils.InitForInstallOptions{
+		License:      installCfg.licenseBytes,
+		AirgapBundle: flags.airgapBundle,
+		NTPServers:   flags.ntpServers,
+	}); err != nil {
+		return nil, fmt.Errorf("configure host: %w", err)
+	}
+
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect hostname: %w", err)
+	}
+
+	cfg, err := buildK0sConfig(&flags, installCfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build k0s config: %w", err)
+	}
+	if err := k0s.WriteK0sConfig(ctx, cfg); err != nil {
+		return nil, fmt.Errorf("create config file: %w", err)
+	}
+	if err := hostutils.CreateSystemdUnitFiles(ctx, logrus.StandardLogger(), rc, hostname, false); err != nil {
+		return nil, fmt.Errorf("create systemd unit files: %w", err)
+	}
+	if err := k0s.Install(rc, hostname); err != nil {
+		return nil, fmt.Errorf("install cluster: %w", err)
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kube client: %w", err)
+	}
+	mcli, err := kubeutils.MetadataClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata client: %w", err)
+	}
+
+	if _, err := recordInstallation(ctx, kcli, installCfg, rc); err != nil {
+		return nil, err
+	}
+
+	hcli, err := helm.NewClient(buildHelmClientOptions(installCfg, rc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create helm client: %w", err)
+	}
+	defer hcli.Close()
+
+	kotsadmNamespace, err := runtimeconfig.KotsadmNamespace(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("get kotsadm namespace: %w", err)
+	}
+
+	var loading *spinner.MessageWriter
+	opts := buildAddonInstallOpts(flags, installCfg, rc, kotsadmNamespace, &loading)
+	opts.KotsInstaller = func() error {
+		dryrun.RecordCommand("kubectl-kots", []string{"install", installCfg.license.Spec.AppSlug, "--namespace", kotsadmNamespace}, nil)
+		return nil
+	}
+
+	addOns := addons.New(
+		addons.WithLogFunc(logrus.Debugf),
+		addons.WithKubernetesClient(kcli),
+		addons.WithMetadataClient(mcli),
+		addons.WithHelmClient(hcli),
+		addons.WithDomains(getDomains()),
+	)
+	if err := addOns.Install(ctx, *opts); err != nil {
+		return nil, fmt.Errorf("install addons: %w", err)
+	}
+
+	if err := extensions.Install(ctx, hcli, nil, installCfg.isAirgap); err != nil {
+		return nil, fmt.Errorf("install extensions: %w", err)
+	}
+
+	metadata, err := ecmetadata.GatherVersionMetadata(release.GetChannelRelease())
+	if err != nil {
+		return nil, fmt.Errorf("gather version metadata: %w", err)
+	}
+	dryrun.RecordImages(metadata.Images)
+
+	return dryrun.GetPlan(), nil
+}
+
+// printInstallPlan writes the plan to w in the given format.
+func printInstallPlan(w io.Writer, format string, plan *drtypes.Plan) error {
+	if format == outputFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+
+	fmt.Fprintln(w, "The installation would make the following changes. Nothing has been changed.")
+
+	for _, section := range planFileSections {
+		var files []drtypes.File
+		for _, file := range plan.Files {
+			if file.Category == section.category {
+				files = append(files, file)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		for _, file := range files {
+			line := "  " + file.Path
+			if file.LinkTarget != "" {
+				line += " -> " + file.LinkTarget
+			}
+			if file.Note != "" {
+				line += fmt.Sprintf(" (%s)", file.Note)
+			}
+			fmt.Fprintln(w, line)
+			if file.Content != "" {
+				fmt.Fprintln(w, indentLines(file.Content, "      "))
+			}
+		}
+	}
+
+	if len(plan.Commands) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, command := range plan.Commands {
+			fmt.Fprintf(w, "  %s\n", command.Cmd)
+		}
+	}
+
+	if len(plan.HelmReleases) > 0 {
+		fmt.Fprintln(w, "\nHelm releases:")
+		for _, rel := range plan.HelmReleases {
+			fmt.Fprintf(w, "  %s in namespace %s (chart %s, version %s)\n", rel.Name, rel.Namespace, rel.Chart, rel.Version)
+			if len(rel.Values) == 0 {
+				continue
+			}
+			values, err := yaml.Marshal(rel.Values)
+			if err != nil {
+				return fmt.Errorf("marshal %s values: %w", rel.Name, err)
+			}
+			fmt.Fprintln(w, indentLines(string(values), "      "))
+		}
+	}
+
+	if len(plan.Images) > 0 {
+		fmt.Fprintln(w, "\nImages:")
+		for _, image := range plan.Images {
+			fmt.Fprintf(w, "  %s\n", image)
+		}
+	}
+
+	return nil
+}
+
+func indentLines(s string, prefix string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
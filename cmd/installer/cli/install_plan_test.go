@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	drtypes "github.com/replicatedhq/embedded-cluster/pkg/dryrun/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_printInstallPlan(t *testing.T) {
+	plan := &drtypes.Plan{
+		Files: []drtypes.File{
+			{Category: drtypes.FileCategoryK0s, Path: "/etc/k0s/k0s.yaml", Content: "apiVersion: k0s.k0sproject.io/v1beta1\nkind: ClusterConfig\n"},
+			{Category: drtypes.FileCategoryMaterialized, Path: "/var/lib/embedded-cluster/bin/k0s"},
+			{Category: drtypes.FileCategorySystemd, Path: "/etc/systemd/system/app.service", LinkTarget: "/etc/systemd/system/k0scontroller.service"},
+			{Category: drtypes.FileCategorySysctl, Path: "/etc/sysctl.d/99-dynamic-embedded-cluster.conf", Note: "raises the limits"},
+		},
+		Commands: []drtypes.Command{
+			{Cmd: "firewall-cmd --permanent --zone ec-net --set-target ACCEPT"},
+		},
+		HelmReleases: []drtypes.HelmRelease{
+			{Name: "openebs", Namespace: "openebs", Chart: "oci://registry/openebs", Version: "4.2.0", Values: map[string]interface{}{"engines": "local"}},
+		},
+		Images: []string{"proxy.replicated.com/anonymous/openebs:4.2.0"},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, printInstallPlan(buf, outputFormatText, plan))
+
+		want := `The installation would make the following changes. Nothing has been changed.
+
+Files materialized:
+  /var/lib/embedded-cluster/bin/k0s
+
+Systemd units:
+  /etc/systemd/system/app.service -> /etc/systemd/system/k0scontroller.service
+
+Sysctl configuration:
+  /etc/sysctl.d/99-dynamic-embedded-cluster.conf (raises the limits)
+
+k0s:
+  /etc/k0s/k0s.yaml
+      apiVersion: k0s.k0sproject.io/v1beta1
+      kind: ClusterConfig
+
+Commands:
+  firewall-cmd --permanent --zone ec-net --set-target ACCEPT
+
+Helm releases:
+  openebs in namespace openebs (chart oci://registry/openebs, version 4.2.0)
+      engines: local
+
+Images:
+  proxy.replicated.com/anonymous/openebs:4.2.0
+`
+		assert.Equal(t, want, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, printInstallPlan(buf, outputFormatJSON, plan))
+
+		var got drtypes.Plan
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, *plan, got)
+	})
+}
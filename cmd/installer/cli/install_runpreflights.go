@@ -14,6 +14,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -68,6 +69,7 @@ func runInstallRunPreflights(ctx context.Context, flags installFlags, installCfg
 	if err := hostutils.ConfigureHost(ctx, rc, release.GetChannelRelease(), hostutils.InitForInstallOptions{
 		License:      installCfg.licenseBytes,
 		AirgapBundle: flags.airgapBundle,
+		NTPServers:   flags.ntpServers,
 	}); err != nil {
 		return fmt.Errorf("configure host: %w", err)
 	}
@@ -86,13 +88,29 @@ func runInstallRunPreflights(ctx context.Context, flags installFlags, installCfg
 }
 
 func runInstallPreflights(ctx context.Context, flags installFlags, installCfg *installConfig, rc runtimeconfig.RuntimeConfig, metricsReporter metrics.ReporterInterface) error {
+	hpf, err := prepareInstallHostPreflights(ctx, flags, installCfg, rc)
+	if err != nil {
+		return err
+	}
+
+	if err := runHostPreflights(ctx, hpf, rc, "install", hostPreflightsRunOptions(rc, flags.disableFilesystemPerformanceCheck), flags.skipHostPreflights, flags.ignoreHostPreflights, flags.assumeYes, metricsReporter); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// prepareInstallHostPreflights returns the host preflights to run before installing.
+func prepareInstallHostPreflights(ctx context.Context, flags installFlags, installCfg *installConfig, rc runtimeconfig.RuntimeConfig) (*troubleshootv1beta2.HostPreflightSpec, error) {
 	replicatedAppURL := replicatedAppURL()
 	proxyRegistryURL := proxyRegistryURL()
 
-	nodeIP, err := netutils.FirstValidAddress(rc.NetworkInterface())
+	// the primary address of the node is in the family of the pod cidr
+	nodeIPs, err := netutils.NodeAddresses(rc.NetworkInterface(), netutils.ClusterIPFamily(rc.PodCIDR(), ""))
 	if err != nil {
-		return fmt.Errorf("unable to find first valid address: %w", err)
+		return nil, fmt.Errorf("unable to find first valid address: %w", err)
 	}
+	nodeIP := nodeIPs[0]
 
 	// Calculate airgap storage space requirement
 	var controllerAirgapStorageSpace string
@@ -117,24 +135,19 @@ func runInstallPreflights(ctx context.Context, flags installFlags, installCfg *i
 		Proxy:                             rc.ProxySpec(),
 		PodCIDR:                           rc.PodCIDR(),
 		ServiceCIDR:                       rc.ServiceCIDR(),
+		IPv6PodCIDR:                       rc.IPv6PodCIDR(),
+		IPv6ServiceCIDR:                   rc.IPv6ServiceCIDR(),
 		NodeIP:                            nodeIP,
 		IsAirgap:                          installCfg.isAirgap,
 		ControllerAirgapStorageSpace:      controllerAirgapStorageSpace,
 		DisableFilesystemPerformanceCheck: flags.disableFilesystemPerformanceCheck,
 		K8sVersion:                        versions.K0sVersion,
+		VendorHostPreflights:              release.GetRawHostPreflights(),
+		VendorVariables:                   vendorHostPreflightVariables(),
 	}
 	if globalCIDR := rc.GlobalCIDR(); globalCIDR != "" {
 		opts.GlobalCIDR = &globalCIDR
 	}
 
-	hpf, err := preflights.PrepareHostPreflights(ctx, opts)
-	if err != nil {
-		return err
-	}
-
-	if err := runHostPreflights(ctx, hpf, rc, flags.skipHostPreflights, flags.ignoreHostPreflights, flags.assumeYes, metricsReporter); err != nil {
-		return err
-	}
-
-	return nil
+	return preflights.PrepareHostPreflights(ctx, opts)
 }
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"testing"
@@ -20,6 +21,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
 	"github.com/replicatedhq/embedded-cluster/pkg/prompts/plain"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
@@ -43,6 +45,11 @@ func (m *mockNetworkLookup) FirstValidIPNet(networkInterface string) (*net.IPNet
 	return ipnet, nil
 }
 
+func (m *mockNetworkLookup) FirstValidIPv6Net(networkInterface string) (*net.IPNet, error) {
+	_, ipnet, _ := net.ParseCIDR("fd00:1::/64")
+	return ipnet, nil
+}
+
 // Helper function to create bool pointer
 func boolPtr(b bool) *bool {
 	return &b
@@ -198,6 +205,11 @@ func Test_buildInstallFlags_ProxyConfig(t *testing.T) {
 			// Override the network lookup with our mock
 			defaultNetworkLookupImpl = &mockNetworkLookup{}
 
+			// Override the host networks so the cidrs don't depend on the networks of the test host
+			originalListHostNetworks := listHostNetworks
+			listHostNetworks = func() ([]netutils.HostNetwork, error) { return nil, nil }
+			t.Cleanup(func() { listHostNetworks = originalListHostNetworks })
+
 			err := buildInstallFlags(cmd, flags)
 			assert.NoError(t, err, "unexpected error")
 			assert.Equal(t, tt.want, flags.proxySpec)
@@ -299,11 +311,19 @@ func Test_buildInstallFlags_CIDRConfig(t *testing.T) {
 	defaultPodCIDR, defaultServiceCIDR, err := newconfig.SplitCIDR(ecv1beta1.DefaultNetworkCIDR)
 	assert.NoError(t, err, "failed to split default CIDR")
 
+	hostNetworks := []netutils.HostNetwork{
+		{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Interface: "eth0", Address: "192.168.1.10"},
+		{Prefix: netip.MustParsePrefix("10.244.0.0/16"), Interface: "tun0"},
+		{Prefix: netip.MustParsePrefix("10.245.0.0/16"), Interface: "docker0", Address: "10.245.0.1"},
+	}
+
 	tests := []struct {
-		name        string
-		init        func(t *testing.T, flagSet *pflag.FlagSet)
-		expected    *newconfig.CIDRConfig
-		expectError bool
+		name                string
+		init                func(t *testing.T, flagSet *pflag.FlagSet)
+		hostNetworks        []netutils.HostNetwork
+		expected            *newconfig.CIDRConfig
+		expectError         bool
+		expectErrorContains string
 	}{
 		{
 			name: "with pod and service flags",
@@ -378,6 +398,62 @@ func Test_buildInstallFlags_CIDRConfig(t *testing.T) {
 				GlobalCIDR:  ptr.To(ecv1beta1.DefaultNetworkCIDR),
 			},
 		},
+		{
+			name:         "with no flags and the default cidr in use - should pick the first free candidate",
+			hostNetworks: hostNetworks,
+			expected: &newconfig.CIDRConfig{
+				PodCIDR:     "10.246.0.0/17",
+				ServiceCIDR: "10.246.128.0/17",
+				GlobalCIDR:  ptr.To("10.246.0.0/16"),
+			},
+		},
+		{
+			name: "with cidr candidates flag",
+			init: func(t *testing.T, flagSet *pflag.FlagSet) {
+				flagSet.Set("cidr-candidates", "10.245.0.0/16,172.28.0.0/16")
+			},
+			hostNetworks: hostNetworks,
+			expected: &newconfig.CIDRConfig{
+				PodCIDR:     "172.28.0.0/17",
+				ServiceCIDR: "172.28.128.0/17",
+				GlobalCIDR:  ptr.To("172.28.0.0/16"),
+			},
+		},
+		{
+			name: "with no free cidr candidate - should error",
+			init: func(t *testing.T, flagSet *pflag.FlagSet) {
+				flagSet.Set("cidr-candidates", "10.244.0.0/16,10.245.0.0/16")
+			},
+			hostNetworks:        hostNetworks,
+			expectError:         true,
+			expectErrorContains: "all candidate cidrs (10.244.0.0/16, 10.245.0.0/16) overlap with the networks of this host",
+		},
+		{
+			name: "with ipv6 cidr candidate - should error",
+			init: func(t *testing.T, flagSet *pflag.FlagSet) {
+				flagSet.Set("cidr-candidates", "fd00:10::/48")
+			},
+			expectError:         true,
+			expectErrorContains: "--cidr-candidates only accepts IPv4 cidrs",
+		},
+		{
+			name: "with cidr flag overlapping a vpn route - should error",
+			init: func(t *testing.T, flagSet *pflag.FlagSet) {
+				flagSet.Set("cidr", "10.244.0.0/16")
+			},
+			hostNetworks:        hostNetworks,
+			expectError:         true,
+			expectErrorContains: "the pod cidr 10.244.0.0/17 overlaps with the route to 10.244.0.0/16 via tun0 (VPN)",
+		},
+		{
+			name: "with service flag overlapping a docker bridge - should error",
+			init: func(t *testing.T, flagSet *pflag.FlagSet) {
+				flagSet.Set("service-cidr", "10.245.128.0/17")
+			},
+			hostNetworks:        hostNetworks,
+			expectError:         true,
+			expectErrorContains: "the service cidr 10.245.128.0/17 overlaps with the address 10.245.0.1 of docker0 (Docker bridge)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -397,10 +473,17 @@ func Test_buildInstallFlags_CIDRConfig(t *testing.T) {
 				tt.init(t, flagSet)
 			}
 
+			originalListHostNetworks := listHostNetworks
+			listHostNetworks = func() ([]netutils.HostNetwork, error) { return tt.hostNetworks, nil }
+			t.Cleanup(func() { listHostNetworks = originalListHostNetworks })
+
 			err := buildInstallFlags(cmd, flags)
 
 			if tt.expectError {
 				assert.Error(t, err)
+				if tt.expectErrorContains != "" {
+					assert.Contains(t, err.Error(), tt.expectErrorContains)
+				}
 			} else {
 				assert.NoError(t, err, "unexpected error")
 				assert.Equal(t, tt.expected, flags.cidrConfig)
@@ -409,6 +492,60 @@ func Test_buildInstallFlags_CIDRConfig(t *testing.T) {
 	}
 }
 
+func Test_buildInstallFlags_ResumeCIDR(t *testing.T) {
+	dataDir := t.TempDir()
+	rc := runtimeconfig.New(nil)
+	rc.SetDataDir(dataDir)
+	installCfg := &installConfig{clusterID: "cluster-id", licenseBytes: []byte("license")}
+
+	newCmd := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		mustAddCIDRFlags(cmd.Flags())
+		mustAddProxyFlags(cmd.Flags())
+		return cmd
+	}
+
+	var hostNetworks []netutils.HostNetwork
+	originalListHostNetworks := listHostNetworks
+	listHostNetworks = func() ([]netutils.HostNetwork, error) { return hostNetworks, nil }
+	t.Cleanup(func() { listHostNetworks = originalListHostNetworks })
+
+	// the interrupted install selects the cidr and records it in its checkpoint
+	flags := &installFlags{networkInterface: "eth0", dataDir: dataDir}
+	require.NoError(t, buildInstallFlags(newCmd(), flags))
+	cp, err := buildInstallCheckpoint(flags, installCfg, rc)
+	require.NoError(t, err)
+	require.NoError(t, cp.Complete(installStepInitialize))
+	installed := flags.cidrConfig
+
+	// the host now routes the cidr to the cluster, with the blackhole route Calico adds for the
+	// block of the pod cidr and the service addresses of kube-proxy
+	hostNetworks = []netutils.HostNetwork{
+		{Prefix: netip.MustParsePrefix("10.244.0.0/26"), Interface: "*"},
+		{Prefix: netip.MustParsePrefix("10.244.128.1/32"), Interface: "kube-ipvs0", Address: "10.244.128.1"},
+	}
+
+	// selecting the cidr again would pick another range than the one installed
+	flags = &installFlags{networkInterface: "eth0", dataDir: dataDir}
+	require.NoError(t, buildInstallFlags(newCmd(), flags))
+	assert.Equal(t, ptr.To("10.245.0.0/16"), flags.cidrConfig.GlobalCIDR)
+
+	// and the cidr of the interrupted install overlaps with the host networks
+	cmd := newCmd()
+	require.NoError(t, cmd.Flags().Set("cidr", *installed.GlobalCIDR))
+	flags = &installFlags{networkInterface: "eth0", dataDir: dataDir}
+	err = buildInstallFlags(cmd, flags)
+	require.ErrorContains(t, err, "overlaps with the route to 10.244.0.0/26 via *")
+
+	flags = &installFlags{networkInterface: "eth0", dataDir: dataDir, resume: true}
+	require.NoError(t, buildInstallFlags(newCmd(), flags))
+	assert.Equal(t, installed, flags.cidrConfig)
+
+	resumed, err := buildInstallCheckpoint(flags, installCfg, rc)
+	require.NoError(t, err)
+	assert.Equal(t, []installStep{installStepInitialize}, resumed.CompletedSteps)
+}
+
 func Test_buildInstallFlags_TLSValidation(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -952,6 +1089,25 @@ func Test_buildRuntimeConfig(t *testing.T) {
 				req.Equal("10.0.0.0/16", rc.GlobalCIDR())
 			},
 		},
+		{
+			name: "with dns upstreams",
+			flags: &installFlags{
+				adminConsolePort:        8800,
+				managerPort:             8801,
+				localArtifactMirrorPort: 8802,
+				cidrConfig: &newconfig.CIDRConfig{
+					PodCIDR:     "10.0.0.0/24",
+					ServiceCIDR: "10.1.0.0/24",
+				},
+				dnsUpstreams: []string{"10.10.0.53", "10.10.1.53"},
+			},
+			installCfg: &installConfig{},
+			wantErr:    false,
+			validate: func(t *testing.T, rc runtimeconfig.RuntimeConfig) {
+				req := require.New(t)
+				req.Equal([]string{"10.10.0.53", "10.10.1.53"}, rc.DNSUpstreams())
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -1513,6 +1669,24 @@ func Test_buildK0sConfig(t *testing.T) {
 				req := require.New(t)
 				req.Equal("172.16.0.0/20", cfg.Spec.Network.PodCIDR)
 				req.Equal("172.17.0.0/20", cfg.Spec.Network.ServiceCIDR)
+				req.Empty(cfg.Spec.Network.Calico.IPAutodetectionMethod)
+			},
+		},
+		{
+			name: "pod network interface sets the calico autodetection",
+			flags: &installFlags{
+				podNetworkInterface: "eth1|ens.*",
+				cidrConfig: &newconfig.CIDRConfig{
+					PodCIDR:     "10.0.0.0/24",
+					ServiceCIDR: "10.1.0.0/24",
+				},
+			},
+			installCfg: &installConfig{},
+			wantErr:    false,
+			validate: func(t *testing.T, cfg *k0sv1beta1.ClusterConfig) {
+				req := require.New(t)
+				req.Equal("interface=eth1|ens.*", cfg.Spec.Network.Calico.IPAutodetectionMethod)
+				req.Equal("interface=eth1|ens.*", cfg.Spec.Network.Calico.IPv6AutodetectionMethod)
 			},
 		},
 	}
@@ -0,0 +1,452 @@
+package cli
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+
+	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/spf13/cobra"
+)
+
+const (
+	wizardTLSSelfSigned = "Generate a self-signed certificate"
+	wizardTLSProvided   = "Use my own certificate and key"
+
+	wizardPreflightsRerun    = "Re-run host preflights"
+	wizardPreflightsContinue = "Continue with the installation"
+	wizardPreflightsAbort    = "Abort the installation"
+)
+
+func addWizardFlag(cmd *cobra.Command, flags *installFlags) error {
+	cmd.Flags().BoolVar(&flags.wizard, "wizard", false, "Walk through the installation options and host preflights in a full-screen terminal wizard")
+	mustSetFlagTargetLinux(cmd.Flags(), "wizard")
+
+	return nil
+}
+
+// validateWizardFlags makes sure the wizard can be used with the other flags provided. The
+// wizard needs a terminal to ask its questions.
+func validateWizardFlags(cmd *cobra.Command, flags *installFlags) error {
+	if flags.plan {
+		return fmt.Errorf("--wizard cannot be used with --plan")
+	}
+	if flags.assumeYes {
+		return fmt.Errorf("--wizard cannot be used with --yes")
+	}
+	if format, _ := cmd.Flags().GetString("output"); format == outputFormatJSON {
+		return fmt.Errorf("--wizard cannot be used with --output json")
+	}
+	if !prompts.IsTerminal() {
+		return fmt.Errorf("--wizard requires an interactive terminal")
+	}
+	return nil
+}
+
+// installWizard asks for the installation options one screen at a time and sets the matching
+// flags, so the rest of the install flow handles them as if they had been passed on the command
+// line.
+type installWizard struct {
+	appTitle         string
+	prompt           prompts.Prompt
+	out              io.Writer
+	listInterfaces   func() ([]netutils.NetworkInterface, error)
+	defaultInterface func() (string, error)
+}
+
+type installWizardStep struct {
+	name string
+	run  func(cmd *cobra.Command) error
+}
+
+func newInstallWizard(appTitle string) *installWizard {
+	return &installWizard{
+		appTitle:         appTitle,
+		prompt:           prompts.New(),
+		out:              humanOutput(),
+		listInterfaces:   netutils.ListValidNetworkInterfaces,
+		defaultInterface: newconfig.DetermineBestNetworkInterface,
+	}
+}
+
+// Run walks through every step and repeats them until the user accepts the settings in the
+// review screen.
+func (w *installWizard) Run(cmd *cobra.Command) error {
+	steps := []installWizardStep{
+		{"Network interface", w.networkInterfaceStep},
+		{"Network ranges", w.cidrStep},
+		{"Proxy", w.proxyStep},
+		{"Admin Console TLS", w.tlsStep},
+		{"Data directory", w.dataDirStep},
+		{"Review", nil},
+	}
+
+	for {
+		for i, step := range steps[:len(steps)-1] {
+			w.header(fmt.Sprintf("Step %d of %d: %s", i+1, len(steps), step.name))
+			if err := step.run(cmd); err != nil {
+				return err
+			}
+		}
+
+		w.header(fmt.Sprintf("Step %d of %d: %s", len(steps), len(steps), steps[len(steps)-1].name))
+		confirmed, err := w.reviewStep(cmd)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			return nil
+		}
+	}
+}
+
+// header clears the screen and prints the title of the current screen.
+func (w *installWizard) header(title string) {
+	printWizardHeader(w.out, fmt.Sprintf("Install %s: %s", w.appTitle, title))
+}
+
+func (w *installWizard) networkInterfaceStep(cmd *cobra.Command) error {
+	ifaces, err := w.listInterfaces()
+	if err != nil {
+		return fmt.Errorf("unable to list network interfaces: %w", err)
+	}
+	if len(ifaces) == 0 {
+		return fmt.Errorf("no valid network interfaces found")
+	}
+
+	current, _ := cmd.Flags().GetString("network-interface")
+	if current == "" {
+		current, _ = w.defaultInterface()
+	}
+
+	options := make([]string, 0, len(ifaces))
+	names := map[string]string{}
+	defvalue := ""
+	for _, iface := range ifaces {
+		option := networkInterfaceOption(iface)
+		options = append(options, option)
+		names[option] = iface.Name()
+		if iface.Name() == current || defvalue == "" {
+			defvalue = option
+		}
+	}
+
+	fmt.Fprintln(w.out, "Nodes in the cluster talk to each other over this interface.")
+	choice, err := w.prompt.Select("Network interface:", options, defvalue)
+	if err != nil {
+		return fmt.Errorf("failed to get network interface: %w", err)
+	}
+
+	return setWizardFlag(cmd, "network-interface", names[choice])
+}
+
+// networkInterfaceOption returns how an interface is listed, with its addresses so users can
+// tell the interfaces apart.
+func networkInterfaceOption(iface netutils.NetworkInterface) string {
+	addrs, err := iface.Addrs()
+	if err != nil || len(addrs) == 0 {
+		return iface.Name()
+	}
+
+	ips := []string{}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipnet.IP.String())
+		}
+	}
+	if len(ips) == 0 {
+		return iface.Name()
+	}
+	return fmt.Sprintf("%s (%s)", iface.Name(), strings.Join(ips, ", "))
+}
+
+func (w *installWizard) cidrStep(cmd *cobra.Command) error {
+	if anyFlagChanged(cmd, "pod-cidr", "service-cidr") {
+		fmt.Fprintln(w.out, "The Pod and Service ranges were set with --pod-cidr and --service-cidr.")
+		return w.prompt.PressEnter("Press enter to continue")
+	}
+
+	current, _ := cmd.Flags().GetString("cidr")
+
+	// without --cidr, the install selects the first candidate that is free on this host, so that
+	// is the range suggested. Accepting it leaves --cidr unset and the selection to the install.
+	selected := ""
+	if !cmd.Flags().Changed("cidr") {
+		cidr, err := selectFreeCIDR(cmd, current)
+		if err != nil {
+			fmt.Fprintf(w.out, "%v\n", err)
+		} else {
+			selected, current = cidr, cidr
+		}
+	}
+
+	fmt.Fprintln(w.out, "Pods and Services get their IP addresses from this range. It must be a private /16 or")
+	fmt.Fprintln(w.out, "larger range that does not overlap with the networks the hosts can reach.")
+	for {
+		cidr, err := w.prompt.Input("CIDR:", current, true)
+		if err != nil {
+			return fmt.Errorf("failed to get cidr: %w", err)
+		}
+		if err := newconfig.ValidateCIDR(cidr); err != nil {
+			fmt.Fprintf(w.out, "Invalid CIDR: %v\n", err)
+			current = cidr
+			continue
+		}
+		if selected != "" && cidr == selected {
+			return nil
+		}
+		if err := checkWizardCIDRConflicts(cidr); err != nil {
+			fmt.Fprintf(w.out, "CIDR %s can't be used: %v\n", cidr, err)
+			current = cidr
+			continue
+		}
+		return setWizardFlag(cmd, "cidr", cidr)
+	}
+}
+
+// checkWizardCIDRConflicts checks the pod and service ranges split from the cidr against the
+// networks of this host, as the install does with --cidr.
+func checkWizardCIDRConflicts(cidr string) error {
+	podCIDR, serviceCIDR, err := newconfig.SplitCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	return checkCIDRConflicts(&newconfig.CIDRConfig{PodCIDR: podCIDR, ServiceCIDR: serviceCIDR})
+}
+
+func (w *installWizard) proxyStep(cmd *cobra.Command) error {
+	httpProxy, _ := cmd.Flags().GetString("http-proxy")
+	httpsProxy, _ := cmd.Flags().GetString("https-proxy")
+	noProxy, _ := cmd.Flags().GetString("no-proxy")
+
+	useProxy, err := w.prompt.Confirm("Do the hosts need a proxy to reach the internet?", httpProxy != "" || httpsProxy != "")
+	if err != nil {
+		return fmt.Errorf("failed to get proxy confirmation: %w", err)
+	}
+	if !useProxy {
+		for _, name := range []string{"http-proxy", "https-proxy", "no-proxy"} {
+			if err := setWizardFlag(cmd, name, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if httpProxy, err = w.prompt.Input("HTTP proxy:", httpProxy, false); err != nil {
+		return fmt.Errorf("failed to get http proxy: %w", err)
+	}
+	if httpsProxy == "" {
+		httpsProxy = httpProxy
+	}
+	if httpsProxy, err = w.prompt.Input("HTTPS proxy:", httpsProxy, false); err != nil {
+		return fmt.Errorf("failed to get https proxy: %w", err)
+	}
+	fmt.Fprintln(w.out, "The cluster's own addresses are added to the list of hosts that skip the proxy.")
+	if noProxy, err = w.prompt.Input("Additional hosts that skip the proxy (comma-separated):", noProxy, false); err != nil {
+		return fmt.Errorf("failed to get no proxy: %w", err)
+	}
+
+	if err := setWizardFlag(cmd, "http-proxy", httpProxy); err != nil {
+		return err
+	}
+	if err := setWizardFlag(cmd, "https-proxy", httpsProxy); err != nil {
+		return err
+	}
+	return setWizardFlag(cmd, "no-proxy", noProxy)
+}
+
+func (w *installWizard) tlsStep(cmd *cobra.Command) error {
+	certFile, _ := cmd.Flags().GetString("tls-cert")
+	keyFile, _ := cmd.Flags().GetString("tls-key")
+	hostname, _ := cmd.Flags().GetString("hostname")
+
+	defvalue := wizardTLSSelfSigned
+	if certFile != "" {
+		defvalue = wizardTLSProvided
+	}
+	choice, err := w.prompt.Select("Admin Console certificate:", []string{wizardTLSSelfSigned, wizardTLSProvided}, defvalue)
+	if err != nil {
+		return fmt.Errorf("failed to get tls option: %w", err)
+	}
+
+	if choice == wizardTLSSelfSigned {
+		certFile, keyFile = "", ""
+	} else {
+		for {
+			if certFile, err = w.prompt.Input("Path to the TLS certificate file:", certFile, true); err != nil {
+				return fmt.Errorf("failed to get tls certificate: %w", err)
+			}
+			if keyFile, err = w.prompt.Input("Path to the TLS key file:", keyFile, true); err != nil {
+				return fmt.Errorf("failed to get tls key: %w", err)
+			}
+			if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+				fmt.Fprintf(w.out, "Unable to load the certificate and key: %v\n", err)
+				continue
+			}
+			break
+		}
+	}
+
+	if hostname, err = w.prompt.Input("Hostname to use for accessing the Admin Console (optional):", hostname, false); err != nil {
+		return fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	if err := setWizardFlag(cmd, "tls-cert", certFile); err != nil {
+		return err
+	}
+	if err := setWizardFlag(cmd, "tls-key", keyFile); err != nil {
+		return err
+	}
+	return setWizardFlag(cmd, "hostname", hostname)
+}
+
+func (w *installWizard) dataDirStep(cmd *cobra.Command) error {
+	current, _ := cmd.Flags().GetString("data-dir")
+
+	fmt.Fprintln(w.out, "Binaries, images and cluster data are stored in this directory.")
+	for {
+		dataDir, err := w.prompt.Input("Data directory:", current, true)
+		if err != nil {
+			return fmt.Errorf("failed to get data directory: %w", err)
+		}
+		if !filepath.IsAbs(dataDir) {
+			fmt.Fprintln(w.out, "The data directory must be an absolute path.")
+			current = dataDir
+			continue
+		}
+		return setWizardFlag(cmd, "data-dir", dataDir)
+	}
+}
+
+func (w *installWizard) reviewStep(cmd *cobra.Command) (bool, error) {
+	value := func(name string) string {
+		v, _ := cmd.Flags().GetString(name)
+		return v
+	}
+	orDefault := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	}
+
+	cidr := value("cidr")
+	if anyFlagChanged(cmd, "pod-cidr", "service-cidr") {
+		cidr = fmt.Sprintf("pods %s, services %s", value("pod-cidr"), value("service-cidr"))
+	} else if !cmd.Flags().Changed("cidr") {
+		if selected, err := selectFreeCIDR(cmd, cidr); err == nil {
+			cidr = selected
+		}
+	}
+	tlsCert := "self-signed"
+	if value("tls-cert") != "" {
+		tlsCert = fmt.Sprintf("%s, %s", value("tls-cert"), value("tls-key"))
+	}
+
+	fmt.Fprintf(w.out, "  Network interface:  %s\n", value("network-interface"))
+	fmt.Fprintf(w.out, "  CIDR:               %s\n", cidr)
+	fmt.Fprintf(w.out, "  HTTP proxy:         %s\n", orDefault(value("http-proxy"), "none"))
+	fmt.Fprintf(w.out, "  HTTPS proxy:        %s\n", orDefault(value("https-proxy"), "none"))
+	fmt.Fprintf(w.out, "  No proxy:           %s\n", orDefault(value("no-proxy"), "none"))
+	fmt.Fprintf(w.out, "  TLS certificate:    %s\n", tlsCert)
+	fmt.Fprintf(w.out, "  Hostname:           %s\n", orDefault(value("hostname"), "none"))
+	fmt.Fprintf(w.out, "  Data directory:     %s\n", value("data-dir"))
+	fmt.Fprintln(w.out)
+
+	confirmed, err := w.prompt.Confirm("Do you want to install with these settings? Answer no to change them.", true)
+	if err != nil {
+		return false, fmt.Errorf("failed to get confirmation: %w", err)
+	}
+	return confirmed, nil
+}
+
+func setWizardFlag(cmd *cobra.Command, name, value string) error {
+	if err := cmd.Flags().Set(name, value); err != nil {
+		return fmt.Errorf("unable to set --%s: %w", name, err)
+	}
+	return nil
+}
+
+// printWizardHeader clears the terminal and prints the title of a wizard screen.
+func printWizardHeader(out io.Writer, title string) {
+	fmt.Fprintf(out, "\033[H\033[2J\033[1m%s\033[0m\n\n", title)
+}
+
+// runInstallWizardPreflights runs the host preflights and shows the results until they pass or
+// the user decides how to proceed. Failing checks can be fixed from another shell and re-run
+// without restarting the installation.
+func runInstallWizardPreflights(ctx context.Context, flags installFlags, installCfg *installConfig, rc runtimeconfig.RuntimeConfig, metricsReporter metrics.ReporterInterface, prompt prompts.Prompt) error {
+	hpf, err := prepareInstallHostPreflights(ctx, flags, installCfg, rc)
+	if err != nil {
+		return err
+	}
+
+	if dryrun.Enabled() || flags.skipHostPreflights || (len(hpf.Collectors) == 0 && len(hpf.Analyzers) == 0) {
+		return runHostPreflights(ctx, hpf, rc, "install", hostPreflightsRunOptions(rc, flags.disableFilesystemPerformanceCheck), flags.skipHostPreflights, flags.ignoreHostPreflights, flags.assumeYes, metricsReporter)
+	}
+
+	saveHostPreflightsRecheckSpec(hpf, rc)
+
+	opts := hostPreflightsRunOptions(rc, flags.disableFilesystemPerformanceCheck)
+
+	for {
+		printWizardHeader(humanOutput(), "Host preflights")
+
+		loading := spinner.Start()
+		output, err := executeHostPreflights(ctx, hpf, rc, "install", opts, loading)
+		if err != nil {
+			return err
+		}
+
+		if !output.HasFail() && !output.HasWarn() {
+			loading.Infof("Host preflights passed")
+			loading.Close()
+			return nil
+		}
+
+		if output.HasFail() {
+			loading.ErrorClosef("%d host preflights failed and %d warned", len(output.Fail), len(output.Warn))
+		} else {
+			loading.Warnf("%d host preflights warned", len(output.Warn))
+			loading.Close()
+		}
+		preflights.PrintTableWithoutInfo(output)
+
+		options := []string{wizardPreflightsRerun}
+		if !output.HasFail() || (flags.ignoreHostPreflights && !output.HasStrictFailures()) {
+			options = append(options, wizardPreflightsContinue)
+		}
+		options = append(options, wizardPreflightsAbort)
+
+		choice, err := prompt.Select("Fix the issues above from another shell, then choose how to proceed:", options, wizardPreflightsRerun)
+		if err != nil {
+			return fmt.Errorf("failed to get preflights option: %w", err)
+		}
+
+		switch choice {
+		case wizardPreflightsRerun:
+			continue
+		case wizardPreflightsContinue:
+			if metricsReporter != nil {
+				metricsReporter.ReportHostPreflightsBypassed(ctx, output)
+			}
+			return nil
+		default:
+			if metricsReporter != nil {
+				metricsReporter.ReportHostPreflightsFailed(ctx, output)
+			}
+			return ErrPreflightsHaveFail
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type wizardNetworkInterface struct {
+	name string
+	ip   string
+}
+
+func (i wizardNetworkInterface) Name() string     { return i.name }
+func (i wizardNetworkInterface) Flags() net.Flags { return net.FlagUp }
+func (i wizardNetworkInterface) Addrs() ([]net.Addr, error) {
+	return []net.Addr{&net.IPNet{IP: net.ParseIP(i.ip), Mask: net.CIDRMask(24, 32)}}, nil
+}
+
+func Test_installWizard_Run(t *testing.T) {
+	cmd := InstallCmd(context.Background(), "my-app", "My App")
+
+	prompt := prompts.NewMock()
+	out := &bytes.Buffer{}
+	w := &installWizard{
+		appTitle: "My App",
+		prompt:   prompt,
+		out:      out,
+		listInterfaces: func() ([]netutils.NetworkInterface, error) {
+			return []netutils.NetworkInterface{
+				wizardNetworkInterface{"eth0", "10.0.0.5"},
+				wizardNetworkInterface{"eth1", "192.168.1.5"},
+			}, nil
+		},
+		defaultInterface: func() (string, error) { return "eth1", nil },
+	}
+
+	originalListHostNetworks := listHostNetworks
+	listHostNetworks = func() ([]netutils.HostNetwork, error) {
+		return []netutils.HostNetwork{
+			{Prefix: netip.MustParsePrefix("10.244.0.0/16"), Interface: "tun0"},
+		}, nil
+	}
+	t.Cleanup(func() { listHostNetworks = originalListHostNetworks })
+
+	interfaces := []string{"eth0 (10.0.0.5)", "eth1 (192.168.1.5)"}
+	tlsOptions := []string{wizardTLSSelfSigned, wizardTLSProvided}
+
+	// first pass, rejected in the review screen
+	prompt.On("Select", "Network interface:", interfaces, "eth1 (192.168.1.5)").Return("eth0 (10.0.0.5)", nil).Once()
+	prompt.On("Input", "CIDR:", "10.245.0.0/16", true).Return("10.0.0.0/24", nil).Once()
+	prompt.On("Input", "CIDR:", "10.0.0.0/24", true).Return("10.244.0.0/16", nil).Once()
+	prompt.On("Input", "CIDR:", "10.244.0.0/16", true).Return("172.16.0.0/16", nil).Once()
+	prompt.On("Confirm", "Do the hosts need a proxy to reach the internet?", false).Return(false, nil).Once()
+	prompt.On("Select", "Admin Console certificate:", tlsOptions, wizardTLSSelfSigned).Return(wizardTLSSelfSigned, nil).Once()
+	prompt.On("Input", "Hostname to use for accessing the Admin Console (optional):", "", false).Return("", nil).Once()
+	prompt.On("Input", "Data directory:", "/var/lib/embedded-cluster", true).Return("data", nil).Once()
+	prompt.On("Input", "Data directory:", "data", true).Return("/opt/my-app", nil).Once()
+	prompt.On("Confirm", mock.Anything, true).Return(false, nil).Once()
+
+	// second pass, previous answers are the defaults
+	prompt.On("Select", "Network interface:", interfaces, "eth0 (10.0.0.5)").Return("eth0 (10.0.0.5)", nil).Once()
+	prompt.On("Input", "CIDR:", "172.16.0.0/16", true).Return("172.16.0.0/16", nil).Once()
+	prompt.On("Confirm", "Do the hosts need a proxy to reach the internet?", false).Return(true, nil).Once()
+	prompt.On("Input", "HTTP proxy:", "", false).Return("http://proxy:3128", nil).Once()
+	prompt.On("Input", "HTTPS proxy:", "http://proxy:3128", false).Return("http://proxy:3128", nil).Once()
+	prompt.On("Input", "Additional hosts that skip the proxy (comma-separated):", "", false).Return("internal.example.com", nil).Once()
+	prompt.On("Select", "Admin Console certificate:", tlsOptions, wizardTLSSelfSigned).Return(wizardTLSSelfSigned, nil).Once()
+	prompt.On("Input", "Hostname to use for accessing the Admin Console (optional):", "", false).Return("console.example.com", nil).Once()
+	prompt.On("Input", "Data directory:", "/opt/my-app", true).Return("/opt/my-app", nil).Once()
+	prompt.On("Confirm", mock.Anything, true).Return(true, nil).Once()
+
+	require.NoError(t, w.Run(cmd))
+	prompt.AssertExpectations(t)
+
+	for name, want := range map[string]string{
+		"network-interface": "eth0",
+		"cidr":              "172.16.0.0/16",
+		"http-proxy":        "http://proxy:3128",
+		"https-proxy":       "http://proxy:3128",
+		"no-proxy":          "internal.example.com",
+		"tls-cert":          "",
+		"tls-key":           "",
+		"hostname":          "console.example.com",
+		"data-dir":          "/opt/my-app",
+	} {
+		got, err := cmd.Flags().GetString(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, name)
+	}
+
+	assert.Contains(t, out.String(), "Install My App: Step 1 of 6: Network interface")
+	assert.Contains(t, out.String(), "Invalid CIDR:")
+	assert.Contains(t, out.String(), "CIDR 10.244.0.0/16 can't be used: the pod cidr 10.244.0.0/17 overlaps")
+	assert.Contains(t, out.String(), "The data directory must be an absolute path.")
+}
+
+func Test_installWizard_cidrStep(t *testing.T) {
+	originalListHostNetworks := listHostNetworks
+	listHostNetworks = func() ([]netutils.HostNetwork, error) {
+		return []netutils.HostNetwork{
+			{Prefix: netip.MustParsePrefix("10.244.0.0/16"), Interface: "tun0"},
+		}, nil
+	}
+	t.Cleanup(func() { listHostNetworks = originalListHostNetworks })
+
+	tests := []struct {
+		name        string
+		args        []string
+		answers     [][2]string
+		wantCIDR    string
+		wantChanged bool
+	}{
+		{
+			name:     "accepting the selected range leaves the flag unset",
+			answers:  [][2]string{{"10.245.0.0/16", "10.245.0.0/16"}},
+			wantCIDR: "10.244.0.0/16",
+		},
+		{
+			name:        "another free range sets the flag",
+			answers:     [][2]string{{"10.245.0.0/16", "172.16.0.0/16"}},
+			wantCIDR:    "172.16.0.0/16",
+			wantChanged: true,
+		},
+		{
+			name:        "a range set with the flag is checked against the host networks",
+			args:        []string{"--cidr", "10.244.0.0/16"},
+			answers:     [][2]string{{"10.244.0.0/16", "10.244.0.0/16"}, {"10.244.0.0/16", "10.246.0.0/16"}},
+			wantCIDR:    "10.246.0.0/16",
+			wantChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := InstallCmd(context.Background(), "my-app", "My App")
+			require.NoError(t, cmd.Flags().Parse(tt.args))
+
+			prompt := prompts.NewMock()
+			for _, answer := range tt.answers {
+				prompt.On("Input", "CIDR:", answer[0], true).Return(answer[1], nil).Once()
+			}
+			w := &installWizard{appTitle: "My App", prompt: prompt, out: &bytes.Buffer{}}
+
+			require.NoError(t, w.cidrStep(cmd))
+			prompt.AssertExpectations(t)
+
+			got, err := cmd.Flags().GetString("cidr")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCIDR, got)
+			assert.Equal(t, tt.wantChanged, cmd.Flags().Changed("cidr"))
+		})
+	}
+}
+
+func Test_validateWizardFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		terminal bool
+		wantErr  string
+	}{
+		{
+			name:     "interactive terminal",
+			terminal: true,
+		},
+		{
+			name:     "with yes",
+			args:     []string{"--yes"},
+			terminal: true,
+			wantErr:  "--wizard cannot be used with --yes",
+		},
+		{
+			name:     "with json output",
+			args:     []string{"--output", "json"},
+			terminal: true,
+			wantErr:  "--wizard cannot be used with --output json",
+		},
+		{
+			name:    "not a terminal",
+			wantErr: "--wizard requires an interactive terminal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompts.SetTerminal(tt.terminal)
+			t.Cleanup(func() { prompts.SetTerminal(false) })
+
+			var flags installFlags
+			cmd := InstallCmd(context.Background(), "my-app", "My App")
+			require.NoError(t, cmd.Flags().Parse(tt.args))
+			flags.assumeYes, _ = cmd.Flags().GetBool("yes")
+
+			err := validateWizardFlags(cmd, &flags)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// validateInterfaceFlags validates the --pod-network-interface and --admin-console-address
+// flags. The pod network interface ends up in the Calico autodetection method, which matches
+// the names of the interfaces of every node against it.
+func validateInterfaceFlags(podNetworkInterface string, adminConsoleAddress string) error {
+	if podNetworkInterface != "" {
+		if _, err := regexp.Compile(podNetworkInterface); err != nil {
+			return fmt.Errorf("invalid --pod-network-interface %q: %w", podNetworkInterface, err)
+		}
+	}
+	if adminConsoleAddress != "" {
+		if err := netutils.ValidateHostAddress(adminConsoleAddress); err != nil {
+			return fmt.Errorf("invalid --admin-console-address: %w", err)
+		}
+	}
+	return nil
+}
+
+// joinControlPlaneInterface returns the interface a joining controller advertises the address
+// of its Kubernetes API and etcd on. Unless set on the command line, the joining node uses the
+// control plane interface of the cluster, or its own network interface if the cluster does not
+// separate them. Workers run neither so they keep their network interface.
+func joinControlPlaneInterface(flags JoinCmdFlags, rc runtimeconfig.RuntimeConfig, isWorker bool) string {
+	if isWorker {
+		return flags.networkInterface
+	}
+	if flags.controlPlaneInterface != "" {
+		return flags.controlPlaneInterface
+	}
+	if iface := rc.Get().Network.ControlPlaneInterface; iface != "" {
+		return iface
+	}
+	return flags.networkInterface
+}
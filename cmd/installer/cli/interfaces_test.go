@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validateInterfaceFlags(t *testing.T) {
+	tests := []struct {
+		name                string
+		podNetworkInterface string
+		adminConsoleAddress string
+		wantErr             string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:                "interface expression and hostname",
+			podNetworkInterface: "eth1|ens.*",
+			adminConsoleAddress: "console.example.com",
+		},
+		{
+			name:                "ipv6 address",
+			adminConsoleAddress: "2001:db8::10",
+		},
+		{
+			name:                "invalid interface expression",
+			podNetworkInterface: "eth(",
+			wantErr:             `invalid --pod-network-interface "eth("`,
+		},
+		{
+			name:                "address with a scheme",
+			adminConsoleAddress: "https://console.example.com",
+			wantErr:             "invalid --admin-console-address",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInterfaceFlags(tt.podNetworkInterface, tt.adminConsoleAddress)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func Test_joinControlPlaneInterface(t *testing.T) {
+	separate := runtimeconfig.New(&ecv1beta1.RuntimeConfigSpec{
+		Network: ecv1beta1.NetworkSpec{NetworkInterface: "eth0", ControlPlaneInterface: "eth1"},
+	})
+	shared := runtimeconfig.New(&ecv1beta1.RuntimeConfigSpec{
+		Network: ecv1beta1.NetworkSpec{NetworkInterface: "eth0"},
+	})
+
+	flags := JoinCmdFlags{networkInterface: "ens3"}
+	assert.Equal(t, "eth1", joinControlPlaneInterface(flags, separate, false))
+	assert.Equal(t, "ens3", joinControlPlaneInterface(flags, shared, false))
+	assert.Equal(t, "ens3", joinControlPlaneInterface(flags, separate, true))
+
+	flags.controlPlaneInterface = "ens4"
+	assert.Equal(t, "ens4", joinControlPlaneInterface(flags, separate, false))
+}
+
+func Test_getAdminConsoleURL(t *testing.T) {
+	assert.Equal(t, "http://console.example.com:30000", getAdminConsoleURL("console.example.com", "10.0.0.10", "eth0", 30000))
+	assert.Equal(t, "http://10.0.0.10:30000", getAdminConsoleURL("", "10.0.0.10", "eth0", 30000))
+	assert.Equal(t, "http://[2001:db8::10]:30000", getAdminConsoleURL("", "2001:db8::10", "eth0", 30000))
+}
@@ -44,10 +44,12 @@ import (
 type JoinCmdFlags struct {
 	noHA                              bool
 	networkInterface                  string
+	controlPlaneInterface             string
 	assumeYes                         bool
 	skipHostPreflights                bool
 	ignoreHostPreflights              bool
 	disableFilesystemPerformanceCheck bool
+	ntpServers                        []string
 	embeddedAssetsSize                int64
 }
 
@@ -63,6 +65,10 @@ func JoinCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 		Short: fmt.Sprintf("Join a node to the %s cluster", appTitle),
 		Args:  cobra.ExactArgs(2),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupOutput(cmd); err != nil {
+				return err
+			}
+
 			if err := preRunJoin(cmd, &flags); err != nil {
 				return err
 			}
@@ -107,9 +113,11 @@ func JoinCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 	if err := addJoinFlags(cmd, &flags); err != nil {
 		panic(err)
 	}
+	mustAddOutputFlag(cmd)
 
 	cmd.AddCommand(JoinRunPreflightsCmd(ctx, appSlug, appTitle))
 	cmd.AddCommand(JoinPrintCommandCmd(ctx, appTitle))
+	cmd.AddCommand(JoinClusterPreflightsAgentCmd(ctx))
 
 	return cmd
 }
@@ -150,6 +158,7 @@ func addJoinFlags(cmd *cobra.Command, flags *JoinCmdFlags) error {
 	}
 
 	cmd.Flags().StringVar(&flags.networkInterface, "network-interface", "", "The network interface to use for the cluster")
+	cmd.Flags().StringVar(&flags.controlPlaneInterface, "control-plane-interface", "", "The network interface the Kubernetes API and etcd of a controller advertise their address on (default: the one of the cluster or --network-interface)")
 	cmd.Flags().BoolVar(&flags.ignoreHostPreflights, "ignore-host-preflights", false, "Run host preflight checks, but prompt the user to continue if they fail instead of exiting.")
 	cmd.Flags().BoolVar(&flags.noHA, "no-ha", false, "Do not prompt for or enable high availability.")
 
@@ -161,11 +170,13 @@ func addJoinFlags(cmd *cobra.Command, flags *JoinCmdFlags) error {
 		return err
 	}
 
-	cmd.Flags().BoolVar(&flags.disableFilesystemPerformanceCheck, "disable-filesystem-performance-check", false, "Disable the filesystem write latency performance check")
+	cmd.Flags().BoolVar(&flags.disableFilesystemPerformanceCheck, "disable-filesystem-performance-check", false, "Disable the filesystem write latency and storage performance checks")
 	if err := cmd.Flags().MarkHidden("disable-filesystem-performance-check"); err != nil {
 		return err
 	}
 
+	cmd.Flags().StringSliceVar(&flags.ntpServers, "ntp-servers", nil, "NTP servers to configure chrony or systemd-timesyncd with before the host preflights run")
+
 	cmd.Flags().BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
 	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
 
@@ -183,53 +194,67 @@ func runJoin(ctx context.Context, appSlug string, flags JoinCmdFlags, rc runtime
 		return err
 	}
 
-	cidrCfg, err := initializeJoin(ctx, appSlug, rc, jcmd, kotsAPIAddress)
-	if err != nil {
+	var cidrCfg *newconfig.CIDRConfig
+	if err := runPhase(phaseInitialize, func() (err error) {
+		cidrCfg, err = initializeJoin(ctx, appSlug, flags, rc, jcmd, kotsAPIAddress)
+		return err
+	}); err != nil {
 		return fmt.Errorf("unable to initialize join: %w", err)
 	}
 
 	logrus.Debugf("running join preflights")
-	if err := runJoinPreflights(ctx, jcmd, flags, rc, cidrCfg, metricsReporter.reporter); err != nil {
+	if err := runPhase(phaseHostPreflights, func() error {
+		return runJoinPreflights(ctx, jcmd, kotsAPIAddress, flags, rc, cidrCfg, metricsReporter.reporter)
+	}); err != nil {
 		if errors.Is(err, preflights.ErrPreflightsHaveFail) {
 			return NewErrorNothingElseToAdd(err)
 		}
 		return fmt.Errorf("unable to run join preflights: %w", err)
 	}
 
-	logrus.Debugf("installing and joining cluster")
-	loading := spinner.Start()
-	loading.Infof("Installing node")
-	if err := installAndJoinCluster(ctx, rc, jcmd, appSlug, flags, isWorker); err != nil {
-		loading.ErrorClosef("Failed to install node")
-		return err
-	}
+	var kcli client.Client
+	var mcli metadata.Interface
+	if err := runPhase(phaseNode, func() (err error) {
+		logrus.Debugf("installing and joining cluster")
+		loading := spinner.Start()
+		loading.Infof("Installing node")
+		if err := installAndJoinCluster(ctx, rc, jcmd, appSlug, flags, isWorker); err != nil {
+			loading.ErrorClosef("Failed to install node")
+			return err
+		}
 
-	kcli, err := kubeutils.KubeClient()
-	if err != nil {
-		loading.ErrorClosef("Failed to install node")
-		return fmt.Errorf("unable to get kube client: %w", err)
-	}
+		kcli, err = kubeutils.KubeClient()
+		if err != nil {
+			loading.ErrorClosef("Failed to install node")
+			return fmt.Errorf("unable to get kube client: %w", err)
+		}
 
-	mcli, err := kubeutils.MetadataClient()
-	if err != nil {
-		loading.ErrorClosef("Failed to install node")
-		return fmt.Errorf("unable to get metadata client: %w", err)
-	}
+		mcli, err = kubeutils.MetadataClient()
+		if err != nil {
+			loading.ErrorClosef("Failed to install node")
+			return fmt.Errorf("unable to get metadata client: %w", err)
+		}
 
-	nodename, err := nodeutil.GetHostname("")
-	if err != nil {
-		loading.ErrorClosef("Failed to install node")
-		return fmt.Errorf("unable to get hostname: %w", err)
-	}
+		nodename, err := nodeutil.GetHostname("")
+		if err != nil {
+			loading.ErrorClosef("Failed to install node")
+			return fmt.Errorf("unable to get hostname: %w", err)
+		}
 
-	logrus.Debugf("waiting for node to join cluster")
-	loading.Infof("Waiting for node")
-	if err := waitForNodeToJoin(ctx, kcli, nodename, isWorker); err != nil {
-		loading.ErrorClosef("Node failed to become ready")
-		return fmt.Errorf("unable to wait for node: %w", err)
+		logrus.Debugf("waiting for node to join cluster")
+		loading.Infof("Waiting for node")
+		if err := waitForNodeToJoin(ctx, kcli, nodename, isWorker); err != nil {
+			loading.ErrorClosef("Node failed to become ready")
+			return fmt.Errorf("unable to wait for node: %w", err)
+		}
+		recordNodeFacts(ctx, flags.networkInterface)
+
+		loading.Closef("Node is ready")
+		return nil
+	}); err != nil {
+		return err
 	}
 
-	loading.Closef("Node is ready")
 	logrus.Infof("\nNode joined the cluster successfully.\n")
 	if isWorker {
 		logrus.Debugf("worker node join finished")
@@ -286,23 +311,30 @@ func runJoinVerifyAndPrompt(appSlug string, flags JoinCmdFlags, rc runtimeconfig
 	if proxySpec := rc.ProxySpec(); proxySpec != nil {
 		newconfig.SetProxyEnv(proxySpec)
 
-		proxyOK, localIP, err := newconfig.CheckProxyConfigForLocalIP(proxySpec, flags.networkInterface, nil)
-		if err != nil {
-			return fmt.Errorf("failed to check proxy config for local IP: %w", err)
+		// controllers also reach each other on the address of their control plane interface
+		interfaces := []string{flags.networkInterface}
+		if iface := joinControlPlaneInterface(flags, rc, isWorker); iface != flags.networkInterface {
+			interfaces = append(interfaces, iface)
 		}
+		for _, iface := range interfaces {
+			proxyOK, localIP, err := newconfig.CheckProxyConfigForLocalIP(proxySpec, netutils.JoinCIDRs(rc.PodCIDR(), rc.IPv6PodCIDR()), iface, nil)
+			if err != nil {
+				return fmt.Errorf("failed to check proxy config for local IP: %w", err)
+			}
 
-		if !proxyOK {
-			logrus.Errorf("\nThis node's IP address %s is not included in the no-proxy list (%s).", localIP, proxySpec.NoProxy)
-			logrus.Infof(`The no-proxy list cannot easily be modified after installing.`)
-			logrus.Infof(`Recreate the first node and pass all node IP addresses to --no-proxy.`)
-			return NewErrorNothingElseToAdd(errors.New("node ip address not included in no-proxy list"))
+			if !proxyOK {
+				logrus.Errorf("\nThis node's IP address %s is not included in the no-proxy list (%s).", localIP, proxySpec.NoProxy)
+				logrus.Infof(`The no-proxy list cannot easily be modified after installing.`)
+				logrus.Infof(`Recreate the first node and pass all node IP addresses to --no-proxy.`)
+				return NewErrorNothingElseToAdd(errors.New("node ip address not included in no-proxy list"))
+			}
 		}
 	}
 
 	return nil
 }
 
-func initializeJoin(ctx context.Context, appSlug string, rc runtimeconfig.RuntimeConfig, jcmd *join.JoinCommandResponse, kotsAPIAddress string) (cidrCfg *newconfig.CIDRConfig, err error) {
+func initializeJoin(ctx context.Context, appSlug string, flags JoinCmdFlags, rc runtimeconfig.RuntimeConfig, jcmd *join.JoinCommandResponse, kotsAPIAddress string) (cidrCfg *newconfig.CIDRConfig, err error) {
 	logrus.Info("")
 	spinner := spinner.Start()
 	spinner.Infof("Initializing")
@@ -350,10 +382,20 @@ func initializeJoin(ctx context.Context, appSlug string, rc runtimeconfig.Runtim
 	}
 
 	logrus.Debugf("configuring firewalld")
-	if err := hostutils.ConfigureFirewalld(ctx, cidrCfg.PodCIDR, cidrCfg.ServiceCIDR); err != nil {
+	if err := hostutils.ConfigureFirewalld(ctx, netutils.JoinCIDRs(cidrCfg.PodCIDR, cidrCfg.IPv6PodCIDR), netutils.JoinCIDRs(cidrCfg.ServiceCIDR, cidrCfg.IPv6ServiceCIDR)); err != nil {
 		logrus.Debugf("unable to configure firewalld: %v", err)
 	}
 
+	logrus.Debugf("configuring dns upstreams")
+	if err := hostutils.ConfigureDNSUpstreams(rc); err != nil {
+		return nil, fmt.Errorf("unable to configure dns upstreams: %w", err)
+	}
+
+	logrus.Debugf("configuring time synchronization")
+	if err := hostutils.ConfigureTimeSync(ctx, flags.ntpServers); err != nil {
+		return nil, fmt.Errorf("unable to configure time synchronization: %w", err)
+	}
+
 	return cidrCfg, nil
 }
 
@@ -395,8 +437,10 @@ func getJoinCIDRConfig(rc runtimeconfig.RuntimeConfig) (*newconfig.CIDRConfig, e
 	}
 
 	return &newconfig.CIDRConfig{
-		PodCIDR:     podCIDR,
-		ServiceCIDR: serviceCIDR,
+		PodCIDR:         podCIDR,
+		ServiceCIDR:     serviceCIDR,
+		IPv6PodCIDR:     rc.IPv6PodCIDR(),
+		IPv6ServiceCIDR: rc.IPv6ServiceCIDR(),
 	}, nil
 }
 
@@ -429,7 +473,7 @@ func installAndJoinCluster(ctx context.Context, rc runtimeconfig.RuntimeConfig,
 	}
 
 	logrus.Debugf("overriding network configuration")
-	if err := applyNetworkConfiguration(flags.networkInterface, rc, jcmd); err != nil {
+	if err := applyNetworkConfiguration(joinControlPlaneInterface(flags, rc, isWorker), rc, jcmd); err != nil {
 		return fmt.Errorf("unable to apply network configuration: %w", err)
 	}
 
@@ -477,26 +521,33 @@ func installK0sBinary(rc runtimeconfig.RuntimeConfig) error {
 	return nil
 }
 
-func applyNetworkConfiguration(networkInterface string, rc runtimeconfig.RuntimeConfig, jcmd *join.JoinCommandResponse) error {
+func applyNetworkConfiguration(controlPlaneInterface string, rc runtimeconfig.RuntimeConfig, jcmd *join.JoinCommandResponse) error {
 	domains := domains.GetDomains(jcmd.InstallationSpec.Config, release.GetChannelRelease())
 	clusterSpec := config.RenderK0sConfig(domains.ProxyRegistryDomain)
 
-	address, err := netutils.FirstValidAddress(networkInterface)
+	cidrCfg, err := getJoinCIDRConfig(rc)
 	if err != nil {
-		return fmt.Errorf("unable to find first valid address: %w", err)
+		return fmt.Errorf("unable to get join CIDR config: %w", err)
 	}
 
-	cidrCfg, err := getJoinCIDRConfig(rc)
+	// the api and etcd listen on the ipv6 address of the node in ipv6 only clusters
+	addresses, err := netutils.NodeAddresses(controlPlaneInterface, netutils.ClusterIPFamily(cidrCfg.PodCIDR, ""))
 	if err != nil {
-		return fmt.Errorf("unable to get join CIDR config: %w", err)
+		return fmt.Errorf("unable to find first valid address of the control plane interface, set it with --control-plane-interface: %w", err)
 	}
 
-	clusterSpec.Spec.API.Address = address
-	clusterSpec.Spec.Storage.Etcd.PeerAddress = address
+	clusterSpec.Spec.API.Address = addresses[0]
+	clusterSpec.Spec.Storage.Etcd.PeerAddress = addresses[0]
 	// NOTE: we should be copying everything from the in cluster config spec and overriding
 	// the node specific config from clusterSpec.GetClusterWideConfig()
 	clusterSpec.Spec.Network.PodCIDR = cidrCfg.PodCIDR
 	clusterSpec.Spec.Network.ServiceCIDR = cidrCfg.ServiceCIDR
+	if cidrCfg.IPv6PodCIDR != "" {
+		config.EnableDualStack(clusterSpec, cidrCfg.IPv6PodCIDR, cidrCfg.IPv6ServiceCIDR)
+	}
+	if rc.PodNetworkInterface() != "" {
+		config.SetCalicoInterface(clusterSpec, rc.PodNetworkInterface())
+	}
 
 	if rc.NodePortRange() != "" {
 		if clusterSpec.Spec.API.ExtraArgs == nil {
@@ -588,7 +639,7 @@ func runK0sInstallCommand(rc runtimeconfig.RuntimeConfig, networkInterface strin
 	args := strings.Split(fullcmd, " ")
 	args = append(args, "--token-file", "/etc/k0s/join-token")
 
-	nodeIP, err := netutils.FirstValidAddress(networkInterface)
+	nodeIPs, err := netutils.NodeAddresses(networkInterface, netutils.ClusterIPFamily(rc.PodCIDR(), rc.IPv6PodCIDR()))
 	if err != nil {
 		return fmt.Errorf("unable to find first valid address: %w", err)
 	}
@@ -597,7 +648,7 @@ func runK0sInstallCommand(rc runtimeconfig.RuntimeConfig, networkInterface strin
 		args = append(args, "--profile", profile)
 	}
 
-	args = append(args, config.AdditionalInstallFlags(rc, nodeIP, hostname)...)
+	args = append(args, config.AdditionalInstallFlags(rc, strings.Join(nodeIPs, ","), hostname)...)
 
 	if strings.Contains(fullcmd, "controller") {
 		args = append(args, config.AdditionalInstallFlagsController()...)
@@ -705,5 +756,7 @@ func maybeEnableHA(ctx context.Context, kcli client.Client, mcli metadata.Interf
 		KotsadmNamespace:   kotsadmNamespace,
 	}
 
-	return addOns.EnableHA(ctx, opts, loading)
+	return runPhase(phaseEnableHA, func() error {
+		return addOns.EnableHA(ctx, opts, loading)
+	})
 }
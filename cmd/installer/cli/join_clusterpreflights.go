@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/types/join"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/clusterpreflights"
+	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/sshutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	clusterPreflightsDialTimeout   = 5 * time.Second
+	clusterPreflightsListenTimeout = 30 * time.Second
+)
+
+// JoinClusterPreflightsAgentCmd returns the command run on a joining node by node add to
+// compare it with the cluster. It reports the facts of the node and the outcome of the
+// connections to the cluster, then listens for the connections from the cluster.
+func JoinClusterPreflightsAgentCmd(ctx context.Context) *cobra.Command {
+	var opts clusterpreflights.AgentOptions
+
+	cmd := &cobra.Command{
+		Use:    "cluster-preflights-agent",
+		Short:  "Report the facts compared by the cluster before this node joins",
+		Hidden: true,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.NetworkInterface == "" {
+				iface, err := newconfig.DetermineBestNetworkInterface()
+				if err != nil {
+					return fmt.Errorf("unable to determine the network interface: %w", err)
+				}
+				opts.NetworkInterface = iface
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return clusterpreflights.RunAgent(cmd.Context(), opts, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.NetworkInterface, "network-interface", "", "The network interface to use for the cluster")
+	cmd.Flags().StringSliceVar(&opts.Dial, "dial", nil, "Addresses of the cluster to connect to")
+	cmd.Flags().IntSliceVar(&opts.ListenPorts, "listen-ports", nil, "Ports to accept connections from the cluster on")
+	cmd.Flags().DurationVar(&opts.DialTimeout, "dial-timeout", clusterPreflightsDialTimeout, "How long to wait for each connection to the cluster")
+	cmd.Flags().DurationVar(&opts.ListenTimeout, "listen-timeout", clusterPreflightsListenTimeout, "How long to wait for the connections from the cluster")
+
+	return cmd
+}
+
+// nodeClusterPreflights compares the nodes joined over SSH with the cluster before they join.
+type nodeClusterPreflights struct {
+	local        clusterpreflights.NodeFacts
+	listNodes    func(ctx context.Context) ([]clusterpreflights.ClusterNode, error)
+	getJoinToken func(ctx context.Context, kotsAPIAddress, token string) (*join.JoinCommandResponse, error)
+}
+
+// newNodeClusterPreflights collects the facts of this controller, the node the joining nodes
+// are compared with.
+func newNodeClusterPreflights(rc runtimeconfig.RuntimeConfig) (*nodeClusterPreflights, error) {
+	local, err := clusterpreflights.CollectNodeFacts(rc.NetworkInterface())
+	if err != nil {
+		return nil, fmt.Errorf("unable to collect the facts of this node: %w", err)
+	}
+	return &nodeClusterPreflights{
+		local:        local,
+		listNodes:    listClusterPreflightsNodes,
+		getJoinToken: kotsadm.GetJoinToken,
+	}, nil
+}
+
+// listClusterPreflightsNodes lists the nodes in the cluster. It is called for every joining
+// node so controllers joined earlier in the same run are included.
+func listClusterPreflightsNodes(ctx context.Context) ([]clusterpreflights.ClusterNode, error) {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kube client: %w", err)
+	}
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+	return clusterpreflights.ClusterNodes(clusterpreflights.NodeFactsFromNodes(nodes.Items)), nil
+}
+
+// recordNodeFacts records the facts of this node on its Node for the nodes joining after it to
+// be compared with. A failure is not fatal, the node is then compared on its kernel version only.
+func recordNodeFacts(ctx context.Context, networkInterface string) {
+	facts, err := clusterpreflights.CollectNodeFacts(networkInterface)
+	if err != nil {
+		logrus.Debugf("unable to collect the facts of this node: %v", err)
+		return
+	}
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		logrus.Debugf("unable to get kube client: %v", err)
+		return
+	}
+	if err := clusterpreflights.RecordNodeFacts(ctx, kcli, facts); err != nil {
+		logrus.Debugf("unable to record the facts of this node: %v", err)
+	}
+}
+
+// runClusterPreflights runs the agent on the node and compares its report with the cluster.
+// While the agent listens, this controller connects to the node on the ports the node must be
+// reachable on. The results are written to the log file and an error listing the failures is
+// returned.
+func (j *nodeJoiner) runClusterPreflights(ctx context.Context, client sshutils.Client, node nodeinventory.Node, user, remotePath string, logs io.Writer) error {
+	c := j.clusterPreflights
+
+	jcmd, err := c.getJoinToken(ctx, j.kotsAPIAddress, j.joinToken(node))
+	if err != nil {
+		return fmt.Errorf("get join token: %w", err)
+	}
+	ports, err := clusterpreflights.ListenPorts(jcmd.TCPConnectionsRequired)
+	if err != nil {
+		return err
+	}
+	nodes, err := c.listNodes(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []string{remotePath, "join", "cluster-preflights-agent"}
+	if len(jcmd.TCPConnectionsRequired) > 0 {
+		args = append(args, "--dial", strings.Join(jcmd.TCPConnectionsRequired, ","))
+	}
+	if len(ports) > 0 {
+		strPorts := make([]string, len(ports))
+		for i, port := range ports {
+			strPorts[i] = strconv.Itoa(port)
+		}
+		args = append(args, "--listen-ports", strings.Join(strPorts, ","))
+	}
+	if j.inv.Spec.NetworkInterface != "" {
+		args = append(args, "--network-interface", j.inv.Spec.NetworkInterface)
+	}
+
+	agentCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	report := newAgentReportWriter()
+	agentErr := make(chan error, 1)
+	go func() {
+		agentErr <- client.Run(agentCtx, remoteCommand(user, args...), io.MultiWriter(logs, report), logs)
+	}()
+
+	select {
+	case <-report.ready:
+	case err := <-agentErr:
+		return fmt.Errorf("run agent: %w", err)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	joining, err := clusterpreflights.ReadAgentReport(report.line)
+	if err != nil {
+		return err
+	}
+
+	// the cluster reaches the node on the address of its cluster network interface, the SSH
+	// address can be on another network
+	host := joining.Facts.Address
+	if host == "" {
+		host = node.Address
+	}
+	addresses := []string{}
+	for _, listener := range joining.Listeners {
+		if listener.Error == "" {
+			addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(listener.Port)))
+		}
+	}
+	reverseDials := clusterpreflights.DialAll(ctx, addresses, clusterPreflightsDialTimeout)
+
+	if err := <-agentErr; err != nil {
+		fmt.Fprintf(logs, "cluster preflights agent exited: %v\n", err)
+	}
+
+	output := clusterpreflights.Run(clusterpreflights.Input{
+		Joining:      *joining,
+		ReceivedAt:   report.receivedAt,
+		Local:        c.local,
+		Nodes:        nodes,
+		ReverseDials: reverseDials,
+	})
+	for _, record := range output.Pass {
+		fmt.Fprintf(logs, "PASS %s: %s\n", record.Title, record.Message)
+	}
+	for _, record := range output.Warn {
+		fmt.Fprintf(logs, "WARN %s: %s\n", record.Title, record.Message)
+		logrus.Warnf("Node %s: %s", node.Address, record.Message)
+	}
+	if !output.HasFail() {
+		return nil
+	}
+	msgs := []string{}
+	for _, record := range output.Fail {
+		fmt.Fprintf(logs, "FAIL %s: %s\n", record.Title, record.Message)
+		msgs = append(msgs, record.Message)
+	}
+	return errors.New(strings.Join(msgs, " "))
+}
+
+// agentReportWriter keeps the first line written by the agent, the report, and the time it was
+// received at.
+type agentReportWriter struct {
+	mu         sync.Mutex
+	buf        bytes.Buffer
+	line       []byte
+	receivedAt time.Time
+	ready      chan struct{}
+}
+
+func newAgentReportWriter() *agentReportWriter {
+	return &agentReportWriter{ready: make(chan struct{})}
+}
+
+func (w *agentReportWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.line != nil {
+		return len(p), nil
+	}
+	w.buf.Write(p)
+	if i := bytes.IndexByte(w.buf.Bytes(), '\n'); i >= 0 {
+		w.receivedAt = time.Now().UTC()
+		w.line = append([]byte{}, w.buf.Bytes()[:i]...)
+		close(w.ready)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_agentReportWriter(t *testing.T) {
+	w := newAgentReportWriter()
+
+	w.Write([]byte(`{"facts":`))
+	select {
+	case <-w.ready:
+		t.Fatal("report ready before the line was complete")
+	default:
+	}
+
+	w.Write([]byte("{\"hostname\":\"node2\"}}\nlater output\n"))
+	select {
+	case <-w.ready:
+	default:
+		t.Fatal("report not ready after the line was complete")
+	}
+	assert.Equal(t, `{"facts":{"hostname":"node2"}}`, string(w.line))
+	assert.False(t, w.receivedAt.IsZero())
+
+	n, err := w.Write([]byte("more output\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+	assert.Equal(t, `{"facts":{"hostname":"node2"}}`, string(w.line))
+}
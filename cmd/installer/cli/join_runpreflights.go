@@ -2,15 +2,21 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
 	"github.com/replicatedhq/embedded-cluster/kinds/types/join"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/clusterpreflights"
 	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/domains"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/hostutils"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
 	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
 	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
 	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
@@ -80,13 +86,18 @@ func runJoinRunPreflights(ctx context.Context, appSlug string, flags JoinCmdFlag
 		logrus.Debugf("unable to configure kernel modules: %v", err)
 	}
 
+	logrus.Debugf("configuring time synchronization")
+	if err := hostutils.ConfigureTimeSync(ctx, flags.ntpServers); err != nil {
+		return fmt.Errorf("unable to configure time synchronization: %w", err)
+	}
+
 	cidrCfg, err := getJoinCIDRConfig(rc)
 	if err != nil {
 		return fmt.Errorf("unable to get join CIDR config: %w", err)
 	}
 
 	logrus.Debugf("running join preflights")
-	if err := runJoinPreflights(ctx, jcmd, flags, rc, cidrCfg, nil); err != nil {
+	if err := runJoinPreflights(ctx, jcmd, kotsAPIAddress, flags, rc, cidrCfg, nil); err != nil {
 		if errors.Is(err, preflights.ErrPreflightsHaveFail) {
 			return NewErrorNothingElseToAdd(err)
 		}
@@ -98,11 +109,13 @@ func runJoinRunPreflights(ctx context.Context, appSlug string, flags JoinCmdFlag
 	return nil
 }
 
-func runJoinPreflights(ctx context.Context, jcmd *join.JoinCommandResponse, flags JoinCmdFlags, rc runtimeconfig.RuntimeConfig, cidrCfg *newconfig.CIDRConfig, metricsReporter metrics.ReporterInterface) error {
-	nodeIP, err := netutils.FirstValidAddress(flags.networkInterface)
+func runJoinPreflights(ctx context.Context, jcmd *join.JoinCommandResponse, kotsAPIAddress string, flags JoinCmdFlags, rc runtimeconfig.RuntimeConfig, cidrCfg *newconfig.CIDRConfig, metricsReporter metrics.ReporterInterface) error {
+	// the primary address of the node is in the family of the pod cidr
+	nodeIPs, err := netutils.NodeAddresses(flags.networkInterface, netutils.ClusterIPFamily(cidrCfg.PodCIDR, ""))
 	if err != nil {
 		return fmt.Errorf("unable to find first valid address: %w", err)
 	}
+	nodeIP := nodeIPs[0]
 
 	domains := domains.GetDomains(jcmd.InstallationSpec.Config, release.GetChannelRelease())
 
@@ -118,12 +131,16 @@ func runJoinPreflights(ctx context.Context, jcmd *join.JoinCommandResponse, flag
 		Proxy:                             rc.ProxySpec(),
 		PodCIDR:                           cidrCfg.PodCIDR,
 		ServiceCIDR:                       cidrCfg.ServiceCIDR,
+		IPv6PodCIDR:                       cidrCfg.IPv6PodCIDR,
+		IPv6ServiceCIDR:                   cidrCfg.IPv6ServiceCIDR,
 		NodeIP:                            nodeIP,
 		IsAirgap:                          jcmd.InstallationSpec.AirGap,
 		TCPConnectionsRequired:            jcmd.TCPConnectionsRequired,
 		IsJoin:                            true,
 		DisableFilesystemPerformanceCheck: flags.disableFilesystemPerformanceCheck,
 		K8sVersion:                        versions.K0sVersion,
+		VendorHostPreflights:              release.GetRawHostPreflights(),
+		VendorVariables:                   vendorHostPreflightVariables(),
 	}
 
 	// Calculate airgap storage space requirement based on node type
@@ -151,9 +168,57 @@ func runJoinPreflights(ctx context.Context, jcmd *join.JoinCommandResponse, flag
 		return err
 	}
 
-	if err := runHostPreflights(ctx, hpf, rc, flags.skipHostPreflights, flags.ignoreHostPreflights, flags.assumeYes, metricsReporter); err != nil {
+	runOpts := hostPreflightsRunOptions(rc, flags.disableFilesystemPerformanceCheck)
+	if !flags.skipHostPreflights && !dryrun.Enabled() {
+		results := joinClockOffsetResults(ctx, kotsAPIAddress)
+		nodes := joinClusterNodesResults(jcmd, flags.networkInterface)
+		results.Pass = append(results.Pass, nodes.Pass...)
+		results.Warn = append(results.Warn, nodes.Warn...)
+		results.Fail = append(results.Fail, nodes.Fail...)
+		runOpts.AdditionalResults = results
+	}
+
+	if err := runHostPreflights(ctx, hpf, rc, "join", runOpts, flags.skipHostPreflights, flags.ignoreHostPreflights, flags.assumeYes, metricsReporter); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// joinClockOffsetResults compares the clock of this host with the clock of the cluster through
+// the Admin Console the join command was fetched from. Joins run by node add are also compared
+// by the controller, this covers the joins run by hand.
+func joinClockOffsetResults(ctx context.Context, kotsAPIAddress string) *apitypes.PreflightsOutput {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// only the date of the response is used, the certificate is generally self-signed
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	offset, err := clusterpreflights.MeasureClockOffset(ctx, client, kotsAPIAddress, fmt.Sprintf("https://%s/", kotsAPIAddress))
+	if err != nil {
+		return &apitypes.PreflightsOutput{
+			Warn: []apitypes.PreflightsRecord{{
+				Title:   "Clock Synchronization",
+				Message: fmt.Sprintf("Unable to compare the clock on this host with the clock on %s: %v", kotsAPIAddress, err),
+			}},
+		}
+	}
+	logrus.Debugf("clock offset with %s: %s (+/- %s)", kotsAPIAddress, offset.Offset, offset.Uncertainty)
+	return clusterpreflights.CheckClockOffset(offset, clusterpreflights.DefaultMaxClockSkew)
+}
+
+// joinClusterNodesResults compares this host with the facts the nodes in the cluster recorded, as
+// received with the join command. The join command is all a join run by hand knows of the cluster
+// before k0s starts.
+func joinClusterNodesResults(jcmd *join.JoinCommandResponse, networkInterface string) *apitypes.PreflightsOutput {
+	facts, err := clusterpreflights.CollectNodeFacts(networkInterface)
+	if err != nil {
+		return &apitypes.PreflightsOutput{
+			Warn: []apitypes.PreflightsRecord{{
+				Title:   "Cluster Nodes",
+				Message: fmt.Sprintf("Unable to compare this host with the nodes in the cluster: %v", err),
+			}},
+		}
+	}
+	return clusterpreflights.Compare(facts, clusterpreflights.ClusterNodes(jcmd.InstallationSpec.NodeFacts))
+}
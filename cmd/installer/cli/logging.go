@@ -22,6 +22,10 @@ const MaxLogFiles = 100
 // stderr.
 type StdoutLogger struct{}
 
+// stdoutLoggerOutput is where the StdoutLogger writes non fatal logs. It is changed to stderr when
+// stdout is reserved for the machine-readable progress stream.
+var stdoutLoggerOutput io.Writer = os.Stdout
+
 // Levels defines on which log levels this hook would trigger.
 func (hook *StdoutLogger) Levels() []logrus.Level {
 	return []logrus.Level{
@@ -35,7 +39,7 @@ func (hook *StdoutLogger) Levels() []logrus.Level {
 // Fire executes the hook for the given entry.
 func (hook *StdoutLogger) Fire(entry *logrus.Entry) error {
 	message := fmt.Sprintf("%s\n", entry.Message)
-	output := os.Stdout
+	output := stdoutLoggerOutput
 	if entry.Level == logrus.FatalLevel {
 		output = os.Stderr
 	}
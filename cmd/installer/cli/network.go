@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// NetworkCmd returns the parent command for changing the network configuration of the cluster.
+func NetworkCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "network",
+		Short: "Manage the cluster network",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	cmd.AddCommand(NetworkMigrateCIDRCmd(ctx, appSlug, appTitle))
+
+	return cmd
+}
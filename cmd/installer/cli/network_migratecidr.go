@@ -0,0 +1,457 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/cidrmigration"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/hostutils"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/sshutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	nodeutil "k8s.io/component-helpers/node/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type networkMigrateCIDRFlags struct {
+	podCIDR       string
+	nodePortRange string
+	nodesFile     string
+	dryRun        bool
+	assumeYes     bool
+	drainTimeout  time.Duration
+	// nodeConfigOnly updates the configuration of the node the command runs on and restarts it
+	// if it is a controller. Used to update the other nodes over SSH.
+	nodeConfigOnly bool
+}
+
+// NetworkMigrateCIDRCmd returns a cobra command for changing the pod CIDR and the node port range
+// of an existing cluster.
+func NetworkMigrateCIDRCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
+	var flags networkMigrateCIDRFlags
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "migrate-cidr",
+		Short: fmt.Sprintf("Change the pod CIDR and node port range of the %s cluster", appTitle),
+		Long: fmt.Sprintf(`Change the pod CIDR and the node port range of the %s cluster.
+
+Run it as root on a controller. The other nodes are updated over SSH and must be listed in the
+NodeInventory file passed with --nodes. Use --dry-run to print the plan without changing
+anything.
+
+The pods are moved to the new pod CIDR with a Calico IP pool migration. A pool is created for
+the new CIDR and the pool of the current CIDR stops handing out addresses. The controllers are
+restarted one at a time with the new configuration, starting with this one, and the runtime
+configuration and the firewalld zone of the workers are updated. Every node is then drained, controllers first, so its pods restart with addresses in the new CIDR. The pool of the
+current CIDR is deleted once no pod uses it.
+
+Expect downtime. The Kubernetes API is unavailable while each controller restarts, and
+workloads without replicas on other nodes are unavailable while their node drains. Pods in the
+current and the new CIDR reach each other during the migration.
+
+Changing the service CIDR is not supported. The add-ons rely on fixed addresses in it, reinstall
+the cluster to use a different service CIDR.`, appTitle),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("network migrate-cidr command must be run as root")
+			}
+			if flags.podCIDR == "" && flags.nodePortRange == "" {
+				return fmt.Errorf("at least one of --pod-cidr or --node-port-range must be set")
+			}
+
+			rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+			_ = rc.SetEnv()
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.nodeConfigOnly {
+				return migrateLocalNodeConfig(cmd.Context(), rc, flags.podCIDR, flags.nodePortRange)
+			}
+			return runNetworkMigrateCIDR(cmd.Context(), appSlug, rc, flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.podCIDR, "pod-cidr", "", "The new IPv4 CIDR the pods get their addresses from")
+	cmd.Flags().StringVar(&flags.nodePortRange, "node-port-range", "", "The new range of ports for NodePort services, e.g. 30000-32767")
+	cmd.Flags().StringVar(&flags.nodesFile, "nodes", "", "Path to a NodeInventory file listing the other nodes, required when the cluster has more than one node")
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "Print the migration plan without changing anything")
+	cmd.Flags().BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().DurationVar(&flags.drainTimeout, "drain-timeout", 2*time.Minute, "Time to wait for each node to drain before deleting the pods left on it")
+	cmd.Flags().BoolVar(&flags.nodeConfigOnly, "node-config-only", false, "Only update the configuration of this node and restart it if it is a controller")
+	mustMarkFlagHidden(cmd.Flags(), "node-config-only")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+
+	return cmd
+}
+
+func runNetworkMigrateCIDR(ctx context.Context, appSlug string, rc runtimeconfig.RuntimeConfig, flags networkMigrateCIDRFlags) error {
+	status, err := k0s.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get k0s status, is this a cluster node?: %w", err)
+	}
+	if status.Role != "controller" {
+		return fmt.Errorf("network migrate-cidr command must be run on a controller node")
+	}
+
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return fmt.Errorf("unable to get hostname: %w", err)
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	current := cidrmigration.Network{
+		PodCIDR:       rc.PodCIDR(),
+		ServiceCIDR:   rc.ServiceCIDR(),
+		IPv6PodCIDR:   rc.IPv6PodCIDR(),
+		NodePortRange: rc.NodePortRange(),
+	}
+	plan, err := cidrmigration.NewPlan(ctx, kcli, current, hostname, cidrmigration.Options{
+		PodCIDR:       flags.podCIDR,
+		NodePortRange: flags.nodePortRange,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to plan the migration: %w", err)
+	}
+
+	var inv *nodeinventory.NodeInventory
+	others := append(slices.Clone(plan.Controllers[1:]), plan.Workers...)
+	if len(others) > 0 {
+		if flags.nodesFile == "" {
+			return fmt.Errorf("the cluster has %d nodes, list the other nodes in a NodeInventory file with --nodes", len(others)+1)
+		}
+		if inv, err = nodeinventory.ParseFile(flags.nodesFile, config.GetControllerRoleName()); err != nil {
+			return fmt.Errorf("unable to read node inventory: %w", err)
+		}
+	}
+	remotes, err := inventoryNodes(ctx, kcli, inv, others)
+	if err != nil {
+		return err
+	}
+
+	printMigrationPlan(humanOutput(), plan)
+	if flags.dryRun {
+		return nil
+	}
+
+	if !flags.assumeYes {
+		confirmed, err := prompts.New().Confirm("Do you want to continue?", false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			return fmt.Errorf("Aborting")
+		}
+	}
+
+	if plan.ChangesPodCIDR() {
+		logrus.Infof("Creating the IP pool for %s", plan.PodCIDR)
+		if err := cidrmigration.CreateIPPool(ctx, kcli, current.PodCIDR, plan.PodCIDR); err != nil {
+			return fmt.Errorf("unable to create the new ip pool: %w", err)
+		}
+		if err := cidrmigration.DisableIPPool(ctx, kcli, current.PodCIDR); err != nil {
+			return fmt.Errorf("unable to disable the current ip pool: %w", err)
+		}
+	}
+
+	logrus.Infof("Updating controller %s", hostname)
+	if err := migrateNodeConfig(ctx, rc, true, plan.PodCIDR, plan.NodePortRange); err != nil {
+		return fmt.Errorf("unable to update controller %s: %w", hostname, err)
+	}
+	if err := kubeutils.WaitForNode(ctx, kcli, hostname, false); err != nil {
+		return fmt.Errorf("controller %s did not become ready: %w", hostname, err)
+	}
+
+	for _, name := range plan.Controllers[1:] {
+		logrus.Infof("Updating controller %s", name)
+		if err := migrateRemoteNode(ctx, appSlug, inv, remotes[name], plan); err != nil {
+			return fmt.Errorf("unable to update controller %s: %w", name, err)
+		}
+		if err := kubeutils.WaitForNode(ctx, kcli, name, false); err != nil {
+			return fmt.Errorf("controller %s did not become ready: %w", name, err)
+		}
+	}
+
+	for _, name := range plan.Workers {
+		logrus.Infof("Updating worker %s", name)
+		if err := migrateRemoteNode(ctx, appSlug, inv, remotes[name], plan); err != nil {
+			return fmt.Errorf("unable to update worker %s: %w", name, err)
+		}
+	}
+
+	if plan.ChangesPodCIDR() {
+		if err := updateClusterConfigPodCIDR(ctx, kcli, plan.PodCIDR); err != nil {
+			return err
+		}
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get latest installation: %w", err)
+	}
+	if err := kubeutils.UpdateInstallation(ctx, kcli, in, func(in *ecv1beta1.Installation) {
+		if in.Spec.RuntimeConfig == nil {
+			in.Spec.RuntimeConfig = ecv1beta1.GetDefaultRuntimeConfig()
+		}
+		in.Spec.RuntimeConfig.Network = migratedNetworkSpec(in.Spec.RuntimeConfig.Network, plan.PodCIDR, plan.NodePortRange)
+	}); err != nil {
+		return fmt.Errorf("unable to update installation: %w", err)
+	}
+
+	if !plan.ChangesPodCIDR() {
+		logrus.Infof("Node port range changed to %s.", plan.NodePortRange)
+		return nil
+	}
+
+	for _, name := range plan.Controllers {
+		if err := recyclePodsOnNode(ctx, kcli, name, false, current.PodCIDR, flags.drainTimeout); err != nil {
+			return err
+		}
+	}
+	for _, name := range plan.Workers {
+		if err := recyclePodsOnNode(ctx, kcli, name, true, current.PodCIDR, flags.drainTimeout); err != nil {
+			return err
+		}
+	}
+
+	inUse, err := cidrmigration.DeleteIPPool(ctx, kcli, current.PodCIDR)
+	if err != nil {
+		return fmt.Errorf("unable to delete the ip pool of %s: %w", current.PodCIDR, err)
+	}
+	if len(inUse) > 0 {
+		logrus.Warnf("%d pods still have addresses in %s, the IP pool of %s was kept. Delete these pods and run the command again to remove it.", len(inUse), current.PodCIDR, current.PodCIDR)
+		for _, pod := range inUse {
+			logrus.Warnf("  %s/%s on %s", pod.Namespace, pod.Name, pod.Spec.NodeName)
+		}
+	}
+
+	logrus.Infof("Pod CIDR changed to %s.", plan.PodCIDR)
+	return nil
+}
+
+// printMigrationPlan writes the steps of the migration and the expected downtime.
+func printMigrationPlan(w io.Writer, plan *cidrmigration.Plan) {
+	fmt.Fprintln(w, "The migration will:")
+	for i, step := range plan.Steps() {
+		fmt.Fprintf(w, "  %d. %s\n", i+1, step)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, plan.Downtime())
+	fmt.Fprintln(w)
+}
+
+// migrateLocalNodeConfig updates the configuration of the node the command runs on, it is run
+// over SSH on the other nodes of the cluster.
+func migrateLocalNodeConfig(ctx context.Context, rc runtimeconfig.RuntimeConfig, podCIDR, nodePortRange string) error {
+	status, err := k0s.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get k0s status, is this a cluster node?: %w", err)
+	}
+	return migrateNodeConfig(ctx, rc, status.Role == "controller", podCIDR, nodePortRange)
+}
+
+// migrateNodeConfig sets the pod cidr and the node port range in the runtime configuration of
+// the node the command runs on and adds the pod cidr to its firewalld zone. Controllers also get
+// them in their k0s configuration and are restarted. Empty values are left unchanged.
+func migrateNodeConfig(ctx context.Context, rc runtimeconfig.RuntimeConfig, isController bool, podCIDR, nodePortRange string) error {
+	if isController {
+		if err := cidrmigration.UpdateK0sConfigFile(runtimeconfig.K0sConfigPath, podCIDR, nodePortRange); err != nil {
+			return fmt.Errorf("unable to update k0s config: %w", err)
+		}
+	}
+
+	rc.SetNetworkSpec(migratedNetworkSpec(rc.Get().Network, podCIDR, nodePortRange))
+	if err := rc.WriteToDisk(); err != nil {
+		return fmt.Errorf("unable to write runtime config: %w", err)
+	}
+
+	if podCIDR != "" {
+		// the previous pod cidr stays in the zone, pods in both ranges reach each other during
+		// the migration
+		podNetwork := netutils.JoinCIDRs(rc.PodCIDR(), rc.IPv6PodCIDR())
+		serviceNetwork := netutils.JoinCIDRs(rc.ServiceCIDR(), rc.IPv6ServiceCIDR())
+		if err := hostutils.ConfigureFirewalld(ctx, podNetwork, serviceNetwork); err != nil {
+			return fmt.Errorf("unable to configure firewalld: %w", err)
+		}
+	}
+
+	if !isController {
+		return nil
+	}
+	if err := systemd.Restart(ctx, "k0scontroller"); err != nil {
+		return fmt.Errorf("unable to restart k0s: %w", err)
+	}
+	if err := k0s.WaitForK0s(); err != nil {
+		return fmt.Errorf("unable to wait for k0s: %w", err)
+	}
+	return nil
+}
+
+// migratedNetworkSpec returns the network spec with the new pod cidr and node port range. The
+// global cidr is cleared when the pod cidr changes as it no longer holds the pod and service
+// ranges.
+func migratedNetworkSpec(spec ecv1beta1.NetworkSpec, podCIDR, nodePortRange string) ecv1beta1.NetworkSpec {
+	if podCIDR != "" {
+		spec.PodCIDR = podCIDR
+		spec.GlobalCIDR = ""
+	}
+	if nodePortRange != "" {
+		spec.NodePortRange = nodePortRange
+	}
+	return spec
+}
+
+// inventoryNodes returns the inventory entries of the nodes, by node name. The entries are
+// matched by the addresses and hostnames of the nodes.
+func inventoryNodes(ctx context.Context, kcli client.Client, inv *nodeinventory.NodeInventory, names []string) (map[string]nodeinventory.Node, error) {
+	result := map[string]nodeinventory.Node{}
+	for _, name := range names {
+		var node corev1.Node
+		if err := kcli.Get(ctx, client.ObjectKey{Name: name}, &node); err != nil {
+			return nil, fmt.Errorf("unable to get node %s: %w", name, err)
+		}
+		addresses := []string{node.Name}
+		for _, address := range node.Status.Addresses {
+			addresses = append(addresses, address.Address)
+		}
+
+		found := false
+		for _, entry := range inv.Spec.Nodes {
+			if slices.Contains(addresses, entry.Address) {
+				result[name], found = entry, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("node %s is not listed in the node inventory", name)
+		}
+	}
+	return result, nil
+}
+
+// migrateRemoteNode copies the binary to another node over SSH and runs the command there to
+// update its configuration, and restart it if it is a controller. The output is written to a log
+// file named after the node.
+func migrateRemoteNode(ctx context.Context, appSlug string, inv *nodeinventory.NodeInventory, node nodeinventory.Node, plan *cidrmigration.Plan) error {
+	logFile := filepath.Join(runtimeconfig.EmbeddedClusterLogsSubDir(), fmt.Sprintf("network-migrate-cidr-%s.log", node.Address))
+	logs, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create log file: %w", err)
+	}
+	defer logs.Close()
+
+	sshSpec := inv.SSHSpec(node)
+	sshClient, err := sshutils.Dial(ctx, sshutils.Config{
+		Address:               node.Address,
+		Port:                  sshSpec.Port,
+		User:                  sshSpec.User,
+		PrivateKeyPath:        sshSpec.PrivateKeyPath,
+		KnownHostsPath:        sshSpec.KnownHostsPath,
+		InsecureIgnoreHostKey: sshSpec.InsecureIgnoreHostKey,
+	})
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer sshClient.Close()
+
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to get path to the binary: %w", err)
+	}
+	remotePath, cleanup, err := uploadBinary(ctx, sshClient, binaryPath, appSlug, logs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	args := []string{remotePath, "network", "migrate-cidr", "--node-config-only"}
+	if plan.ChangesPodCIDR() {
+		args = append(args, "--pod-cidr", plan.PodCIDR)
+	}
+	if plan.ChangesNodePortRange() {
+		args = append(args, "--node-port-range", plan.NodePortRange)
+	}
+	if err := sshClient.Run(ctx, remoteCommand(sshSpec.User, args...), logs, logs); err != nil {
+		return fmt.Errorf("update configuration, see %s: %w", logFile, err)
+	}
+	return nil
+}
+
+// updateClusterConfigPodCIDR sets the pod cidr in the cluster config k0s keeps in the cluster,
+// if it is stored there.
+func updateClusterConfigPodCIDR(ctx context.Context, kcli client.Client, podCIDR string) error {
+	var cfg k0sv1beta1.ClusterConfig
+	if err := kcli.Get(ctx, client.ObjectKey{Name: "k0s", Namespace: "kube-system"}, &cfg); err != nil {
+		return fmt.Errorf("unable to get cluster config: %w", err)
+	}
+	if cfg.Spec == nil || cfg.Spec.Network == nil || cfg.Spec.Network.PodCIDR == "" {
+		return nil
+	}
+	cfg.Spec.Network.PodCIDR = podCIDR
+	if err := kcli.Update(ctx, &cfg); err != nil {
+		return fmt.Errorf("unable to update cluster config: %w", err)
+	}
+	return nil
+}
+
+// recyclePodsOnNode drains the node so its pods are recreated with addresses in the new pool,
+// deletes the pods left with an address in the previous cidr and uncordons the node.
+func recyclePodsOnNode(ctx context.Context, kcli client.Client, name string, isWorker bool, previousCIDR string, timeout time.Duration) error {
+	logrus.Infof("Draining node %s", name)
+	out, err := helpers.RunCommand(k0sBinPath, "kubectl", "drain",
+		"--ignore-daemonsets",
+		"--delete-emptydir-data",
+		"--timeout", timeout.String(),
+		name,
+	)
+	if err != nil {
+		logrus.Debugf("Node drain did not complete: %v, %s", err, out)
+	}
+
+	pods, err := cidrmigration.PodsInCIDR(ctx, kcli, name, previousCIDR)
+	if err != nil {
+		return fmt.Errorf("unable to list the pods on node %s: %w", name, err)
+	}
+	for _, pod := range pods {
+		if err := kcli.Delete(ctx, &pod); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("unable to delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+
+	if out, err := helpers.RunCommand(k0sBinPath, "kubectl", "uncordon", name); err != nil {
+		return fmt.Errorf("unable to uncordon node %s: %w: %s", name, err, out)
+	}
+	if err := kubeutils.WaitForNode(ctx, kcli, name, isWorker); err != nil {
+		return fmt.Errorf("node %s did not become ready: %w", name, err)
+	}
+	return nil
+}
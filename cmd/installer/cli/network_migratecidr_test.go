@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_migratedNetworkSpec(t *testing.T) {
+	spec := ecv1beta1.NetworkSpec{
+		NetworkInterface: "eth0",
+		GlobalCIDR:       "10.244.0.0/16",
+		PodCIDR:          "10.244.0.0/17",
+		ServiceCIDR:      "10.244.128.0/17",
+		NodePortRange:    "80-32767",
+	}
+
+	got := migratedNetworkSpec(spec, "10.100.0.0/16", "")
+	assert.Equal(t, ecv1beta1.NetworkSpec{
+		NetworkInterface: "eth0",
+		PodCIDR:          "10.100.0.0/16",
+		ServiceCIDR:      "10.244.128.0/17",
+		NodePortRange:    "80-32767",
+	}, got)
+
+	got = migratedNetworkSpec(spec, "", "30000-32767")
+	assert.Equal(t, ecv1beta1.NetworkSpec{
+		NetworkInterface: "eth0",
+		GlobalCIDR:       "10.244.0.0/16",
+		PodCIDR:          "10.244.0.0/17",
+		ServiceCIDR:      "10.244.128.0/17",
+		NodePortRange:    "30000-32767",
+	}, got)
+}
+
+func Test_inventoryNodes(t *testing.T) {
+	node := func(name, address string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: address}},
+			},
+		}
+	}
+	kcli := fake.NewClientBuilder().WithObjects(
+		node("node-b", "192.168.1.2"),
+		node("node-c", "192.168.1.3"),
+		node("node-d", "192.168.1.4"),
+	).Build()
+
+	inv := &nodeinventory.NodeInventory{Spec: nodeinventory.NodeInventorySpec{
+		Nodes: []nodeinventory.Node{
+			{Address: "192.168.1.2", Role: nodeinventory.RoleController},
+			{Address: "node-c", Role: nodeinventory.RoleController},
+			{Address: "192.168.1.4", Role: "worker"},
+		},
+	}}
+
+	got, err := inventoryNodes(context.Background(), kcli, inv, []string{"node-b", "node-c", "node-d"})
+	require.NoError(t, err)
+	assert.Equal(t, "192.168.1.2", got["node-b"].Address)
+	assert.Equal(t, "node-c", got["node-c"].Address)
+	assert.Equal(t, "192.168.1.4", got["node-d"].Address)
+
+	inv.Spec.Nodes = inv.Spec.Nodes[:1]
+	_, err = inventoryNodes(context.Background(), kcli, inv, []string{"node-b", "node-c"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "node node-c is not listed in the node inventory")
+
+	got, err = inventoryNodes(context.Background(), kcli, nil, nil)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
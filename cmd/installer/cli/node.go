@@ -13,9 +13,14 @@ func NodeCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return nil
 		},
-		Hidden: true,
 	}
 
+	cmd.AddCommand(NodeAddCmd(ctx, appSlug, appTitle))
+	cmd.AddCommand(NodeListCmd(ctx, appTitle))
+	cmd.AddCommand(NodeStatusCmd(ctx, appTitle))
+	cmd.AddCommand(NodeRemoveCmd(ctx, appTitle))
+	cmd.AddCommand(NodeReplaceCmd(ctx, appSlug, appTitle))
+
 	// here for legacy reasons
 	joinCmd := JoinCmd(ctx, appSlug, appTitle)
 	joinCmd.Hidden = true
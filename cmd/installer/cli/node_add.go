@@ -0,0 +1,490 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/kotscli"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/sshutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodeAddCmd returns a cobra command for joining the nodes listed in an inventory file to the
+// cluster over SSH.
+func NodeAddCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
+	var nodesFile string
+	var rc runtimeconfig.RuntimeConfig
+	var inv *nodeinventory.NodeInventory
+
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: fmt.Sprintf("Join nodes to the %s cluster over SSH", appTitle),
+		Long: fmt.Sprintf(`Join the nodes listed in a NodeInventory file to the %s cluster.
+
+The binary is copied to every node over SSH, the join host preflights are run and the node is
+joined. Controllers join first, one at a time, followed by all other nodes at once. The output
+of each node is written to a log file under %s.`, appTitle, runtimeconfig.EmbeddedClusterLogsSubDir()),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupOutput(cmd); err != nil {
+				return err
+			}
+
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("node add command must be run as root")
+			}
+
+			var err error
+			inv, err = nodeinventory.ParseFile(nodesFile, config.GetControllerRoleName())
+			if err != nil {
+				return fmt.Errorf("unable to read node inventory: %w", err)
+			}
+			if err := nodeinventory.ValidateJoinTokens(inv); err != nil {
+				return fmt.Errorf("invalid node inventory: %w", err)
+			}
+
+			rc, err = rcutil.GetRuntimeConfigFromCluster(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to init runtime config from cluster: %w", err)
+			}
+
+			os.Setenv("TMPDIR", rc.EmbeddedClusterTmpSubDir())
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runNodeAdd(cmd.Context(), appSlug, rc, inv)
+		},
+	}
+
+	cmd.Flags().StringVar(&nodesFile, "nodes", "", "Path to the NodeInventory file listing the nodes to join")
+	mustMarkFlagRequired(cmd.Flags(), "nodes")
+	mustAddOutputFlag(cmd)
+
+	return cmd
+}
+
+func addNodesFlag(cmd *cobra.Command, flags *installFlags) error {
+	cmd.Flags().StringVar(&flags.nodesFile, "nodes", "", "Path to a NodeInventory file listing more nodes to join over SSH once the installation completes")
+	mustSetFlagTargetLinux(cmd.Flags(), "nodes")
+
+	return nil
+}
+
+// runNodeAdd joins the nodes in the inventory to the cluster and reports the outcome for each
+// of them. An error is returned if any node failed to join.
+func runNodeAdd(ctx context.Context, appSlug string, rc runtimeconfig.RuntimeConfig, inv *nodeinventory.NodeInventory) error {
+	logrus.Debugf("fetching controller join command")
+	joinCommand, err := kotscli.GetJoinCommand(ctx, rc)
+	if err != nil {
+		return fmt.Errorf("unable to get join command: %w", err)
+	}
+	joiner, err := newNodeJoiner(appSlug, rc, joinCommand, inv)
+	if err != nil {
+		return err
+	}
+	results := joiner.joinAll(ctx)
+
+	printNodeJoinResults(humanOutput(), results)
+
+	failed := 0
+	for _, result := range results {
+		if result.status != progress.StatusSucceeded {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return NewErrorNothingElseToAdd(fmt.Errorf("%d of %d nodes did not join the cluster", failed, len(results)))
+	}
+
+	return nil
+}
+
+// runInstallNodeAdd joins the nodes in the inventory to the cluster that was just installed. The
+// join tokens of the roles other than controller can't be known before the cluster is up, they
+// are generated by its Admin Console.
+func runInstallNodeAdd(ctx context.Context, appSlug string, rc runtimeconfig.RuntimeConfig, inv *nodeinventory.NodeInventory) error {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+	if err := generateJoinTokens(ctx, kcli, inv); err != nil {
+		return err
+	}
+	return runNodeAdd(ctx, appSlug, rc, inv)
+}
+
+// generateJoinTokens sets the join token of every role of the nodes in the inventory other than
+// controller to one generated by the Admin Console of the cluster.
+func generateJoinTokens(ctx context.Context, kcli client.Client, inv *nodeinventory.NodeInventory) error {
+	tokens := map[string]string{}
+	for _, role := range inv.Roles() {
+		joinCommand, err := generateJoinCommand(ctx, kcli, role)
+		if err != nil {
+			return err
+		}
+		_, token, err := parseJoinCommand(joinCommand)
+		if err != nil {
+			return fmt.Errorf("join command for role %s: %w", role, err)
+		}
+		tokens[role] = token
+	}
+	if len(tokens) > 0 {
+		inv.Spec.JoinTokens = tokens
+	}
+	return nil
+}
+
+// generateJoinCommand returns the join command for a node joining with the role, with a new
+// token generated by the Admin Console of the cluster.
+func generateJoinCommand(ctx context.Context, kcli client.Client, role string) (string, error) {
+	namespace, err := runtimeconfig.KotsadmNamespace(ctx, kcli)
+	if err != nil {
+		return "", fmt.Errorf("unable to get kotsadm namespace: %w", err)
+	}
+	address, authString, err := kotsadmAPIAccess(ctx, kcli, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	logrus.Debugf("generating join command for role %s", role)
+	joinCommand, err := kotsadm.GenerateJoinCommand(ctx, address, authString, []string{role})
+	if err != nil {
+		return "", fmt.Errorf("unable to generate join command for role %s: %w", role, err)
+	}
+	return joinCommand, nil
+}
+
+// kotsadmAPIAccess returns the in-cluster address of the kotsadm api and the auth string
+// authorizing requests to it as an admin.
+func kotsadmAPIAccess(ctx context.Context, kcli client.Client, namespace string) (string, string, error) {
+	var secret corev1.Secret
+	if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "kotsadm-authstring"}, &secret); err != nil {
+		return "", "", fmt.Errorf("unable to get kotsadm auth string: %w", err)
+	}
+	authString := strings.TrimSpace(string(secret.Data["kotsadm-authstring"]))
+	if authString == "" {
+		return "", "", fmt.Errorf("kotsadm auth string is empty")
+	}
+
+	var svc corev1.Service
+	if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: "kotsadm"}, &svc); err != nil {
+		return "", "", fmt.Errorf("unable to get kotsadm service: %w", err)
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "http" {
+			return net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(int(port.Port))), authString, nil
+		}
+	}
+	return "", "", fmt.Errorf("kotsadm service has no http port")
+}
+
+// parseJoinCommand returns the address of the Admin Console and the join token from a join
+// command in the form "sudo ./app join <address> <token>".
+func parseJoinCommand(joinCommand string) (string, string, error) {
+	fields := strings.Fields(joinCommand)
+	for i, field := range fields {
+		if field == "join" && i+2 < len(fields) {
+			return fields[i+1], fields[i+2], nil
+		}
+	}
+	return "", "", fmt.Errorf("unable to parse join command: address and token not found")
+}
+
+// nodeJoinResult is the outcome of joining a single node.
+type nodeJoinResult struct {
+	node     nodeinventory.Node
+	status   progress.Status
+	duration time.Duration
+	logFile  string
+	err      error
+}
+
+// nodeJoiner joins nodes to the cluster by running the binary on them over SSH.
+type nodeJoiner struct {
+	appSlug         string
+	binaryPath      string
+	kotsAPIAddress  string
+	controllerToken string
+	inv             *nodeinventory.NodeInventory
+	logsDir         string
+	dial            sshutils.DialFunc
+	// clusterPreflights compares the nodes with the cluster before they join. Skipped if nil.
+	clusterPreflights *nodeClusterPreflights
+}
+
+// newNodeJoiner returns a joiner for the nodes in the inventory, using the controller join
+// command. The nodes are compared with the cluster before they join.
+func newNodeJoiner(appSlug string, rc runtimeconfig.RuntimeConfig, joinCommand string, inv *nodeinventory.NodeInventory) (*nodeJoiner, error) {
+	kotsAPIAddress, controllerToken, err := parseJoinCommand(joinCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get path to the binary: %w", err)
+	}
+
+	clusterPreflights, err := newNodeClusterPreflights(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nodeJoiner{
+		appSlug:           appSlug,
+		binaryPath:        binaryPath,
+		kotsAPIAddress:    kotsAPIAddress,
+		controllerToken:   controllerToken,
+		inv:               inv,
+		logsDir:           runtimeconfig.EmbeddedClusterLogsSubDir(),
+		dial:              sshutils.Dial,
+		clusterPreflights: clusterPreflights,
+	}, nil
+}
+
+// joinAll joins the nodes in the inventory. Controllers join one at a time, as required when
+// growing the control plane, and the first one failing stops the remaining nodes from joining.
+// All other nodes join at once.
+func (j *nodeJoiner) joinAll(ctx context.Context) []nodeJoinResult {
+	nodes := j.inv.JoinOrder()
+
+	results := make([]nodeJoinResult, len(nodes))
+	for i, node := range nodes {
+		results[i] = nodeJoinResult{node: node, status: progress.StatusSkipped}
+	}
+
+	i := 0
+	for ; i < len(nodes) && j.inv.IsController(nodes[i]); i++ {
+		results[i] = j.join(ctx, nodes[i])
+		if results[i].err != nil {
+			for _, result := range results[i+1:] {
+				progress.Node(result.node.Address, progress.StatusSkipped, "a controller failed to join", nil)
+			}
+			return results
+		}
+	}
+
+	var wg sync.WaitGroup
+	for ; i < len(nodes); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = j.join(ctx, nodes[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// join joins a single node, reporting its progress. The output of the commands run on the
+// node is written to a log file named after it.
+func (j *nodeJoiner) join(ctx context.Context, node nodeinventory.Node) nodeJoinResult {
+	result := nodeJoinResult{
+		node:    node,
+		logFile: filepath.Join(j.logsDir, fmt.Sprintf("node-add-%s.log", node.Address)),
+	}
+
+	logrus.Infof("Joining %s as %s", node.Address, node.Role)
+	progress.Node(node.Address, progress.StatusRunning, node.Role, nil)
+
+	start := time.Now()
+	result.err = j.joinNode(ctx, node, result.logFile)
+	result.duration = time.Since(start).Round(time.Second)
+
+	if result.err != nil {
+		result.status = progress.StatusFailed
+		logrus.Errorf("Node %s failed to join: %v", node.Address, result.err)
+	} else {
+		result.status = progress.StatusSucceeded
+		logrus.Infof("Node %s joined", node.Address)
+	}
+	progress.Node(node.Address, result.status, node.Role, result.err)
+
+	return result
+}
+
+func (j *nodeJoiner) joinNode(ctx context.Context, node nodeinventory.Node, logFile string) error {
+	logs, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("create log file: %w", err)
+	}
+	defer logs.Close()
+
+	sshSpec := j.inv.SSHSpec(node)
+	client, err := j.dial(ctx, sshutils.Config{
+		Address:               node.Address,
+		Port:                  sshSpec.Port,
+		User:                  sshSpec.User,
+		PrivateKeyPath:        sshSpec.PrivateKeyPath,
+		KnownHostsPath:        sshSpec.KnownHostsPath,
+		InsecureIgnoreHostKey: sshSpec.InsecureIgnoreHostKey,
+	})
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer client.Close()
+
+	remotePath, cleanup, err := uploadBinary(ctx, client, j.binaryPath, j.appSlug, logs)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if j.clusterPreflights != nil {
+		fmt.Fprintf(logs, "running cluster preflights\n")
+		if err := j.runClusterPreflights(ctx, client, node, sshSpec.User, remotePath, logs); err != nil {
+			return fmt.Errorf("cluster preflights: %w", err)
+		}
+	}
+
+	args := j.joinArgs(node)
+
+	fmt.Fprintf(logs, "running join host preflights\n")
+	preflightsCmd := remoteCommand(sshSpec.User, append([]string{remotePath, "join", "run-preflights"}, args...)...)
+	if err := client.Run(ctx, preflightsCmd, logs, logs); err != nil {
+		return fmt.Errorf("host preflights: %w", err)
+	}
+
+	fmt.Fprintf(logs, "joining node\n")
+	stdout := bytes.NewBuffer(nil)
+	joinCmd := remoteCommand(sshSpec.User, append([]string{remotePath, "join"}, append(args, "--output", "json")...)...)
+	if err := client.Run(ctx, joinCmd, io.MultiWriter(logs, stdout), logs); err != nil {
+		if msg := joinResultError(stdout.Bytes()); msg != "" {
+			return fmt.Errorf("join: %s", msg)
+		}
+		return fmt.Errorf("join: %w", err)
+	}
+
+	return nil
+}
+
+// uploadBinary copies the binary to a new temporary directory on the node, only accessible to
+// the SSH user, and returns its path along with a function removing the directory. A fixed
+// path under /tmp could be replaced by another user of the node before it is run as root.
+func uploadBinary(ctx context.Context, client sshutils.Client, binaryPath string, appSlug string, logs io.Writer) (string, func(), error) {
+	binary, err := os.Open(binaryPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("open binary: %w", err)
+	}
+	defer binary.Close()
+
+	stdout := bytes.NewBuffer(nil)
+	if err := client.Run(ctx, "mktemp -d", stdout, logs); err != nil {
+		return "", nil, fmt.Errorf("create temporary directory: %w", err)
+	}
+	dir := strings.TrimSpace(stdout.String())
+	if !strings.HasPrefix(dir, "/") {
+		return "", nil, fmt.Errorf("create temporary directory: unexpected output %q", dir)
+	}
+	cleanup := func() {
+		_ = client.Run(context.Background(), fmt.Sprintf("rm -rf %s", sshutils.Quote(dir)), logs, logs)
+	}
+
+	remotePath := path.Join(dir, appSlug)
+	if err := client.Upload(ctx, binary, remotePath, 0755); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("copy binary: %w", err)
+	}
+	return remotePath, cleanup, nil
+}
+
+// joinArgs returns the arguments passed to the join commands run on the node.
+func (j *nodeJoiner) joinArgs(node nodeinventory.Node) []string {
+	args := []string{j.kotsAPIAddress, j.joinToken(node), "--yes"}
+	if j.inv.Spec.NoHA {
+		args = append(args, "--no-ha")
+	}
+	if j.inv.Spec.NetworkInterface != "" {
+		args = append(args, "--network-interface", j.inv.Spec.NetworkInterface)
+	}
+	if j.inv.IsController(node) && j.inv.Spec.ControlPlaneInterface != "" {
+		args = append(args, "--control-plane-interface", j.inv.Spec.ControlPlaneInterface)
+	}
+	if len(j.inv.Spec.NTPServers) > 0 {
+		args = append(args, "--ntp-servers", strings.Join(j.inv.Spec.NTPServers, ","))
+	}
+	return args
+}
+
+// joinToken returns the token the node joins with, the one of its role.
+func (j *nodeJoiner) joinToken(node nodeinventory.Node) string {
+	if !j.inv.IsController(node) {
+		return j.inv.Spec.JoinTokens[node.Role]
+	}
+	return j.controllerToken
+}
+
+// remoteCommand builds the command line to run on a node. Commands are run with sudo unless
+// connected as root. sudo must not prompt for a password as nobody is there to answer it.
+func remoteCommand(user string, args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = sshutils.Quote(arg)
+	}
+	cmd := strings.Join(quoted, " ")
+	if user != "root" {
+		cmd = "sudo -n " + cmd
+	}
+	return cmd
+}
+
+// joinResultError returns the error reported in the result event of the progress stream
+// written by the join command, if any.
+func joinResultError(stream []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	for scanner.Scan() {
+		var ev progress.Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		if ev.Type == progress.EventTypeResult && ev.Status == progress.StatusFailed {
+			return ev.Error
+		}
+	}
+	return ""
+}
+
+// printNodeJoinResults writes a summary of the outcome of joining each node.
+func printNodeJoinResults(w io.Writer, results []nodeJoinResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nNODE\tROLE\tSTATUS\tDURATION\tLOGS")
+	for _, result := range results {
+		duration := "-"
+		logFile := "-"
+		if result.status != progress.StatusSkipped {
+			duration = result.duration.String()
+			logFile = result.logFile
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.node.Address, result.node.Role, result.status, duration, logFile)
+	}
+	tw.Flush()
+}
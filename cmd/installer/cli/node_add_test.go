@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/sshutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_generateJoinTokens(t *testing.T) {
+	kcli := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "kotsadm-authstring", Namespace: "kotsadm"},
+			Data:       map[string][]byte{"kotsadm-authstring": []byte("Kots abcdef")},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "kotsadm", Namespace: "kotsadm"},
+			Spec: corev1.ServiceSpec{
+				ClusterIP: "10.96.0.10",
+				Ports:     []corev1.ServicePort{{Name: "http", Port: 3000}},
+			},
+		},
+	).Build()
+
+	client := dryrun.NewKotsadm()
+	client.SetGenerateJoinCommandResponse([]string{"worker"}, "sudo ./my-app join 10.0.0.1:30000 workertoken", nil)
+	client.SetGenerateJoinCommandResponse([]string{"gpu"}, "sudo ./my-app join 10.0.0.1:30000 gputoken", nil)
+	kotsadm.Set(client)
+	t.Cleanup(func() { kotsadm.Set(&kotsadm.Client{}) })
+
+	// an install inventory has no join tokens, the cluster doesn't exist when it is written
+	inv := &nodeinventory.NodeInventory{
+		Spec: nodeinventory.NodeInventorySpec{
+			Nodes: []nodeinventory.Node{
+				{Address: "controller-1", Role: nodeinventory.RoleController},
+				{Address: "worker-1", Role: "worker"},
+				{Address: "gpu-1", Role: "gpu"},
+				{Address: "worker-2", Role: "worker"},
+			},
+		},
+	}
+	require.Error(t, nodeinventory.ValidateJoinTokens(inv))
+
+	require.NoError(t, generateJoinTokens(context.Background(), kcli, inv))
+	assert.Equal(t, map[string]string{"worker": "workertoken", "gpu": "gputoken"}, inv.Spec.JoinTokens)
+	require.NoError(t, nodeinventory.ValidateJoinTokens(inv))
+
+	address, authString, err := kotsadmAPIAccess(context.Background(), kcli, "kotsadm")
+	require.NoError(t, err)
+	assert.Equal(t, "10.96.0.10:3000", address)
+	assert.Equal(t, "Kots abcdef", authString)
+}
+
+func Test_parseJoinCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		joinCommand string
+		wantAddress string
+		wantToken   string
+		wantErr     bool
+	}{
+		{
+			name:        "with sudo",
+			joinCommand: "sudo ./my-app join 10.0.0.1:30000 abcdef\n",
+			wantAddress: "10.0.0.1:30000",
+			wantToken:   "abcdef",
+		},
+		{
+			name:        "without sudo",
+			joinCommand: "./my-app join 10.0.0.1:30000 abcdef",
+			wantAddress: "10.0.0.1:30000",
+			wantToken:   "abcdef",
+		},
+		{
+			name:        "missing token",
+			joinCommand: "sudo ./my-app join 10.0.0.1:30000",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, token, err := parseJoinCommand(tt.joinCommand)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantAddress, address)
+			assert.Equal(t, tt.wantToken, token)
+		})
+	}
+}
+
+func Test_remoteCommand(t *testing.T) {
+	assert.Equal(t, `'/tmp/my-app' 'join' 'run-preflights'`, remoteCommand("root", "/tmp/my-app", "join", "run-preflights"))
+	assert.Equal(t, `sudo -n '/tmp/my-app' 'join'`, remoteCommand("ubuntu", "/tmp/my-app", "join"))
+}
+
+func Test_joinResultError(t *testing.T) {
+	stream := `{"type":"phase","name":"initialize","status":"succeeded"}
+not json
+{"type":"result","status":"failed","error":"host preflights failed"}
+`
+	assert.Equal(t, "host preflights failed", joinResultError([]byte(stream)))
+	assert.Equal(t, "", joinResultError([]byte(`{"type":"result","status":"succeeded"}`)))
+}
+
+type fakeSSHClient struct {
+	address string
+	fail    string
+	mu      *sync.Mutex
+	ran     *[]string
+}
+
+func (c *fakeSSHClient) Run(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	c.mu.Lock()
+	*c.ran = append(*c.ran, c.address+": "+cmd)
+	c.mu.Unlock()
+	if cmd == "mktemp -d" {
+		_, _ = io.WriteString(stdout, "/tmp/tmp.abc\n")
+	}
+	if c.fail != "" && strings.Contains(cmd, c.fail) {
+		return errors.New("command exited with status 1")
+	}
+	return nil
+}
+
+func (c *fakeSSHClient) Upload(ctx context.Context, src io.Reader, dst string, mode os.FileMode) error {
+	c.mu.Lock()
+	*c.ran = append(*c.ran, c.address+": upload "+dst)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeSSHClient) Close() error {
+	return nil
+}
+
+func Test_nodeJoiner_joinAll(t *testing.T) {
+	binaryPath := filepath.Join(t.TempDir(), "my-app")
+	require.NoError(t, os.WriteFile(binaryPath, []byte("binary"), 0755))
+
+	tests := []struct {
+		name         string
+		nodes        []nodeinventory.Node
+		failing      map[string]string
+		wantStatuses map[string]progress.Status
+		wantRan      []string
+	}{
+		{
+			name: "controllers then workers",
+			nodes: []nodeinventory.Node{
+				{Address: "worker-1", Role: "worker"},
+				{Address: "controller-1", Role: nodeinventory.RoleController},
+			},
+			wantStatuses: map[string]progress.Status{
+				"controller-1": progress.StatusSucceeded,
+				"worker-1":     progress.StatusSucceeded,
+			},
+			wantRan: []string{
+				"controller-1: mktemp -d",
+				"controller-1: upload /tmp/tmp.abc/my-app",
+				`controller-1: '/tmp/tmp.abc/my-app' 'join' 'run-preflights' '10.0.0.1:30000' 'controller-token' '--yes'`,
+				`controller-1: '/tmp/tmp.abc/my-app' 'join' '10.0.0.1:30000' 'controller-token' '--yes' '--output' 'json'`,
+				"controller-1: rm -rf '/tmp/tmp.abc'",
+				"worker-1: mktemp -d",
+				"worker-1: upload /tmp/tmp.abc/my-app",
+				`worker-1: '/tmp/tmp.abc/my-app' 'join' 'run-preflights' '10.0.0.1:30000' 'worker-token' '--yes'`,
+				`worker-1: '/tmp/tmp.abc/my-app' 'join' '10.0.0.1:30000' 'worker-token' '--yes' '--output' 'json'`,
+				"worker-1: rm -rf '/tmp/tmp.abc'",
+			},
+		},
+		{
+			name: "failing controller stops the join",
+			nodes: []nodeinventory.Node{
+				{Address: "controller-1", Role: nodeinventory.RoleController},
+				{Address: "controller-2", Role: nodeinventory.RoleController},
+				{Address: "worker-1", Role: "worker"},
+			},
+			failing: map[string]string{"controller-1": "run-preflights"},
+			wantStatuses: map[string]progress.Status{
+				"controller-1": progress.StatusFailed,
+				"controller-2": progress.StatusSkipped,
+				"worker-1":     progress.StatusSkipped,
+			},
+			wantRan: []string{
+				"controller-1: mktemp -d",
+				"controller-1: upload /tmp/tmp.abc/my-app",
+				`controller-1: '/tmp/tmp.abc/my-app' 'join' 'run-preflights' '10.0.0.1:30000' 'controller-token' '--yes'`,
+				"controller-1: rm -rf '/tmp/tmp.abc'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			ran := []string{}
+
+			joiner := &nodeJoiner{
+				appSlug:         "my-app",
+				binaryPath:      binaryPath,
+				kotsAPIAddress:  "10.0.0.1:30000",
+				controllerToken: "controller-token",
+				inv: &nodeinventory.NodeInventory{
+					Spec: nodeinventory.NodeInventorySpec{
+						JoinTokens: map[string]string{"worker": "worker-token"},
+						Nodes:      tt.nodes,
+					},
+				},
+				logsDir: t.TempDir(),
+				dial: func(ctx context.Context, cfg sshutils.Config) (sshutils.Client, error) {
+					return &fakeSSHClient{address: cfg.Address, fail: tt.failing[cfg.Address], mu: &mu, ran: &ran}, nil
+				},
+			}
+
+			results := joiner.joinAll(context.Background())
+
+			statuses := map[string]progress.Status{}
+			for _, result := range results {
+				statuses[result.node.Address] = result.status
+			}
+			assert.Equal(t, tt.wantStatuses, statuses)
+			assert.Equal(t, tt.wantRan, ran)
+		})
+	}
+}
+
+func Test_nodeJoiner_joinArgs(t *testing.T) {
+	joiner := &nodeJoiner{
+		kotsAPIAddress:  "10.0.0.1:30000",
+		controllerToken: "controller-token",
+		inv: &nodeinventory.NodeInventory{
+			Spec: nodeinventory.NodeInventorySpec{
+				JoinTokens:            map[string]string{"worker": "worker-token"},
+				NetworkInterface:      "eth0",
+				ControlPlaneInterface: "eth1",
+			},
+		},
+	}
+
+	assert.Equal(t,
+		[]string{"10.0.0.1:30000", "controller-token", "--yes", "--network-interface", "eth0", "--control-plane-interface", "eth1"},
+		joiner.joinArgs(nodeinventory.Node{Address: "controller-1", Role: nodeinventory.RoleController}),
+	)
+	assert.Equal(t,
+		[]string{"10.0.0.1:30000", "worker-token", "--yes", "--network-interface", "eth0"},
+		joiner.joinArgs(nodeinventory.Node{Address: "worker-1", Role: "worker"}),
+	)
+}
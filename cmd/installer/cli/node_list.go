@@ -0,0 +1,315 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/constants"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	nodeutil "k8s.io/component-helpers/node/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// hostPreflightResultLabel labels the configmaps the operator copies the host preflight
+	// results of each node into. Its value is the name of the node.
+	hostPreflightResultLabel = "embedded-cluster/host-preflight-result"
+
+	lamStatusServing    = "serving"
+	lamStatusNotServing = "not serving"
+)
+
+// nodeSummary holds the health and role of a node.
+type nodeSummary struct {
+	Name           string              `json:"name"`
+	Roles          []string            `json:"roles"`
+	ControlPlane   bool                `json:"controlPlane"`
+	Ready          bool                `json:"ready"`
+	InternalIP     string              `json:"internalIP,omitempty"`
+	Version        string              `json:"version"`
+	Pressure       []string            `json:"pressure,omitempty"`
+	HostPreflights *nodeHostPreflights `json:"hostPreflights,omitempty"`
+	// LocalArtifactMirror is the status of the local artifact mirror, only set for the node the
+	// command runs on as the mirror listens on localhost.
+	LocalArtifactMirror string `json:"localArtifactMirror,omitempty"`
+}
+
+// nodeHostPreflights holds the last host preflight results of a node.
+type nodeHostPreflights struct {
+	UpdatedAt string                    `json:"updatedAt,omitempty"`
+	Output    apitypes.PreflightsOutput `json:"output"`
+}
+
+// NodeListCmd returns a cobra command for listing the nodes in the cluster.
+func NodeListCmd(ctx context.Context, appTitle string) *cobra.Command {
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: fmt.Sprintf("List the nodes in the %s cluster with their role and health", appTitle),
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return preRunNodeStatus(cmd, &rc)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("output")
+
+			summaries, err := getNodeSummaries(cmd.Context(), rc)
+			if err != nil {
+				return err
+			}
+
+			if format == outputFormatJSON {
+				return printJSON(os.Stdout, summaries)
+			}
+			printNodeList(os.Stdout, summaries)
+			return nil
+		},
+	}
+
+	mustAddNodeOutputFlag(cmd)
+
+	return cmd
+}
+
+func mustAddNodeOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", outputFormatText, "Output format. One of: text, json.")
+}
+
+// preRunNodeStatus validates the output flag and discovers the runtime config, which points
+// at the kubeconfig used to reach the cluster.
+func preRunNodeStatus(cmd *cobra.Command, rc *runtimeconfig.RuntimeConfig) error {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("unable to get output flag: %w", err)
+	}
+	if format != outputFormatText && format != outputFormatJSON {
+		return fmt.Errorf("invalid output format %q, must be one of %s or %s", format, outputFormatText, outputFormatJSON)
+	}
+
+	// Skip root check if dryrun mode is enabled
+	if !dryrun.Enabled() && os.Getuid() != 0 {
+		return fmt.Errorf("%s command must be run as root", cmd.CommandPath())
+	}
+
+	*rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+	_ = (*rc).SetEnv()
+
+	return nil
+}
+
+// getNodeSummaries returns the summary of every node in the cluster, sorted by name.
+func getNodeSummaries(ctx context.Context, rc runtimeconfig.RuntimeConfig) ([]nodeSummary, error) {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get kube client: %w", err)
+	}
+
+	localNode, _ := nodeutil.GetHostname("")
+	lamStatus := localArtifactMirrorStatus(ctx, rc.LocalArtifactMirrorPort())
+
+	return listNodeSummaries(ctx, kcli, localNode, lamStatus)
+}
+
+// listNodeSummaries returns the summary of every node in the cluster, sorted by name. The
+// status of the local artifact mirror is only known for the local node.
+func listNodeSummaries(ctx context.Context, kcli client.Client, localNode string, lamStatus string) ([]nodeSummary, error) {
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	results, err := getHostPreflightResults(ctx, kcli)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []nodeSummary{}
+	for _, node := range nodes.Items {
+		summary := buildNodeSummary(node, results[node.Name])
+		if node.Name == localNode {
+			summary.LocalArtifactMirror = lamStatus
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries, nil
+}
+
+// getHostPreflightResults returns the last host preflight results of each node, keyed by the
+// name of the node, as copied into configmaps by the operator.
+func getHostPreflightResults(ctx context.Context, kcli client.Client) (map[string]*nodeHostPreflights, error) {
+	var cms corev1.ConfigMapList
+	if err := kcli.List(ctx, &cms, client.InNamespace(constants.EmbeddedClusterNamespace), client.HasLabels{hostPreflightResultLabel}); err != nil {
+		return nil, fmt.Errorf("unable to list host preflight results: %w", err)
+	}
+
+	results := map[string]*nodeHostPreflights{}
+	for _, cm := range cms.Items {
+		var output apitypes.PreflightsOutput
+		if err := json.Unmarshal([]byte(cm.Data["results.json"]), &output); err != nil {
+			continue
+		}
+		results[cm.Labels[hostPreflightResultLabel]] = &nodeHostPreflights{
+			UpdatedAt: cm.Annotations["update-timestamp"],
+			Output:    output,
+		}
+	}
+	return results, nil
+}
+
+// buildNodeSummary summarizes the role and health of the node.
+func buildNodeSummary(node corev1.Node, hostPreflights *nodeHostPreflights) nodeSummary {
+	summary := nodeSummary{
+		Name:           node.Name,
+		Roles:          nodeRoles(node),
+		Version:        node.Status.NodeInfo.KubeletVersion,
+		HostPreflights: hostPreflights,
+	}
+
+	_, summary.ControlPlane = node.Labels["node-role.kubernetes.io/control-plane"]
+
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			summary.InternalIP = addr.Address
+			break
+		}
+	}
+
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			summary.Ready = cond.Status == corev1.ConditionTrue
+			continue
+		}
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case corev1.NodeDiskPressure:
+			summary.Pressure = append(summary.Pressure, "disk")
+		case corev1.NodeMemoryPressure:
+			summary.Pressure = append(summary.Pressure, "memory")
+		case corev1.NodePIDPressure:
+			summary.Pressure = append(summary.Pressure, "pid")
+		}
+	}
+
+	return summary
+}
+
+// nodeRoles returns the roles assigned to the node when it joined. Roles are stored in the
+// kots.io/embedded-cluster-role-<n> labels, the first one being the controller role for
+// controllers. Nodes missing the labels are reported as controller or worker.
+func nodeRoles(node corev1.Node) []string {
+	roles := []string{}
+	for i := 0; ; i++ {
+		role, ok := node.Labels["kots.io/embedded-cluster-role-"+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		roles = append(roles, role)
+	}
+	if len(roles) > 0 {
+		return roles
+	}
+
+	if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
+		return []string{"controller"}
+	}
+	return []string{"worker"}
+}
+
+// localArtifactMirrorStatus reports whether the local artifact mirror of this node answers on
+// the given port.
+func localArtifactMirrorStatus(ctx context.Context, port int) string {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	url := fmt.Sprintf("http://%s/", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return lamStatusNotServing
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return lamStatusNotServing
+	}
+	resp.Body.Close()
+	return lamStatusServing
+}
+
+// printNodeList writes a table with a line per node.
+func printNodeList(w io.Writer, summaries []nodeSummary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tROLES\tSTATUS\tVERSION\tPRESSURE\tHOST PREFLIGHTS\tARTIFACT MIRROR (THIS NODE ONLY)")
+	for _, summary := range summaries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			summary.Name,
+			strings.Join(summary.Roles, ","),
+			summary.readyString(),
+			summary.Version,
+			summary.pressureString(),
+			summary.hostPreflightsString(),
+			summary.localArtifactMirrorString(),
+		)
+	}
+	tw.Flush()
+}
+
+func (s nodeSummary) readyString() string {
+	if s.Ready {
+		return "Ready"
+	}
+	return "NotReady"
+}
+
+func (s nodeSummary) pressureString() string {
+	if len(s.Pressure) == 0 {
+		return "none"
+	}
+	return strings.Join(s.Pressure, ",")
+}
+
+func (s nodeSummary) localArtifactMirrorString() string {
+	if s.LocalArtifactMirror == "" {
+		return "-"
+	}
+	return s.LocalArtifactMirror
+}
+
+func (s nodeSummary) hostPreflightsString() string {
+	if s.HostPreflights == nil {
+		return "-"
+	}
+	output := s.HostPreflights.Output
+	return fmt.Sprintf("%d passed, %d warnings, %d failures", len(output.Pass), len(output.Warn), len(output.Fail))
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
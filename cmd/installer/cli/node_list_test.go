@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_listNodeSummaries(t *testing.T) {
+	controller := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-1",
+			Labels: map[string]string{
+				"node-role.kubernetes.io/control-plane": "true",
+				"kots.io/embedded-cluster-role":         "total-2",
+				"kots.io/embedded-cluster-role-0":       "controller",
+				"kots.io/embedded-cluster-role-1":       "database",
+			},
+		},
+		Status: corev1.NodeStatus{
+			NodeInfo:  corev1.NodeSystemInfo{KubeletVersion: "v1.33.4+k0s"},
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "10.0.0.1"}},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeDiskPressure, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+	worker := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status: corev1.NodeStatus{
+			NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.33.4+k0s"},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+	results := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-1-host-preflight-results",
+			Namespace:   "embedded-cluster",
+			Labels:      map[string]string{"embedded-cluster/host-preflight-result": "node-1"},
+			Annotations: map[string]string{"update-timestamp": "2025-01-01T00:00:00Z"},
+		},
+		Data: map[string]string{
+			"results.json": `{"pass":[{"title":"CPU"}],"warn":[{"title":"Disk","message":"disk is slow"}],"fail":[]}`,
+		},
+	}
+
+	kcli := fake.NewClientBuilder().WithObjects(controller, worker, results).Build()
+
+	got, err := listNodeSummaries(context.Background(), kcli, "node-1", lamStatusServing)
+	require.NoError(t, err)
+
+	want := []nodeSummary{
+		{
+			Name:    "node-0",
+			Roles:   []string{"worker"},
+			Version: "v1.33.4+k0s",
+		},
+		{
+			Name:         "node-1",
+			Roles:        []string{"controller", "database"},
+			ControlPlane: true,
+			Ready:        true,
+			InternalIP:   "10.0.0.1",
+			Version:      "v1.33.4+k0s",
+			Pressure:     []string{"disk"},
+			HostPreflights: &nodeHostPreflights{
+				UpdatedAt: "2025-01-01T00:00:00Z",
+				Output: apitypes.PreflightsOutput{
+					Pass: []apitypes.PreflightsRecord{{Title: "CPU"}},
+					Warn: []apitypes.PreflightsRecord{{Title: "Disk", Message: "disk is slow"}},
+					Fail: []apitypes.PreflightsRecord{},
+				},
+			},
+			LocalArtifactMirror: lamStatusServing,
+		},
+	}
+	assert.Equal(t, want, got)
+}
+
+func Test_printNodeList(t *testing.T) {
+	summaries := []nodeSummary{
+		{
+			Name:    "node-0",
+			Roles:   []string{"worker"},
+			Version: "v1.33.4+k0s",
+		},
+		{
+			Name:         "node-1",
+			Roles:        []string{"controller", "database"},
+			ControlPlane: true,
+			Ready:        true,
+			Version:      "v1.33.4+k0s",
+			Pressure:     []string{"disk", "memory"},
+			HostPreflights: &nodeHostPreflights{
+				Output: apitypes.PreflightsOutput{
+					Pass: []apitypes.PreflightsRecord{{Title: "CPU"}},
+					Warn: []apitypes.PreflightsRecord{{Title: "Disk", Message: "disk is slow"}},
+				},
+			},
+			LocalArtifactMirror: lamStatusServing,
+		},
+	}
+
+	buf := bytes.NewBuffer(nil)
+	printNodeList(buf, summaries)
+
+	want := `NAME    ROLES                STATUS    VERSION      PRESSURE     HOST PREFLIGHTS                   ARTIFACT MIRROR (THIS NODE ONLY)
+node-0  worker               NotReady  v1.33.4+k0s  none         -                                 -
+node-1  controller,database  Ready     v1.33.4+k0s  disk,memory  1 passed, 1 warnings, 0 failures  serving
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func Test_printNodeStatus(t *testing.T) {
+	summary := nodeSummary{
+		Name:         "node-1",
+		Roles:        []string{"controller"},
+		ControlPlane: true,
+		Ready:        true,
+		InternalIP:   "10.0.0.1",
+		Version:      "v1.33.4+k0s",
+		HostPreflights: &nodeHostPreflights{
+			UpdatedAt: "2025-01-01T00:00:00Z",
+			Output: apitypes.PreflightsOutput{
+				Warn: []apitypes.PreflightsRecord{{Title: "Disk", Message: "disk is slow"}},
+				Fail: []apitypes.PreflightsRecord{{Title: "Memory", Message: "not enough memory"}},
+			},
+		},
+		LocalArtifactMirror: lamStatusServing,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	printNodeStatus(buf, summary)
+
+	want := `Name:             node-1
+Roles:            controller
+Control plane:    yes
+Internal IP:      10.0.0.1
+Status:           Ready
+Version:          v1.33.4+k0s
+Pressure:         none
+Artifact mirror:  serving
+Host preflights:  0 passed, 1 warnings, 1 failures (as of 2025-01-01T00:00:00Z)
+  FAIL  Memory: not enough memory
+  WARN  Disk: disk is slow
+`
+	assert.Equal(t, want, buf.String())
+
+	// the artifact mirror of other nodes is not checked
+	summary.Name = "node-2"
+	summary.LocalArtifactMirror = ""
+	buf.Reset()
+	printNodeStatus(buf, summary)
+	assert.Contains(t, buf.String(), "Artifact mirror:  not checked, only known when run on the node itself\n")
+}
@@ -0,0 +1,409 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	autopilot "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	"github.com/k0sproject/k0s/pkg/etcd"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/constants"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	nodeutil "k8s.io/component-helpers/node/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// openEBSLocalProvisioner is the provisioner of the OpenEBS local volumes. These volumes
+	// live on the disk of a single node and are lost with it.
+	openEBSLocalProvisioner = "openebs.io/local"
+)
+
+type nodeRemoveFlags struct {
+	force        bool
+	assumeYes    bool
+	drainTimeout time.Duration
+}
+
+// NodeRemoveCmd returns a cobra command for removing a node from the cluster from another
+// node, e.g. when the node being removed is no longer reachable.
+func NodeRemoveCmd(ctx context.Context, appTitle string) *cobra.Command {
+	var flags nodeRemoveFlags
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "remove <name>",
+		Short: fmt.Sprintf("Remove a node from the %s cluster without logging into it", appTitle),
+		Long: fmt.Sprintf(`Remove a node from the %s cluster from one of the remaining controller nodes.
+
+The node is drained and removed from the cluster. When removing a controller, its etcd member
+is removed as well. Pods are rescheduled on the other nodes, but the data of OpenEBS local
+volumes stored on the node does not move: the volumes are deleted and their pods start with new
+empty ones. Use reset instead to remove a node that is still running.`, appTitle),
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("node remove command must be run as root")
+			}
+
+			rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+			_ = rc.SetEnv()
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runNodeRemove(cmd.Context(), args[0], flags)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.force, "force", false, "Remove the node even if it is ready or the safety checks fail (implies --yes)")
+	cmd.Flags().BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().DurationVar(&flags.drainTimeout, "drain-timeout", 2*time.Minute, "Time to wait for the node to drain before deleting the pods left on it")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+
+	return cmd
+}
+
+func runNodeRemove(ctx context.Context, name string, flags nodeRemoveFlags) error {
+	status, err := k0s.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get k0s status, is this a cluster node?: %w", err)
+	}
+	if status.Role != "controller" {
+		return fmt.Errorf("node remove command must be run on a controller node")
+	}
+
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return fmt.Errorf("unable to get hostname: %w", err)
+	}
+	if name == hostname {
+		return fmt.Errorf("cannot remove the node this command runs on, use reset instead")
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	var node corev1.Node
+	if err := kcli.Get(ctx, client.ObjectKey{Name: name}, &node); err != nil {
+		return fmt.Errorf("unable to get node %s: %w", name, err)
+	}
+	_, isController := node.Labels["node-role.kubernetes.io/control-plane"]
+
+	if !flags.force {
+		safe, reason, err := checkNodeRemoveSafety(ctx, kcli, status, node)
+		if err != nil {
+			return err
+		}
+		if !safe {
+			return fmt.Errorf("%s\nRun node remove with --force to ignore this", reason)
+		}
+	}
+
+	if isController {
+		if err := maybePrintNodeRemoveHAWarning(ctx, kcli); err != nil && !flags.force {
+			return err
+		}
+	}
+
+	logrus.Warnf("This will remove node %s from the cluster. Data in OpenEBS volumes stored on it will be lost.", name)
+	if !flags.force && !flags.assumeYes {
+		confirmed, err := prompts.New().Confirm("Do you want to continue?", false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			return fmt.Errorf("Aborting")
+		}
+	}
+
+	if err := removeNodeFromCluster(ctx, kcli, name, isController, flags); err != nil {
+		return err
+	}
+
+	logrus.Infof("Node %s removed from the cluster.", name)
+	logrus.Info("Reset the node before joining it again.")
+
+	return nil
+}
+
+// removeNodeFromCluster drains the node and removes it, along with its etcd member for
+// controllers and the OpenEBS local volumes stored on it.
+func removeNodeFromCluster(ctx context.Context, kcli client.Client, name string, isController bool, flags nodeRemoveFlags) error {
+	logrus.Info("Draining node...")
+	drainNodeRemotely(ctx, kcli, name, flags.drainTimeout)
+
+	if isController {
+		logrus.Info("Removing etcd member...")
+		if err := removeEtcdMember(name); err != nil && !checkErrPrompt(flags.assumeYes, flags.force, err) {
+			return err
+		}
+	}
+
+	logrus.Info("Removing OpenEBS volumes stored on the node...")
+	removed, err := deleteOpenEBSVolumesOnNode(ctx, kcli, name)
+	if err != nil && !checkErrPrompt(flags.assumeYes, flags.force, err) {
+		return err
+	}
+	for _, pvc := range removed {
+		logrus.Infof("Deleted volume claim %s, its pods will be rescheduled with a new empty volume as the data stored on the node does not move", pvc)
+	}
+
+	logrus.Info("Removing node from cluster...")
+	if err := deleteNodeObjects(ctx, kcli, name); err != nil && !checkErrPrompt(flags.assumeYes, flags.force, err) {
+		return err
+	}
+
+	if err := removeNodeFromInstallation(ctx, kcli, name); err != nil {
+		logrus.Warnf("Unable to update installation status: %v", err)
+	}
+
+	return nil
+}
+
+// checkNodeRemoveSafety makes sure removing the node won't cause an outage. The node must not be
+// ready, as a node that is still running would join the cluster again, and a controller must
+// pass the checks of a controller leaving the cluster.
+func checkNodeRemoveSafety(ctx context.Context, kcli client.Client, status *k0s.K0sStatus, node corev1.Node) (bool, string, error) {
+	if isNodeReady(node) {
+		return false, fmt.Sprintf("Node %s is ready. Run reset on the node to remove it instead.", node.Name), nil
+	}
+
+	if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; !ok {
+		return true, "", nil
+	}
+
+	return checkControllerLeaveSafety(ctx, kcli, status, node.Name, config.GetControllerRoleName())
+}
+
+// maybePrintNodeRemoveHAWarning warns when removing a controller would leave a high
+// availability cluster with less than three controllers.
+func maybePrintNodeRemoveHAWarning(ctx context.Context, kcli client.Client) error {
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get installation: %w", err)
+	}
+	if !in.Spec.HighAvailability {
+		return nil
+	}
+
+	numControllerNodes, err := kubeutils.NumOfControlPlaneNodes(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to check control plane nodes: %w", err)
+	}
+	if numControllerNodes > 3 {
+		return nil
+	}
+
+	controllerRoleName := config.GetControllerRoleName()
+	logrus.Warnf("High-availability is enabled and requires at least three %s nodes.", controllerRoleName)
+	logrus.Warnf("Removing this node will leave only %d.", numControllerNodes-1)
+	logrus.Warn("This can lead to a loss of functionality and non-recoverable failures.")
+	logrus.Warnf("Join another %s node as soon as possible.", controllerRoleName)
+	logrus.Info("")
+	return nil
+}
+
+// drainNodeRemotely cordons and drains the node. Pods left on the node once the timeout
+// expires, which is expected when the node is down, are deleted without waiting for the
+// kubelet to confirm.
+func drainNodeRemotely(ctx context.Context, kcli client.Client, name string, timeout time.Duration) {
+	out, err := helpers.RunCommand(k0sBinPath, "kubectl", "drain",
+		"--ignore-daemonsets",
+		"--delete-emptydir-data",
+		"--timeout", timeout.String(),
+		name,
+	)
+	if err != nil {
+		logrus.Debugf("Node drain did not complete: %v, %s", err, out)
+	}
+
+	if err := forceDeletePodsOnNode(ctx, kcli, name); err != nil {
+		logrus.Warnf("Unable to delete the pods left on the node (continuing anyway): %v", err)
+	}
+}
+
+// forceDeletePodsOnNode deletes the pods scheduled on the node immediately.
+func forceDeletePodsOnNode(ctx context.Context, kcli client.Client, name string) error {
+	var pods corev1.PodList
+	if err := kcli.List(ctx, &pods, client.MatchingFields{"spec.nodeName": name}); err != nil {
+		return fmt.Errorf("list pods: %w", err)
+	}
+	for _, pod := range pods.Items {
+		if err := kcli.Delete(ctx, &pod, client.GracePeriodSeconds(0)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("delete pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// listEtcdMembers returns the etcd members, mapping their names to their peer urls.
+var listEtcdMembers = func() (etcdMembers, error) {
+	var memberlist etcdMembers
+	out, err := helpers.RunCommand(k0sBinPath, "etcd", "member-list")
+	if err != nil {
+		return memberlist, fmt.Errorf("unable to list etcd members: %w", err)
+	}
+	if err := json.Unmarshal([]byte(out), &memberlist); err != nil {
+		return memberlist, fmt.Errorf("unable to parse etcd members: %w", err)
+	}
+	return memberlist, nil
+}
+
+// removeEtcdMember removes the etcd member of the node, if it is still a member.
+func removeEtcdMember(name string) error {
+	memberlist, err := listEtcdMembers()
+	if err != nil {
+		return err
+	}
+
+	peerURL, ok := memberlist.Members[name]
+	if !ok {
+		logrus.Debugf("Node %s is not an etcd member", name)
+		return nil
+	}
+	u, err := url.Parse(peerURL)
+	if err != nil {
+		return fmt.Errorf("unable to parse peer url %q: %w", peerURL, err)
+	}
+
+	if _, err := helpers.RunCommand(k0sBinPath, "etcd", "leave", "--peer-address", u.Hostname()); err != nil {
+		return fmt.Errorf("unable to remove etcd member: %w", err)
+	}
+	return nil
+}
+
+// deleteOpenEBSVolumesOnNode deletes the OpenEBS local volumes stored on the node along with
+// their claims so the workloads using them can be rescheduled. The names of the deleted
+// claims are returned.
+func deleteOpenEBSVolumesOnNode(ctx context.Context, kcli client.Client, name string) ([]string, error) {
+	var pvs corev1.PersistentVolumeList
+	if err := kcli.List(ctx, &pvs); err != nil {
+		return nil, fmt.Errorf("list persistent volumes: %w", err)
+	}
+
+	removed := []string{}
+	for _, pv := range pvs.Items {
+		if pv.Annotations["pv.kubernetes.io/provisioned-by"] != openEBSLocalProvisioner || !isVolumePinnedToNode(pv, name) {
+			continue
+		}
+
+		if ref := pv.Spec.ClaimRef; ref != nil {
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvc.Namespace, pvc.Name = ref.Namespace, ref.Name
+			if err := kcli.Delete(ctx, pvc); client.IgnoreNotFound(err) != nil {
+				return removed, fmt.Errorf("delete volume claim %s/%s: %w", ref.Namespace, ref.Name, err)
+			}
+			removed = append(removed, fmt.Sprintf("%s/%s", ref.Namespace, ref.Name))
+		}
+
+		if err := kcli.Delete(ctx, &pv); client.IgnoreNotFound(err) != nil {
+			return removed, fmt.Errorf("delete persistent volume %s: %w", pv.Name, err)
+		}
+	}
+
+	return removed, nil
+}
+
+// isVolumePinnedToNode returns true if the node affinity of the volume selects the node by
+// hostname, as done for local volumes.
+func isVolumePinnedToNode(pv corev1.PersistentVolume, name string) bool {
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return false
+	}
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == corev1.LabelHostname && expr.Operator == corev1.NodeSelectorOpIn && slices.Contains(expr.Values, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deleteNodeObjects deletes the Node and, for controllers, the autopilot ControlNode.
+func deleteNodeObjects(ctx context.Context, kcli client.Client, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	controlNode := &autopilot.ControlNode{}
+	controlNode.Name = name
+	if err := kcli.Delete(ctx, controlNode); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete ControlNode: %w", err)
+	}
+
+	node := &corev1.Node{}
+	node.Name = name
+	if err := kcli.Delete(ctx, node); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("unable to delete Node: %w", err)
+	}
+
+	if err := kcli.DeleteAllOf(ctx, &corev1.ConfigMap{},
+		client.InNamespace(constants.EmbeddedClusterNamespace),
+		client.MatchingLabels{hostPreflightResultLabel: name},
+	); err != nil {
+		logrus.Debugf("Unable to delete host preflight results of node %s: %v", name, err)
+	}
+
+	return nil
+}
+
+// nodeRemovedFromInstallationTimeout is how long the operator is given to notice the node is
+// gone before the installation status is updated here.
+var nodeRemovedFromInstallationTimeout = time.Minute
+
+// removeNodeFromInstallation makes sure the node no longer shows in the installation status.
+// The operator does this, and reports the removal, once it notices the node is gone. The
+// status is only updated here if the operator doesn't do it in time.
+func removeNodeFromInstallation(ctx context.Context, kcli client.Client, name string) error {
+	hasNode := func(in *ecv1beta1.Installation) bool {
+		return slices.ContainsFunc(in.Status.NodesStatus, func(s ecv1beta1.NodeStatus) bool {
+			return s.Name == name
+		})
+	}
+
+	var in *ecv1beta1.Installation
+	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, nodeRemovedFromInstallationTimeout, true, func(ctx context.Context) (bool, error) {
+		var err error
+		in, err = kubeutils.GetLatestInstallation(ctx, kcli)
+		if err != nil {
+			return false, err
+		}
+		return !hasNode(in), nil
+	})
+	if err == nil {
+		return nil
+	}
+	if in == nil || !wait.Interrupted(err) {
+		return fmt.Errorf("get latest installation: %w", err)
+	}
+
+	return kubeutils.UpdateInstallationStatus(ctx, kcli, in, func(status *ecv1beta1.InstallationStatus) {
+		status.NodesStatus = slices.DeleteFunc(status.NodesStatus, func(s ecv1beta1.NodeStatus) bool {
+			return s.Name == name
+		})
+	})
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	autopilot "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func localPV(name, node, claimNamespace, claimName string) *corev1.PersistentVolume {
+	return &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{"pv.kubernetes.io/provisioned-by": openEBSLocalProvisioner},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			ClaimRef: &corev1.ObjectReference{Namespace: claimNamespace, Name: claimName},
+			NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      corev1.LabelHostname,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{node},
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func Test_deleteOpenEBSVolumesOnNode(t *testing.T) {
+	pinned := localPV("pv-1", "node-1", "app", "data-0")
+	otherNode := localPV("pv-2", "node-2", "app", "data-1")
+	otherProvisioner := localPV("pv-3", "node-1", "app", "data-2")
+	otherProvisioner.Annotations["pv.kubernetes.io/provisioned-by"] = "nfs"
+
+	objs := []client.Object{pinned, otherNode, otherProvisioner}
+	for _, name := range []string{"data-0", "data-1", "data-2"} {
+		objs = append(objs, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "app", Name: name}})
+	}
+	kcli := fake.NewClientBuilder().WithObjects(objs...).Build()
+
+	removed, err := deleteOpenEBSVolumesOnNode(context.Background(), kcli, "node-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/data-0"}, removed)
+
+	var pvs corev1.PersistentVolumeList
+	require.NoError(t, kcli.List(context.Background(), &pvs))
+	var names []string
+	for _, pv := range pvs.Items {
+		names = append(names, pv.Name)
+	}
+	assert.ElementsMatch(t, []string{"pv-2", "pv-3"}, names)
+
+	err = kcli.Get(context.Background(), client.ObjectKey{Namespace: "app", Name: "data-0"}, &corev1.PersistentVolumeClaim{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	require.NoError(t, kcli.Get(context.Background(), client.ObjectKey{Namespace: "app", Name: "data-1"}, &corev1.PersistentVolumeClaim{}))
+}
+
+func Test_deleteNodeObjects(t *testing.T) {
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&autopilot.ControlNode{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "embedded-cluster",
+			Name:      "node-1-host-preflight-results",
+			Labels:    map[string]string{hostPreflightResultLabel: "node-1"},
+		}},
+	).Build()
+
+	require.NoError(t, deleteNodeObjects(context.Background(), kcli, "node-1"))
+
+	err := kcli.Get(context.Background(), client.ObjectKey{Name: "node-1"}, &corev1.Node{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	err = kcli.Get(context.Background(), client.ObjectKey{Name: "node-1"}, &autopilot.ControlNode{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	err = kcli.Get(context.Background(), client.ObjectKey{Namespace: "embedded-cluster", Name: "node-1-host-preflight-results"}, &corev1.ConfigMap{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	require.NoError(t, kcli.Get(context.Background(), client.ObjectKey{Name: "node-2"}, &corev1.Node{}))
+
+	// removing a worker, or a node already gone, is not an error
+	require.NoError(t, deleteNodeObjects(context.Background(), kcli, "node-1"))
+}
+
+func Test_removeNodeFromInstallation(t *testing.T) {
+	timeout := nodeRemovedFromInstallationTimeout
+	nodeRemovedFromInstallationTimeout = time.Millisecond
+	t.Cleanup(func() { nodeRemovedFromInstallationTimeout = timeout })
+
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20250101000000"},
+		Status: ecv1beta1.InstallationStatus{
+			NodesStatus: []ecv1beta1.NodeStatus{{Name: "node-0"}, {Name: "node-1"}},
+		},
+	}
+	kcli := fake.NewClientBuilder().
+		WithScheme(kubeutils.Scheme).
+		WithStatusSubresource(&ecv1beta1.Installation{}).
+		WithObjects(in).
+		Build()
+
+	require.NoError(t, removeNodeFromInstallation(context.Background(), kcli, "node-1"))
+
+	got, err := kubeutils.GetLatestInstallation(context.Background(), kcli)
+	require.NoError(t, err)
+	assert.Equal(t, []ecv1beta1.NodeStatus{{Name: "node-0"}}, got.Status.NodesStatus)
+}
+
+func Test_checkNodeRemoveSafety(t *testing.T) {
+	ready := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	notReadyWorker := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-2"},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}},
+		},
+	}
+	notReadyController := corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node-3",
+			Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionUnknown}},
+		},
+	}
+	kcli := fake.NewClientBuilder().WithObjects(ready.DeepCopy(), notReadyWorker.DeepCopy(), notReadyController.DeepCopy()).Build()
+
+	safe, reason, err := checkNodeRemoveSafety(context.Background(), kcli, &k0s.K0sStatus{}, ready)
+	require.NoError(t, err)
+	assert.False(t, safe)
+	assert.Contains(t, reason, "Node node-1 is ready")
+
+	safe, _, err = checkNodeRemoveSafety(context.Background(), kcli, &k0s.K0sStatus{}, notReadyWorker)
+	require.NoError(t, err)
+	assert.True(t, safe)
+
+	// the controller checks are shared with reset
+	safe, reason, err = checkNodeRemoveSafety(context.Background(), kcli, &k0s.K0sStatus{}, notReadyController)
+	require.NoError(t, err)
+	assert.False(t, safe)
+	assert.Equal(t, "Cannot remove the last controller node when there are other nodes in the cluster.", reason)
+}
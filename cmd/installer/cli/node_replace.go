@@ -0,0 +1,336 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	nodeutil "k8s.io/component-helpers/node/util"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type nodeReplaceFlags struct {
+	remove      nodeRemoveFlags
+	nodesFile   string
+	joinTimeout time.Duration
+}
+
+// NodeReplaceCmd returns a cobra command for replacing a failed controller with a new one.
+func NodeReplaceCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
+	var flags nodeReplaceFlags
+	var rc runtimeconfig.RuntimeConfig
+	var inv *nodeinventory.NodeInventory
+
+	cmd := &cobra.Command{
+		Use:   "replace <name>",
+		Short: fmt.Sprintf("Replace a failed controller node of the %s cluster", appTitle),
+		Long: fmt.Sprintf(`Replace a failed controller node of the %s cluster from one of the remaining controller nodes.
+
+The command makes sure etcd keeps quorum without the failed controller, removes it and its etcd
+member, and then waits for a single new controller to join. The replacement is joined over SSH
+when a NodeInventory file listing it is provided with --nodes, otherwise the join command to run
+on it is printed. Once the new etcd member is healthy, high availability of the registry and the
+Admin Console is reconciled across the new set of controllers.`, appTitle),
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("node replace command must be run as root")
+			}
+
+			if flags.nodesFile != "" {
+				var err error
+				inv, err = nodeinventory.ParseFile(flags.nodesFile, config.GetControllerRoleName())
+				if err != nil {
+					return fmt.Errorf("unable to read node inventory: %w", err)
+				}
+				if err := validateReplacementInventory(inv); err != nil {
+					return err
+				}
+			}
+
+			rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+			_ = rc.SetEnv()
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runNodeReplace(cmd.Context(), appSlug, rc, args[0], inv, flags)
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.remove.force, "force", false, "Replace the node even if it is ready or the safety checks fail (implies --yes)")
+	cmd.Flags().BoolVarP(&flags.remove.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().DurationVar(&flags.remove.drainTimeout, "drain-timeout", 2*time.Minute, "Time to wait for the node to drain before deleting the pods left on it")
+	cmd.Flags().StringVar(&flags.nodesFile, "nodes", "", "Path to a NodeInventory file listing the replacement controller to join over SSH")
+	cmd.Flags().DurationVar(&flags.joinTimeout, "join-timeout", 30*time.Minute, "Time to wait for the replacement controller to join and its etcd member to be started")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+
+	return cmd
+}
+
+// validateReplacementInventory makes sure the inventory lists a single controller.
+func validateReplacementInventory(inv *nodeinventory.NodeInventory) error {
+	if len(inv.Spec.Nodes) != 1 || !inv.IsController(inv.Spec.Nodes[0]) {
+		return fmt.Errorf("node inventory must list exactly one node with the %s role", inv.ControllerRoleName())
+	}
+	return nil
+}
+
+func runNodeReplace(ctx context.Context, appSlug string, rc runtimeconfig.RuntimeConfig, name string, inv *nodeinventory.NodeInventory, flags nodeReplaceFlags) error {
+	status, err := k0s.GetStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get k0s status, is this a cluster node?: %w", err)
+	}
+	if status.Role != "controller" {
+		return fmt.Errorf("node replace command must be run on a controller node")
+	}
+
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return fmt.Errorf("unable to get hostname: %w", err)
+	}
+	if name == hostname {
+		return fmt.Errorf("cannot replace the node this command runs on")
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	var node corev1.Node
+	if err := kcli.Get(ctx, client.ObjectKey{Name: name}, &node); err != nil {
+		return fmt.Errorf("unable to get node %s: %w", name, err)
+	}
+	if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; !ok {
+		return fmt.Errorf("node %s is not a controller, use node remove and node add instead", name)
+	}
+
+	controllers, err := listControllers(ctx, kcli)
+	if err != nil {
+		return err
+	}
+
+	if !flags.remove.force {
+		safe, reason, err := checkNodeRemoveSafety(ctx, kcli, status, node)
+		if err != nil {
+			return err
+		}
+		if !safe {
+			return fmt.Errorf("%s\nRun node replace with --force to ignore this", reason)
+		}
+
+		members, err := listEtcdMembers()
+		if err != nil {
+			return err
+		}
+		if safe, reason := checkEtcdQuorum(members, name, controllers); !safe {
+			return fmt.Errorf("%s\nRun node replace with --force to ignore this", reason)
+		}
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get latest installation: %w", err)
+	}
+
+	logrus.Warnf("This will remove node %s from the cluster. Data in OpenEBS volumes stored on it will be lost.", name)
+	if !flags.remove.force && !flags.remove.assumeYes {
+		confirmed, err := prompts.New().Confirm("Do you want to continue?", false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			return fmt.Errorf("Aborting")
+		}
+	}
+
+	if err := removeNodeFromCluster(ctx, kcli, name, true, flags.remove); err != nil {
+		return err
+	}
+	logrus.Infof("Node %s removed from the cluster.", name)
+
+	if err := joinReplacementController(ctx, appSlug, rc, kcli, inv); err != nil {
+		return err
+	}
+
+	logrus.Info("Waiting for the replacement controller to join...")
+	// the replacement may reuse the name of the node it replaces, which is no longer in the
+	// cluster at this point
+	existing := []string{}
+	for _, controller := range controllers {
+		if controller.Name != name {
+			existing = append(existing, controller.Name)
+		}
+	}
+	replacement, err := waitForReplacementController(ctx, kcli, existing, flags.joinTimeout)
+	if err != nil {
+		return fmt.Errorf("unable to wait for the replacement controller: %w", err)
+	}
+	logrus.Infof("Controller %s joined and its etcd member is started.", replacement)
+
+	if in.Spec.HighAvailability {
+		if err := reconcileHA(ctx, kcli, rc, in); err != nil {
+			return fmt.Errorf("unable to reconcile high availability: %w", err)
+		}
+	}
+
+	logrus.Infof("Node %s replaced by %s.", name, replacement)
+
+	return nil
+}
+
+// listControllers returns the controller nodes in the cluster.
+func listControllers(ctx context.Context, kcli client.Client) ([]corev1.Node, error) {
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes, client.HasLabels{"node-role.kubernetes.io/control-plane"}); err != nil {
+		return nil, fmt.Errorf("unable to list controller nodes: %w", err)
+	}
+	return nodes.Items, nil
+}
+
+// checkEtcdQuorum makes sure etcd keeps quorum while the member of the node being replaced is
+// swapped for a new one. The members on ready controllers, other than the node being replaced,
+// must be a majority of the current members.
+func checkEtcdQuorum(members etcdMembers, name string, controllers []corev1.Node) (bool, string) {
+	ready := 0
+	for member := range members.Members {
+		if member == name {
+			continue
+		}
+		idx := slices.IndexFunc(controllers, func(node corev1.Node) bool {
+			return node.Name == member
+		})
+		if idx >= 0 && isNodeReady(controllers[idx]) {
+			ready++
+		}
+	}
+
+	quorum := len(members.Members)/2 + 1
+	if ready < quorum {
+		return false, fmt.Sprintf("Only %d of the %d etcd members are on ready controllers other than %s, and %d are needed to keep quorum.", ready, len(members.Members), name, quorum)
+	}
+	return true, ""
+}
+
+func isNodeReady(node corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// joinReplacementController joins the controller in the inventory over SSH. Without an
+// inventory, the join command to run on the replacement is printed instead. The join command is
+// generated for the replacement alone and is never logged, as its token grants access to the
+// cluster as a controller.
+func joinReplacementController(ctx context.Context, appSlug string, rc runtimeconfig.RuntimeConfig, kcli client.Client, inv *nodeinventory.NodeInventory) error {
+	joinCommand, err := generateJoinCommand(ctx, kcli, config.GetControllerRoleName())
+	if err != nil {
+		return err
+	}
+
+	if inv == nil {
+		logrus.Info("Run the following command on the replacement controller to join it to the cluster.")
+		logrus.Warn("It grants access to the cluster as a controller, don't share or store it.")
+		// printed rather than logged so the token stays out of the log files
+		fmt.Fprintf(humanOutput(), "  %s\n", strings.TrimSpace(joinCommand))
+		return nil
+	}
+
+	joiner, err := newNodeJoiner(appSlug, rc, joinCommand, inv)
+	if err != nil {
+		return err
+	}
+	results := joiner.joinAll(ctx)
+
+	printNodeJoinResults(humanOutput(), results)
+
+	for _, result := range results {
+		if result.status != progress.StatusSucceeded {
+			return NewErrorNothingElseToAdd(fmt.Errorf("replacement controller %s did not join the cluster", result.node.Address))
+		}
+	}
+	return nil
+}
+
+// waitForReplacementController waits for a ready controller not in the existing list to show
+// up, and for its etcd member to be started. The name of the new controller is returned.
+func waitForReplacementController(ctx context.Context, kcli client.Client, existing []string, timeout time.Duration) (string, error) {
+	var replacement string
+	err := wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		controllers, err := listControllers(ctx, kcli)
+		if err != nil {
+			logrus.Debugf("Unable to list controllers: %v", err)
+			return false, nil
+		}
+
+		replacement = ""
+		for _, node := range controllers {
+			if !slices.Contains(existing, node.Name) && isNodeReady(node) {
+				replacement = node.Name
+				break
+			}
+		}
+		if replacement == "" {
+			return false, nil
+		}
+
+		members, err := listEtcdMembers()
+		if err != nil {
+			logrus.Debugf("Unable to list etcd members: %v", err)
+			return false, nil
+		}
+		_, ok := members.Members[replacement]
+		return ok, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return replacement, nil
+}
+
+// reconcileHA re-applies high availability to the registry and the Admin Console so their
+// replicas are scheduled on the replacement controller.
+func reconcileHA(ctx context.Context, kcli client.Client, rc runtimeconfig.RuntimeConfig, in *ecv1beta1.Installation) error {
+	addOns, closeAddOns, err := newHAAddOns(kcli, rc, in)
+	if err != nil {
+		return err
+	}
+	defer closeAddOns()
+
+	loading := spinner.Start()
+	defer loading.Close()
+
+	opts, err := newEnableHAOptions(ctx, kcli, rc, in)
+	if err != nil {
+		return err
+	}
+
+	return addOns.ReconcileHA(ctx, opts, loading)
+}
@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg-new/nodeinventory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func controllerNode(name string, ready bool) corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"},
+		},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func Test_checkEtcdQuorum(t *testing.T) {
+	members := etcdMembers{Members: map[string]string{
+		"node-1": "https://10.0.0.1:2380",
+		"node-2": "https://10.0.0.2:2380",
+		"node-3": "https://10.0.0.3:2380",
+	}}
+
+	tests := []struct {
+		name        string
+		controllers []corev1.Node
+		wantSafe    bool
+		wantReason  string
+	}{
+		{
+			name: "two healthy members out of three",
+			controllers: []corev1.Node{
+				controllerNode("node-1", false),
+				controllerNode("node-2", true),
+				controllerNode("node-3", true),
+			},
+			wantSafe: true,
+		},
+		{
+			name: "the node being replaced is still ready",
+			controllers: []corev1.Node{
+				controllerNode("node-1", true),
+				controllerNode("node-2", true),
+				controllerNode("node-3", false),
+			},
+			wantSafe:   false,
+			wantReason: "Only 1 of the 3 etcd members are on ready controllers other than node-1, and 2 are needed to keep quorum.",
+		},
+		{
+			name: "a member without a node",
+			controllers: []corev1.Node{
+				controllerNode("node-1", false),
+				controllerNode("node-2", true),
+			},
+			wantSafe:   false,
+			wantReason: "Only 1 of the 3 etcd members are on ready controllers other than node-1, and 2 are needed to keep quorum.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			safe, reason := checkEtcdQuorum(members, "node-1", tt.controllers)
+			assert.Equal(t, tt.wantSafe, safe)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
+
+func Test_validateReplacementInventory(t *testing.T) {
+	tests := []struct {
+		name    string
+		nodes   []nodeinventory.Node
+		wantErr bool
+	}{
+		{
+			name:  "single controller",
+			nodes: []nodeinventory.Node{{Address: "10.0.0.4", Role: "controller"}},
+		},
+		{
+			name:    "single worker",
+			nodes:   []nodeinventory.Node{{Address: "10.0.0.4", Role: "worker"}},
+			wantErr: true,
+		},
+		{
+			name: "two controllers",
+			nodes: []nodeinventory.Node{
+				{Address: "10.0.0.4", Role: "controller"},
+				{Address: "10.0.0.5", Role: "controller"},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := &nodeinventory.NodeInventory{Spec: nodeinventory.NodeInventorySpec{Nodes: tt.nodes}}
+			err := validateReplacementInventory(inv)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_waitForReplacementController(t *testing.T) {
+	list := listEtcdMembers
+	t.Cleanup(func() { listEtcdMembers = list })
+	listEtcdMembers = func() (etcdMembers, error) {
+		return etcdMembers{Members: map[string]string{
+			"node-2": "https://10.0.0.2:2380",
+			"node-3": "https://10.0.0.3:2380",
+			"node-1": "https://10.0.0.4:2380",
+		}}, nil
+	}
+
+	node1, node2, node3 := controllerNode("node-1", true), controllerNode("node-2", true), controllerNode("node-3", true)
+	kcli := fake.NewClientBuilder().WithObjects(&node1, &node2, &node3).Build()
+
+	// the replacement reuses the name of the node it replaces
+	got, err := waitForReplacementController(context.Background(), kcli, []string{"node-2", "node-3"}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", got)
+
+	// no new controller
+	_, err = waitForReplacementController(context.Background(), kcli, []string{"node-1", "node-2", "node-3"}, time.Millisecond)
+	assert.Error(t, err)
+}
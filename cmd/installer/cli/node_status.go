@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/spf13/cobra"
+)
+
+// NodeStatusCmd returns a cobra command for showing the role and health of a node, including
+// its last host preflight results.
+func NodeStatusCmd(ctx context.Context, appTitle string) *cobra.Command {
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "status <name>",
+		Short: fmt.Sprintf("Show the role and health of a node in the %s cluster", appTitle),
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return preRunNodeStatus(cmd, &rc)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("output")
+
+			summaries, err := getNodeSummaries(cmd.Context(), rc)
+			if err != nil {
+				return err
+			}
+
+			for _, summary := range summaries {
+				if summary.Name != args[0] {
+					continue
+				}
+				if format == outputFormatJSON {
+					return printJSON(os.Stdout, summary)
+				}
+				printNodeStatus(os.Stdout, summary)
+				return nil
+			}
+
+			return fmt.Errorf("node %q not found", args[0])
+		},
+	}
+
+	mustAddNodeOutputFlag(cmd)
+
+	return cmd
+}
+
+// printNodeStatus writes the details of a node, listing the host preflights that didn't pass.
+func printNodeStatus(w io.Writer, summary nodeSummary) {
+	controlPlane := "no"
+	if summary.ControlPlane {
+		controlPlane = "yes"
+	}
+
+	fmt.Fprintf(w, "Name:             %s\n", summary.Name)
+	fmt.Fprintf(w, "Roles:            %s\n", strings.Join(summary.Roles, ", "))
+	fmt.Fprintf(w, "Control plane:    %s\n", controlPlane)
+	fmt.Fprintf(w, "Internal IP:      %s\n", summary.InternalIP)
+	fmt.Fprintf(w, "Status:           %s\n", summary.readyString())
+	fmt.Fprintf(w, "Version:          %s\n", summary.Version)
+	fmt.Fprintf(w, "Pressure:         %s\n", summary.pressureString())
+	if summary.LocalArtifactMirror != "" {
+		fmt.Fprintf(w, "Artifact mirror:  %s\n", summary.LocalArtifactMirror)
+	} else {
+		fmt.Fprintln(w, "Artifact mirror:  not checked, only known when run on the node itself")
+	}
+
+	if summary.HostPreflights == nil {
+		fmt.Fprintln(w, "Host preflights:  no results found")
+		return
+	}
+
+	fmt.Fprintf(w, "Host preflights:  %s", summary.hostPreflightsString())
+	if summary.HostPreflights.UpdatedAt != "" {
+		fmt.Fprintf(w, " (as of %s)", summary.HostPreflights.UpdatedAt)
+	}
+	fmt.Fprintln(w)
+
+	for _, record := range summary.HostPreflights.Output.Fail {
+		fmt.Fprintf(w, "  FAIL  %s: %s\n", record.Title, record.Message)
+	}
+	for _, record := range summary.HostPreflights.Output.Warn {
+		fmt.Fprintf(w, "  WARN  %s: %s\n", record.Title, record.Message)
+	}
+}
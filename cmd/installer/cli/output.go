@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
+	addontypes "github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/spf13/cobra"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+// phases reported on the progress stream by commands other than install, which reports its
+// checkpointed install steps instead.
+const (
+	phaseInitialize     = "initialize"
+	phaseHostPreflights = "host-preflights"
+	phaseNode           = "node"
+	phaseEnableHA       = "enable-ha"
+	phaseDisableHA      = "disable-ha"
+)
+
+func mustAddOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", outputFormatText, "Output format. One of: text, json. With json, progress is written to stdout as newline-delimited JSON events and the regular output is written to stderr. Use with --yes as prompts can't be answered.")
+}
+
+// setupOutput configures the output of the command based on the --output flag. With json,
+// stdout is reserved for the progress stream and everything meant for humans goes to stderr.
+func setupOutput(cmd *cobra.Command) error {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("unable to get output flag: %w", err)
+	}
+
+	switch format {
+	case outputFormatText:
+		return nil
+	case outputFormatJSON:
+		progress.SetWriter(progress.NewWriter(os.Stdout, cmd.Name()))
+		spinner.SetDefaultOutput(os.Stderr)
+		stdoutLoggerOutput = os.Stderr
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q, must be one of %s or %s", format, outputFormatText, outputFormatJSON)
+	}
+}
+
+// humanOutput returns where output meant for humans should be written.
+func humanOutput() io.Writer {
+	if progress.Enabled() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// runPhase runs fn and reports its start and outcome on the progress stream.
+func runPhase(name string, fn func() error) error {
+	progress.Phase(name, progress.StatusRunning, nil)
+	if err := fn(); err != nil {
+		progress.Phase(name, progress.StatusFailed, err)
+		return err
+	}
+	progress.Phase(name, progress.StatusSucceeded, nil)
+	return nil
+}
+
+// emitAddOnProgress reports the progress of an addon on the progress stream.
+func emitAddOnProgress(p addontypes.AddOnProgress) {
+	switch p.Status.State {
+	case apitypes.StateRunning:
+		progress.AddOn(p.Name, progress.StatusRunning, p.Status.Description)
+	case apitypes.StateSucceeded:
+		progress.AddOn(p.Name, progress.StatusSucceeded, p.Status.Description)
+	case apitypes.StateFailed:
+		progress.AddOn(p.Name, progress.StatusFailed, p.Status.Description)
+	}
+}
+
+// emitPreflightResults reports every preflight check result on the progress stream.
+func emitPreflightResults(output *apitypes.PreflightsOutput) {
+	for _, rec := range output.Pass {
+		progress.Preflight(rec.Title, progress.StatusPass, rec.Message)
+	}
+	for _, rec := range output.Warn {
+		progress.Preflight(rec.Title, progress.StatusWarn, rec.Message)
+	}
+	for _, rec := range output.Fail {
+		progress.Preflight(rec.Title, progress.StatusFail, rec.Message)
+	}
+}
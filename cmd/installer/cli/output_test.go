@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_setupOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantErr     string
+		wantEnabled bool
+	}{
+		{
+			name:        "defaults to text",
+			wantEnabled: false,
+		},
+		{
+			name:    "invalid format",
+			args:    []string{"--output", "yaml"},
+			wantErr: `invalid output format "yaml"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { progress.SetWriter(nil) })
+
+			cmd := &cobra.Command{Use: "install"}
+			mustAddOutputFlag(cmd)
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			err := setupOutput(cmd)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEnabled, progress.Enabled())
+		})
+	}
+}
+
+func Test_runPhase(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	progress.SetWriter(progress.NewWriter(buf, "join"))
+	t.Cleanup(func() { progress.SetWriter(nil) })
+
+	err := runPhase(phaseNode, func() error { return nil })
+	require.NoError(t, err)
+
+	err = runPhase(phaseEnableHA, func() error { return errors.New("not enough nodes") })
+	require.EqualError(t, err, "not enough nodes")
+
+	out := buf.String()
+	assert.Contains(t, out, `"type":"phase","name":"node","status":"running"`)
+	assert.Contains(t, out, `"type":"phase","name":"node","status":"succeeded"`)
+	assert.Contains(t, out, `"type":"phase","name":"enable-ha","status":"failed","error":"not enough nodes"`)
+}
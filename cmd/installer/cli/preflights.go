@@ -3,21 +3,47 @@ package cli
 import (
 	"context"
 	"fmt"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
 
 	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
 	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
 	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
 	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
 )
 
+// PreflightsCmd returns a cobra command for checking hosts before installing.
+func PreflightsCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preflights",
+		Short: "Check if hosts meet the installation requirements",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+	}
+
+	cmd.AddCommand(PreflightsRunCmd(ctx, appSlug, appTitle))
+	cmd.AddCommand(PreflightsVerifyCmd(ctx))
+	cmd.AddCommand(PreflightsHistoryCmd(ctx))
+	cmd.AddCommand(PreflightsDiffCmd(ctx))
+
+	return cmd
+}
+
 func runHostPreflights(
 	ctx context.Context,
 	hpf *troubleshootv1beta2.HostPreflightSpec,
 	rc runtimeconfig.RuntimeConfig,
+	source string,
+	opts preflights.RunOptions,
 	skipHostPreflights bool,
 	ignoreHostPreflights bool,
 	assumeYes bool,
@@ -32,6 +58,8 @@ func runHostPreflights(
 		return nil
 	}
 
+	saveHostPreflightsRecheckSpec(hpf, rc)
+
 	spinner := spinner.Start()
 
 	if skipHostPreflights {
@@ -39,30 +67,9 @@ func runHostPreflights(
 		return nil
 	}
 
-	spinner.Infof("Running host preflights")
-
-	opts := preflights.RunOptions{
-		PreflightBinaryPath: rc.PathToEmbeddedClusterBinary("kubectl-preflight"),
-		ProxySpec:           rc.ProxySpec(),
-		ExtraPaths:          []string{rc.EmbeddedClusterBinsSubDir()},
-	}
-	output, stderr, err := preflights.RunHostPreflights(ctx, hpf, opts)
-	if stderr != "" {
-		logrus.Debugf("preflight stderr: %s", stderr)
-	}
-	if err != nil {
-		spinner.ErrorClosef("Failed to run host preflights")
-		return fmt.Errorf("host preflights failed to run: %w", err)
-	}
-
-	err = preflights.SaveToDisk(output, rc.PathToEmbeddedClusterSupportFile("host-preflight-results.json"))
-	if err != nil {
-		logrus.Warnf("save preflights output: %v", err)
-	}
-
-	err = preflights.CopyBundleTo(rc.PathToEmbeddedClusterSupportFile("preflight-bundle.tar.gz"))
+	output, err := executeHostPreflights(ctx, hpf, rc, source, opts, spinner)
 	if err != nil {
-		logrus.Warnf("copy preflight bundle to embedded-cluster support dir: %v", err)
+		return err
 	}
 
 	// Failures found
@@ -80,7 +87,9 @@ func runHostPreflights(
 
 		preflights.PrintTableWithoutInfo(output)
 
-		if ignoreHostPreflights {
+		if ignoreHostPreflights && output.HasStrictFailures() {
+			logrus.Info("\nStrict host preflights failed and can't be ignored.")
+		} else if ignoreHostPreflights {
 			if assumeYes {
 				if metricsReporter != nil {
 					metricsReporter.ReportHostPreflightsBypassed(ctx, output)
@@ -155,3 +164,112 @@ func runHostPreflights(
 
 	return nil
 }
+
+// executeHostPreflights runs the host preflights and stores their output, the preflight bundle
+// and a history entry in the support directory. The callers decide how to act on the output.
+func executeHostPreflights(
+	ctx context.Context,
+	hpf *troubleshootv1beta2.HostPreflightSpec,
+	rc runtimeconfig.RuntimeConfig,
+	source string,
+	opts preflights.RunOptions,
+	loading *spinner.MessageWriter,
+) (*apitypes.PreflightsOutput, error) {
+	loading.Infof("Running host preflights")
+
+	output, stderr, err := preflights.RunHostPreflights(ctx, hpf, opts)
+	if stderr != "" {
+		logrus.Debugf("preflight stderr: %s", stderr)
+	}
+	if err != nil {
+		loading.ErrorClosef("Failed to run host preflights")
+		return nil, fmt.Errorf("host preflights failed to run: %w", err)
+	}
+
+	emitPreflightResults(output)
+
+	err = preflights.SaveToDisk(output, rc.PathToEmbeddedClusterSupportFile("host-preflight-results.json"))
+	if err != nil {
+		logrus.Warnf("save preflights output: %v", err)
+	}
+	saveHostPreflightsHistory(output, rc, source)
+
+	err = preflights.CopyBundleTo(rc.PathToEmbeddedClusterSupportFile("preflight-bundle.tar.gz"))
+	if err != nil {
+		logrus.Warnf("copy preflight bundle to embedded-cluster support dir: %v", err)
+	}
+
+	return output, nil
+}
+
+// saveHostPreflightsRecheckSpec stores the host preflights in the support directory so the
+// operator can run them again periodically and flag the checks that no longer pass. They are
+// saved even if the host preflights are skipped.
+func saveHostPreflightsRecheckSpec(hpf *troubleshootv1beta2.HostPreflightSpec, rc runtimeconfig.RuntimeConfig) {
+	err := preflights.SaveRecheckSpecToDisk(hpf, rc.PathToEmbeddedClusterSupportFile(preflights.RecheckSpecFileName))
+	if err != nil {
+		logrus.Warnf("save host preflights spec: %v", err)
+	}
+}
+
+// saveHostPreflightsHistory adds the output to the history of host preflights runs in the
+// support directory, so runs can be compared with "preflights diff".
+func saveHostPreflightsHistory(output *apitypes.PreflightsOutput, rc runtimeconfig.RuntimeConfig, source string) {
+	_, err := preflights.SaveToHistory(output, rc.PathToEmbeddedClusterSupportFile(preflights.HistoryDirName), source, time.Now())
+	if err != nil {
+		logrus.Warnf("save preflights output to history: %v", err)
+	}
+}
+
+// hostPreflightsRunOptions returns the options used to run the host preflights with the binaries
+// materialized on the host.
+func hostPreflightsRunOptions(rc runtimeconfig.RuntimeConfig, disableFilesystemPerformanceCheck bool) preflights.RunOptions {
+	return preflights.RunOptions{
+		PreflightBinaryPath: rc.PathToEmbeddedClusterBinary("kubectl-preflight"),
+		ProxySpec:           rc.ProxySpec(),
+		ExtraPaths:          []string{rc.EmbeddedClusterBinsSubDir()},
+		StorageBenchmarks:   hostStorageBenchmarks(rc, disableFilesystemPerformanceCheck),
+		DNSDiagnostics:      &preflights.DNSDiagnostics{Upstreams: rc.DNSUpstreams()},
+		IPv6RouteCheck:      hostIPv6RouteCheck(rc),
+	}
+}
+
+// hostIPv6RouteCheck returns the check of the IPv6 ranges of the cluster against the routes of
+// the host, nil when the cluster does not use IPv6.
+func hostIPv6RouteCheck(rc runtimeconfig.RuntimeConfig) *preflights.IPv6RouteCheck {
+	cidrs := []string{}
+	for _, cidr := range []string{rc.PodCIDR(), rc.ServiceCIDR(), rc.IPv6PodCIDR(), rc.IPv6ServiceCIDR()} {
+		if netutils.IsIPv6CIDR(cidr) {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	if len(cidrs) == 0 {
+		return nil
+	}
+	return &preflights.IPv6RouteCheck{CIDRs: cidrs}
+}
+
+// hostStorageBenchmarks returns the benchmarks of the data directory and the OpenEBS data
+// directory storage, run when the Embedded Cluster config of the release sets the storage
+// performance thresholds. They are disabled along with the filesystem performance check.
+func hostStorageBenchmarks(rc runtimeconfig.RuntimeConfig, disableFilesystemPerformanceCheck bool) []preflights.StorageBenchmark {
+	cfg := release.GetEmbeddedClusterConfig()
+	if disableFilesystemPerformanceCheck || cfg == nil || cfg.Spec.HostPreflights.StoragePerformance == nil {
+		return nil
+	}
+	thresholds := *cfg.Spec.HostPreflights.StoragePerformance
+	return []preflights.StorageBenchmark{
+		{Name: "Data Directory", Directory: rc.EmbeddedClusterHomeDirectory(), Thresholds: thresholds},
+		{Name: "OpenEBS Data Directory", Directory: rc.EmbeddedClusterOpenEBSLocalSubDir(), Thresholds: thresholds},
+	}
+}
+
+// vendorHostPreflightVariables returns the variables the vendor host preflights are rendered
+// with, set in the Embedded Cluster config of the release.
+func vendorHostPreflightVariables() map[string]string {
+	cfg := release.GetEmbeddedClusterConfig()
+	if cfg == nil {
+		return nil
+	}
+	return cfg.Spec.HostPreflights.Variables
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/spf13/cobra"
+)
+
+// preflightsDiff is the result of comparing two host preflights runs.
+type preflightsDiff struct {
+	From    string                   `json:"from"`
+	To      string                   `json:"to"`
+	Changes []preflights.CheckChange `json:"changes"`
+}
+
+// PreflightsHistoryCmd returns a cobra command that lists the host preflights runs kept in the
+// support directory.
+func PreflightsHistoryCmd(ctx context.Context) *cobra.Command {
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the host preflights runs on this host",
+		Args:  cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return preRunPreflightsHistory(cmd, &rc)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("output")
+
+			runs, err := preflights.ListHistory(rc.PathToEmbeddedClusterSupportFile(preflights.HistoryDirName))
+			if err != nil {
+				return fmt.Errorf("unable to list host preflights history: %w", err)
+			}
+
+			if format == outputFormatJSON {
+				return printJSON(os.Stdout, runs)
+			}
+			if len(runs) == 0 {
+				fmt.Fprintln(os.Stdout, "No host preflights runs found.")
+				return nil
+			}
+			printPreflightsHistory(os.Stdout, runs)
+			return nil
+		},
+	}
+
+	mustAddNodeOutputFlag(cmd)
+
+	return cmd
+}
+
+// PreflightsDiffCmd returns a cobra command that shows the host preflights that changed state
+// between two runs.
+func PreflightsDiffCmd(ctx context.Context) *cobra.Command {
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "diff [FROM] [TO]",
+		Short: "Show the host preflights that changed between two runs",
+		Long: `Show the host preflights that changed state between two runs.
+
+Runs are referred to by their ID, as listed by "preflights history", or by the path to a report
+written by "preflights run" or to a host preflight results file. Without arguments, the two most
+recent runs on this host are compared. With a single argument, that run is compared with the
+most recent one.`,
+		Args: cobra.MaximumNArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return preRunPreflightsHistory(cmd, &rc)
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("output")
+
+			diff, err := diffPreflightsRuns(rc.PathToEmbeddedClusterSupportFile(preflights.HistoryDirName), args)
+			if err != nil {
+				return err
+			}
+
+			if format == outputFormatJSON {
+				return printJSON(os.Stdout, diff)
+			}
+			printPreflightsDiff(os.Stdout, diff)
+			return nil
+		},
+	}
+
+	mustAddNodeOutputFlag(cmd)
+
+	return cmd
+}
+
+// preRunPreflightsHistory validates the output flag and discovers the runtime config, which
+// points at the support directory holding the history.
+func preRunPreflightsHistory(cmd *cobra.Command, rc *runtimeconfig.RuntimeConfig) error {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return fmt.Errorf("unable to get output flag: %w", err)
+	}
+	if format != outputFormatText && format != outputFormatJSON {
+		return fmt.Errorf("invalid output format %q, must be one of %s or %s", format, outputFormatText, outputFormatJSON)
+	}
+
+	// Skip root check if dryrun mode is enabled
+	if !dryrun.Enabled() && os.Getuid() != 0 {
+		return fmt.Errorf("%s command must be run as root", cmd.CommandPath())
+	}
+
+	*rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+	return nil
+}
+
+// diffPreflightsRuns compares the runs referred to by args, see PreflightsDiffCmd.
+func diffPreflightsRuns(historyDir string, args []string) (*preflightsDiff, error) {
+	runs, err := preflights.ListHistory(historyDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list host preflights history: %w", err)
+	}
+
+	refs := append([]string{}, args...)
+	switch len(refs) {
+	case 0:
+		if len(runs) < 2 {
+			return nil, fmt.Errorf("at least two host preflights runs are needed, found %d", len(runs))
+		}
+		refs = []string{runs[len(runs)-2].ID, runs[len(runs)-1].ID}
+	case 1:
+		if len(runs) == 0 {
+			return nil, fmt.Errorf("no host preflights runs found to compare %s with", refs[0])
+		}
+		refs = append(refs, runs[len(runs)-1].ID)
+	}
+
+	from, err := resolvePreflightsRun(runs, refs[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := resolvePreflightsRun(runs, refs[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return &preflightsDiff{
+		From:    refs[0],
+		To:      refs[1],
+		Changes: preflights.DiffOutputs(from, to),
+	}, nil
+}
+
+// resolvePreflightsRun returns the output of the run with the ID, or with a unique ID prefix,
+// or the output in the file at the path.
+func resolvePreflightsRun(runs []preflights.HistoryRun, ref string) (*apitypes.PreflightsOutput, error) {
+	matches := []preflights.HistoryRun{}
+	for _, run := range runs {
+		if run.ID == ref {
+			return run.Output, nil
+		}
+		if strings.HasPrefix(run.ID, ref) {
+			matches = append(matches, run)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0].Output, nil
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("%s matches %d host preflights runs, use the full ID", ref, len(matches))
+	}
+
+	if _, err := os.Stat(ref); err != nil {
+		return nil, fmt.Errorf("no host preflights run or file named %s", ref)
+	}
+	output, err := preflights.LoadOutputFile(ref)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load host preflights output: %w", err)
+	}
+	return output, nil
+}
+
+// printPreflightsHistory writes a table with a line per run.
+func printPreflightsHistory(w io.Writer, runs []preflights.HistoryRun) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tSOURCE\tRESULT")
+	for _, run := range runs {
+		result := "-"
+		if run.Output != nil {
+			result = fmt.Sprintf("%d passed, %d warnings, %d failures", len(run.Output.Pass), len(run.Output.Warn), len(run.Output.Fail))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", run.ID, run.CreatedAt.Format(time.RFC3339), run.Source, result)
+	}
+	tw.Flush()
+}
+
+// printPreflightsDiff writes a table with a line per check that changed state.
+func printPreflightsDiff(w io.Writer, diff *preflightsDiff) {
+	fmt.Fprintf(w, "Comparing %s with %s\n\n", diff.From, diff.To)
+	if len(diff.Changes) == 0 {
+		fmt.Fprintln(w, "No host preflights changed state.")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tBEFORE\tAFTER\tMESSAGE")
+	for _, change := range diff.Changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", change.Title, change.Before, change.After, change.Message)
+	}
+	tw.Flush()
+}
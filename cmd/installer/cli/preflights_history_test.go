@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_diffPreflightsRuns(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	failing := &apitypes.PreflightsOutput{Fail: []apitypes.PreflightsRecord{{Title: "IP forwarding", Message: "disabled"}}}
+	fixed := &apitypes.PreflightsOutput{Pass: []apitypes.PreflightsRecord{{Title: "IP forwarding", Message: "enabled"}}}
+
+	first, err := preflights.SaveToHistory(failing, dir, "install", start)
+	require.NoError(t, err)
+	second, err := preflights.SaveToHistory(failing, dir, "preflights-run", start.Add(time.Hour))
+	require.NoError(t, err)
+	third, err := preflights.SaveToHistory(fixed, dir, "preflights-run", start.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	require.NoError(t, os.WriteFile(reportPath, []byte(`{"metadata":{"hostname":"node1"},"output":{"warn":[{"title":"IP forwarding","message":"unknown"}]}}`), 0644))
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantFrom    string
+		wantTo      string
+		wantChanges []preflights.CheckChange
+		wantErr     string
+	}{
+		{
+			name:     "two most recent runs",
+			wantFrom: second.ID,
+			wantTo:   third.ID,
+			wantChanges: []preflights.CheckChange{
+				{Title: "IP forwarding", Before: preflights.CheckStateFail, After: preflights.CheckStatePass, Message: "enabled"},
+			},
+		},
+		{
+			name:        "run compared with the most recent one",
+			args:        []string{first.ID},
+			wantFrom:    first.ID,
+			wantTo:      third.ID,
+			wantChanges: []preflights.CheckChange{{Title: "IP forwarding", Before: preflights.CheckStateFail, After: preflights.CheckStatePass, Message: "enabled"}},
+		},
+		{
+			name:        "unchanged runs by id prefix",
+			args:        []string{"20240101T120000", "20240101T130000"},
+			wantFrom:    "20240101T120000",
+			wantTo:      "20240101T130000",
+			wantChanges: []preflights.CheckChange{},
+		},
+		{
+			name:        "report file",
+			args:        []string{reportPath, third.ID},
+			wantFrom:    reportPath,
+			wantTo:      third.ID,
+			wantChanges: []preflights.CheckChange{{Title: "IP forwarding", Before: preflights.CheckStateWarn, After: preflights.CheckStatePass, Message: "enabled"}},
+		},
+		{
+			name:    "ambiguous prefix",
+			args:    []string{"20240101T1", third.ID},
+			wantErr: "matches 3 host preflights runs",
+		},
+		{
+			name:    "unknown run",
+			args:    []string{"missing", third.ID},
+			wantErr: "no host preflights run or file named missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := diffPreflightsRuns(dir, tt.args)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, diff.From)
+			assert.Equal(t, tt.wantTo, diff.To)
+			assert.Equal(t, tt.wantChanges, diff.Changes)
+		})
+	}
+}
+
+func Test_diffPreflightsRunsNotEnoughRuns(t *testing.T) {
+	dir := t.TempDir()
+	_, err := diffPreflightsRuns(dir, nil)
+	require.ErrorContains(t, err, "at least two host preflights runs are needed, found 0")
+}
+
+func Test_printPreflightsDiff(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	printPreflightsDiff(buf, &preflightsDiff{
+		From: "a",
+		To:   "b",
+		Changes: []preflights.CheckChange{
+			{Title: "IP forwarding", Before: preflights.CheckStateFail, After: preflights.CheckStatePass, Message: "enabled"},
+		},
+	})
+	assert.Contains(t, buf.String(), "Comparing a with b")
+	assert.Regexp(t, `IP forwarding\s+fail\s+pass\s+enabled`, buf.String())
+
+	buf.Reset()
+	printPreflightsDiff(buf, &preflightsDiff{From: "a", To: "b", Changes: []preflights.CheckChange{}})
+	assert.Contains(t, buf.String(), "No host preflights changed state.")
+}
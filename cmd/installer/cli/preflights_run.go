@@ -0,0 +1,528 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/goods"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/hostutils"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	nodeutil "k8s.io/component-helpers/node/util"
+)
+
+type preflightsRunFlags struct {
+	dataDir                           string
+	networkInterface                  string
+	adminConsolePort                  int
+	localArtifactMirrorPort           int
+	airgap                            bool
+	airgapBundle                      string
+	disableFilesystemPerformanceCheck bool
+	dnsUpstreams                      []string
+	signingKeyFile                    string
+	reportDir                         string
+	fix                               bool
+	assumeYes                         bool
+}
+
+// PreflightsRunCmd returns a cobra command that runs the host preflights against a target
+// configuration without installing anything, and writes a signed report.
+func PreflightsRunCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
+	var flags preflightsRunFlags
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: fmt.Sprintf("Check if this host meets the requirements to install %s", appTitle),
+		Long: fmt.Sprintf(`Check if this host meets the requirements to install %s, without installing anything.
+
+The host is evaluated against the configuration passed with the flags, which should match the
+flags that will be used on install day. The results are written to a JSON and an HTML report in
+the report directory. With --signing-key, the JSON report is signed with the key and can be
+checked with "preflights verify" and the matching public key. Without it, the report is not
+signed and nothing shows it was not modified.
+
+With --fix, the failing host preflights the installer fixes on its own when it configures the
+host, such as sysctl settings and kernel modules, are fixed and the host preflights are run
+again. The changes are shown before they are made.`, appTitle),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := setupOutput(cmd); err != nil {
+				return err
+			}
+
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("preflights run command must be run as root")
+			}
+
+			if flags.fix {
+				installed, err := k0s.IsInstalled()
+				if err != nil {
+					return fmt.Errorf("unable to check if the cluster is installed: %w", err)
+				}
+				// applying the settings of the installer could revert ones changed on the host after the
+				// cluster was installed
+				if installed {
+					return fmt.Errorf("--fix cannot be used once the cluster is installed")
+				}
+			}
+
+			return validateCIDRFlags(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := runPreflightsRun(cmd.Context(), cmd, appSlug, flags); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.dataDir, "data-dir", ecv1beta1.DefaultDataDir, "Path to the data directory that will be used for the installation")
+	cmd.Flags().StringVar(&flags.networkInterface, "network-interface", "", "The network interface that will be used for the cluster")
+	cmd.Flags().IntVar(&flags.adminConsolePort, "admin-console-port", ecv1beta1.DefaultAdminConsolePort, "Port on which the Admin Console will be served")
+	cmd.Flags().IntVar(&flags.localArtifactMirrorPort, "local-artifact-mirror-port", ecv1beta1.DefaultLocalArtifactMirrorPort, "Port on which the Local Artifact Mirror will be served")
+	cmd.Flags().BoolVar(&flags.airgap, "airgap", false, "Check the requirements of an air gap installation")
+	cmd.Flags().StringVar(&flags.airgapBundle, "airgap-bundle", "", "Path to the air gap bundle that will be used for the installation, to also check the disk space it needs")
+	cmd.Flags().BoolVar(&flags.disableFilesystemPerformanceCheck, "disable-filesystem-performance-check", false, "Disable the filesystem write latency and storage performance checks")
+	mustMarkFlagHidden(cmd.Flags(), "disable-filesystem-performance-check")
+	cmd.Flags().StringSliceVar(&flags.dnsUpstreams, "dns-upstream", nil, "Nameservers CoreDNS will forward to, checked instead of the nameservers in /etc/resolv.conf")
+	mustAddCIDRFlags(cmd.Flags())
+	mustAddProxyFlags(cmd.Flags())
+
+	cmd.Flags().StringVar(&flags.signingKeyFile, "signing-key", "", "Path to a PEM encoded ed25519 private key used to sign the report")
+	cmd.Flags().StringVar(&flags.reportDir, "report-dir", ".", "Directory where the reports are written")
+	cmd.Flags().BoolVar(&flags.fix, "fix", false, "Fix the failing host preflights the installer knows how to fix, then run them again")
+	cmd.Flags().BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+	mustAddOutputFlag(cmd)
+
+	return cmd
+}
+
+func runPreflightsRun(ctx context.Context, cmd *cobra.Command, appSlug string, flags preflightsRunFlags) error {
+	var signingKey ed25519.PrivateKey
+	if flags.signingKeyFile != "" {
+		data, err := os.ReadFile(flags.signingKeyFile)
+		if err != nil {
+			return fmt.Errorf("unable to read signing key: %w", err)
+		}
+		if signingKey, err = preflights.ParseReportSigningKey(data); err != nil {
+			return fmt.Errorf("unable to parse signing key: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(flags.reportDir, 0755); err != nil {
+		return fmt.Errorf("unable to create report directory: %w", err)
+	}
+
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return fmt.Errorf("unable to get hostname: %w", err)
+	}
+
+	rc, opts, err := buildPreflightsRunConfig(cmd, flags)
+	if err != nil {
+		return err
+	}
+
+	hpf, err := preflights.PrepareHostPreflights(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("unable to prepare host preflights: %w", err)
+	}
+
+	if dryrun.Enabled() {
+		dryrun.RecordHostPreflightSpec(hpf)
+		return nil
+	}
+
+	// the preflight binaries are written to a temporary directory so the host is left as it was
+	toolsDir, err := os.MkdirTemp("", "embedded-cluster-preflights-")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(toolsDir)
+
+	toolsRC := runtimeconfig.New(nil)
+	toolsRC.SetDataDir(toolsDir)
+	if err := goods.NewMaterializer(toolsRC).Binaries(); err != nil {
+		return fmt.Errorf("unable to materialize binaries: %w", err)
+	}
+
+	run := func() (*apitypes.PreflightsOutput, error) {
+		return runPreflightsRunChecks(ctx, hpf, rc, toolsRC, hostStorageBenchmarks(rc, flags.disableFilesystemPerformanceCheck))
+	}
+
+	// nothing is kept in the data directory, the host may not be installed yet. the report can be
+	// compared with the runs of the installer with "preflights diff".
+	output, err := run()
+	if err != nil {
+		return err
+	}
+
+	var remediations []preflights.Remediation
+	if flags.fix && output.HasFail() {
+		remediations, output, err = fixPreflightsRun(output, flags.assumeYes, run)
+		if err != nil {
+			return err
+		}
+	}
+
+	report := &preflights.Report{
+		Metadata: preflights.ReportMetadata{
+			Hostname:  hostname,
+			CreatedAt: time.Now().UTC(),
+			AppSlug:   appSlug,
+			Version:   preflightsReportVersion(),
+		},
+		Target: preflights.ReportTarget{
+			DataDir:                 opts.DataDir,
+			NetworkInterface:        rc.NetworkInterface(),
+			NodeIP:                  opts.NodeIP,
+			GlobalCIDR:              rc.GlobalCIDR(),
+			PodCIDR:                 opts.PodCIDR,
+			ServiceCIDR:             opts.ServiceCIDR,
+			AdminConsolePort:        opts.AdminConsolePort,
+			LocalArtifactMirrorPort: opts.LocalArtifactMirrorPort,
+			Proxy:                   opts.Proxy,
+			IsAirgap:                opts.IsAirgap,
+		},
+		Output:       output,
+		Remediations: remediations,
+	}
+	if signingKey != nil {
+		if err := report.Sign(signingKey); err != nil {
+			return fmt.Errorf("unable to sign report: %w", err)
+		}
+	}
+
+	jsonPath := filepath.Join(flags.reportDir, fmt.Sprintf("%s-host-preflights.json", hostname))
+	if err := preflights.SaveReportJSON(report, jsonPath); err != nil {
+		return err
+	}
+	htmlPath := filepath.Join(flags.reportDir, fmt.Sprintf("%s-host-preflights.html", hostname))
+	if err := preflights.SaveReportHTML(report, htmlPath); err != nil {
+		return err
+	}
+	bundlePath := filepath.Join(flags.reportDir, fmt.Sprintf("%s-preflight-bundle.tar.gz", hostname))
+	if err := preflights.CopyBundleTo(bundlePath); err != nil {
+		logrus.Warnf("copy preflight bundle to report directory: %v", err)
+	}
+
+	logrus.Infof("\nReports written to %s and %s", jsonPath, htmlPath)
+	if signingKey == nil {
+		logrus.Infof("The report is not signed. Use --signing-key to sign it so it can be verified.")
+	}
+
+	if output.HasFail() {
+		return NewErrorNothingElseToAdd(ErrPreflightsHaveFail)
+	}
+
+	return nil
+}
+
+// runPreflightsRunChecks runs the host preflights with the binaries in the tools directory and
+// prints the failures and warnings.
+func runPreflightsRunChecks(ctx context.Context, hpf *troubleshootv1beta2.HostPreflightSpec, rc runtimeconfig.RuntimeConfig, toolsRC runtimeconfig.RuntimeConfig, storageBenchmarks []preflights.StorageBenchmark) (*apitypes.PreflightsOutput, error) {
+	loading := spinner.Start()
+	loading.Infof("Running host preflights")
+
+	var output *apitypes.PreflightsOutput
+	err := runPhase(phaseHostPreflights, func() error {
+		out, stderr, err := preflights.RunHostPreflights(ctx, hpf, preflights.RunOptions{
+			PreflightBinaryPath: toolsRC.PathToEmbeddedClusterBinary("kubectl-preflight"),
+			ProxySpec:           rc.ProxySpec(),
+			ExtraPaths:          []string{toolsRC.EmbeddedClusterBinsSubDir()},
+			StorageBenchmarks:   storageBenchmarks,
+			DNSDiagnostics:      &preflights.DNSDiagnostics{Upstreams: rc.DNSUpstreams()},
+			IPv6RouteCheck:      hostIPv6RouteCheck(rc),
+		})
+		if stderr != "" {
+			logrus.Debugf("preflight stderr: %s", stderr)
+		}
+		if err != nil {
+			return fmt.Errorf("host preflights failed to run: %w", err)
+		}
+		emitPreflightResults(out)
+		output = out
+		return nil
+	})
+	if err != nil {
+		loading.ErrorClosef("Failed to run host preflights")
+		return nil, err
+	}
+
+	if output.HasFail() {
+		loading.ErrorClosef("%d host preflights failed and %d warned", len(output.Fail), len(output.Warn))
+	} else if output.HasWarn() {
+		loading.Warnf("%d host preflights warned", len(output.Warn))
+		loading.Close()
+	} else {
+		loading.Infof("Host preflights passed")
+		loading.Close()
+	}
+	if output.HasFail() || output.HasWarn() {
+		preflights.PrintTableWithoutInfo(output)
+	}
+
+	return output, nil
+}
+
+// fixPreflightsRun makes the changes to the host that fix the failing host preflights, once
+// confirmed, and runs the host preflights again. Only the changes the installer makes when it
+// configures the host are made, all of them are listed as the installer makes them at once. The
+// changes are returned along with the new results.
+func fixPreflightsRun(output *apitypes.PreflightsOutput, assumeYes bool, rerun func() (*apitypes.PreflightsOutput, error)) ([]preflights.Remediation, *apitypes.PreflightsOutput, error) {
+	remediations := preflights.PlanRemediations(output, hostutils.SysctlChanges(), hostutils.KernelModulesToLoad())
+	if len(remediations) == 0 {
+		logrus.Info("\nNone of the failing host preflights can be fixed automatically.")
+		return nil, output, nil
+	}
+
+	logrus.Info("\nThe following changes will be made to fix the failing host preflights:")
+	along := []preflights.Remediation{}
+	for _, r := range remediations {
+		if r.Check == "" {
+			along = append(along, r)
+			continue
+		}
+		logrus.Infof("  %s", r)
+	}
+	if len(along) > 0 {
+		logrus.Info("The installer makes these changes along with them:")
+		for _, r := range along {
+			logrus.Infof("  %s", r)
+		}
+	}
+	files := []string{}
+	if preflights.NeedsKernelModules(remediations) {
+		files = append(files, hostutils.KernelModulesConfigFile().Path)
+	}
+	if preflights.NeedsSysctl(remediations) {
+		for _, f := range hostutils.SysctlConfigFiles() {
+			files = append(files, f.Path)
+		}
+	}
+	logrus.Infof("The changes are persisted in %s.", strings.Join(files, ", "))
+
+	if !assumeYes {
+		confirmed, err := prompts.New().Confirm("Do you want to make these changes?", true)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			return nil, output, nil
+		}
+	}
+	logrus.Info("")
+
+	if preflights.NeedsKernelModules(remediations) {
+		if err := hostutils.ConfigureKernelModules(); err != nil {
+			return nil, nil, fmt.Errorf("unable to configure kernel modules: %w", err)
+		}
+	}
+	if preflights.NeedsSysctl(remediations) {
+		if err := hostutils.ConfigureSysctl(); err != nil {
+			return nil, nil, fmt.Errorf("unable to configure sysctl: %w", err)
+		}
+	}
+
+	output, err := rerun()
+	if err != nil {
+		return nil, nil, err
+	}
+	return remediations, output, nil
+}
+
+// buildPreflightsRunConfig returns the runtime config describing the target configuration and the
+// options to prepare the host preflights with. Nothing is created in the target data directory.
+func buildPreflightsRunConfig(cmd *cobra.Command, flags preflightsRunFlags) (runtimeconfig.RuntimeConfig, preflights.PrepareHostPreflightOptions, error) {
+	var opts preflights.PrepareHostPreflightOptions
+
+	if flags.adminConsolePort == flags.localArtifactMirrorPort {
+		return nil, opts, fmt.Errorf("local artifact mirror port cannot be the same as admin console port")
+	}
+
+	dataDir, err := filepath.Abs(flags.dataDir)
+	if err != nil {
+		return nil, opts, fmt.Errorf("construct path for directory: %w", err)
+	}
+
+	networkInterface := flags.networkInterface
+	if networkInterface == "" {
+		if networkInterface, err = newconfig.DetermineBestNetworkInterface(); err != nil {
+			return nil, opts, fmt.Errorf("unable to determine the network interface, use --network-interface: %w", err)
+		}
+	}
+
+	cidrCfg, err := getCIDRConfig(cmd)
+	if err != nil {
+		return nil, opts, fmt.Errorf("unable to get cidr config: %w", err)
+	}
+
+	proxySpec, err := parseProxyFlags(cmd, networkInterface, "", cidrCfg)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	dnsUpstreams, err := parseDNSUpstreams(flags.dnsUpstreams)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	networkSpec := ecv1beta1.NetworkSpec{
+		NetworkInterface: networkInterface,
+		PodCIDR:          cidrCfg.PodCIDR,
+		ServiceCIDR:      cidrCfg.ServiceCIDR,
+		IPv6PodCIDR:      cidrCfg.IPv6PodCIDR,
+		IPv6ServiceCIDR:  cidrCfg.IPv6ServiceCIDR,
+		DNSUpstreams:     dnsUpstreams,
+	}
+	if cidrCfg.GlobalCIDR != nil {
+		networkSpec.GlobalCIDR = *cidrCfg.GlobalCIDR
+	}
+
+	rc := runtimeconfig.New(nil)
+	rc.SetDataDir(dataDir)
+	rc.SetAdminConsolePort(flags.adminConsolePort)
+	rc.SetLocalArtifactMirrorPort(flags.localArtifactMirrorPort)
+	rc.SetProxySpec(proxySpec)
+	rc.SetNetworkSpec(networkSpec)
+
+	// the primary address of the node is in the family of the pod cidr
+	nodeIPs, err := netutils.NodeAddresses(rc.NetworkInterface(), netutils.ClusterIPFamily(rc.PodCIDR(), ""))
+	if err != nil {
+		return nil, opts, fmt.Errorf("unable to find first valid address: %w", err)
+	}
+	nodeIP := nodeIPs[0]
+
+	isAirgap := flags.airgap || flags.airgapBundle != ""
+	var controllerAirgapStorageSpace string
+	if flags.airgapBundle != "" {
+		metadata, err := airgap.AirgapMetadataFromPath(flags.airgapBundle)
+		if err != nil {
+			return nil, opts, fmt.Errorf("failed to get airgap info: %w", err)
+		}
+		embeddedAssetsSize, err := goods.SizeOfEmbeddedAssets()
+		if err != nil {
+			return nil, opts, fmt.Errorf("failed to get size of embedded files: %w", err)
+		}
+		if metadata.AirgapInfo != nil {
+			controllerAirgapStorageSpace = preflights.CalculateAirgapStorageSpace(preflights.AirgapStorageSpaceCalcArgs{
+				UncompressedSize:   metadata.AirgapInfo.Spec.UncompressedSize,
+				EmbeddedAssetsSize: embeddedAssetsSize,
+				K0sImageSize:       metadata.K0sImageSize,
+				IsController:       true,
+			})
+		}
+	}
+
+	opts = preflights.PrepareHostPreflightOptions{
+		HostPreflightSpec:                 release.GetHostPreflights(),
+		ReplicatedAppURL:                  replicatedAppURL(),
+		ProxyRegistryURL:                  proxyRegistryURL(),
+		AdminConsolePort:                  rc.AdminConsolePort(),
+		LocalArtifactMirrorPort:           rc.LocalArtifactMirrorPort(),
+		DataDir:                           rc.EmbeddedClusterHomeDirectory(),
+		K0sDataDir:                        rc.EmbeddedClusterK0sSubDir(),
+		OpenEBSDataDir:                    rc.EmbeddedClusterOpenEBSLocalSubDir(),
+		Proxy:                             rc.ProxySpec(),
+		PodCIDR:                           rc.PodCIDR(),
+		ServiceCIDR:                       rc.ServiceCIDR(),
+		IPv6PodCIDR:                       rc.IPv6PodCIDR(),
+		IPv6ServiceCIDR:                   rc.IPv6ServiceCIDR(),
+		NodeIP:                            nodeIP,
+		IsAirgap:                          isAirgap,
+		ControllerAirgapStorageSpace:      controllerAirgapStorageSpace,
+		DisableFilesystemPerformanceCheck: flags.disableFilesystemPerformanceCheck,
+		K8sVersion:                        versions.K0sVersion,
+		VendorHostPreflights:              release.GetRawHostPreflights(),
+		VendorVariables:                   vendorHostPreflightVariables(),
+	}
+	if globalCIDR := rc.GlobalCIDR(); globalCIDR != "" {
+		opts.GlobalCIDR = &globalCIDR
+	}
+
+	return rc, opts, nil
+}
+
+// preflightsReportVersion returns the version of the release the host was checked for.
+func preflightsReportVersion() string {
+	if channelRelease := release.GetChannelRelease(); channelRelease != nil && channelRelease.VersionLabel != "" {
+		return channelRelease.VersionLabel
+	}
+	return versions.Version
+}
+
+// PreflightsVerifyCmd returns a cobra command that checks the signature of a host preflights
+// report.
+func PreflightsVerifyCmd(ctx context.Context) *cobra.Command {
+	var publicKeyFile string
+
+	cmd := &cobra.Command{
+		Use:   "verify <report>",
+		Short: "Verify the signature of a host preflights report",
+		Long: `Verify the signature of a host preflights report.
+
+The report must be signed with the private key matching the public key passed with --public-key.
+The public key embedded in the report is not trusted, as anyone who modifies the report can sign
+it again with another key.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				return fmt.Errorf("unable to read public key: %w", err)
+			}
+			publicKey, err := preflights.ParseReportPublicKey(data)
+			if err != nil {
+				return fmt.Errorf("unable to parse public key: %w", err)
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("unable to open report: %w", err)
+			}
+			defer f.Close()
+
+			report, err := preflights.ReportFromReader(f)
+			if err != nil {
+				return err
+			}
+
+			if err := report.Verify(publicKey); err != nil {
+				if errors.Is(err, preflights.ErrReportSignatureInvalid) {
+					return fmt.Errorf("%w, the report was modified after it was created", err)
+				}
+				return err
+			}
+
+			logrus.Infof("The report for %s created at %s is signed with the provided key and was not modified.", report.Metadata.Hostname, report.Metadata.CreatedAt.Format(time.RFC3339))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&publicKeyFile, "public-key", "", "Path to the PEM encoded ed25519 public key the report must be signed with")
+	mustMarkFlagRequired(cmd.Flags(), "public-key")
+
+	return cmd
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PreflightsVerifyCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	pubPath := filepath.Join(dir, "key.pub")
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644))
+
+	report := &preflights.Report{
+		Metadata: preflights.ReportMetadata{Hostname: "node-1"},
+		Output:   &apitypes.PreflightsOutput{Pass: []apitypes.PreflightsRecord{{Title: "CPU"}}},
+	}
+	require.NoError(t, report.Sign(key))
+	signedPath := filepath.Join(dir, "signed.json")
+	require.NoError(t, preflights.SaveReportJSON(report, signedPath))
+
+	report.Output.Fail = []apitypes.PreflightsRecord{{Title: "Memory"}}
+	tamperedPath := filepath.Join(dir, "tampered.json")
+	require.NoError(t, preflights.SaveReportJSON(report, tamperedPath))
+
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, report.Sign(otherKey))
+	resignedPath := filepath.Join(dir, "resigned.json")
+	require.NoError(t, preflights.SaveReportJSON(report, resignedPath))
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "without the public key",
+			args:    []string{signedPath},
+			wantErr: `required flag(s) "public-key" not set`,
+		},
+		{
+			name: "signed report with the expected key",
+			args: []string{signedPath, "--public-key", pubPath},
+		},
+		{
+			name:    "modified report signed again with another key",
+			args:    []string{resignedPath, "--public-key", pubPath},
+			wantErr: "report was not signed with the expected key",
+		},
+		{
+			name:    "modified report",
+			args:    []string{tamperedPath, "--public-key", pubPath},
+			wantErr: "report signature is invalid, the report was modified after it was created",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := PreflightsVerifyCmd(context.Background())
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 // NetworkLookup defines the interface for network lookups
 type NetworkLookup interface {
 	FirstValidIPNet(networkInterface string) (*net.IPNet, error)
+	FirstValidIPv6Net(networkInterface string) (*net.IPNet, error)
 }
 
 type defaultNetworkLookup struct{}
@@ -22,6 +23,10 @@ func (d *defaultNetworkLookup) FirstValidIPNet(networkInterface string) (*net.IP
 	return netutils.FirstValidIPNet(networkInterface)
 }
 
+func (d *defaultNetworkLookup) FirstValidIPv6Net(networkInterface string) (*net.IPNet, error) {
+	return netutils.FirstValidIPv6Net(networkInterface)
+}
+
 var defaultNetworkLookupImpl NetworkLookup = &defaultNetworkLookup{}
 
 func mustAddProxyFlags(flagSet *pflag.FlagSet) {
@@ -30,8 +35,8 @@ func mustAddProxyFlags(flagSet *pflag.FlagSet) {
 	flagSet.String("no-proxy", "", "Comma-separated list of hosts for which not to use a proxy (overrides no_proxy/NO_PROXY environment variables)")
 }
 
-func parseProxyFlags(cmd *cobra.Command, networkInterface string, cidrCfg *newconfig.CIDRConfig) (*ecv1beta1.ProxySpec, error) {
-	p, err := getProxySpec(cmd, networkInterface, cidrCfg)
+func parseProxyFlags(cmd *cobra.Command, networkInterface string, controlPlaneInterface string, cidrCfg *newconfig.CIDRConfig) (*ecv1beta1.ProxySpec, error) {
+	p, err := getProxySpec(cmd, networkInterface, controlPlaneInterface, cidrCfg)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get proxy spec from flags: %w", err)
 	}
@@ -40,7 +45,7 @@ func parseProxyFlags(cmd *cobra.Command, networkInterface string, cidrCfg *newco
 	return p, nil
 }
 
-func getProxySpec(cmd *cobra.Command, networkInterface string, cidrCfg *newconfig.CIDRConfig) (*ecv1beta1.ProxySpec, error) {
+func getProxySpec(cmd *cobra.Command, networkInterface string, controlPlaneInterface string, cidrCfg *newconfig.CIDRConfig) (*ecv1beta1.ProxySpec, error) {
 	// Command-line flags have the highest precedence
 	httpProxy, err := cmd.Flags().GetString("http-proxy")
 	if err != nil {
@@ -54,7 +59,12 @@ func getProxySpec(cmd *cobra.Command, networkInterface string, cidrCfg *newconfi
 	if err != nil {
 		return nil, fmt.Errorf("unable to get no-proxy flag: %w", err)
 	}
-	proxy, err := newconfig.GetProxySpec(httpProxy, httpsProxy, noProxy, cidrCfg.PodCIDR, cidrCfg.ServiceCIDR, networkInterface, defaultNetworkLookupImpl)
+	proxy, err := newconfig.GetProxySpec(
+		httpProxy, httpsProxy, noProxy,
+		netutils.JoinCIDRs(cidrCfg.PodCIDR, cidrCfg.IPv6PodCIDR),
+		netutils.JoinCIDRs(cidrCfg.ServiceCIDR, cidrCfg.IPv6ServiceCIDR),
+		networkInterface, controlPlaneInterface, defaultNetworkLookupImpl,
+	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to get proxy spec: %w", err)
 	}
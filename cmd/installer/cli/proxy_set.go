@@ -0,0 +1,415 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ecrelease "github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	newconfig "github.com/replicatedhq/embedded-cluster/pkg-new/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type proxySetFlags struct {
+	httpProxy  string
+	httpsProxy string
+	noProxy    string
+	overrides  string
+	assumeYes  bool
+	timeout    time.Duration
+}
+
+// ProxyCmd returns the parent command for changing the proxy of the cluster.
+func ProxyCmd(ctx context.Context, appTitle string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Manage the proxy of the cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.Help()
+			os.Exit(1)
+			return nil
+		},
+	}
+
+	cmd.AddCommand(ProxySetCmd(ctx, appTitle))
+	cmd.AddCommand(ProxyUnsetCmd(ctx, appTitle))
+
+	return cmd
+}
+
+// ProxySetCmd returns a cobra command for changing the proxy of an existing cluster.
+func ProxySetCmd(ctx context.Context, appTitle string) *cobra.Command {
+	var flags proxySetFlags
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "set",
+		Short: fmt.Sprintf("Change the proxy of the %s cluster", appTitle),
+		Long: fmt.Sprintf(`Change the proxy of the %s cluster.
+
+Run it as root on a controller. The flags that are not set keep their current value. The
+no-proxy list is built again from the hosts in --no-proxy, or the ones provided at install time,
+with the pod and service CIDRs and the networks of the interfaces of this node added to it.
+
+The add-ons using the proxy are upgraded with it and their pods restart. The k0s and local
+artifact mirror services of every node are then configured with the new proxy and restarted,
+one node at a time, controllers first. The Kubernetes API of a controller is unavailable while
+it restarts.`, appTitle),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("proxy set command must be run as root")
+			}
+			if !cmd.Flags().Changed("http-proxy") && !cmd.Flags().Changed("https-proxy") && !cmd.Flags().Changed("no-proxy") {
+				return fmt.Errorf("at least one of --http-proxy, --https-proxy or --no-proxy must be set")
+			}
+			if err := validateProxyURLFlag("--http-proxy", flags.httpProxy); err != nil {
+				return err
+			}
+			if err := validateProxyURLFlag("--https-proxy", flags.httpsProxy); err != nil {
+				return err
+			}
+
+			rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+			_ = rc.SetEnv()
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			httpProxy, httpsProxy, noProxy := mergeProxyFlags(rc.ProxySpec(), cmd.Flags(), flags)
+			proxy, err := newconfig.BuildProxySpec(
+				httpProxy, httpsProxy, noProxy,
+				netutils.JoinCIDRs(rc.PodCIDR(), rc.IPv6PodCIDR()),
+				netutils.JoinCIDRs(rc.ServiceCIDR(), rc.IPv6ServiceCIDR()),
+				rc.NetworkInterface(), rc.ControlPlaneInterface(), nil,
+			)
+			if err != nil {
+				return fmt.Errorf("unable to build proxy spec: %w", err)
+			}
+			return runProxyChange(cmd.Context(), rc, proxy, flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.httpProxy, "http-proxy", "", "HTTP proxy to use, empty to stop using one")
+	cmd.Flags().StringVar(&flags.httpsProxy, "https-proxy", "", "HTTPS proxy to use, empty to stop using one")
+	cmd.Flags().StringVar(&flags.noProxy, "no-proxy", "", "Comma-separated list of hosts for which not to use a proxy")
+	cmd.Flags().StringVar(&flags.overrides, "overrides", "", "File with an EmbeddedClusterConfig object to override the default configuration")
+	mustMarkFlagHidden(cmd.Flags(), "overrides")
+	cmd.Flags().BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().DurationVar(&flags.timeout, "timeout", 30*time.Minute, "Time to wait for every node to be configured with the new proxy")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+
+	return cmd
+}
+
+// ProxyUnsetCmd returns a cobra command for removing the proxy of an existing cluster.
+func ProxyUnsetCmd(ctx context.Context, appTitle string) *cobra.Command {
+	var flags proxySetFlags
+	var rc runtimeconfig.RuntimeConfig
+
+	cmd := &cobra.Command{
+		Use:   "unset",
+		Short: fmt.Sprintf("Stop using a proxy in the %s cluster", appTitle),
+		Long: fmt.Sprintf(`Stop using a proxy in the %s cluster.
+
+Run it as root on a controller. The add-ons and the k0s and local artifact mirror services of
+every node are restarted without the proxy, as they are by proxy set.`, appTitle),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// Skip root check if dryrun mode is enabled
+			if !dryrun.Enabled() && os.Getuid() != 0 {
+				return fmt.Errorf("proxy unset command must be run as root")
+			}
+
+			rc = rcutil.InitBestRuntimeConfig(cmd.Context())
+
+			_ = rc.SetEnv()
+
+			return nil
+		},
+		PostRun: func(cmd *cobra.Command, args []string) {
+			rc.Cleanup()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runProxyChange(cmd.Context(), rc, nil, flags)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.overrides, "overrides", "", "File with an EmbeddedClusterConfig object to override the default configuration")
+	mustMarkFlagHidden(cmd.Flags(), "overrides")
+	cmd.Flags().BoolVarP(&flags.assumeYes, "yes", "y", false, "Assume yes to all prompts.")
+	cmd.Flags().DurationVar(&flags.timeout, "timeout", 30*time.Minute, "Time to wait for every node to be configured without the proxy")
+	cmd.Flags().SetNormalizeFunc(normalizeNoPromptToYes)
+
+	return cmd
+}
+
+// mergeProxyFlags returns the values of the proxy flags that were set, and the values of the
+// current proxy for the others. The no-proxy list of the current proxy also holds the defaults
+// added to it so the hosts the user provided are kept instead.
+func mergeProxyFlags(current *ecv1beta1.ProxySpec, fs *pflag.FlagSet, flags proxySetFlags) (string, string, string) {
+	if current == nil {
+		current = &ecv1beta1.ProxySpec{}
+	}
+	httpProxy, httpsProxy, noProxy := current.HTTPProxy, current.HTTPSProxy, current.ProvidedNoProxy
+	if fs.Changed("http-proxy") {
+		httpProxy = flags.httpProxy
+	}
+	if fs.Changed("https-proxy") {
+		httpsProxy = flags.httpsProxy
+	}
+	if fs.Changed("no-proxy") {
+		noProxy = flags.noProxy
+	}
+	return httpProxy, httpsProxy, noProxy
+}
+
+func validateProxyURLFlag(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	// proxy urls may hold credentials so the value is left out of the error
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid %s: must be a URL with a scheme and a host, e.g. http://proxy.example.com:3128", name)
+	}
+	return nil
+}
+
+// runProxyChange upgrades the add-ons using the proxy, sets it in the installation and waits for
+// the operator to configure it on every node. A nil proxy removes it. The add-ons are upgraded
+// first as the operator restarts k0s on every node, and with it the Kubernetes API, once the
+// installation changes.
+func runProxyChange(ctx context.Context, rc runtimeconfig.RuntimeConfig, proxy *ecv1beta1.ProxySpec, flags proxySetFlags) error {
+	// End user config (overrides file), the one passed at install time
+	eucfg, err := helpers.ParseEndUserConfig(flags.overrides)
+	if err != nil {
+		return fmt.Errorf("process overrides file: %w", err)
+	}
+
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get latest installation: %w", err)
+	}
+	if in.Status.State != ecv1beta1.InstallationStateInstalled {
+		return fmt.Errorf("the installation is %s, wait for it to be %s and try again", in.Status.State, ecv1beta1.InstallationStateInstalled)
+	}
+	if in.Spec.Config != nil && strings.TrimPrefix(in.Spec.Config.Version, "v") != strings.TrimPrefix(versions.Version, "v") {
+		return fmt.Errorf("embedded cluster version mismatch - this binary is version %q, but the cluster is running version %q", versions.Version, in.Spec.Config.Version)
+	}
+
+	var current *ecv1beta1.ProxySpec
+	if in.Spec.RuntimeConfig != nil {
+		current = in.Spec.RuntimeConfig.Proxy
+	}
+	if sameProxy(current, proxy) {
+		logrus.Info("The proxy of the cluster already has these values.")
+		return nil
+	}
+
+	printProxyChange(humanOutput(), current, proxy)
+	if !flags.assumeYes {
+		confirmed, err := prompts.New().Confirm("Do you want to continue?", false)
+		if err != nil {
+			return fmt.Errorf("failed to get confirmation: %w", err)
+		}
+		if !confirmed {
+			return fmt.Errorf("Aborting")
+		}
+	}
+
+	// the commands run from now on reach the network through the new proxy
+	replaceProxyEnv(proxy)
+
+	rc.SetProxySpec(proxy)
+
+	loading := spinner.Start()
+	loading.Infof("Upgrading the add-ons with the new proxy")
+	if err := reconcileAddOnsProxy(ctx, kcli, rc, in, eucfg); err != nil {
+		loading.ErrorClosef("Failed to upgrade the add-ons")
+		return err
+	}
+	loading.Closef("Add-ons upgraded")
+
+	if err := kubeutils.UpdateInstallation(ctx, kcli, in, func(in *ecv1beta1.Installation) {
+		if in.Spec.RuntimeConfig == nil {
+			in.Spec.RuntimeConfig = ecv1beta1.GetDefaultRuntimeConfig()
+		}
+		in.Spec.RuntimeConfig.Proxy = proxy
+	}); err != nil {
+		return fmt.Errorf("unable to update installation: %w", err)
+	}
+
+	if err := rc.WriteToDisk(); err != nil {
+		return fmt.Errorf("unable to write runtime config: %w", err)
+	}
+
+	loading = spinner.Start()
+	loading.Infof("Configuring the proxy of the nodes")
+	if err := waitForHostProxy(ctx, kcli, in.Generation, flags.timeout, loading); err != nil {
+		loading.ErrorClosef("Failed to configure the proxy of the nodes")
+		return err
+	}
+	loading.Closef("Proxy configured on every node")
+
+	return nil
+}
+
+// reconcileAddOnsProxy upgrades the add-ons using the proxy with the proxy of the runtime config
+// and the overrides of the end user config, if any.
+func reconcileAddOnsProxy(ctx context.Context, kcli client.Client, rc runtimeconfig.RuntimeConfig, in *ecv1beta1.Installation, eucfg *ecv1beta1.Config) error {
+	meta, err := ecrelease.MetadataFor(ctx, in, kcli)
+	if err != nil {
+		return fmt.Errorf("unable to get release metadata: %w", err)
+	}
+
+	addOns, closeAddOns, err := newHAAddOns(kcli, rc, in)
+	if err != nil {
+		return err
+	}
+	defer closeAddOns()
+
+	kotsadmNamespace, err := runtimeconfig.KotsadmNamespace(ctx, kcli)
+	if err != nil {
+		return fmt.Errorf("get kotsadm namespace: %w", err)
+	}
+
+	var euCfgSpec *ecv1beta1.ConfigSpec
+	if eucfg != nil {
+		euCfgSpec = &eucfg.Spec
+	}
+
+	opts := addons.UpgradeOptions{
+		ClusterID:               in.Spec.ClusterID,
+		AdminConsolePort:        rc.AdminConsolePort(),
+		IsAirgap:                in.Spec.AirGap,
+		IsHA:                    in.Spec.HighAvailability,
+		DisasterRecoveryEnabled: in.Spec.LicenseInfo != nil && in.Spec.LicenseInfo.IsDisasterRecoverySupported,
+		IsMultiNodeEnabled:      in.Spec.LicenseInfo != nil && in.Spec.LicenseInfo.IsMultiNodeEnabled,
+		EmbeddedConfigSpec:      in.Spec.Config,
+		EndUserConfigSpec:       euCfgSpec,
+		ProxySpec:               rc.ProxySpec(),
+		HostCABundlePath:        rc.HostCABundlePath(),
+		KotsadmNamespace:        kotsadmNamespace,
+		DataDir:                 rc.EmbeddedClusterHomeDirectory(),
+		K0sDataDir:              rc.EmbeddedClusterK0sSubDir(),
+		OpenEBSDataDir:          rc.EmbeddedClusterOpenEBSLocalSubDir(),
+		SeaweedFSDataDir:        rc.EmbeddedClusterSeaweedFSSubDir(),
+		ServiceCIDR:             rc.ServiceCIDR(),
+	}
+	if err := addOns.ReconcileProxy(ctx, in, meta, opts); err != nil {
+		return fmt.Errorf("unable to upgrade add-ons: %w", err)
+	}
+	return nil
+}
+
+// waitForHostProxy waits for the operator to report the proxy of the installation generation
+// configured on every node. The progress of the operator is written to the spinner.
+func waitForHostProxy(ctx context.Context, kcli client.Client, generation int64, timeout time.Duration, loading *spinner.MessageWriter) error {
+	var last *metav1.Condition
+	err := wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+		if err != nil {
+			logrus.Debugf("Unable to get latest installation: %v", err)
+			return false, nil
+		}
+		cond := meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostProxyConfigured)
+		if cond == nil || cond.ObservedGeneration < generation {
+			return false, nil
+		}
+		last = cond
+		loading.Infof("%s", cond.Message)
+		return cond.Status == metav1.ConditionTrue, nil
+	})
+	if err != nil {
+		if last != nil && last.Reason == "Failed" {
+			return fmt.Errorf("unable to configure the proxy of the nodes: %s", last.Message)
+		}
+		return fmt.Errorf("timed out waiting for the proxy to be configured on every node: %w", err)
+	}
+	return nil
+}
+
+// printProxyChange writes the current and the new values of the proxy and what changing it
+// restarts.
+func printProxyChange(w io.Writer, current, proxy *ecv1beta1.ProxySpec) {
+	value := func(p *ecv1beta1.ProxySpec, field func(*ecv1beta1.ProxySpec) string) string {
+		if p == nil || field(p) == "" {
+			return "none"
+		}
+		return redactProxyURL(field(p))
+	}
+	fields := []struct {
+		name  string
+		value func(*ecv1beta1.ProxySpec) string
+	}{
+		{"HTTP proxy", func(p *ecv1beta1.ProxySpec) string { return p.HTTPProxy }},
+		{"HTTPS proxy", func(p *ecv1beta1.ProxySpec) string { return p.HTTPSProxy }},
+		{"No proxy", func(p *ecv1beta1.ProxySpec) string { return p.NoProxy }},
+	}
+
+	fmt.Fprintln(w, "The proxy of the cluster will change:")
+	for _, f := range fields {
+		fmt.Fprintf(w, "  %-12s %s -> %s\n", f.name+":", value(current, f.value), value(proxy, f.value))
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "The add-ons using the proxy restart their pods. The k0s and local artifact mirror services")
+	fmt.Fprintln(w, "of every node restart one node at a time, the Kubernetes API of a controller is unavailable")
+	fmt.Fprintln(w, "while it restarts.")
+	fmt.Fprintln(w)
+}
+
+// redactProxyURL hides the password of the proxy url, if any.
+func redactProxyURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	return u.Redacted()
+}
+
+// sameProxy returns true if both proxies have the same values.
+func sameProxy(a, b *ecv1beta1.ProxySpec) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.HTTPProxy == b.HTTPProxy && a.HTTPSProxy == b.HTTPSProxy && a.NoProxy == b.NoProxy
+}
+
+// replaceProxyEnv sets the proxy environment variables to the new proxy. The runtime config set
+// them to the current one.
+func replaceProxyEnv(proxy *ecv1beta1.ProxySpec) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		os.Unsetenv(name)
+	}
+	newconfig.SetProxyEnv(proxy)
+}
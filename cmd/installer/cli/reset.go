@@ -516,23 +516,24 @@ func (h *hostInfo) checkResetSafety(ctx context.Context, force bool) (bool, stri
 		return false, "", fmt.Errorf("unable to load cluster client: %w", h.KclientError)
 	}
 
-	etcdClient, err := etcd.NewClient(h.Status.Vars.CertRootDir, h.Status.Vars.EtcdCertDir, h.Status.ClusterConfig.Spec.Storage.Etcd)
-	if err != nil {
-		return false, "", fmt.Errorf("unable to create etcd client: %w", err)
-	}
-	if etcdClient.Health(ctx) != nil {
-		return false, "Etcd is not ready. Please wait up to 5 minutes and try again.", nil
-	}
+	return checkControllerLeaveSafety(ctx, h.Kclient, &h.Status, h.Hostname, h.RoleName)
+}
 
+// checkControllerLeaveSafety performs the checks shared by the commands taking a controller out
+// of the cluster, reset and node remove, to see if it would cause an outage: etcd must be
+// healthy and the last controller can't leave while other nodes remain.
+func checkControllerLeaveSafety(ctx context.Context, kcli client.Client, status *k0s.K0sStatus, name string, roleName string) (bool, string, error) {
 	// get a rough picture of the cluster topology
 	workers := []string{}
 	controllers := []string{}
 	nodeList := corev1.NodeList{}
-	err = h.Kclient.List(ctx, &nodeList)
-	if err != nil {
+	if err := kcli.List(ctx, &nodeList); err != nil {
 		return false, "", fmt.Errorf("unable to list Nodes: %w", err)
 	}
 	for _, node := range nodeList.Items {
+		if node.Name == name {
+			continue
+		}
 		labels := node.GetLabels()
 		if _, ok := labels["node-role.kubernetes.io/control-plane"]; ok {
 			controllers = append(controllers, node.Name)
@@ -540,10 +541,19 @@ func (h *hostInfo) checkResetSafety(ctx context.Context, force bool) (bool, stri
 			workers = append(workers, node.Name)
 		}
 	}
-	if len(workers) > 0 && len(controllers) == 1 {
-		message := fmt.Sprintf("Cannot reset the last %s node when there are other nodes in the cluster.", h.RoleName)
+	if len(workers) > 0 && len(controllers) == 0 {
+		message := fmt.Sprintf("Cannot remove the last %s node when there are other nodes in the cluster.", roleName)
 		return false, message, nil
 	}
+
+	etcdClient, err := etcd.NewClient(status.Vars.CertRootDir, status.Vars.EtcdCertDir, status.ClusterConfig.Spec.Storage.Etcd)
+	if err != nil {
+		return false, "", fmt.Errorf("unable to create etcd client: %w", err)
+	}
+	if etcdClient.Health(ctx) != nil {
+		return false, "Etcd is not ready. Please wait up to 5 minutes and try again.", nil
+	}
+
 	return true, "", nil
 }
 
@@ -98,6 +98,9 @@ func RestoreCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "restore",
 		Short: fmt.Sprintf("Restore %s from a backup", appTitle),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return setupOutput(cmd)
+		},
 		PostRun: func(cmd *cobra.Command, args []string) {
 			rc.Cleanup()
 		},
@@ -121,6 +124,7 @@ func RestoreCmd(ctx context.Context, appSlug, appTitle string) *cobra.Command {
 	cmd.Flags().BoolVar(&skipStoreValidation, "skip-store-validation", false, "Skip validation of the backup storage location")
 
 	mustAddInstallFlags(cmd, &flags)
+	mustAddOutputFlag(cmd)
 
 	return cmd
 }
@@ -191,7 +195,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 
 	switch state {
 	case ecRestoreStateNew:
-		err = runRestoreStepNew(ctx, appSlug, appTitle, flags, installCfg, rc, &s3Store, skipStoreValidation)
+		err = runPhase(string(ecRestoreStateNew), func() error {
+			return runRestoreStepNew(ctx, appSlug, appTitle, flags, installCfg, rc, &s3Store, skipStoreValidation)
+		})
 		if err != nil {
 			return err
 		}
@@ -205,7 +211,12 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		backup, ok, err := runRestoreStepConfirmBackup(ctx, installCfg, rc)
+		var backup *disasterrecovery.ReplicatedBackup
+		var ok bool
+		err = runPhase(string(ecRestoreStateConfirmBackup), func() (err error) {
+			backup, ok, err = runRestoreStepConfirmBackup(ctx, installCfg, rc)
+			return err
+		})
 		if err != nil {
 			return err
 		} else if !ok {
@@ -222,7 +233,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreECInstall(ctx, rc, backupToRestore)
+		err = runPhase(string(ecRestoreStateRestoreECInstall), func() error {
+			return runRestoreECInstall(ctx, rc, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -236,7 +249,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreAdminConsole(ctx, backupToRestore)
+		err = runPhase(string(ecRestoreStateRestoreAdminConsole), func() error {
+			return runRestoreAdminConsole(ctx, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -250,7 +265,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreWaitForNodes(ctx, flags, rc, backupToRestore)
+		err = runPhase(string(ecRestoreStateWaitForNodes), func() error {
+			return runRestoreWaitForNodes(ctx, flags, rc, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -264,7 +281,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreSeaweedFS(ctx, installCfg, backupToRestore)
+		err = runPhase(string(ecRestoreStateRestoreSeaweedFS), func() error {
+			return runRestoreSeaweedFS(ctx, installCfg, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -278,7 +297,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreRegistry(ctx, installCfg, backupToRestore)
+		err = runPhase(string(ecRestoreStateRestoreRegistry), func() error {
+			return runRestoreRegistry(ctx, installCfg, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -292,7 +313,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreEnableAdminConsoleHA(ctx, installCfg, rc, backupToRestore)
+		err = runPhase(string(ecRestoreStateAdminConsoleEnableHA), func() error {
+			return runRestoreEnableAdminConsoleHA(ctx, installCfg, rc, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -306,7 +329,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreECO(ctx, backupToRestore)
+		err = runPhase(string(ecRestoreStateRestoreECO), func() error {
+			return runRestoreECO(ctx, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -320,7 +345,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreExtensions(ctx, installCfg, rc)
+		err = runPhase(string(ecRestoreStateRestoreExtensions), func() error {
+			return runRestoreExtensions(ctx, installCfg, rc)
+		})
 		if err != nil {
 			return err
 		}
@@ -334,7 +361,9 @@ func runRestore(ctx context.Context, appSlug, appTitle string, flags installFlag
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreApp(ctx, backupToRestore)
+		err = runPhase(string(ecRestoreStateRestoreApp), func() error {
+			return runRestoreApp(ctx, backupToRestore)
+		})
 		if err != nil {
 			return err
 		}
@@ -465,6 +494,7 @@ func installAddonsForRestore(ctx context.Context, kcli client.Client, mcli metad
 	var loading *spinner.MessageWriter
 	go func() {
 		for progress := range progressChan {
+			emitAddOnProgress(progress)
 			switch progress.Status.State {
 			case apitypes.StateRunning:
 				loading = spinner.Start()
@@ -513,7 +543,7 @@ func runRestoreStepConfirmBackup(ctx context.Context, installCfg *installConfig,
 	}
 
 	logrus.Debugf("waiting for backups to become available")
-	backups, err := waitForBackups(ctx, os.Stdout, kcli, k0sCfg, rc, installCfg.isAirgap)
+	backups, err := waitForBackups(ctx, humanOutput(), kcli, k0sCfg, rc, installCfg.isAirgap)
 	if err != nil {
 		return nil, false, err
 	}
@@ -1682,7 +1712,7 @@ func waitForAdditionalNodes(ctx context.Context, highAvailability bool, networkI
 		return fmt.Errorf("unable to create kube client: %w", err)
 	}
 
-	adminConsoleURL := getAdminConsoleURL("", networkInterface, rc.AdminConsolePort())
+	adminConsoleURL := getAdminConsoleURL("", rc.AdminConsoleAddress(), networkInterface, rc.AdminConsolePort())
 
 	successColor := "\033[32m"
 	colorReset := "\033[0m"
@@ -7,6 +7,7 @@ import (
 	"os"
 	"syscall"
 
+	"github.com/replicatedhq/embedded-cluster/pkg-new/progress"
 	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
 	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
@@ -35,6 +36,7 @@ func NewErrorNothingElseToAdd(err error) ErrorNothingElseToAdd {
 func InitAndExecute(ctx context.Context) {
 	cmd := RootCmd(ctx)
 	err := cmd.Execute()
+	progress.Result(err)
 	if err != nil {
 		if !errors.As(err, &ErrorNothingElseToAdd{}) {
 			if isErrPermissionForkExec(err) {
@@ -109,7 +111,11 @@ func RootCmd(ctx context.Context) *cobra.Command {
 	cmd.AddCommand(JoinCmd(ctx, appSlug, appTitle))
 	cmd.AddCommand(ShellCmd(ctx, appTitle))
 	cmd.AddCommand(NodeCmd(ctx, appSlug, appTitle))
+	cmd.AddCommand(NetworkCmd(ctx, appSlug, appTitle))
+	cmd.AddCommand(ProxyCmd(ctx, appTitle))
 	cmd.AddCommand(EnableHACmd(ctx, appTitle))
+	cmd.AddCommand(DisableHACmd(ctx, appTitle))
+	cmd.AddCommand(PreflightsCmd(ctx, appSlug, appTitle))
 	cmd.AddCommand(VersionCmd(ctx, appTitle))
 	cmd.AddCommand(ResetCmd(ctx, appTitle))
 	cmd.AddCommand(MaterializeCmd(ctx))
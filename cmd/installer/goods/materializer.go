@@ -78,14 +78,32 @@ func Binary(name string) (string, error) {
 	return dstpath.Name(), nil
 }
 
+const (
+	// LocalArtifactMirrorUnitFilePath is where the local-artifact-mirror systemd unit file is
+	// materialized.
+	LocalArtifactMirrorUnitFilePath = "/etc/systemd/system/local-artifact-mirror.service"
+	// CalicoNetworkManagerConfigPath is where the network manager configuration file is
+	// materialized.
+	CalicoNetworkManagerConfigPath = "/etc/NetworkManager/conf.d/embedded-cluster.conf"
+)
+
+// LocalArtifactMirrorUnit returns the content of the local-artifact-mirror systemd unit file.
+func LocalArtifactMirrorUnit() ([]byte, error) {
+	return systemdfs.ReadFile("systemd/local-artifact-mirror.service")
+}
+
+// CalicoNetworkManagerConfig returns the content of the network manager configuration file.
+func CalicoNetworkManagerConfig() ([]byte, error) {
+	return systemdfs.ReadFile("systemd/calico-network-manager.conf")
+}
+
 // LocalArtifactMirrorUnitFile writes to disk the local-artifact-mirror systemd unit file.
 func (m *Materializer) LocalArtifactMirrorUnitFile() error {
-	content, err := systemdfs.ReadFile("systemd/local-artifact-mirror.service")
+	content, err := LocalArtifactMirrorUnit()
 	if err != nil {
 		return fmt.Errorf("unable to open unit file: %w", err)
 	}
-	dstpath := "/etc/systemd/system/local-artifact-mirror.service"
-	if err := os.WriteFile(dstpath, content, 0644); err != nil {
+	if err := os.WriteFile(LocalArtifactMirrorUnitFilePath, content, 0644); err != nil {
 		return fmt.Errorf("unable to write file: %w", err)
 	}
 	return nil
@@ -95,12 +113,11 @@ func (m *Materializer) LocalArtifactMirrorUnitFile() error {
 // configuration file instructs the network manager to ignore any interface being managed by
 // the calico network cni.
 func (m *Materializer) CalicoNetworkManagerConfig() error {
-	content, err := systemdfs.ReadFile("systemd/calico-network-manager.conf")
+	content, err := CalicoNetworkManagerConfig()
 	if err != nil {
 		return fmt.Errorf("unable to open network manager config file: %w", err)
 	}
-	dstpath := "/etc/NetworkManager/conf.d/embedded-cluster.conf"
-	if err := os.WriteFile(dstpath, content, 0644); err != nil {
+	if err := os.WriteFile(CalicoNetworkManagerConfigPath, content, 0644); err != nil {
 		return fmt.Errorf("unable to write file: %w", err)
 	}
 	return nil
@@ -120,6 +137,38 @@ func (m *Materializer) Materialize() error {
 	return nil
 }
 
+// MaterializedFiles returns the paths of all files written to disk by Materialize.
+func (m *Materializer) MaterializedFiles() ([]string, error) {
+	paths := []string{m.rc.PathToEmbeddedClusterBinary(runtimeconfig.AppSlug())}
+
+	entries, err := binfs.ReadDir("bins")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read embedded-cluster bins dir: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == PlaceHolder {
+			continue
+		}
+		paths = append(paths, m.rc.PathToEmbeddedClusterBinary(entry.Name()))
+	}
+
+	paths = append(paths,
+		m.rc.PathToEmbeddedClusterBinary("kubectl"),
+		m.rc.PathToEmbeddedClusterBinary("kubectl_completion_bash.sh"),
+	)
+
+	entries, err = supportfs.ReadDir("support")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read embedded-cluster support dir: %w", err)
+	}
+	for _, entry := range entries {
+		paths = append(paths, m.rc.PathToEmbeddedClusterSupportFile(entry.Name()))
+	}
+	paths = append(paths, m.rc.PathToEmbeddedClusterSupportFile("host-support-bundle-remote.yaml"))
+
+	return paths, nil
+}
+
 // SizeOfEmbeddedAssets returns the size of all embedded assets.
 func SizeOfEmbeddedAssets() (int64, error) {
 	binSize, err := calculateFSSize(binfs, "bins")
@@ -178,6 +178,39 @@ type Domains struct {
 	ReplicatedRegistryDomain string `json:"replicatedRegistryDomain,omitempty"`
 }
 
+// HostPreflights configures the host preflights shipped in the release
+type HostPreflights struct {
+	// Variables are available as .Vendor to the host preflights annotated with
+	// embedded-cluster.replicated.com/template: "true"
+	// +kubebuilder:validation:Optional
+	Variables map[string]string `json:"variables,omitempty"`
+	// StoragePerformance sets the storage performance required from the data directory and
+	// the OpenEBS data directory. The storage is only benchmarked when it is set.
+	// +kubebuilder:validation:Optional
+	StoragePerformance *StoragePerformance `json:"storagePerformance,omitempty"`
+}
+
+// StoragePerformance holds the thresholds of the storage benchmark run along with the host
+// preflights. Unset thresholds are not checked.
+type StoragePerformance struct {
+	// MinSequentialWriteMBps is the minimum sequential write throughput, in megabytes per second.
+	// +kubebuilder:validation:Optional
+	MinSequentialWriteMBps int `json:"minSequentialWriteMBps,omitempty"`
+	// MinRandomWriteIOPS is the minimum number of synchronous random 4k writes per second.
+	// +kubebuilder:validation:Optional
+	MinRandomWriteIOPS int `json:"minRandomWriteIOPS,omitempty"`
+	// MaxFsyncLatencyP50 is the maximum median latency of a write followed by an fsync.
+	// +kubebuilder:validation:Optional
+	MaxFsyncLatencyP50 *metav1.Duration `json:"maxFsyncLatencyP50,omitempty"`
+	// MaxFsyncLatencyP99 is the maximum 99th percentile latency of a write followed by an fsync.
+	// +kubebuilder:validation:Optional
+	MaxFsyncLatencyP99 *metav1.Duration `json:"maxFsyncLatencyP99,omitempty"`
+	// Strict makes the storage checks block the installation even when host preflights are
+	// ignored.
+	// +kubebuilder:validation:Optional
+	Strict bool `json:"strict,omitempty"`
+}
+
 // ConfigSpec defines the desired state of Config
 type ConfigSpec struct {
 	Version              string               `json:"version,omitempty"`
@@ -187,6 +220,7 @@ type ConfigSpec struct {
 	UnsupportedOverrides UnsupportedOverrides `json:"unsupportedOverrides,omitempty"`
 	Extensions           Extensions           `json:"extensions,omitempty"`
 	Domains              Domains              `json:"domains,omitempty"`
+	HostPreflights       HostPreflights       `json:"hostPreflights,omitempty"`
 }
 
 // OverrideForBuiltIn returns the override for the built-in extension with the
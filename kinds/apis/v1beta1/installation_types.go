@@ -50,6 +50,9 @@ const (
 
 const (
 	ConditionTypeV2MigrationInProgress = "V2MigrationInProgress"
+	ConditionTypeInstallCheckpoint     = "InstallCheckpoint"
+	ConditionTypeHostPreflightsDrift   = "HostPreflightsDrift"
+	ConditionTypeHostProxyConfigured   = "HostProxyConfigured"
 )
 
 // ConfigSecretEntryName holds the entry name we are looking for in the secret
@@ -64,6 +67,16 @@ type NodeStatus struct {
 	Hash string `json:"hash"`
 }
 
+// NodeFacts holds what a node reported about itself when it was installed or joined. Nodes
+// joining the cluster are compared with them before k0s starts.
+type NodeFacts struct {
+	Name             string `json:"name"`
+	KernelVersion    string `json:"kernelVersion,omitempty"`
+	CgroupVersion    int    `json:"cgroupVersion,omitempty"`
+	NetworkInterface string `json:"networkInterface,omitempty"`
+	MTU              int    `json:"mtu,omitempty"`
+}
+
 // ArtifactsLocation defines a location from where we can download an
 // airgap bundle. It contains individual URLs for each component of the
 // bundle. These URLs are expected to point to a registry running inside
@@ -91,7 +104,24 @@ type NetworkSpec struct {
 	GlobalCIDR       string `json:"globalCIDR,omitempty"`
 	PodCIDR          string `json:"podCIDR,omitempty"`
 	ServiceCIDR      string `json:"serviceCIDR,omitempty"`
-	NodePortRange    string `json:"nodePortRange,omitempty"`
+	// IPv6PodCIDR and IPv6ServiceCIDR are the IPv6 CIDRs of a dual-stack cluster, PodCIDR and
+	// ServiceCIDR hold the IPv4 ones. They hold IPv6 CIDRs themselves in IPv6 only clusters.
+	IPv6PodCIDR     string `json:"ipv6PodCIDR,omitempty"`
+	IPv6ServiceCIDR string `json:"ipv6ServiceCIDR,omitempty"`
+	NodePortRange   string `json:"nodePortRange,omitempty"`
+	// DNSUpstreams are the nameservers CoreDNS forwards the queries it does not answer to, in
+	// place of the nameservers in the resolv.conf of the hosts.
+	DNSUpstreams []string `json:"dnsUpstreams,omitempty"`
+	// ControlPlaneInterface is the interface the Kubernetes API server and etcd advertise their
+	// address on. NetworkInterface is used when empty.
+	ControlPlaneInterface string `json:"controlPlaneInterface,omitempty"`
+	// PodNetworkInterface is the interface Calico autodetects the address of the nodes on, pod
+	// traffic between the nodes goes through it. Calico autodetects the interface when empty.
+	PodNetworkInterface string `json:"podNetworkInterface,omitempty"`
+	// AdminConsoleAddress is the address, an IP or a hostname, users reach the admin console on
+	// from outside the cluster. It is discovered from the cloud provider or NetworkInterface when
+	// empty.
+	AdminConsoleAddress string `json:"adminConsoleAddress,omitempty"`
 }
 
 // AdminConsoleSpec holds the admin console configuration.
@@ -151,6 +181,11 @@ type InstallationSpec struct {
 	// RuntimeConfig holds the runtime configuration used at installation time.
 	RuntimeConfig *RuntimeConfigSpec `json:"runtimeConfig,omitempty"`
 
+	// NodeFacts holds the facts of the nodes in the cluster, kept up to date by the operator.
+	// Joining nodes receive them with the join command and have no other way to see the
+	// cluster before they join.
+	NodeFacts []NodeFacts `json:"nodeFacts,omitempty"`
+
 	// TODO: all fields below should be moved to RuntimeConfig
 
 	// HighAvailability indicates if the installation is high availability.
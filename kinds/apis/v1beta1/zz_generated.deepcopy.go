@@ -195,6 +195,7 @@ func (in *ConfigSpec) DeepCopyInto(out *ConfigSpec) {
 	in.UnsupportedOverrides.DeepCopyInto(&out.UnsupportedOverrides)
 	in.Extensions.DeepCopyInto(&out.Extensions)
 	out.Domains = in.Domains
+	in.HostPreflights.DeepCopyInto(&out.HostPreflights)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigSpec.
@@ -287,6 +288,33 @@ func (in *Helm) DeepCopy() *Helm {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPreflights) DeepCopyInto(out *HostPreflights) {
+	*out = *in
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.StoragePerformance != nil {
+		in, out := &in.StoragePerformance, &out.StoragePerformance
+		*out = new(StoragePerformance)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPreflights.
+func (in *HostPreflights) DeepCopy() *HostPreflights {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPreflights)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Installation) DeepCopyInto(out *Installation) {
 	*out = *in
@@ -374,6 +402,11 @@ func (in *InstallationSpec) DeepCopyInto(out *InstallationSpec) {
 		*out = new(RuntimeConfigSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeFacts != nil {
+		in, out := &in.NodeFacts, &out.NodeFacts
+		*out = make([]NodeFacts, len(*in))
+		copy(*out, *in)
+	}
 	if in.Deprecated_Proxy != nil {
 		in, out := &in.Deprecated_Proxy, &out.Deprecated_Proxy
 		*out = new(ProxySpec)
@@ -382,7 +415,7 @@ func (in *InstallationSpec) DeepCopyInto(out *InstallationSpec) {
 	if in.Deprecated_Network != nil {
 		in, out := &in.Deprecated_Network, &out.Deprecated_Network
 		*out = new(NetworkSpec)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Deprecated_AdminConsole != nil {
 		in, out := &in.Deprecated_AdminConsole, &out.Deprecated_AdminConsole
@@ -552,6 +585,11 @@ func (in *ManagerSpec) DeepCopy() *ManagerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
 	*out = *in
+	if in.DNSUpstreams != nil {
+		in, out := &in.DNSUpstreams, &out.DNSUpstreams
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkSpec.
@@ -641,6 +679,21 @@ func (in *NodeRole) DeepCopy() *NodeRole {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeFacts) DeepCopyInto(out *NodeFacts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeFacts.
+func (in *NodeFacts) DeepCopy() *NodeFacts {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeFacts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeStatus) DeepCopyInto(out *NodeStatus) {
 	*out = *in
@@ -702,7 +755,7 @@ func (in *RuntimeConfigSpec) DeepCopyInto(out *RuntimeConfigSpec) {
 		*out = new(ProxySpec)
 		**out = **in
 	}
-	out.Network = in.Network
+	in.Network.DeepCopyInto(&out.Network)
 	out.AdminConsole = in.AdminConsole
 	out.LocalArtifactMirror = in.LocalArtifactMirror
 	out.Manager = in.Manager
@@ -718,6 +771,31 @@ func (in *RuntimeConfigSpec) DeepCopy() *RuntimeConfigSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StoragePerformance) DeepCopyInto(out *StoragePerformance) {
+	*out = *in
+	if in.MaxFsyncLatencyP50 != nil {
+		in, out := &in.MaxFsyncLatencyP50, &out.MaxFsyncLatencyP50
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxFsyncLatencyP99 != nil {
+		in, out := &in.MaxFsyncLatencyP99, &out.MaxFsyncLatencyP99
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StoragePerformance.
+func (in *StoragePerformance) DeepCopy() *StoragePerformance {
+	if in == nil {
+		return nil
+	}
+	out := new(StoragePerformance)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UnsupportedOverrides) DeepCopyInto(out *UnsupportedOverrides) {
 	*out = *in
@@ -0,0 +1,435 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// hostPreflightRecheckInterval is how often the host preflights are run again on every node.
+var hostPreflightRecheckInterval = 6 * time.Hour
+
+const (
+	hostPreflightRecheckJobPrefix = "host-preflight-recheck-"
+
+	// hostPreflightResultLabel labels the configmaps holding the host preflight results copied
+	// from the nodes when they were installed or joined.
+	hostPreflightResultLabel = "embedded-cluster/host-preflight-result"
+	// hostPreflightRecheckLabel labels the configmaps holding the host preflight results of the
+	// last recheck of a node.
+	hostPreflightRecheckLabel = "embedded-cluster/host-preflight-recheck"
+
+	// updateTimestampAnnotation is set by the jobs to the time the results were stored.
+	updateTimestampAnnotation = "update-timestamp"
+	// evaluatedTimestampAnnotation is set by the operator to the update timestamp of the results
+	// it last evaluated, so events are recorded once per recheck.
+	evaluatedTimestampAnnotation = "embedded-cluster/evaluated-timestamp"
+	// driftAnnotation holds the titles of the host preflights that drifted in the last evaluated
+	// recheck, as a JSON list.
+	driftAnnotation = "embedded-cluster/host-preflight-drift"
+)
+
+// hostPreflightRecheckJob is a job we create periodically on every node to run the host
+// preflights stored on the node at install or join time again. The preflight binary runs in the
+// host root so it sees the host as the installer did. The results are stored in a configmap.
+// During a reconcile cycle we will populate the node name, any env variables and labels.
+var hostPreflightRecheckJob = &batchv1.Job{
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: ecNamespace,
+	},
+	Spec: batchv1.JobSpec{
+		BackoffLimit:            ptr.To(int32(2)),
+		TTLSecondsAfterFinished: ptr.To(int32(1 * 60)), // we don't want to keep the job around. Delete it shortly after it finishes.
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "embedded-cluster-operator",
+				HostNetwork:        true,
+				HostPID:            true,
+				Tolerations: []corev1.Toleration{
+					{Operator: corev1.TolerationOpExists},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: "host",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: ecv1beta1.DefaultDataDir,
+								Type: ptr.To(corev1.HostPathDirectory),
+							},
+						},
+					},
+					{
+						Name: "k0s",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: runtimeconfig.K0sBinaryPath,
+								Type: ptr.To(corev1.HostPathFile),
+							},
+						},
+					},
+					{
+						Name: "host-root",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: "/",
+								Type: ptr.To(corev1.HostPathDirectory),
+							},
+						},
+					},
+				},
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{
+					{
+						Name:  "host-preflight-recheck",
+						Image: "busybox:latest",
+						Command: []string{
+							"/bin/sh",
+							"-e",
+							"-c",
+							"if [ -f /embedded-cluster/support/host-preflight-spec.yaml ]; " +
+								"then " +
+								"chroot /host /bin/sh -c 'cd ${EC_DATA_DIR}/tmp && PATH=${PATH}:${EC_DATA_DIR}/bin exec ${EC_DATA_DIR}/bin/kubectl-preflight " +
+								"--interactive=false --format=json ${EC_DATA_DIR}/support/host-preflight-spec.yaml' > /tmp/results.json || [ $? -ge 2 ]; " +
+								"rm -f /embedded-cluster/tmp/preflightbundle-*.tar.gz; " +
+								"FROM_FILE=--from-file=results.json=/tmp/results.json; " +
+								"else " +
+								"echo '/embedded-cluster/support/host-preflight-spec.yaml does not exist'; " +
+								"fi; " +
+								"/embedded-cluster/bin/kubectl create configmap ${HSPF_CM_NAME} ${FROM_FILE} " +
+								"-n embedded-cluster --dry-run=client -oyaml | " +
+								"/embedded-cluster/bin/kubectl label -f - embedded-cluster/host-preflight-recheck=${EC_NODE_NAME} --local -o yaml | " +
+								"/embedded-cluster/bin/kubectl apply -f - && " +
+								"/embedded-cluster/bin/kubectl annotate configmap ${HSPF_CM_NAME} \"update-timestamp=$(date +'%Y-%m-%dT%H:%M:%SZ')\" --overwrite",
+						},
+						Env: []corev1.EnvVar{
+							{
+								Name:  "KUBECONFIG",
+								Value: "", // make k0s kubectl not use admin.conf
+							},
+						},
+						SecurityContext: &corev1.SecurityContext{
+							Privileged: ptr.To(true),
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "host",
+								MountPath: "/embedded-cluster",
+								ReadOnly:  false,
+							},
+							{
+								Name:      "k0s",
+								MountPath: runtimeconfig.K0sBinaryPath,
+								ReadOnly:  true,
+							},
+							{
+								Name:      "host-root",
+								MountPath: "/host",
+								ReadOnly:  false,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// ReconcileHostPreflightRechecks runs the host preflights again on the nodes whose last recheck
+// is older than hostPreflightRecheckInterval, and compares the results with the ones from the
+// time the nodes were installed or joined. Checks that passed back then and now fail or warn
+// have drifted: an event is recorded when a check drifts or recovers and the HostPreflightsDrift
+// condition lists the drifted checks of every node. Installation is not updated remotely, the
+// caller must save the object after the call.
+func (r *InstallationReconciler) ReconcileHostPreflightRechecks(ctx context.Context, in *ecv1beta1.Installation) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	baselines, err := r.listHostPreflightResults(ctx, hostPreflightResultLabel)
+	if err != nil {
+		return fmt.Errorf("failed to list host preflight results: %w", err)
+	}
+	rechecks, err := r.listHostPreflightResults(ctx, hostPreflightRecheckLabel)
+	if err != nil {
+		return fmt.Errorf("failed to list host preflight rechecks: %w", err)
+	}
+
+	now := time.Now()
+	evaluated := false
+	drifted := map[string][]string{}
+	for _, node := range nodes.Items {
+		recheck := rechecks[node.Name]
+		if hostPreflightRecheckDue(recheck, node.CreationTimestamp.Time, now) {
+			if err := r.ensureHostPreflightRecheckJob(ctx, in, node.Name); err != nil {
+				return fmt.Errorf("failed to ensure host preflight recheck job for node %s: %w", node.Name, err)
+			}
+		}
+		if recheck == nil {
+			continue
+		}
+		evaluated = true
+
+		drift, err := hostPreflightsDrift(baselines[node.Name], recheck)
+		if err != nil {
+			log.Error(err, "Failed to evaluate host preflight recheck", "node", node.Name)
+			continue
+		}
+		titles := []string{}
+		for _, record := range drift {
+			titles = append(titles, record.Title)
+		}
+		if len(titles) > 0 {
+			drifted[node.Name] = titles
+		}
+
+		if err := r.recordHostPreflightDrift(ctx, in, node.Name, recheck, drift); err != nil {
+			return fmt.Errorf("failed to record host preflight drift for node %s: %w", node.Name, err)
+		}
+	}
+
+	if evaluated {
+		in.Status.SetCondition(hostPreflightsDriftCondition(drifted))
+	}
+	return nil
+}
+
+// listHostPreflightResults returns the host preflight results configmaps with the label, by the
+// name of the node they were collected from.
+func (r *InstallationReconciler) listHostPreflightResults(ctx context.Context, label string) (map[string]*corev1.ConfigMap, error) {
+	var cms corev1.ConfigMapList
+	if err := r.List(ctx, &cms, client.InNamespace(ecNamespace), client.HasLabels{label}); err != nil {
+		return nil, err
+	}
+	results := map[string]*corev1.ConfigMap{}
+	for i := range cms.Items {
+		results[cms.Items[i].Labels[label]] = &cms.Items[i]
+	}
+	return results, nil
+}
+
+// hostPreflightRecheckDue returns true if the last recheck of the node is older than
+// hostPreflightRecheckInterval. Nodes are first rechecked one interval after they joined, once
+// the results from the time they joined were copied.
+func hostPreflightRecheckDue(recheck *corev1.ConfigMap, joined time.Time, now time.Time) bool {
+	last := joined
+	if recheck != nil {
+		updated, err := time.Parse(time.RFC3339, recheck.Annotations[updateTimestampAnnotation])
+		if err != nil {
+			return true
+		}
+		last = updated
+	}
+	return now.Sub(last) >= hostPreflightRecheckInterval
+}
+
+// ensureHostPreflightRecheckJob creates the job that runs the host preflights on the node. A
+// job that is still around from the previous recheck is left alone.
+func (r *InstallationReconciler) ensureHostPreflightRecheckJob(ctx context.Context, in *ecv1beta1.Installation, nodeName string) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	job := constructHostPreflightRecheckJob(r.RuntimeConfig, in, nodeName)
+
+	// overrides the job image if the environment says so.
+	if img := os.Getenv("EMBEDDEDCLUSTER_UTILS_IMAGE"); img != "" {
+		job.Spec.Template.Spec.Containers[0].Image = img
+	}
+
+	if err := r.Create(ctx, job); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+		return nil
+	}
+	log.Info("Host preflight recheck job for node created", "node", nodeName, "installation", in.Name)
+	return nil
+}
+
+func constructHostPreflightRecheckJob(rc runtimeconfig.RuntimeConfig, in *ecv1beta1.Installation, nodeName string) *batchv1.Job {
+	labels := map[string]string{
+		"embedded-cluster/node-name":    nodeName,
+		"embedded-cluster/installation": in.Name,
+	}
+
+	job := hostPreflightRecheckJob.DeepCopy()
+	job.Name = util.NameWithLengthLimit(hostPreflightRecheckJobPrefix, nodeName)
+
+	job.Spec.Template.Labels, job.Labels = labels, labels
+	job.Spec.Template.Spec.NodeName = nodeName
+	job.Spec.Template.Spec.Volumes[0].HostPath.Path = rc.EmbeddedClusterHomeDirectory()
+
+	env := []corev1.EnvVar{
+		{Name: "EC_NODE_NAME", Value: nodeName},
+		{Name: "EC_DATA_DIR", Value: rc.EmbeddedClusterHomeDirectory()},
+		{Name: "HSPF_CM_NAME", Value: util.NameWithLengthLimit(nodeName, "-host-preflight-recheck")},
+	}
+	// the host preflights check access to the internet through the proxy, if any
+	if proxy := rc.ProxySpec(); proxy != nil {
+		env = append(env,
+			corev1.EnvVar{Name: "HTTP_PROXY", Value: proxy.HTTPProxy},
+			corev1.EnvVar{Name: "HTTPS_PROXY", Value: proxy.HTTPSProxy},
+			corev1.EnvVar{Name: "NO_PROXY", Value: proxy.NoProxy},
+		)
+	}
+	job.Spec.Template.Spec.Containers[0].Env = append(job.Spec.Template.Spec.Containers[0].Env, env...)
+
+	return job
+}
+
+// hostPreflightsDrift returns the failed and warned checks of the recheck that passed, or were
+// not run, when the node was installed or joined. If the results from back then are missing,
+// every failed and warned check is returned.
+func hostPreflightsDrift(baseline, recheck *corev1.ConfigMap) ([]apitypes.PreflightsRecord, error) {
+	current, err := hostPreflightsOutputFromConfigMap(recheck)
+	if err != nil {
+		return nil, fmt.Errorf("parse recheck results: %w", err)
+	}
+	if current == nil {
+		return nil, nil
+	}
+
+	before := map[string]bool{}
+	if baseline != nil {
+		previous, err := hostPreflightsOutputFromConfigMap(baseline)
+		if err != nil {
+			return nil, fmt.Errorf("parse install results: %w", err)
+		}
+		if previous != nil {
+			for _, record := range append(previous.Fail, previous.Warn...) {
+				before[record.Title] = true
+			}
+		}
+	}
+
+	drift := []apitypes.PreflightsRecord{}
+	for _, record := range append(current.Fail, current.Warn...) {
+		if !before[record.Title] {
+			drift = append(drift, record)
+		}
+	}
+	sort.SliceStable(drift, func(i, j int) bool { return drift[i].Title < drift[j].Title })
+	return drift, nil
+}
+
+// hostPreflightsOutputFromConfigMap returns the host preflight results stored in the configmap,
+// nil if the node had no host preflights to run.
+func hostPreflightsOutputFromConfigMap(cm *corev1.ConfigMap) (*apitypes.PreflightsOutput, error) {
+	data, ok := cm.Data["results.json"]
+	if !ok {
+		return nil, nil
+	}
+	var output apitypes.PreflightsOutput
+	if err := json.Unmarshal([]byte(data), &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// recordHostPreflightDrift records an event for every check that drifted or recovered since the
+// previous recheck of the node. Each recheck is only evaluated once.
+func (r *InstallationReconciler) recordHostPreflightDrift(ctx context.Context, in *ecv1beta1.Installation, nodeName string, recheck *corev1.ConfigMap, drift []apitypes.PreflightsRecord) error {
+	updated := recheck.Annotations[updateTimestampAnnotation]
+	if updated == "" || recheck.Annotations[evaluatedTimestampAnnotation] == updated {
+		return nil
+	}
+
+	previous := map[string]bool{}
+	if data := recheck.Annotations[driftAnnotation]; data != "" {
+		var titles []string
+		if err := json.Unmarshal([]byte(data), &titles); err == nil {
+			for _, title := range titles {
+				previous[title] = true
+			}
+		}
+	}
+
+	current := map[string]bool{}
+	titles := []string{}
+	for _, record := range drift {
+		current[record.Title] = true
+		titles = append(titles, record.Title)
+		if !previous[record.Title] {
+			r.Recorder.Eventf(in, corev1.EventTypeWarning, "HostPreflightDrift", "Host preflight %q no longer passes on node %s: %s", record.Title, nodeName, record.Message)
+		}
+	}
+	for title := range previous {
+		if !current[title] {
+			r.Recorder.Eventf(in, corev1.EventTypeNormal, "HostPreflightRecovered", "Host preflight %q passes again on node %s", title, nodeName)
+		}
+	}
+
+	data, err := json.Marshal(titles)
+	if err != nil {
+		return fmt.Errorf("marshal drifted host preflights: %w", err)
+	}
+	patch := client.MergeFrom(recheck.DeepCopy())
+	if recheck.Annotations == nil {
+		recheck.Annotations = map[string]string{}
+	}
+	recheck.Annotations[evaluatedTimestampAnnotation] = updated
+	recheck.Annotations[driftAnnotation] = string(data)
+	if err := r.Patch(ctx, recheck, patch); err != nil {
+		return fmt.Errorf("failed to patch configmap: %w", err)
+	}
+	return nil
+}
+
+// reportHostPreflightRecheckError records an event and marks the drift as unknown when the host
+// preflights could not be rechecked.
+func (r *InstallationReconciler) reportHostPreflightRecheckError(in *ecv1beta1.Installation, err error) {
+	r.Recorder.Eventf(in, corev1.EventTypeWarning, "HostPreflightRecheckFailed", "Failed to recheck host preflights: %v", err)
+	in.Status.SetCondition(metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeHostPreflightsDrift,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "RecheckFailed",
+		Message: fmt.Sprintf("Unable to recheck host preflights: %v", err),
+	})
+}
+
+// hostPreflightsDriftCondition returns the HostPreflightsDrift condition for the drifted checks
+// of every node.
+func hostPreflightsDriftCondition(drifted map[string][]string) metav1.Condition {
+	if len(drifted) == 0 {
+		return metav1.Condition{
+			Type:    ecv1beta1.ConditionTypeHostPreflightsDrift,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoDrift",
+			Message: "Host preflights still pass on every node",
+		}
+	}
+
+	nodes := []string{}
+	for node := range drifted {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	msgs := []string{}
+	for _, node := range nodes {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", node, strings.Join(drifted[node], ", ")))
+	}
+	return metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeHostPreflightsDrift,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DriftDetected",
+		Message: fmt.Sprintf("Host preflights no longer pass on %d node(s): %s", len(nodes), strings.Join(msgs, "; ")),
+	}
+}
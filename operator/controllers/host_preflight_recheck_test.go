@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newHostPreflightResultsConfigMap(name, label, node, results, updated string) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   ecNamespace,
+			Labels:      map[string]string{label: node},
+			Annotations: map[string]string{updateTimestampAnnotation: updated},
+		},
+		Data: map[string]string{},
+	}
+	if results != "" {
+		cm.Data["results.json"] = results
+	}
+	return cm
+}
+
+func TestInstallationReconciler_constructHostPreflightRecheckJob(t *testing.T) {
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-name"},
+		Spec: ecv1beta1.InstallationSpec{
+			RuntimeConfig: &ecv1beta1.RuntimeConfigSpec{
+				DataDir: "/data",
+				Proxy:   &ecv1beta1.ProxySpec{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128", NoProxy: "10.0.0.0/8"},
+			},
+		},
+	}
+	rc := runtimeconfig.New(in.Spec.RuntimeConfig)
+
+	job := constructHostPreflightRecheckJob(rc, in, "my-node")
+
+	assert.Equal(t, "host-preflight-recheck-my-node", job.Name)
+	assert.Equal(t, "my-node", job.Spec.Template.Spec.NodeName)
+	assert.True(t, job.Spec.Template.Spec.HostNetwork)
+	require.Len(t, job.Spec.Template.Spec.Volumes, 3)
+	assert.Equal(t, "/data", job.Spec.Template.Spec.Volumes[0].HostPath.Path)
+	assert.Equal(t, "/", job.Spec.Template.Spec.Volumes[2].HostPath.Path)
+
+	env := map[string]string{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "my-node", env["EC_NODE_NAME"])
+	assert.Equal(t, "/data", env["EC_DATA_DIR"])
+	assert.Equal(t, "my-node-host-preflight-recheck", env["HSPF_CM_NAME"])
+	assert.Equal(t, "http://proxy:3128", env["HTTPS_PROXY"])
+	assert.Equal(t, "10.0.0.0/8", env["NO_PROXY"])
+
+	// the template is not modified
+	assert.Empty(t, hostPreflightRecheckJob.Spec.Template.Spec.NodeName)
+}
+
+func Test_hostPreflightRecheckDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		recheck *corev1.ConfigMap
+		joined  time.Time
+		want    bool
+	}{
+		{
+			name:   "recently joined node is not rechecked",
+			joined: now.Add(-time.Hour),
+			want:   false,
+		},
+		{
+			name:   "node joined an interval ago is rechecked",
+			joined: now.Add(-hostPreflightRecheckInterval),
+			want:   true,
+		},
+		{
+			name:    "recent recheck",
+			recheck: newHostPreflightResultsConfigMap("cm", hostPreflightRecheckLabel, "node1", "", now.Add(-time.Hour).Format(time.RFC3339)),
+			joined:  now.Add(-30 * 24 * time.Hour),
+			want:    false,
+		},
+		{
+			name:    "old recheck",
+			recheck: newHostPreflightResultsConfigMap("cm", hostPreflightRecheckLabel, "node1", "", now.Add(-7*time.Hour).Format(time.RFC3339)),
+			joined:  now.Add(-30 * 24 * time.Hour),
+			want:    true,
+		},
+		{
+			name:    "recheck without timestamp",
+			recheck: newHostPreflightResultsConfigMap("cm", hostPreflightRecheckLabel, "node1", "", ""),
+			joined:  now,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, hostPreflightRecheckDue(tt.recheck, tt.joined, now))
+		})
+	}
+}
+
+func Test_hostPreflightsDrift(t *testing.T) {
+	baseline := newHostPreflightResultsConfigMap("base", hostPreflightResultLabel, "node1",
+		`{"pass":[{"title":"IP forwarding"},{"title":"System Clock"}],"warn":[{"title":"Memory"}]}`, "")
+
+	tests := []struct {
+		name     string
+		baseline *corev1.ConfigMap
+		recheck  *corev1.ConfigMap
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "checks that passed before and now fail drifted",
+			baseline: baseline,
+			recheck: newHostPreflightResultsConfigMap("re", hostPreflightRecheckLabel, "node1",
+				`{"pass":[{"title":"System Clock"}],"warn":[{"title":"Memory"}],"fail":[{"title":"IP forwarding"}]}`, ""),
+			want: []string{"IP forwarding"},
+		},
+		{
+			name:     "no drift",
+			baseline: baseline,
+			recheck: newHostPreflightResultsConfigMap("re", hostPreflightRecheckLabel, "node1",
+				`{"pass":[{"title":"IP forwarding"},{"title":"System Clock"}],"warn":[{"title":"Memory"}]}`, ""),
+			want: []string{},
+		},
+		{
+			name: "without a baseline every failure drifted",
+			recheck: newHostPreflightResultsConfigMap("re", hostPreflightRecheckLabel, "node1",
+				`{"warn":[{"title":"System Clock"}],"fail":[{"title":"IP forwarding"}]}`, ""),
+			want: []string{"IP forwarding", "System Clock"},
+		},
+		{
+			name:     "node without host preflights",
+			baseline: baseline,
+			recheck:  newHostPreflightResultsConfigMap("re", hostPreflightRecheckLabel, "node1", "", ""),
+			want:     nil,
+		},
+		{
+			name:    "invalid results",
+			recheck: newHostPreflightResultsConfigMap("re", hostPreflightRecheckLabel, "node1", "not json", ""),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift, err := hostPreflightsDrift(tt.baseline, tt.recheck)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, drift)
+				return
+			}
+			titles := []string{}
+			for _, record := range drift {
+				titles = append(titles, record.Title)
+			}
+			assert.Equal(t, tt.want, titles)
+		})
+	}
+}
+
+func TestInstallationReconciler_ReconcileHostPreflightRechecks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-name"},
+		Spec: ecv1beta1.InstallationSpec{
+			RuntimeConfig: &ecv1beta1.RuntimeConfigSpec{DataDir: "/var/lib/embedded-cluster"},
+		},
+	}
+	node1 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))}}
+	node2 := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2", CreationTimestamp: metav1.NewTime(now.Add(-24 * time.Hour))}}
+	baseline := newHostPreflightResultsConfigMap("node1-host-preflight-results", hostPreflightResultLabel, "node1",
+		`{"pass":[{"title":"IP forwarding"}]}`, now.Add(-24*time.Hour).Format(time.RFC3339))
+	updated := now.Add(-time.Hour).Format(time.RFC3339)
+	recheck := newHostPreflightResultsConfigMap("node1-host-preflight-recheck", hostPreflightRecheckLabel, "node1",
+		`{"fail":[{"title":"IP forwarding","message":"IP forwarding is disabled"}]}`, updated)
+
+	kcli := clientfake.NewClientBuilder().WithObjects(node1, node2, baseline, recheck).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &InstallationReconciler{
+		Client:        kcli,
+		Recorder:      recorder,
+		RuntimeConfig: runtimeconfig.New(in.Spec.RuntimeConfig),
+	}
+
+	err := r.ReconcileHostPreflightRechecks(ctx, in)
+	require.NoError(t, err)
+
+	// node2 was never rechecked, node1 was rechecked an hour ago
+	var jobs batchv1.JobList
+	require.NoError(t, kcli.List(ctx, &jobs, client.InNamespace(ecNamespace)))
+	require.Len(t, jobs.Items, 1)
+	assert.Equal(t, "host-preflight-recheck-node2", jobs.Items[0].Name)
+
+	cond := meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostPreflightsDrift)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Contains(t, cond.Message, "node1: IP forwarding")
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, `Host preflight "IP forwarding" no longer passes on node node1`)
+
+	var got corev1.ConfigMap
+	require.NoError(t, kcli.Get(ctx, client.ObjectKeyFromObject(recheck), &got))
+	assert.Equal(t, updated, got.Annotations[evaluatedTimestampAnnotation])
+	assert.Equal(t, `["IP forwarding"]`, got.Annotations[driftAnnotation])
+
+	// the same recheck is not evaluated twice
+	err = r.ReconcileHostPreflightRechecks(ctx, in)
+	require.NoError(t, err)
+	assert.Empty(t, recorder.Events)
+
+	// the check passes again on the next recheck
+	updated = now.Format(time.RFC3339)
+	got.Annotations[updateTimestampAnnotation] = updated
+	got.Data["results.json"] = `{"pass":[{"title":"IP forwarding"}]}`
+	require.NoError(t, kcli.Update(ctx, &got))
+
+	err = r.ReconcileHostPreflightRechecks(ctx, in)
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, `Host preflight "IP forwarding" passes again on node node1`)
+
+	cond = meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostPreflightsDrift)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+}
+
+func TestInstallationReconciler_reportHostPreflightRecheckError(t *testing.T) {
+	in := &ecv1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "install-name"}}
+	in.Status.SetCondition(hostPreflightsDriftCondition(map[string][]string{"node1": {"IP forwarding"}}))
+
+	recorder := record.NewFakeRecorder(10)
+	r := &InstallationReconciler{Recorder: recorder}
+
+	r.reportHostPreflightRecheckError(in, errors.New("failed to list nodes: connection refused"))
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "Warning HostPreflightRecheckFailed Failed to recheck host preflights: failed to list nodes: connection refused")
+
+	cond := meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostPreflightsDrift)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionUnknown, cond.Status)
+	assert.Equal(t, "RecheckFailed", cond.Reason)
+}
@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// hostProxyRequeueAfter is how often the installation is reconciled while the proxy is rolled
+// out to the nodes. The jobs are not watched.
+var hostProxyRequeueAfter = 10 * time.Second
+
+// hostProxySettleTime is how long a node must stay ready after its job succeeded before the
+// next node is configured. The services are restarted in the background once the job is done.
+var hostProxySettleTime = 30 * time.Second
+
+const (
+	hostProxyJobPrefix = "host-proxy-"
+
+	// hostProxyLabel labels the jobs with the hash of the proxy they configure.
+	hostProxyLabel = "embedded-cluster/host-proxy"
+	// hostProxyAnnotation is set by the operator on the nodes to the hash of the proxy
+	// configured on the host.
+	hostProxyAnnotation = "embedded-cluster/host-proxy"
+)
+
+// hostProxyScript writes the proxy drop-ins of the k0s and the local artifact mirror services,
+// or removes them when the content is empty, and restarts the services whose drop-in changed.
+// k0s is restarted without blocking as it runs the pod of the job.
+const hostProxyScript = `apply() {
+	if [ -z "$2" ]; then
+		[ -f "/host$1" ] || return 1
+		rm -f "/host$1"
+		return 0
+	fi
+	if [ -f "/host$1" ] && printf '%s' "$2" | cmp -s - "/host$1"; then
+		return 1
+	fi
+	mkdir -p "$(dirname "/host$1")"
+	printf '%s' "$2" > "/host$1"
+}
+restart=""
+if apply "$K0S_PROXY_FILE" "$PROXY_CONTENT"; then restart="$restart $K0S_SERVICE"; fi
+if apply "$LAM_PROXY_FILE" "$PROXY_CONTENT"; then restart="$restart local-artifact-mirror"; fi
+if [ -z "$restart" ]; then
+	echo "proxy already configured"
+	exit 0
+fi
+chroot /host systemctl daemon-reload
+echo "restarting$restart"
+chroot /host systemctl restart --no-block $restart
+`
+
+// hostProxyJob is a job we create on the nodes, one at a time, when the proxy of the cluster
+// changes. During a reconcile cycle we will populate the node name, any env variables and labels.
+var hostProxyJob = &batchv1.Job{
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: ecNamespace,
+	},
+	Spec: batchv1.JobSpec{
+		BackoffLimit:            ptr.To(int32(2)),
+		TTLSecondsAfterFinished: ptr.To(int32(1 * 60)), // we don't want to keep the job around. Delete it shortly after it finishes.
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "embedded-cluster-operator",
+				HostNetwork:        true,
+				HostPID:            true,
+				Tolerations: []corev1.Toleration{
+					{Operator: corev1.TolerationOpExists},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: "host-root",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: "/",
+								Type: ptr.To(corev1.HostPathDirectory),
+							},
+						},
+					},
+				},
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{
+					{
+						Name:    "host-proxy",
+						Image:   "busybox:latest",
+						Command: []string{"/bin/sh", "-e", "-c", hostProxyScript},
+						SecurityContext: &corev1.SecurityContext{
+							Privileged: ptr.To(true),
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "host-root",
+								MountPath: "/host",
+								ReadOnly:  false,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// ReconcileHostProxy rolls the proxy of the installation out to the systemd drop-ins of the k0s
+// and the local artifact mirror services of the nodes, one node at a time, controllers first.
+// The hash of the proxy configured on a node is kept in an annotation of the node. A node is
+// configured once its job succeeded and it stayed ready for hostProxySettleTime. The
+// HostProxyConfigured condition reports the progress. It returns true while the rollout is in
+// progress. Installation is not updated remotely, the caller must save the object after the call.
+func (r *InstallationReconciler) ReconcileHostProxy(ctx context.Context, in *ecv1beta1.Installation) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+
+	// upgrades restart k0s on their own, the proxy is rolled out once they are done.
+	if in.Status.State != ecv1beta1.InstallationStateInstalled {
+		return false, nil
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return false, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	sortHostProxyNodes(nodes.Items)
+
+	proxy := r.RuntimeConfig.ProxySpec()
+	hash := hostProxyHash(proxy)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Annotations[hostProxyAnnotation] == hash {
+			continue
+		}
+		progress := fmt.Sprintf("%d/%d nodes configured", i, len(nodes.Items))
+
+		var job batchv1.Job
+		key := types.NamespacedName{Namespace: ecNamespace, Name: util.NameWithLengthLimit(hostProxyJobPrefix, node.Name)}
+		if err := r.Get(ctx, key, &job); err != nil && !k8serrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to get host proxy job for node %s: %w", node.Name, err)
+		} else if k8serrors.IsNotFound(err) || job.Labels[hostProxyLabel] != hash {
+			if err := r.createHostProxyJob(ctx, in, node, hash); err != nil {
+				return false, fmt.Errorf("failed to create host proxy job for node %s: %w", node.Name, err)
+			}
+			in.Status.SetCondition(hostProxyCondition(in, metav1.ConditionFalse, "Configuring", fmt.Sprintf("Configuring the proxy of node %s (%s)", node.Name, progress)))
+			return true, nil
+		}
+
+		if isJobFailed(&job) {
+			// the job is deleted after its ttl and created again on the next reconcile.
+			in.Status.SetCondition(hostProxyCondition(in, metav1.ConditionFalse, "Failed", fmt.Sprintf("Failed to configure the proxy of node %s (%s), see the logs of job %s", node.Name, progress, job.Name)))
+			return true, nil
+		}
+		if job.Status.Succeeded == 0 || job.Status.CompletionTime == nil || time.Since(job.Status.CompletionTime.Time) < hostProxySettleTime || !isNodeReady(node) {
+			in.Status.SetCondition(hostProxyCondition(in, metav1.ConditionFalse, "Configuring", fmt.Sprintf("Configuring the proxy of node %s (%s)", node.Name, progress)))
+			return true, nil
+		}
+
+		patch := client.MergeFrom(node.DeepCopy())
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[hostProxyAnnotation] = hash
+		if err := r.Patch(ctx, node, patch); err != nil {
+			return false, fmt.Errorf("failed to annotate node %s: %w", node.Name, err)
+		}
+		log.Info("Proxy configured on node", "node", node.Name, "installation", in.Name)
+	}
+
+	in.Status.SetCondition(hostProxyCondition(in, metav1.ConditionTrue, "Configured", "The proxy is configured on every node"))
+	return false, nil
+}
+
+// createHostProxyJob creates the job that configures the proxy on the node, replacing the job
+// of a previous proxy if it is still around.
+func (r *InstallationReconciler) createHostProxyJob(ctx context.Context, in *ecv1beta1.Installation, node *corev1.Node, hash string) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	job := constructHostProxyJob(in, node, r.RuntimeConfig.ProxySpec(), hash)
+
+	// overrides the job image if the environment says so.
+	if img := os.Getenv("EMBEDDEDCLUSTER_UTILS_IMAGE"); img != "" {
+		job.Spec.Template.Spec.Containers[0].Image = img
+	}
+
+	stale := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: job.Name, Namespace: job.Namespace}}
+	if err := r.Delete(ctx, stale, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete previous job: %w", err)
+	}
+	if err := r.Create(ctx, job); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create job: %w", err)
+		}
+		return nil
+	}
+	log.Info("Host proxy job for node created", "node", node.Name, "installation", in.Name)
+	return nil
+}
+
+func constructHostProxyJob(in *ecv1beta1.Installation, node *corev1.Node, proxy *ecv1beta1.ProxySpec, hash string) *batchv1.Job {
+	labels := map[string]string{
+		"embedded-cluster/node-name":    node.Name,
+		"embedded-cluster/installation": in.Name,
+		hostProxyLabel:                  hash,
+	}
+
+	job := hostProxyJob.DeepCopy()
+	job.Name = util.NameWithLengthLimit(hostProxyJobPrefix, node.Name)
+
+	job.Spec.Template.Labels, job.Labels = labels, labels
+	job.Spec.Template.Spec.NodeName = node.Name
+
+	k0sService := "k0sworker.service"
+	if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
+		k0sService = "k0scontroller.service"
+	}
+	job.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{
+		{Name: "K0S_SERVICE", Value: k0sService},
+		{Name: "K0S_PROXY_FILE", Value: systemd.DropInFilePath(k0sService, systemd.ProxyDropInFileName)},
+		{Name: "LAM_PROXY_FILE", Value: systemd.DropInFilePath("local-artifact-mirror.service", systemd.ProxyDropInFileName)},
+		{Name: "PROXY_CONTENT", Value: hostProxyContent(proxy)},
+	}
+
+	return job
+}
+
+// hostProxyContent returns the content of the proxy drop-ins, empty if there is no proxy.
+func hostProxyContent(proxy *ecv1beta1.ProxySpec) string {
+	if proxy == nil {
+		return ""
+	}
+	return systemd.ProxyDropInContent(proxy.HTTPProxy, proxy.HTTPSProxy, proxy.NoProxy)
+}
+
+// hostProxyHash returns a short hash of the proxy drop-ins, used as a label value.
+func hostProxyHash(proxy *ecv1beta1.ProxySpec) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(hostProxyContent(proxy))))[:16]
+}
+
+// sortHostProxyNodes sorts the nodes by name, controllers first.
+func sortHostProxyNodes(nodes []corev1.Node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		_, ci := nodes[i].Labels["node-role.kubernetes.io/control-plane"]
+		_, cj := nodes[j].Labels["node-role.kubernetes.io/control-plane"]
+		if ci != cj {
+			return ci
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+}
+
+func hostProxyCondition(in *ecv1beta1.Installation, status metav1.ConditionStatus, reason, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               ecv1beta1.ConditionTypeHostProxyConfigured,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: in.Generation,
+	}
+}
+
+func isJobFailed(job *batchv1.Job) bool {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newHostProxyNode(name string, controller bool) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+		Status: corev1.NodeStatus{
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	if controller {
+		node.Labels["node-role.kubernetes.io/control-plane"] = "true"
+	}
+	return node
+}
+
+func Test_constructHostProxyJob(t *testing.T) {
+	in := &ecv1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "install-name"}}
+	proxy := &ecv1beta1.ProxySpec{HTTPProxy: "http://proxy:3128", HTTPSProxy: "http://proxy:3128", NoProxy: "10.0.0.0/8"}
+
+	job := constructHostProxyJob(in, newHostProxyNode("my-node", true), proxy, hostProxyHash(proxy))
+
+	assert.Equal(t, "host-proxy-my-node", job.Name)
+	assert.Equal(t, "my-node", job.Spec.Template.Spec.NodeName)
+	assert.Equal(t, hostProxyHash(proxy), job.Labels[hostProxyLabel])
+
+	env := map[string]string{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "k0scontroller.service", env["K0S_SERVICE"])
+	assert.Equal(t, "/etc/systemd/system/k0scontroller.service.d/http-proxy.conf", env["K0S_PROXY_FILE"])
+	assert.Equal(t, "/etc/systemd/system/local-artifact-mirror.service.d/http-proxy.conf", env["LAM_PROXY_FILE"])
+	assert.Contains(t, env["PROXY_CONTENT"], `Environment="NO_PROXY=10.0.0.0/8"`)
+
+	// unsetting the proxy removes the drop-ins of a worker
+	job = constructHostProxyJob(in, newHostProxyNode("my-node", false), nil, hostProxyHash(nil))
+	env = map[string]string{}
+	for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "k0sworker.service", env["K0S_SERVICE"])
+	assert.Empty(t, env["PROXY_CONTENT"])
+	assert.NotEqual(t, hostProxyHash(proxy), hostProxyHash(nil))
+
+	// the template is not modified
+	assert.Empty(t, hostProxyJob.Spec.Template.Spec.NodeName)
+}
+
+func TestInstallationReconciler_ReconcileHostProxy(t *testing.T) {
+	ctx := context.Background()
+
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-name", Generation: 3},
+		Spec: ecv1beta1.InstallationSpec{
+			RuntimeConfig: &ecv1beta1.RuntimeConfigSpec{
+				Proxy: &ecv1beta1.ProxySpec{HTTPProxy: "http://proxy:3128", NoProxy: "10.0.0.0/8"},
+			},
+		},
+		Status: ecv1beta1.InstallationStatus{State: ecv1beta1.InstallationStateInstalled},
+	}
+	kcli := clientfake.NewClientBuilder().WithObjects(
+		newHostProxyNode("worker", false),
+		newHostProxyNode("controller", true),
+	).Build()
+	r := &InstallationReconciler{
+		Client:        kcli,
+		RuntimeConfig: runtimeconfig.New(in.Spec.RuntimeConfig),
+	}
+
+	// the controller is configured first
+	rolling, err := r.ReconcileHostProxy(ctx, in)
+	require.NoError(t, err)
+	assert.True(t, rolling)
+
+	var jobs batchv1.JobList
+	require.NoError(t, kcli.List(ctx, &jobs, client.InNamespace(ecNamespace)))
+	require.Len(t, jobs.Items, 1)
+	assert.Equal(t, "host-proxy-controller", jobs.Items[0].Name)
+
+	cond := meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostProxyConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "Configuring the proxy of node controller (0/2 nodes configured)", cond.Message)
+	assert.Equal(t, int64(3), cond.ObservedGeneration)
+
+	// the node is not done until it stayed ready for a while after the job succeeded
+	job := jobs.Items[0]
+	job.Status.Succeeded = 1
+	job.Status.CompletionTime = ptrTime(time.Now())
+	require.NoError(t, kcli.Status().Update(ctx, &job))
+
+	rolling, err = r.ReconcileHostProxy(ctx, in)
+	require.NoError(t, err)
+	assert.True(t, rolling)
+
+	job.Status.CompletionTime = ptrTime(time.Now().Add(-time.Minute))
+	require.NoError(t, kcli.Status().Update(ctx, &job))
+
+	rolling, err = r.ReconcileHostProxy(ctx, in)
+	require.NoError(t, err)
+	assert.True(t, rolling)
+
+	var node corev1.Node
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Name: "controller"}, &node))
+	assert.Equal(t, hostProxyHash(in.Spec.RuntimeConfig.Proxy), node.Annotations[hostProxyAnnotation])
+
+	cond = meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostProxyConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, "Configuring the proxy of node worker (1/2 nodes configured)", cond.Message)
+
+	// a failed job is reported
+	var workerJob batchv1.Job
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: "host-proxy-worker"}, &workerJob))
+	workerJob.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	require.NoError(t, kcli.Status().Update(ctx, &workerJob))
+
+	rolling, err = r.ReconcileHostProxy(ctx, in)
+	require.NoError(t, err)
+	assert.True(t, rolling)
+	cond = meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostProxyConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, "Failed", cond.Reason)
+
+	// the rollout is done once every node is annotated
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Name: "worker"}, &node))
+	node.Annotations = map[string]string{hostProxyAnnotation: hostProxyHash(in.Spec.RuntimeConfig.Proxy)}
+	require.NoError(t, kcli.Update(ctx, &node))
+
+	rolling, err = r.ReconcileHostProxy(ctx, in)
+	require.NoError(t, err)
+	assert.False(t, rolling)
+	cond = meta.FindStatusCondition(in.Status.Conditions, ecv1beta1.ConditionTypeHostProxyConfigured)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+
+	// a new proxy replaces the job of the previous one
+	in.Spec.RuntimeConfig.Proxy = nil
+	r.RuntimeConfig.Set(in.Spec.RuntimeConfig)
+
+	rolling, err = r.ReconcileHostProxy(ctx, in)
+	require.NoError(t, err)
+	assert.True(t, rolling)
+	var controllerJob batchv1.Job
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Namespace: ecNamespace, Name: "host-proxy-controller"}, &controllerJob))
+	assert.Equal(t, hostProxyHash(nil), controllerJob.Labels[hostProxyLabel])
+}
+
+func ptrTime(t time.Time) *metav1.Time {
+	mt := metav1.NewTime(t)
+	return &mt
+}
@@ -32,12 +32,14 @@ import (
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/metrics"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/openebs"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/clusterpreflights"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/upgrade"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -245,6 +247,30 @@ func (r *InstallationReconciler) ReconcileNodeStatuses(ctx context.Context, in *
 	return batch, nil
 }
 
+// ReconcileNodeFacts copies the facts the nodes recorded on their Node to the Installation spec,
+// joining nodes receive them with the join command. Unlike the status, the spec is patched right
+// away and only when the facts changed.
+func (r *InstallationReconciler) ReconcileNodeFacts(ctx context.Context, in *ecv1beta1.Installation) error {
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	facts := clusterpreflights.NodeFactsFromNodes(nodes.Items)
+	if equality.Semantic.DeepEqual(facts, in.Spec.NodeFacts) {
+		return nil
+	}
+	patched := in.DeepCopy()
+	patched.Spec.NodeFacts = facts
+	if err := r.Patch(ctx, patched, client.MergeFrom(in)); err != nil {
+		return fmt.Errorf("failed to patch installation: %w", err)
+	}
+	// the status is saved later from in, only take the new spec and version
+	in.Spec.NodeFacts = facts
+	in.ResourceVersion = patched.ResourceVersion
+	in.Generation = patched.Generation
+	return nil
+}
+
 // ReportNodesChanges reports node changes to the metrics endpoint.
 func (r *InstallationReconciler) ReportNodesChanges(ctx context.Context, in *ecv1beta1.Installation, batch *NodeEventsBatch) {
 	for _, ev := range batch.NodesAdded {
@@ -371,7 +397,7 @@ func constructHostPreflightResultsJob(rc runtimeconfig.RuntimeConfig, in *ecv1be
 	return job
 }
 
-//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;patch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
@@ -430,11 +456,29 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, fmt.Errorf("failed to reconcile node status: %w", err)
 	}
 
+	// keep the facts of the nodes up to date for the nodes joining by hand
+	if err := r.ReconcileNodeFacts(ctx, in); err != nil {
+		log.Error(err, "Failed to reconcile node facts")
+	}
+
 	// Copy host preflight results to a configmap for each node
 	if err := r.CopyHostPreflightResultsFromNodes(ctx, in, events); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to copy host preflight results: %w", err)
 	}
 
+	// run the host preflights again on the nodes and flag the checks that no longer pass. the
+	// rechecks are informational, a failure is reported but does not stop the reconcile.
+	if err := r.ReconcileHostPreflightRechecks(ctx, in); err != nil {
+		log.Error(err, "Failed to reconcile host preflight rechecks")
+		r.reportHostPreflightRecheckError(in, err)
+	}
+
+	// roll the proxy out to the systemd services of the nodes, one node at a time
+	rollingProxy, err := r.ReconcileHostProxy(ctx, in)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile host proxy: %w", err)
+	}
+
 	// cleanup openebs stateful pods
 	if err := r.ReconcileOpenebs(ctx, in); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to reconcile openebs: %w", err)
@@ -467,6 +511,9 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	log.Info("Installation reconciliation ended")
+	if rollingProxy {
+		return ctrl.Result{RequeueAfter: hostProxyRequeueAfter}, nil
+	}
 	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
@@ -12,7 +12,9 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/clusterpreflights"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -732,3 +734,40 @@ func (m *mockClient) IsObjectNamespaced(obj runtime.Object) (bool, error) {
 func (m *mockClient) SubResource(subResource string) client.SubResourceClient {
 	return m.fake.SubResource(subResource)
 }
+
+func TestInstallationReconciler_ReconcileNodeFacts(t *testing.T) {
+	ctx := context.Background()
+
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "install-name"},
+		Spec:       ecv1beta1.InstallationSpec{ClusterID: "cluster-id"},
+	}
+	node1 := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node1",
+			Annotations: map[string]string{clusterpreflights.NodeFactsAnnotation: `{"name":"node1","cgroupVersion":2,"networkInterface":"eth0","mtu":1500}`},
+		},
+		Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KernelVersion: "6.1.0"}},
+	}
+	kcli := clientfake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in.DeepCopy(), node1).Build()
+	r := &InstallationReconciler{Client: kcli}
+
+	require.NoError(t, kcli.Get(ctx, client.ObjectKeyFromObject(in), in))
+	in.Status.State = ecv1beta1.InstallationStateInstalled
+
+	require.NoError(t, r.ReconcileNodeFacts(ctx, in))
+	want := []ecv1beta1.NodeFacts{{Name: "node1", KernelVersion: "6.1.0", CgroupVersion: 2, NetworkInterface: "eth0", MTU: 1500}}
+	assert.Equal(t, want, in.Spec.NodeFacts)
+	// the status in memory is kept to be saved by the caller
+	assert.Equal(t, ecv1beta1.InstallationStateInstalled, in.Status.State)
+
+	var stored ecv1beta1.Installation
+	require.NoError(t, kcli.Get(ctx, client.ObjectKeyFromObject(in), &stored))
+	assert.Equal(t, want, stored.Spec.NodeFacts)
+	assert.Equal(t, stored.ResourceVersion, in.ResourceVersion)
+
+	// nothing is patched when the facts did not change
+	require.NoError(t, r.ReconcileNodeFacts(ctx, in))
+	require.NoError(t, kcli.Get(ctx, client.ObjectKeyFromObject(in), &stored))
+	assert.Equal(t, in.ResourceVersion, stored.ResourceVersion)
+}
@@ -15,6 +15,7 @@ func MigrateCmd() *cobra.Command {
 
 	cmd.AddCommand(
 		MigrateRegistryDataCmd(),
+		MigrateRegistryDataToPVCCmd(),
 	)
 
 	return cmd
@@ -38,3 +39,22 @@ func MigrateRegistryDataCmd() *cobra.Command {
 
 	return cmd
 }
+
+func MigrateRegistryDataToPVCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "registry-data-to-pvc",
+		Short:        "Run the registry-data migration back to the registry PVC",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			err := migrate.RegistryDataToPVC(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to migrate registry data to pvc: %w", err)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,155 @@
+package cidrmigration
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ipPoolGVK is the kind of the Calico IP pools. k0s does not run the Calico API server, the
+// pools are managed through the custom resources Calico stores them in.
+var ipPoolGVK = schema.GroupVersionKind{
+	Group:   "crd.projectcalico.org",
+	Version: "v1",
+	Kind:    "IPPool",
+}
+
+// ipPoolCopiedFields are the settings of the current pool the new pool is created with.
+var ipPoolCopiedFields = []string{"blockSize", "ipipMode", "vxlanMode", "natOutgoing", "nodeSelector"}
+
+// IPPoolName returns the name of the pool created for the cidr.
+func IPPoolName(cidr string) string {
+	return "ipv4-ippool-" + strings.NewReplacer(".", "-", "/", "-").Replace(cidr)
+}
+
+// FindIPPool returns the pool of the cidr, nil if there is none.
+func FindIPPool(ctx context.Context, kcli client.Client, cidr string) (*unstructured.Unstructured, error) {
+	pools := &unstructured.UnstructuredList{}
+	pools.SetGroupVersionKind(ipPoolGVK.GroupVersion().WithKind(ipPoolGVK.Kind + "List"))
+	if err := kcli.List(ctx, pools); err != nil {
+		return nil, fmt.Errorf("list ip pools: %w", err)
+	}
+	for i := range pools.Items {
+		if poolCIDR, _, _ := unstructured.NestedString(pools.Items[i].Object, "spec", "cidr"); poolCIDR == cidr {
+			return &pools.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateIPPool creates an enabled pool for the cidr with the settings of the pool of the
+// current cidr. An existing pool for the cidr is enabled, so the migration can be run again.
+func CreateIPPool(ctx context.Context, kcli client.Client, currentCIDR, cidr string) error {
+	current, err := FindIPPool(ctx, kcli, currentCIDR)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("no ip pool found for the current pod cidr %s", currentCIDR)
+	}
+
+	existing, err := FindIPPool(ctx, kcli, cidr)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return setIPPoolDisabled(ctx, kcli, existing, false)
+	}
+
+	spec := map[string]interface{}{"cidr": cidr}
+	for _, field := range ipPoolCopiedFields {
+		if value, ok, _ := unstructured.NestedFieldCopy(current.Object, "spec", field); ok {
+			spec[field] = value
+		}
+	}
+	pool := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	pool.SetGroupVersionKind(ipPoolGVK)
+	pool.SetName(IPPoolName(cidr))
+	if err := kcli.Create(ctx, pool); err != nil {
+		return fmt.Errorf("create ip pool %s: %w", pool.GetName(), err)
+	}
+	return nil
+}
+
+// DisableIPPool stops Calico from allocating the addresses of the pool of the cidr to new pods.
+// The pods already using them keep working.
+func DisableIPPool(ctx context.Context, kcli client.Client, cidr string) error {
+	pool, err := FindIPPool(ctx, kcli, cidr)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return fmt.Errorf("no ip pool found for %s", cidr)
+	}
+	return setIPPoolDisabled(ctx, kcli, pool, true)
+}
+
+func setIPPoolDisabled(ctx context.Context, kcli client.Client, pool *unstructured.Unstructured, disabled bool) error {
+	if err := unstructured.SetNestedField(pool.Object, disabled, "spec", "disabled"); err != nil {
+		return fmt.Errorf("set disabled field: %w", err)
+	}
+	if err := kcli.Update(ctx, pool); err != nil {
+		return fmt.Errorf("update ip pool %s: %w", pool.GetName(), err)
+	}
+	return nil
+}
+
+// DeleteIPPool deletes the pool of the cidr unless pods still use its addresses. It returns the
+// pods using them, the pool is only deleted if there are none.
+func DeleteIPPool(ctx context.Context, kcli client.Client, cidr string) ([]corev1.Pod, error) {
+	pods, err := PodsInCIDR(ctx, kcli, "", cidr)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods) > 0 {
+		return pods, nil
+	}
+
+	pool, err := FindIPPool(ctx, kcli, cidr)
+	if err != nil || pool == nil {
+		return nil, err
+	}
+	if err := kcli.Delete(ctx, pool); err != nil && !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("delete ip pool %s: %w", pool.GetName(), err)
+	}
+	return nil, nil
+}
+
+// PodsInCIDR returns the pods of the pod network with an address in the cidr. Only the pods on
+// the node are returned unless nodeName is empty.
+func PodsInCIDR(ctx context.Context, kcli client.Client, nodeName, cidr string) ([]corev1.Pod, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("parse cidr: %w", err)
+	}
+
+	opts := []client.ListOption{}
+	if nodeName != "" {
+		opts = append(opts, client.MatchingFields{"spec.nodeName": nodeName})
+	}
+	var pods corev1.PodList
+	if err := kcli.List(ctx, &pods, opts...); err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+
+	result := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Spec.HostNetwork {
+			continue
+		}
+		for _, podIP := range pod.Status.PodIPs {
+			if addr, err := netip.ParseAddr(podIP.IP); err == nil && prefix.Contains(addr) {
+				result = append(result, pod)
+				break
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,141 @@
+package cidrmigration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testIPPool(name, cidr string) *unstructured.Unstructured {
+	pool := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"cidr":         cidr,
+			"blockSize":    int64(26),
+			"ipipMode":     "Never",
+			"vxlanMode":    "Always",
+			"natOutgoing":  true,
+			"nodeSelector": "all()",
+		},
+	}}
+	pool.SetGroupVersionKind(ipPoolGVK)
+	pool.SetName(name)
+	return pool
+}
+
+func testPod(name, nodeName, ip string, hostNetwork bool) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: nodeName, HostNetwork: hostNetwork},
+		Status:     corev1.PodStatus{PodIP: ip, PodIPs: []corev1.PodIP{{IP: ip}}},
+	}
+}
+
+func newTestClient(objects ...client.Object) client.Client {
+	return fake.NewClientBuilder().
+		WithObjects(objects...).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(obj client.Object) []string {
+			return []string{obj.(*corev1.Pod).Spec.NodeName}
+		}).
+		Build()
+}
+
+func TestCreateIPPool(t *testing.T) {
+	ctx := context.Background()
+	kcli := newTestClient(testIPPool("default-ipv4-ippool", "10.244.0.0/17"))
+
+	require.NoError(t, CreateIPPool(ctx, kcli, "10.244.0.0/17", "10.100.0.0/16"))
+
+	pool, err := FindIPPool(ctx, kcli, "10.100.0.0/16")
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+	assert.Equal(t, "ipv4-ippool-10-100-0-0-16", pool.GetName())
+	spec, _, _ := unstructured.NestedMap(pool.Object, "spec")
+	assert.Equal(t, map[string]interface{}{
+		"cidr":         "10.100.0.0/16",
+		"blockSize":    int64(26),
+		"ipipMode":     "Never",
+		"vxlanMode":    "Always",
+		"natOutgoing":  true,
+		"nodeSelector": "all()",
+	}, spec)
+
+	// running it again enables the existing pool
+	require.NoError(t, DisableIPPool(ctx, kcli, "10.100.0.0/16"))
+	require.NoError(t, CreateIPPool(ctx, kcli, "10.244.0.0/17", "10.100.0.0/16"))
+	pool, err = FindIPPool(ctx, kcli, "10.100.0.0/16")
+	require.NoError(t, err)
+	disabled, _, _ := unstructured.NestedBool(pool.Object, "spec", "disabled")
+	assert.False(t, disabled)
+}
+
+func TestCreateIPPoolNoCurrentPool(t *testing.T) {
+	kcli := newTestClient()
+
+	err := CreateIPPool(context.Background(), kcli, "10.244.0.0/17", "10.100.0.0/16")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no ip pool found for the current pod cidr 10.244.0.0/17")
+}
+
+func TestDisableIPPool(t *testing.T) {
+	ctx := context.Background()
+	kcli := newTestClient(testIPPool("default-ipv4-ippool", "10.244.0.0/17"))
+
+	require.NoError(t, DisableIPPool(ctx, kcli, "10.244.0.0/17"))
+
+	pool, err := FindIPPool(ctx, kcli, "10.244.0.0/17")
+	require.NoError(t, err)
+	disabled, _, _ := unstructured.NestedBool(pool.Object, "spec", "disabled")
+	assert.True(t, disabled)
+}
+
+func TestDeleteIPPool(t *testing.T) {
+	ctx := context.Background()
+	pod := testPod("app", "node-a", "10.244.0.5", false)
+	kcli := newTestClient(
+		testIPPool("default-ipv4-ippool", "10.244.0.0/17"),
+		pod,
+		testPod("host", "node-a", "10.244.0.6", true),
+		testPod("moved", "node-a", "10.100.0.5", false),
+	)
+
+	inUse, err := DeleteIPPool(ctx, kcli, "10.244.0.0/17")
+	require.NoError(t, err)
+	require.Len(t, inUse, 1)
+	assert.Equal(t, "app", inUse[0].Name)
+	pool, err := FindIPPool(ctx, kcli, "10.244.0.0/17")
+	require.NoError(t, err)
+	assert.NotNil(t, pool)
+
+	require.NoError(t, kcli.Delete(ctx, pod))
+	inUse, err = DeleteIPPool(ctx, kcli, "10.244.0.0/17")
+	require.NoError(t, err)
+	assert.Empty(t, inUse)
+	pool, err = FindIPPool(ctx, kcli, "10.244.0.0/17")
+	require.NoError(t, err)
+	assert.Nil(t, pool)
+}
+
+func TestPodsInCIDR(t *testing.T) {
+	kcli := newTestClient(
+		testPod("a1", "node-a", "10.244.0.5", false),
+		testPod("a2", "node-a", "10.100.0.5", false),
+		testPod("a3", "node-a", "192.168.1.1", true),
+		testPod("b1", "node-b", "10.244.1.5", false),
+	)
+
+	pods, err := PodsInCIDR(context.Background(), kcli, "node-a", "10.244.0.0/17")
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	assert.Equal(t, "a1", pods[0].Name)
+
+	pods, err = PodsInCIDR(context.Background(), kcli, "", "10.244.0.0/17")
+	require.NoError(t, err)
+	assert.Len(t, pods, 2)
+}
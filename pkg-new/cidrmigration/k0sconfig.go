@@ -0,0 +1,43 @@
+package cidrmigration
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+// UpdateK0sConfigFile sets the pod cidr and the node port range in the k0s configuration file
+// of a controller, leaving the values that are empty untouched. The file is edited without
+// going through the k0s types so the fields this binary does not know about are kept.
+func UpdateK0sConfigFile(path, podCIDR, nodePortRange string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read node config: %w", err)
+	}
+	cfg := map[string]interface{}{}
+	if err := k8syaml.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("unable to unmarshal node config: %w", err)
+	}
+
+	if podCIDR != "" {
+		if err := unstructured.SetNestedField(cfg, podCIDR, "spec", "network", "podCIDR"); err != nil {
+			return fmt.Errorf("unable to set pod cidr: %w", err)
+		}
+	}
+	if nodePortRange != "" {
+		if err := unstructured.SetNestedField(cfg, nodePortRange, "spec", "api", "extraArgs", "service-node-port-range"); err != nil {
+			return fmt.Errorf("unable to set node port range: %w", err)
+		}
+	}
+
+	data, err = k8syaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal node config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("unable to write node config file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cidrmigration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const testK0sConfig = `apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+metadata:
+  name: k0s
+spec:
+  api:
+    address: 192.168.1.1
+    extraArgs:
+      service-node-port-range: 80-32767
+  network:
+    podCIDR: 10.244.0.0/17
+    provider: calico
+    serviceCIDR: 10.244.128.0/17
+  someFutureField: kept
+`
+
+func TestUpdateK0sConfigFile(t *testing.T) {
+	tests := []struct {
+		name          string
+		podCIDR       string
+		nodePortRange string
+		want          string
+	}{
+		{
+			name:          "pod cidr and node port range",
+			podCIDR:       "10.100.0.0/16",
+			nodePortRange: "30000-32767",
+			want: `apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+metadata:
+  name: k0s
+spec:
+  api:
+    address: 192.168.1.1
+    extraArgs:
+      service-node-port-range: 30000-32767
+  network:
+    podCIDR: 10.100.0.0/16
+    provider: calico
+    serviceCIDR: 10.244.128.0/17
+  someFutureField: kept
+`,
+		},
+		{
+			name:          "node port range only",
+			nodePortRange: "30000-32767",
+			want: `apiVersion: k0s.k0sproject.io/v1beta1
+kind: ClusterConfig
+metadata:
+  name: k0s
+spec:
+  api:
+    address: 192.168.1.1
+    extraArgs:
+      service-node-port-range: 30000-32767
+  network:
+    podCIDR: 10.244.0.0/17
+    provider: calico
+    serviceCIDR: 10.244.128.0/17
+  someFutureField: kept
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "k0s.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(testK0sConfig), 0600))
+
+			require.NoError(t, UpdateK0sConfigFile(path, tt.podCIDR, tt.nodePortRange))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var got, want map[string]interface{}
+			require.NoError(t, k8syaml.Unmarshal(data, &got))
+			require.NoError(t, k8syaml.Unmarshal([]byte(tt.want), &want))
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestUpdateK0sConfigFileMissing(t *testing.T) {
+	err := UpdateK0sConfigFile(filepath.Join(t.TempDir(), "k0s.yaml"), "10.100.0.0/16", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read node config")
+}
@@ -0,0 +1,246 @@
+// Package cidrmigration changes the pod CIDR and the node port range of an existing cluster.
+package cidrmigration
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// controlPlaneLabel is the label of the controller nodes.
+const controlPlaneLabel = "node-role.kubernetes.io/control-plane"
+
+// Network is the network configuration of the cluster.
+type Network struct {
+	PodCIDR       string
+	ServiceCIDR   string
+	IPv6PodCIDR   string
+	NodePortRange string
+}
+
+// Options are the changes to make to the network of the cluster.
+type Options struct {
+	// PodCIDR is the new pod CIDR, the pod CIDR is not changed if empty.
+	PodCIDR string
+	// NodePortRange is the new node port range, the range is not changed if empty.
+	NodePortRange string
+}
+
+// Plan is the validated list of changes to make to the cluster and the order in which the nodes
+// are restarted.
+type Plan struct {
+	Current Network
+	// PodCIDR is the new pod CIDR, empty if unchanged.
+	PodCIDR string
+	// NodePortRange is the new node port range, empty if unchanged.
+	NodePortRange string
+	// Controllers are the names of the controller nodes, the node the migration runs on first.
+	Controllers []string
+	// Workers are the names of the worker nodes.
+	Workers []string
+}
+
+// NewPlan validates the options against the cluster and returns the plan to apply them.
+// localNode is the name of the controller the migration runs on.
+func NewPlan(ctx context.Context, kcli client.Client, current Network, localNode string, opts Options) (*Plan, error) {
+	plan := &Plan{Current: current}
+
+	var nodes corev1.NodeList
+	if err := kcli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	slices.SortFunc(nodes.Items, func(a, b corev1.Node) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, node := range nodes.Items {
+		if _, ok := node.Labels[controlPlaneLabel]; !ok {
+			plan.Workers = append(plan.Workers, node.Name)
+		} else if node.Name == localNode {
+			plan.Controllers = append([]string{node.Name}, plan.Controllers...)
+		} else {
+			plan.Controllers = append(plan.Controllers, node.Name)
+		}
+	}
+	if !slices.Contains(plan.Controllers, localNode) {
+		return nil, fmt.Errorf("node %s is not a controller of the cluster", localNode)
+	}
+
+	if opts.PodCIDR != "" && opts.PodCIDR != current.PodCIDR {
+		if err := validatePodCIDR(opts.PodCIDR, current, nodes.Items); err != nil {
+			return nil, err
+		}
+		plan.PodCIDR = opts.PodCIDR
+	}
+
+	if opts.NodePortRange != "" && opts.NodePortRange != current.NodePortRange {
+		if err := validateNodePortRange(ctx, kcli, opts.NodePortRange); err != nil {
+			return nil, err
+		}
+		plan.NodePortRange = opts.NodePortRange
+	}
+
+	if plan.PodCIDR == "" && plan.NodePortRange == "" {
+		return nil, fmt.Errorf("nothing to change, the pod cidr and node port range are already set to the requested values")
+	}
+	return plan, nil
+}
+
+// ChangesPodCIDR returns true if the plan moves the pods to a new CIDR.
+func (p *Plan) ChangesPodCIDR() bool {
+	return p.PodCIDR != ""
+}
+
+// ChangesNodePortRange returns true if the plan changes the node port range.
+func (p *Plan) ChangesNodePortRange() bool {
+	return p.NodePortRange != ""
+}
+
+// Steps describes the steps of the migration in the order they run.
+func (p *Plan) Steps() []string {
+	steps := []string{}
+	if p.ChangesPodCIDR() {
+		steps = append(steps, fmt.Sprintf("Create a Calico IP pool for %s and stop allocating addresses from %s", p.PodCIDR, p.Current.PodCIDR))
+	}
+	changes := []string{}
+	if p.ChangesPodCIDR() {
+		changes = append(changes, fmt.Sprintf("pod cidr %s", p.PodCIDR))
+	}
+	if p.ChangesNodePortRange() {
+		changes = append(changes, fmt.Sprintf("node port range %s", p.NodePortRange))
+	}
+	firewalld := ""
+	if p.ChangesPodCIDR() {
+		firewalld = fmt.Sprintf(", add %s to its firewalld zone", p.PodCIDR)
+	}
+	for _, name := range p.Controllers {
+		steps = append(steps, fmt.Sprintf("Set the %s in the k0s and runtime configuration of controller %s%s and restart it", strings.Join(changes, " and "), name, firewalld))
+	}
+	for _, name := range p.Workers {
+		steps = append(steps, fmt.Sprintf("Set the %s in the runtime configuration of worker %s%s", strings.Join(changes, " and "), name, firewalld))
+	}
+	steps = append(steps, "Update the installation with the new network configuration")
+	if p.ChangesPodCIDR() {
+		for _, name := range append(slices.Clone(p.Controllers), p.Workers...) {
+			steps = append(steps, fmt.Sprintf("Drain node %s so its pods restart with addresses in %s, then uncordon it", name, p.PodCIDR))
+		}
+		steps = append(steps, fmt.Sprintf("Delete the Calico IP pool for %s once no pod uses it", p.Current.PodCIDR))
+	}
+	return steps
+}
+
+// Downtime describes what is unavailable while the plan runs.
+func (p *Plan) Downtime() string {
+	msg := "The Kubernetes API is unavailable for up to a minute while each controller restarts, one at a time."
+	if p.ChangesPodCIDR() {
+		msg += " The pods on each node restart while the node drains, workloads without replicas on other nodes are unavailable until their pods are running again."
+		msg += fmt.Sprintf(" Pods in %s and %s reach each other during the migration.", p.Current.PodCIDR, p.PodCIDR)
+	}
+	if p.ChangesNodePortRange() {
+		msg += " Node ports outside the new range stop being accepted once the controllers restart."
+	}
+	return msg
+}
+
+// validatePodCIDR checks that the pods can be moved to the cidr. Only IPv4 single-stack clusters
+// are supported, the new range must not overlap with the service range nor the node addresses.
+func validatePodCIDR(cidr string, current Network, nodes []corev1.Node) error {
+	if netutils.IsIPv6CIDR(current.PodCIDR) || current.IPv6PodCIDR != "" {
+		return fmt.Errorf("the pod cidr can only be changed in ipv4 single-stack clusters")
+	}
+	if netutils.IsIPv6CIDR(cidr) {
+		return fmt.Errorf("pod cidr %s is not an ipv4 cidr", cidr)
+	}
+	if err := netutils.ValidateCIDR(cidr, 24, true); err != nil {
+		return fmt.Errorf("invalid pod cidr: %w", err)
+	}
+
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid pod cidr: %w", err)
+	}
+	for _, other := range []struct{ name, cidr string }{
+		{"current pod cidr", current.PodCIDR},
+		{"service cidr", current.ServiceCIDR},
+	} {
+		if other.cidr == "" {
+			continue
+		}
+		otherPrefix, err := netip.ParsePrefix(other.cidr)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", other.name, err)
+		}
+		if prefix.Overlaps(otherPrefix) {
+			return fmt.Errorf("pod cidr %s overlaps with the %s %s", cidr, other.name, other.cidr)
+		}
+	}
+
+	for _, node := range nodes {
+		for _, address := range node.Status.Addresses {
+			if address.Type != corev1.NodeInternalIP && address.Type != corev1.NodeExternalIP {
+				continue
+			}
+			addr, err := netip.ParseAddr(address.Address)
+			if err != nil {
+				continue
+			}
+			if prefix.Contains(addr) {
+				return fmt.Errorf("pod cidr %s contains the address %s of node %s", cidr, address.Address, node.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateNodePortRange checks that the range is valid and that the node ports of the existing
+// services are in it. Services keep their node ports and would be outside the range otherwise.
+func validateNodePortRange(ctx context.Context, kcli client.Client, nodePortRange string) error {
+	low, high, err := ParseNodePortRange(nodePortRange)
+	if err != nil {
+		return err
+	}
+
+	var services corev1.ServiceList
+	if err := kcli.List(ctx, &services); err != nil {
+		return fmt.Errorf("list services: %w", err)
+	}
+	outside := []string{}
+	for _, svc := range services.Items {
+		for _, port := range svc.Spec.Ports {
+			if port.NodePort != 0 && (port.NodePort < low || port.NodePort > high) {
+				outside = append(outside, fmt.Sprintf("%s/%s (%d)", svc.Namespace, svc.Name, port.NodePort))
+			}
+		}
+	}
+	if len(outside) > 0 {
+		return fmt.Errorf("the node ports of these services are outside of %s: %s", nodePortRange, strings.Join(outside, ", "))
+	}
+	return nil
+}
+
+// ParseNodePortRange returns the first and last ports of a node port range in the form
+// "30000-32767".
+func ParseNodePortRange(nodePortRange string) (int32, int32, error) {
+	parts := strings.Split(nodePortRange, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid node port range %q: expected the form <first>-<last>", nodePortRange)
+	}
+	low, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid node port range %q: %w", nodePortRange, err)
+	}
+	high, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid node port range %q: %w", nodePortRange, err)
+	}
+	if low == 0 || low >= high {
+		return 0, 0, fmt.Errorf("invalid node port range %q: the first port must be between 1 and the last port", nodePortRange)
+	}
+	return int32(low), int32(high), nil
+}
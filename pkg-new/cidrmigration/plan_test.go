@@ -0,0 +1,226 @@
+package cidrmigration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testNode(name, address string, controller bool) *corev1.Node {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: address}},
+		},
+	}
+	if controller {
+		node.Labels[controlPlaneLabel] = "true"
+	}
+	return node
+}
+
+func testNodePortService(name string, nodePort int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kotsadm"},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{{Port: 3000, NodePort: nodePort}},
+		},
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	current := Network{
+		PodCIDR:       "10.244.0.0/17",
+		ServiceCIDR:   "10.244.128.0/17",
+		NodePortRange: "80-32767",
+	}
+	objects := []client.Object{
+		testNode("node-c", "192.168.1.3", false),
+		testNode("node-b", "192.168.1.2", true),
+		testNode("node-a", "192.168.1.1", true),
+		testNode("node-d", "192.168.1.4", true),
+		testNodePortService("kotsadm", 30000),
+	}
+
+	tests := []struct {
+		name            string
+		current         Network
+		opts            Options
+		wantPodCIDR     string
+		wantPortRange   string
+		wantControllers []string
+		wantErr         string
+	}{
+		{
+			name:            "pod cidr and node port range",
+			current:         current,
+			opts:            Options{PodCIDR: "10.100.0.0/16", NodePortRange: "30000-32767"},
+			wantPodCIDR:     "10.100.0.0/16",
+			wantPortRange:   "30000-32767",
+			wantControllers: []string{"node-b", "node-a", "node-d"},
+		},
+		{
+			name:            "unchanged pod cidr is ignored",
+			current:         current,
+			opts:            Options{PodCIDR: "10.244.0.0/17", NodePortRange: "30000-32767"},
+			wantPortRange:   "30000-32767",
+			wantControllers: []string{"node-b", "node-a", "node-d"},
+		},
+		{
+			name:    "nothing to change",
+			current: current,
+			opts:    Options{PodCIDR: "10.244.0.0/17", NodePortRange: "80-32767"},
+			wantErr: "nothing to change",
+		},
+		{
+			name:    "overlaps with the current pod cidr",
+			current: current,
+			opts:    Options{PodCIDR: "10.244.0.0/16"},
+			wantErr: "overlaps with the current pod cidr",
+		},
+		{
+			name:    "overlaps with the service cidr",
+			current: current,
+			opts:    Options{PodCIDR: "10.244.192.0/18"},
+			wantErr: "overlaps with the service cidr 10.244.128.0/17",
+		},
+		{
+			name:    "contains a node address",
+			current: current,
+			opts:    Options{PodCIDR: "192.168.0.0/16"},
+			wantErr: "contains the address 192.168.1.1 of node node-a",
+		},
+		{
+			name:    "too small",
+			current: current,
+			opts:    Options{PodCIDR: "10.100.0.0/25"},
+			wantErr: "too small",
+		},
+		{
+			name:    "not private",
+			current: current,
+			opts:    Options{PodCIDR: "8.8.0.0/16"},
+			wantErr: "not in a private IP address range",
+		},
+		{
+			name:    "ipv6 pod cidr",
+			current: current,
+			opts:    Options{PodCIDR: "fd00:10::/48"},
+			wantErr: "not an ipv4 cidr",
+		},
+		{
+			name: "dual-stack cluster",
+			current: Network{
+				PodCIDR:       "10.244.0.0/17",
+				ServiceCIDR:   "10.244.128.0/17",
+				IPv6PodCIDR:   "fd00:10::/49",
+				NodePortRange: "80-32767",
+			},
+			opts:    Options{PodCIDR: "10.100.0.0/16"},
+			wantErr: "only be changed in ipv4 single-stack clusters",
+		},
+		{
+			name:    "node port outside of the range",
+			current: current,
+			opts:    Options{NodePortRange: "31000-32767"},
+			wantErr: "kotsadm/kotsadm (30000)",
+		},
+		{
+			name:    "invalid node port range",
+			current: current,
+			opts:    Options{NodePortRange: "32767-30000"},
+			wantErr: "invalid node port range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kcli := fake.NewClientBuilder().WithObjects(objects...).Build()
+
+			plan, err := NewPlan(context.Background(), kcli, tt.current, "node-b", tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPodCIDR, plan.PodCIDR)
+			assert.Equal(t, tt.wantPortRange, plan.NodePortRange)
+			assert.Equal(t, tt.wantControllers, plan.Controllers)
+			assert.Equal(t, []string{"node-c"}, plan.Workers)
+		})
+	}
+}
+
+func TestNewPlanNotController(t *testing.T) {
+	kcli := fake.NewClientBuilder().WithObjects(testNode("node-a", "192.168.1.1", true), testNode("node-b", "192.168.1.2", false)).Build()
+
+	_, err := NewPlan(context.Background(), kcli, Network{PodCIDR: "10.244.0.0/17"}, "node-b", Options{PodCIDR: "10.100.0.0/16"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "node node-b is not a controller")
+}
+
+func TestPlanSteps(t *testing.T) {
+	plan := &Plan{
+		Current:     Network{PodCIDR: "10.244.0.0/17"},
+		PodCIDR:     "10.100.0.0/16",
+		Controllers: []string{"node-a"},
+		Workers:     []string{"node-b"},
+	}
+	assert.Equal(t, []string{
+		"Create a Calico IP pool for 10.100.0.0/16 and stop allocating addresses from 10.244.0.0/17",
+		"Set the pod cidr 10.100.0.0/16 in the k0s and runtime configuration of controller node-a, add 10.100.0.0/16 to its firewalld zone and restart it",
+		"Set the pod cidr 10.100.0.0/16 in the runtime configuration of worker node-b, add 10.100.0.0/16 to its firewalld zone",
+		"Update the installation with the new network configuration",
+		"Drain node node-a so its pods restart with addresses in 10.100.0.0/16, then uncordon it",
+		"Drain node node-b so its pods restart with addresses in 10.100.0.0/16, then uncordon it",
+		"Delete the Calico IP pool for 10.244.0.0/17 once no pod uses it",
+	}, plan.Steps())
+
+	plan = &Plan{
+		Current:       Network{PodCIDR: "10.244.0.0/17"},
+		NodePortRange: "30000-32767",
+		Controllers:   []string{"node-a"},
+		Workers:       []string{"node-b"},
+	}
+	assert.Equal(t, []string{
+		"Set the node port range 30000-32767 in the k0s and runtime configuration of controller node-a and restart it",
+		"Set the node port range 30000-32767 in the runtime configuration of worker node-b",
+		"Update the installation with the new network configuration",
+	}, plan.Steps())
+}
+
+func TestParseNodePortRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantLow  int32
+		wantHigh int32
+		wantErr  bool
+	}{
+		{input: "80-32767", wantLow: 80, wantHigh: 32767},
+		{input: "30000-32767", wantLow: 30000, wantHigh: 32767},
+		{input: "30000", wantErr: true},
+		{input: "0-100", wantErr: true},
+		{input: "100-100", wantErr: true},
+		{input: "100-65536", wantErr: true},
+		{input: "a-b", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			low, high, err := ParseNodePortRange(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantLow, low)
+			assert.Equal(t, tt.wantHigh, high)
+		})
+	}
+}
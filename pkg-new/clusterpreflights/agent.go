@@ -0,0 +1,150 @@
+package clusterpreflights
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AgentOptions configures the agent run on the joining node.
+type AgentOptions struct {
+	// NetworkInterface is the interface the node will use for the cluster.
+	NetworkInterface string
+	// Dial holds the addresses of the nodes in the cluster the joining node must reach.
+	Dial []string
+	// ListenPorts holds the ports the nodes in the cluster must reach on the joining node.
+	ListenPorts []int
+	// DialTimeout is how long to wait for each connection.
+	DialTimeout time.Duration
+	// ListenTimeout is how long to wait for connections on the listen ports.
+	ListenTimeout time.Duration
+}
+
+// AgentReport is written by the agent once it is ready to accept connections.
+type AgentReport struct {
+	Facts     NodeFacts      `json:"facts"`
+	Dials     []DialResult   `json:"dials"`
+	Listeners []ListenResult `json:"listeners"`
+}
+
+// DialResult is the outcome of a TCP connection attempt.
+type DialResult struct {
+	Address string `json:"address"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ListenResult is the outcome of listening on a port of the joining node.
+type ListenResult struct {
+	Port  int    `json:"port"`
+	Error string `json:"error,omitempty"`
+}
+
+// RunAgent collects the facts of the joining node, checks it can reach the cluster and listens
+// on the ports the cluster must reach. The report is written to out as a single JSON line once
+// the listeners are up. The agent then accepts connections until every port received one or
+// the listen timeout expires.
+func RunAgent(ctx context.Context, opts AgentOptions, out io.Writer) error {
+	facts, err := CollectNodeFacts(opts.NetworkInterface)
+	if err != nil {
+		return fmt.Errorf("collect node facts: %w", err)
+	}
+
+	report := AgentReport{
+		Facts: facts,
+		Dials: DialAll(ctx, opts.Dial, opts.DialTimeout),
+	}
+
+	var wg sync.WaitGroup
+	listeners := []net.Listener{}
+	for _, port := range opts.ListenPorts {
+		l, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+		if err != nil {
+			report.Listeners = append(report.Listeners, ListenResult{Port: port, Error: err.Error()})
+			continue
+		}
+		report.Listeners = append(report.Listeners, ListenResult{Port: port})
+		listeners = append(listeners, l)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acceptOne(l)
+		}()
+	}
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+
+	// the time is taken last so the clock skew is not skewed by the time spent dialing
+	report.Facts.Time = time.Now().UTC()
+	if err := json.NewEncoder(out).Encode(report); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(opts.ListenTimeout):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// acceptOne waits for a single connection on the listener and closes it.
+func acceptOne(l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	conn.Close()
+}
+
+// DialAll opens a TCP connection to every address at once and reports the outcome for each of
+// them, in the same order.
+func DialAll(ctx context.Context, addresses []string, timeout time.Duration) []DialResult {
+	results := make([]DialResult, len(addresses))
+
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			results[i] = DialResult{Address: address}
+			dialer := net.Dialer{Timeout: timeout}
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			conn.Close()
+		}(i, address)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// ReadAgentReport decodes the report written by the agent.
+func ReadAgentReport(data []byte) (*AgentReport, error) {
+	var report AgentReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("decode agent report: %w", err)
+	}
+	if report.Facts.Hostname == "" {
+		return nil, errors.New("agent report has no node facts")
+	}
+	return &report, nil
+}
@@ -0,0 +1,263 @@
+package clusterpreflights
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+)
+
+const (
+	// DefaultMaxClockSkew is the largest clock difference allowed between the joining node and
+	// the cluster. etcd and certificate validation misbehave past a couple of seconds.
+	DefaultMaxClockSkew = 2 * time.Second
+)
+
+// ClusterNode is a node already in the cluster. The cgroup version, the network interface and
+// its MTU are known only for the nodes that recorded their facts, they are zero otherwise.
+type ClusterNode struct {
+	Name             string
+	KernelVersion    string
+	CgroupVersion    int
+	NetworkInterface string
+	MTU              int
+}
+
+// Input holds everything compared before a node joins.
+type Input struct {
+	// Joining is the report written by the agent on the joining node.
+	Joining AgentReport
+	// ReceivedAt is the local time the report was received at.
+	ReceivedAt time.Time
+	// Local holds the facts of the controller running the checks, if any.
+	Local NodeFacts
+	// Nodes holds the nodes already in the cluster.
+	Nodes []ClusterNode
+	// ReverseDials holds the connections from the controller to the joining node.
+	ReverseDials []DialResult
+	// MaxClockSkew is the largest clock difference allowed, DefaultMaxClockSkew if zero.
+	MaxClockSkew time.Duration
+}
+
+// Run compares the joining node with the cluster and returns the result of every check.
+func Run(in Input) *apitypes.PreflightsOutput {
+	out := &apitypes.PreflightsOutput{}
+
+	checkHostname(out, in)
+	checkClockSkew(out, in)
+	checkMTU(out, in)
+	checkKernelVersion(out, in)
+	checkCgroupVersion(out, in)
+	checkReachability(out, in)
+
+	return out
+}
+
+// Compare compares a node with the nodes in the cluster without connecting to them. It is used
+// by the nodes joined by hand, they only know the cluster through the join command. Nothing is
+// compared when the nodes are not known.
+func Compare(facts NodeFacts, nodes []ClusterNode) *apitypes.PreflightsOutput {
+	out := &apitypes.PreflightsOutput{}
+	if len(nodes) == 0 {
+		return out
+	}
+	in := Input{Joining: AgentReport{Facts: facts}, Nodes: nodes}
+
+	checkHostname(out, in)
+	checkMTU(out, in)
+	checkKernelVersion(out, in)
+	checkCgroupVersion(out, in)
+
+	return out
+}
+
+// peers returns the nodes the joining node is compared with. The facts of the controller running
+// the checks replace the ones it recorded, they are current.
+func (in Input) peers() []ClusterNode {
+	peers := []ClusterNode{}
+	for _, node := range in.Nodes {
+		if in.Local.Hostname != "" && node.Name == in.Local.Hostname {
+			continue
+		}
+		peers = append(peers, node)
+	}
+	if in.Local.Hostname != "" {
+		peers = append(peers, ClusterNode{
+			Name:             in.Local.Hostname,
+			KernelVersion:    in.Local.KernelVersion,
+			CgroupVersion:    in.Local.CgroupVersion,
+			NetworkInterface: in.Local.NetworkInterface,
+			MTU:              in.Local.MTU,
+		})
+	}
+	return peers
+}
+
+func pass(out *apitypes.PreflightsOutput, title, format string, args ...any) {
+	out.Pass = append(out.Pass, apitypes.PreflightsRecord{Title: title, Message: fmt.Sprintf(format, args...)})
+}
+
+func warn(out *apitypes.PreflightsOutput, title, format string, args ...any) {
+	out.Warn = append(out.Warn, apitypes.PreflightsRecord{Title: title, Message: fmt.Sprintf(format, args...)})
+}
+
+func fail(out *apitypes.PreflightsOutput, title, format string, args ...any) {
+	out.Fail = append(out.Fail, apitypes.PreflightsRecord{Title: title, Message: fmt.Sprintf(format, args...)})
+}
+
+func checkHostname(out *apitypes.PreflightsOutput, in Input) {
+	const title = "Unique Hostname"
+
+	hostname := in.Joining.Facts.Hostname
+	for _, node := range in.Nodes {
+		if node.Name == hostname {
+			fail(out, title, "A node named %s is already in the cluster. Every node must have a unique hostname.", hostname)
+			return
+		}
+	}
+	pass(out, title, "No other node is named %s.", hostname)
+}
+
+func checkClockSkew(out *apitypes.PreflightsOutput, in Input) {
+	const title = "Clock Synchronization"
+
+	maxSkew := in.MaxClockSkew
+	if maxSkew == 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+
+	skew := in.Joining.Facts.Time.Sub(in.ReceivedAt)
+	direction := "ahead of"
+	if skew < 0 {
+		skew, direction = -skew, "behind"
+	}
+	skew = skew.Round(time.Millisecond)
+
+	if skew > maxSkew {
+		fail(out, title, "The clock on %s is %s %s the clock on %s. Clocks must be within %s of each other. Synchronize the clocks with NTP.", in.Joining.Facts.Hostname, skew, direction, in.Local.Hostname, maxSkew)
+		return
+	}
+	pass(out, title, "The clock on %s is within %s of the clock on %s.", in.Joining.Facts.Hostname, maxSkew, in.Local.Hostname)
+}
+
+func checkMTU(out *apitypes.PreflightsOutput, in Input) {
+	const title = "Network Interface MTU"
+
+	joining := in.Joining.Facts
+	compared, others := 0, []string{}
+	for _, node := range in.peers() {
+		if node.MTU == 0 {
+			continue
+		}
+		compared++
+		if node.MTU != joining.MTU {
+			others = append(others, fmt.Sprintf("%d on %s (%s)", node.MTU, node.Name, node.NetworkInterface))
+		}
+	}
+	if compared == 0 {
+		return
+	}
+	if len(others) > 0 {
+		sort.Strings(others)
+		fail(out, title, "The MTU of %s on %s is %d but the cluster uses %s. A mismatch breaks the pod network for large packets.", joining.NetworkInterface, joining.Hostname, joining.MTU, strings.Join(others, ", "))
+		return
+	}
+	pass(out, title, "The MTU of %s on %s matches the cluster (%d).", joining.NetworkInterface, joining.Hostname, joining.MTU)
+}
+
+func checkKernelVersion(out *apitypes.PreflightsOutput, in Input) {
+	const title = "Kernel Version"
+
+	joining := in.Joining.Facts
+	others := []string{}
+	for _, node := range in.Nodes {
+		if node.KernelVersion != "" && node.KernelVersion != joining.KernelVersion {
+			others = append(others, fmt.Sprintf("%s on %s", node.KernelVersion, node.Name))
+		}
+	}
+	if len(others) > 0 {
+		sort.Strings(others)
+		warn(out, title, "%s runs kernel %s but the cluster runs %s. Nodes with different kernels can behave differently.", joining.Hostname, joining.KernelVersion, strings.Join(others, ", "))
+		return
+	}
+	pass(out, title, "%s runs the same kernel as the cluster (%s).", joining.Hostname, joining.KernelVersion)
+}
+
+func checkCgroupVersion(out *apitypes.PreflightsOutput, in Input) {
+	const title = "Cgroup Version"
+
+	joining := in.Joining.Facts
+	compared, others := 0, []string{}
+	for _, node := range in.peers() {
+		if node.CgroupVersion == 0 {
+			continue
+		}
+		compared++
+		if node.CgroupVersion != joining.CgroupVersion {
+			others = append(others, fmt.Sprintf("v%d on %s", node.CgroupVersion, node.Name))
+		}
+	}
+	if compared == 0 {
+		return
+	}
+	if len(others) > 0 {
+		sort.Strings(others)
+		warn(out, title, "%s uses cgroup v%d but the cluster uses %s. Resource limits are enforced differently on each version.", joining.Hostname, joining.CgroupVersion, strings.Join(others, ", "))
+		return
+	}
+	pass(out, title, "%s uses cgroup v%d, like the cluster.", joining.Hostname, joining.CgroupVersion)
+}
+
+func checkReachability(out *apitypes.PreflightsOutput, in Input) {
+	joining, local := in.Joining.Facts.Hostname, in.Local.Hostname
+
+	for _, dial := range in.Joining.Dials {
+		title := fmt.Sprintf("TCP Connection from %s to %s", joining, dial.Address)
+		if dial.Error != "" {
+			fail(out, title, "%s can't connect to %s: %s. Check the routes and firewalls between the nodes.", joining, dial.Address, dial.Error)
+			continue
+		}
+		pass(out, title, "%s connected to %s.", joining, dial.Address)
+	}
+
+	for _, listener := range in.Joining.Listeners {
+		if listener.Error != "" {
+			fail(out, fmt.Sprintf("Listen on Port %d", listener.Port), "%s can't listen on port %d: %s. The port must be free for the cluster to reach the node.", joining, listener.Port, listener.Error)
+		}
+	}
+
+	for _, dial := range in.ReverseDials {
+		title := fmt.Sprintf("TCP Connection from %s to %s", local, dial.Address)
+		if dial.Error != "" {
+			fail(out, title, "%s can't connect to %s: %s. Check the routes and firewalls between the nodes.", local, dial.Address, dial.Error)
+			continue
+		}
+		pass(out, title, "%s connected to %s.", local, dial.Address)
+	}
+}
+
+// ListenPorts returns the ports of the addresses the joining node must reach. The cluster must
+// reach the joining node on the same ports.
+func ListenPorts(addresses []string) ([]int, error) {
+	seen := map[int]bool{}
+	ports := []int{}
+	for _, address := range addresses {
+		_, p, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("parse address %s: %w", address, err)
+		}
+		port, err := net.LookupPort("tcp", p)
+		if err != nil {
+			return nil, fmt.Errorf("parse port of %s: %w", address, err)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports, nil
+}
@@ -0,0 +1,235 @@
+package clusterpreflights
+
+import (
+	"testing"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	local := NodeFacts{
+		Hostname:         "node1",
+		Time:             now,
+		KernelVersion:    "6.1.0",
+		CgroupVersion:    2,
+		NetworkInterface: "eth0",
+		MTU:              1500,
+	}
+	joining := func(fn func(*NodeFacts)) AgentReport {
+		facts := local
+		facts.Hostname = "node2"
+		if fn != nil {
+			fn(&facts)
+		}
+		return AgentReport{Facts: facts}
+	}
+	nodes := []ClusterNode{{Name: "node1", KernelVersion: "6.1.0"}}
+
+	tests := []struct {
+		name      string
+		in        Input
+		wantFail  []string
+		wantWarn  []string
+		wantPass  int
+		wantInMsg string
+	}{
+		{
+			name:     "matching node passes",
+			in:       Input{Joining: joining(nil), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantPass: 5,
+		},
+		{
+			name:      "duplicate hostname fails",
+			in:        Input{Joining: joining(func(f *NodeFacts) { f.Hostname = "node1" }), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantFail:  []string{"Unique Hostname"},
+			wantPass:  4,
+			wantInMsg: "A node named node1 is already in the cluster",
+		},
+		{
+			name:      "clock ahead fails",
+			in:        Input{Joining: joining(func(f *NodeFacts) { f.Time = now.Add(5 * time.Second) }), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantFail:  []string{"Clock Synchronization"},
+			wantPass:  4,
+			wantInMsg: "5s ahead of",
+		},
+		{
+			name:      "clock behind fails",
+			in:        Input{Joining: joining(func(f *NodeFacts) { f.Time = now.Add(-3 * time.Second) }), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantFail:  []string{"Clock Synchronization"},
+			wantPass:  4,
+			wantInMsg: "3s behind",
+		},
+		{
+			name:     "clock skew within custom limit passes",
+			in:       Input{Joining: joining(func(f *NodeFacts) { f.Time = now.Add(5 * time.Second) }), ReceivedAt: now, Local: local, Nodes: nodes, MaxClockSkew: 10 * time.Second},
+			wantPass: 5,
+		},
+		{
+			name:      "mtu mismatch fails",
+			in:        Input{Joining: joining(func(f *NodeFacts) { f.MTU = 9000 }), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantFail:  []string{"Network Interface MTU"},
+			wantPass:  4,
+			wantInMsg: "is 9000 but",
+		},
+		{
+			name: "mtu is compared with every node that recorded it",
+			in: Input{
+				Joining:    joining(nil),
+				ReceivedAt: now,
+				Local:      local,
+				Nodes: []ClusterNode{
+					{Name: "node1", KernelVersion: "6.1.0", MTU: 1500},
+					{Name: "node3", KernelVersion: "6.1.0", NetworkInterface: "ens5", MTU: 1450},
+					{Name: "node4", KernelVersion: "6.1.0"},
+				},
+			},
+			wantFail:  []string{"Network Interface MTU"},
+			wantPass:  4,
+			wantInMsg: "the cluster uses 1450 on node3 (ens5)",
+		},
+		{
+			name:      "kernel mismatch warns",
+			in:        Input{Joining: joining(func(f *NodeFacts) { f.KernelVersion = "5.15.0" }), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantWarn:  []string{"Kernel Version"},
+			wantPass:  4,
+			wantInMsg: "6.1.0 on node1",
+		},
+		{
+			name:      "cgroup mismatch warns",
+			in:        Input{Joining: joining(func(f *NodeFacts) { f.CgroupVersion = 1 }), ReceivedAt: now, Local: local, Nodes: nodes},
+			wantWarn:  []string{"Cgroup Version"},
+			wantPass:  4,
+			wantInMsg: "cgroup v1",
+		},
+		{
+			name: "unreachable ports fail in both directions",
+			in: Input{
+				Joining: func() AgentReport {
+					r := joining(nil)
+					r.Dials = []DialResult{
+						{Address: "10.0.0.1:6443"},
+						{Address: "10.0.0.1:2380", Error: "connection refused"},
+					}
+					r.Listeners = []ListenResult{{Port: 6443}, {Port: 2380, Error: "address already in use"}}
+					return r
+				}(),
+				ReceivedAt: now,
+				Local:      local,
+				Nodes:      nodes,
+				ReverseDials: []DialResult{
+					{Address: "10.0.0.2:6443", Error: "i/o timeout"},
+				},
+			},
+			wantFail: []string{
+				"TCP Connection from node2 to 10.0.0.1:2380",
+				"Listen on Port 2380",
+				"TCP Connection from node1 to 10.0.0.2:6443",
+			},
+			wantPass: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Run(tt.in)
+
+			titles := func(records []apitypes.PreflightsRecord) []string {
+				result := []string{}
+				for _, r := range records {
+					result = append(result, r.Title)
+				}
+				return result
+			}
+
+			assert.ElementsMatch(t, tt.wantFail, titles(out.Fail))
+			assert.ElementsMatch(t, tt.wantWarn, titles(out.Warn))
+			assert.Len(t, out.Pass, tt.wantPass)
+
+			if tt.wantInMsg != "" {
+				records := append(out.Fail, out.Warn...)
+				require.NotEmpty(t, records)
+				assert.Contains(t, records[0].Message, tt.wantInMsg)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	facts := NodeFacts{Hostname: "node3", KernelVersion: "6.1.0", CgroupVersion: 2, NetworkInterface: "eth0", MTU: 1500}
+
+	out := Compare(facts, nil)
+	assert.Empty(t, out.Pass)
+	assert.Empty(t, out.Warn)
+	assert.Empty(t, out.Fail)
+
+	out = Compare(facts, []ClusterNode{
+		{Name: "node1", KernelVersion: "6.1.0", CgroupVersion: 2, NetworkInterface: "eth0", MTU: 1500},
+		{Name: "node2", KernelVersion: "6.1.0", CgroupVersion: 1, NetworkInterface: "eth0", MTU: 9000},
+	})
+	require.Len(t, out.Fail, 1)
+	assert.Equal(t, "Network Interface MTU", out.Fail[0].Title)
+	assert.Contains(t, out.Fail[0].Message, "9000 on node2 (eth0)")
+	require.Len(t, out.Warn, 1)
+	assert.Equal(t, "Cgroup Version", out.Warn[0].Title)
+	assert.Len(t, out.Pass, 2)
+
+	// nodes that did not record their facts are compared on their name and kernel only
+	out = Compare(facts, []ClusterNode{{Name: "node3", KernelVersion: "6.1.0"}})
+	require.Len(t, out.Fail, 1)
+	assert.Equal(t, "Unique Hostname", out.Fail[0].Title)
+	assert.Len(t, out.Pass, 1)
+}
+
+func TestListenPorts(t *testing.T) {
+	tests := []struct {
+		name      string
+		addresses []string
+		want      []int
+		wantErr   bool
+	}{
+		{
+			name:      "unique sorted ports",
+			addresses: []string{"10.0.0.1:6443", "10.0.0.2:2380", "10.0.0.1:2380", "[fd00::1]:9443"},
+			want:      []int{2380, 6443, 9443},
+		},
+		{
+			name:      "no addresses",
+			addresses: nil,
+			want:      []int{},
+		},
+		{
+			name:      "missing port",
+			addresses: []string{"10.0.0.1"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListenPorts(tt.addresses)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadAgentReport(t *testing.T) {
+	report, err := ReadAgentReport([]byte(`{"facts":{"hostname":"node2","mtu":1500},"dials":[{"address":"10.0.0.1:6443"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "node2", report.Facts.Hostname)
+	assert.Equal(t, 1500, report.Facts.MTU)
+	assert.Len(t, report.Dials, 1)
+
+	_, err = ReadAgentReport([]byte(`{}`))
+	assert.Error(t, err)
+
+	_, err = ReadAgentReport([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package clusterpreflights
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+)
+
+// dateResolution is the resolution of the HTTP Date header.
+const dateResolution = time.Second
+
+// ClockOffset is the offset of the local clock from the clock of a server in the cluster,
+// measured when a node joins without going through a controller.
+type ClockOffset struct {
+	// Server is the address of the server the clock was compared with.
+	Server string
+	// Offset is positive when the local clock is ahead of the server's.
+	Offset time.Duration
+	// Uncertainty bounds the error of the offset. The Date header only has a one second
+	// resolution and the server may have stamped the response any time during the round trip.
+	Uncertainty time.Duration
+}
+
+// MeasureClockOffset estimates the offset of the local clock from the clock of the server at the
+// url from the Date header of its response. The status of the response does not matter.
+func MeasureClockOffset(ctx context.Context, client *http.Client, server, url string) (*ClockOffset, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s: %w", url, err)
+	}
+	received := time.Now()
+	resp.Body.Close()
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return nil, fmt.Errorf("parse date header: %w", err)
+	}
+
+	return clockOffset(server, sent, received, date), nil
+}
+
+// clockOffset compares the middle of the round trip with the middle of the second the server
+// stamped the response with.
+func clockOffset(server string, sent, received, date time.Time) *ClockOffset {
+	roundTrip := received.Sub(sent)
+	local := sent.Add(roundTrip / 2)
+	remote := date.Add(dateResolution / 2)
+	return &ClockOffset{
+		Server:      server,
+		Offset:      local.Sub(remote),
+		Uncertainty: roundTrip/2 + dateResolution/2,
+	}
+}
+
+// CheckClockOffset checks the offset of the local clock from the cluster. It only fails when the
+// clocks are certainly further apart than maxSkew, DefaultMaxClockSkew if zero.
+func CheckClockOffset(offset *ClockOffset, maxSkew time.Duration) *apitypes.PreflightsOutput {
+	const title = "Clock Synchronization"
+
+	out := &apitypes.PreflightsOutput{}
+	if maxSkew == 0 {
+		maxSkew = DefaultMaxClockSkew
+	}
+
+	skew := offset.Offset
+	direction := "ahead of"
+	if skew < 0 {
+		skew, direction = -skew, "behind"
+	}
+	skew = skew.Round(time.Millisecond)
+
+	if skew-offset.Uncertainty > maxSkew {
+		fail(out, title, "The clock on this host is %s %s the clock on %s. Clocks must be within %s of each other. Synchronize the clocks with NTP.", skew, direction, offset.Server, maxSkew)
+		return out
+	}
+	pass(out, title, "The clock on this host is within %s of the clock on %s.", maxSkew, offset.Server)
+	return out
+}
@@ -0,0 +1,77 @@
+package clusterpreflights
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClockOffset(t *testing.T) {
+	date := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// the response was stamped during a 200ms round trip ending 3s after the server's date
+	offset := clockOffset("10.0.0.1:30000", date.Add(2800*time.Millisecond), date.Add(3*time.Second), date)
+	assert.Equal(t, "10.0.0.1:30000", offset.Server)
+	assert.Equal(t, 2400*time.Millisecond, offset.Offset)
+	assert.Equal(t, 600*time.Millisecond, offset.Uncertainty)
+}
+
+func TestCheckClockOffset(t *testing.T) {
+	tests := []struct {
+		name      string
+		offset    ClockOffset
+		wantFail  bool
+		wantInMsg string
+	}{
+		{
+			name:   "in sync",
+			offset: ClockOffset{Server: "node1", Offset: 300 * time.Millisecond, Uncertainty: 600 * time.Millisecond},
+		},
+		{
+			name:   "skew within the uncertainty passes",
+			offset: ClockOffset{Server: "node1", Offset: 2400 * time.Millisecond, Uncertainty: 600 * time.Millisecond},
+		},
+		{
+			name:      "ahead fails",
+			offset:    ClockOffset{Server: "node1", Offset: 5 * time.Second, Uncertainty: 600 * time.Millisecond},
+			wantFail:  true,
+			wantInMsg: "5s ahead of the clock on node1",
+		},
+		{
+			name:      "behind fails",
+			offset:    ClockOffset{Server: "node1", Offset: -time.Minute, Uncertainty: 600 * time.Millisecond},
+			wantFail:  true,
+			wantInMsg: "1m0s behind the clock on node1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := CheckClockOffset(&tt.offset, 0)
+			if tt.wantFail {
+				require.Len(t, out.Fail, 1)
+				assert.Equal(t, "Clock Synchronization", out.Fail[0].Title)
+				assert.Contains(t, out.Fail[0].Message, tt.wantInMsg)
+				return
+			}
+			assert.Empty(t, out.Fail)
+			require.Len(t, out.Pass, 1)
+		})
+	}
+}
+
+func TestMeasureClockOffset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	offset, err := MeasureClockOffset(context.Background(), server.Client(), "node1", server.URL)
+	require.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), offset.Offset.Seconds(), 2)
+}
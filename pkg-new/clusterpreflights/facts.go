@@ -0,0 +1,73 @@
+// Package clusterpreflights compares a node about to join the cluster with the nodes already in
+// it. Host preflights check every host in isolation and can't catch nodes that are fine on their
+// own but don't match the rest of the cluster.
+package clusterpreflights
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	nodeutil "k8s.io/component-helpers/node/util"
+)
+
+const (
+	osReleasePath         = "/proc/sys/kernel/osrelease"
+	cgroupControllersPath = "/sys/fs/cgroup/cgroup.controllers"
+)
+
+// NodeFacts holds what is compared between the joining node and the nodes in the cluster.
+type NodeFacts struct {
+	Hostname         string    `json:"hostname"`
+	Time             time.Time `json:"time"`
+	KernelVersion    string    `json:"kernelVersion"`
+	CgroupVersion    int       `json:"cgroupVersion"`
+	NetworkInterface string    `json:"networkInterface"`
+	Address          string    `json:"address,omitempty"`
+	MTU              int       `json:"mtu"`
+}
+
+// CollectNodeFacts returns the facts of the host it runs on. The address and the MTU are the ones
+// of the network interface used by the cluster.
+func CollectNodeFacts(networkInterface string) (NodeFacts, error) {
+	hostname, err := nodeutil.GetHostname("")
+	if err != nil {
+		return NodeFacts{}, fmt.Errorf("get hostname: %w", err)
+	}
+
+	kernel, err := os.ReadFile(osReleasePath)
+	if err != nil {
+		return NodeFacts{}, fmt.Errorf("read kernel version: %w", err)
+	}
+
+	cgroupVersion := 1
+	if _, err := os.Stat(cgroupControllersPath); err == nil {
+		cgroupVersion = 2
+	}
+
+	iface, err := net.InterfaceByName(networkInterface)
+	if err != nil {
+		return NodeFacts{}, fmt.Errorf("get network interface %s: %w", networkInterface, err)
+	}
+
+	// the interface of an IPv6 only cluster has no IPv4 address
+	address, err := netutils.FirstValidAddress(networkInterface)
+	if err != nil {
+		if address, err = netutils.FirstValidIPv6Address(networkInterface); err != nil {
+			return NodeFacts{}, fmt.Errorf("get address of network interface %s: %w", networkInterface, err)
+		}
+	}
+
+	return NodeFacts{
+		Hostname:         hostname,
+		Time:             time.Now().UTC(),
+		KernelVersion:    strings.TrimSpace(string(kernel)),
+		CgroupVersion:    cgroupVersion,
+		NetworkInterface: networkInterface,
+		Address:          address,
+		MTU:              iface.MTU,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package clusterpreflights
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NodeFactsAnnotation holds the facts a node recorded on its Node when it was installed or
+// joined. The operator copies them to the installation for the nodes joined by hand.
+const NodeFactsAnnotation = "embedded-cluster/node-facts"
+
+// RecordNodeFacts records the facts of the node on its Node. Nodes can patch their own Node, so
+// workers record their facts too.
+func RecordNodeFacts(ctx context.Context, kcli client.Client, facts NodeFacts) error {
+	data, err := json.Marshal(ecv1beta1.NodeFacts{
+		Name:             facts.Hostname,
+		KernelVersion:    facts.KernelVersion,
+		CgroupVersion:    facts.CgroupVersion,
+		NetworkInterface: facts.NetworkInterface,
+		MTU:              facts.MTU,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal node facts: %w", err)
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]string{NodeFactsAnnotation: string(data)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal patch: %w", err)
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: facts.Hostname}}
+	if err := kcli.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("patch node %s: %w", facts.Hostname, err)
+	}
+	return nil
+}
+
+// NodeFactsFromNodes returns the facts recorded on the nodes, sorted by name. The nodes that did
+// not record their facts, like the ones joined by an older version, are returned with their
+// kernel version only.
+func NodeFactsFromNodes(nodes []corev1.Node) []ecv1beta1.NodeFacts {
+	result := []ecv1beta1.NodeFacts{}
+	for _, node := range nodes {
+		var facts ecv1beta1.NodeFacts
+		if data, ok := node.Annotations[NodeFactsAnnotation]; ok {
+			// a malformed annotation is ignored, the node is compared on its kernel only
+			_ = json.Unmarshal([]byte(data), &facts)
+		}
+		facts.Name = node.Name
+		if kernel := node.Status.NodeInfo.KernelVersion; kernel != "" {
+			facts.KernelVersion = kernel
+		}
+		result = append(result, facts)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// ClusterNodes returns the nodes to compare with from their recorded facts.
+func ClusterNodes(facts []ecv1beta1.NodeFacts) []ClusterNode {
+	result := []ClusterNode{}
+	for _, f := range facts {
+		result = append(result, ClusterNode{
+			Name:             f.Name,
+			KernelVersion:    f.KernelVersion,
+			CgroupVersion:    f.CgroupVersion,
+			NetworkInterface: f.NetworkInterface,
+			MTU:              f.MTU,
+		})
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+package clusterpreflights
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordNodeFacts(t *testing.T) {
+	ctx := context.Background()
+	kcli := clientfake.NewClientBuilder().WithObjects(
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", Annotations: map[string]string{"other": "kept"}}},
+	).Build()
+
+	facts := NodeFacts{Hostname: "node1", KernelVersion: "6.1.0", CgroupVersion: 2, NetworkInterface: "eth0", Address: "10.0.0.1", MTU: 1500}
+	require.NoError(t, RecordNodeFacts(ctx, kcli, facts))
+
+	var node corev1.Node
+	require.NoError(t, kcli.Get(ctx, client.ObjectKey{Name: "node1"}, &node))
+	assert.Equal(t, "kept", node.Annotations["other"])
+	assert.JSONEq(t, `{"name":"node1","kernelVersion":"6.1.0","cgroupVersion":2,"networkInterface":"eth0","mtu":1500}`, node.Annotations[NodeFactsAnnotation])
+
+	assert.Error(t, RecordNodeFacts(ctx, kcli, NodeFacts{Hostname: "missing"}))
+}
+
+func TestNodeFactsFromNodes(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node2"},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KernelVersion: "5.15.0"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "node1",
+				Annotations: map[string]string{NodeFactsAnnotation: `{"name":"node1","kernelVersion":"6.0.0","cgroupVersion":2,"networkInterface":"eth0","mtu":1500}`},
+			},
+			Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KernelVersion: "6.1.0"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node3", Annotations: map[string]string{NodeFactsAnnotation: `not json`}},
+		},
+	}
+
+	want := []ecv1beta1.NodeFacts{
+		{Name: "node1", KernelVersion: "6.1.0", CgroupVersion: 2, NetworkInterface: "eth0", MTU: 1500},
+		{Name: "node2", KernelVersion: "5.15.0"},
+		{Name: "node3"},
+	}
+	assert.Equal(t, want, NodeFactsFromNodes(nodes))
+
+	assert.Equal(t, []ClusterNode{
+		{Name: "node1", KernelVersion: "6.1.0", CgroupVersion: 2, NetworkInterface: "eth0", MTU: 1500},
+		{Name: "node2", KernelVersion: "5.15.0"},
+		{Name: "node3"},
+	}, ClusterNodes(want))
+}
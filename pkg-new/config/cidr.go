@@ -4,20 +4,52 @@ import (
 	"fmt"
 	"net"
 
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
 )
 
+// DefaultCIDRCandidates are the CIDR blocks tried in order when no CIDR is set, the first one
+// that does not overlap with the networks of the host is used.
+var DefaultCIDRCandidates = []string{
+	ecv1beta1.DefaultNetworkCIDR,
+	"10.245.0.0/16",
+	"10.246.0.0/16",
+	"10.247.0.0/16",
+	"10.248.0.0/16",
+	"172.28.0.0/16",
+	"172.29.0.0/16",
+}
+
 func ValidateCIDR(cidr string) error {
+	if netutils.IsIPv6CIDR(cidr) {
+		return ValidateIPv6CIDR(cidr)
+	}
 	if err := netutils.ValidateCIDR(cidr, 16, true); err != nil {
 		return fmt.Errorf("unable to validate cidr flag: %w", err)
 	}
 	return nil
 }
 
+// ValidateIPv6CIDR validates the IPv6 CIDR block the IPv6 pod and service CIDRs are split from.
+func ValidateIPv6CIDR(cidr string) error {
+	if err := netutils.ValidateIPv6CIDR(cidr); err != nil {
+		return fmt.Errorf("unable to validate ipv6 cidr: %w", err)
+	}
+	return nil
+}
+
 type CIDRConfig struct {
 	PodCIDR     string
 	ServiceCIDR string
 	GlobalCIDR  *string
+	// IPv6PodCIDR and IPv6ServiceCIDR are set for dual-stack clusters.
+	IPv6PodCIDR     string
+	IPv6ServiceCIDR string
+}
+
+// IPFamily returns the IP family of the cluster network.
+func (c *CIDRConfig) IPFamily() netutils.IPFamily {
+	return netutils.ClusterIPFamily(c.PodCIDR, c.IPv6PodCIDR)
 }
 
 // SplitCIDR takes a CIDR string and splits it into pod and service CIDRs
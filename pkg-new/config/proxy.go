@@ -15,6 +15,7 @@ import (
 // NetworkLookup defines the interface for network lookups
 type NetworkLookup interface {
 	FirstValidIPNet(networkInterface string) (*net.IPNet, error)
+	FirstValidIPv6Net(networkInterface string) (*net.IPNet, error)
 }
 
 type defaultNetworkLookup struct{}
@@ -23,6 +24,10 @@ func (d *defaultNetworkLookup) FirstValidIPNet(networkInterface string) (*net.IP
 	return netutils.FirstValidIPNet(networkInterface)
 }
 
+func (d *defaultNetworkLookup) FirstValidIPv6Net(networkInterface string) (*net.IPNet, error) {
+	return netutils.FirstValidIPv6Net(networkInterface)
+}
+
 var defaultNetworkLookupImpl NetworkLookup = &defaultNetworkLookup{}
 
 func GetNetworkIPNet(networkInterface string, lookup NetworkLookup) (*net.IPNet, error) {
@@ -32,7 +37,11 @@ func GetNetworkIPNet(networkInterface string, lookup NetworkLookup) (*net.IPNet,
 	return lookup.FirstValidIPNet(networkInterface)
 }
 
-func GetProxySpec(httpProxy, httpsProxy, noProxy string, podCIDR string, serviceCIDR string, networkInterface string, lookup NetworkLookup) (*ecv1beta1.ProxySpec, error) {
+// GetProxySpec returns the proxy spec of the cluster, the no-proxy list covering the pod and service
+// CIDRs and the networks of the network and control plane interfaces of the node. The CIDRs of
+// both families of a dual-stack cluster are separated by a comma in podCIDR and serviceCIDR. An
+// empty controlPlaneInterface means the control plane uses the network interface.
+func GetProxySpec(httpProxy, httpsProxy, noProxy string, podCIDR string, serviceCIDR string, networkInterface string, controlPlaneInterface string, lookup NetworkLookup) (*ecv1beta1.ProxySpec, error) {
 	proxy := &ecv1beta1.ProxySpec{
 		HTTPProxy:       httpProxy,
 		HTTPSProxy:      httpsProxy,
@@ -41,8 +50,21 @@ func GetProxySpec(httpProxy, httpsProxy, noProxy string, podCIDR string, service
 
 	SetProxyDefaults(proxy)
 
+	return BuildProxySpec(proxy.HTTPProxy, proxy.HTTPSProxy, proxy.ProvidedNoProxy, podCIDR, serviceCIDR, networkInterface, controlPlaneInterface, lookup)
+}
+
+// BuildProxySpec is GetProxySpec without the fallback to the proxy environment variables. It is
+// used to change the proxy of a running cluster, where the environment holds the proxy being
+// replaced. It returns nil if no proxy is provided.
+func BuildProxySpec(httpProxy, httpsProxy, noProxy string, podCIDR string, serviceCIDR string, networkInterface string, controlPlaneInterface string, lookup NetworkLookup) (*ecv1beta1.ProxySpec, error) {
+	proxy := &ecv1beta1.ProxySpec{
+		HTTPProxy:       httpProxy,
+		HTTPSProxy:      httpsProxy,
+		ProvidedNoProxy: noProxy,
+	}
+
 	// Now that we have all no-proxy entries (from flags/env), merge in defaults
-	if err := populateNoProxy(proxy, podCIDR, serviceCIDR, networkInterface, lookup); err != nil {
+	if err := populateNoProxy(proxy, podCIDR, serviceCIDR, networkInterface, controlPlaneInterface, lookup); err != nil {
 		return nil, fmt.Errorf("unable to combine no-proxy supplied values and defaults: %w", err)
 	}
 
@@ -84,7 +106,7 @@ func SetProxyDefaults(proxy *ecv1beta1.ProxySpec) {
 	}
 }
 
-func populateNoProxy(proxy *ecv1beta1.ProxySpec, podCIDR string, serviceCIDR string, networkInterface string, lookup NetworkLookup) error {
+func populateNoProxy(proxy *ecv1beta1.ProxySpec, podCIDR string, serviceCIDR string, networkInterface string, controlPlaneInterface string, lookup NetworkLookup) error {
 	if proxy.ProvidedNoProxy == "" && proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" {
 		return nil
 	}
@@ -100,24 +122,34 @@ func populateNoProxy(proxy *ecv1beta1.ProxySpec, podCIDR string, serviceCIDR str
 		noProxy = append(noProxy, strings.Split(proxy.ProvidedNoProxy, ",")...)
 	}
 
-	// If we have a proxy set, ensure the local IP is in the no-proxy list
+	// If we have a proxy set, ensure the local IPs are in the no-proxy list, the ones the
+	// Kubernetes API and etcd advertise included
 	if proxy.HTTPProxy != "" || proxy.HTTPSProxy != "" {
-		ipnet, err := GetNetworkIPNet(networkInterface, lookup)
+		ipnets, err := nodeIPNets(podCIDR, networkInterface, lookup)
 		if err != nil {
-			return fmt.Errorf("failed to get first valid ip net: %w", err)
+			return err
 		}
-		cleanIPNet, err := cleanCIDR(ipnet)
-		if err != nil {
-			return fmt.Errorf("failed to clean subnet: %w", err)
+		if controlPlaneInterface != "" && controlPlaneInterface != networkInterface {
+			cpIPNets, err := nodeIPNets(podCIDR, controlPlaneInterface, lookup)
+			if err != nil {
+				return fmt.Errorf("control plane interface %s: %w", controlPlaneInterface, err)
+			}
+			ipnets = append(ipnets, cpIPNets...)
 		}
+		for _, ipnet := range ipnets {
+			cleanIPNet, err := cleanCIDR(ipnet)
+			if err != nil {
+				return fmt.Errorf("failed to clean subnet: %w", err)
+			}
 
-		// Check if the local IP is already covered by any of the no-proxy entries
-		isValid, err := NoProxyHasLocalIP(strings.Join(noProxy, ","), ipnet.IP.String())
-		if err != nil {
-			return fmt.Errorf("failed to validate no-proxy: %w", err)
-		} else if !isValid {
-			logrus.Debugf("The node IP (%q) is not included in the no-proxy list. Adding the network interface's subnet (%q).", ipnet.IP.String(), cleanIPNet)
-			noProxy = append(noProxy, cleanIPNet)
+			// Check if the local IP is already covered by any of the no-proxy entries
+			isValid, err := NoProxyHasLocalIP(strings.Join(noProxy, ","), ipnet.IP.String())
+			if err != nil {
+				return fmt.Errorf("failed to validate no-proxy: %w", err)
+			} else if !isValid {
+				logrus.Debugf("The node IP (%q) is not included in the no-proxy list. Adding the network interface's subnet (%q).", ipnet.IP.String(), cleanIPNet)
+				noProxy = append(noProxy, cleanIPNet)
+			}
 		}
 	}
 
@@ -125,6 +157,38 @@ func populateNoProxy(proxy *ecv1beta1.ProxySpec, podCIDR string, serviceCIDR str
 	return nil
 }
 
+// nodeIPNets returns the networks of the addresses of the node for the IP families of the pod
+// CIDRs, the IPv4 one first.
+func nodeIPNets(podCIDR string, networkInterface string, lookup NetworkLookup) ([]*net.IPNet, error) {
+	if lookup == nil {
+		lookup = defaultNetworkLookupImpl
+	}
+
+	cidrs := strings.Split(podCIDR, ",")
+	ipv6PodCIDR := ""
+	if len(cidrs) > 1 {
+		ipv6PodCIDR = cidrs[1]
+	}
+	family := netutils.ClusterIPFamily(cidrs[0], ipv6PodCIDR)
+
+	ipnets := []*net.IPNet{}
+	if family != netutils.IPFamilyIPv6 {
+		ipnet, err := lookup.FirstValidIPNet(networkInterface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get first valid ip net: %w", err)
+		}
+		ipnets = append(ipnets, ipnet)
+	}
+	if family != netutils.IPFamilyIPv4 {
+		ipnet, err := lookup.FirstValidIPv6Net(networkInterface)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get first valid ipv6 net: %w", err)
+		}
+		ipnets = append(ipnets, ipnet)
+	}
+	return ipnets, nil
+}
+
 // SetProxyEnv sets the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment variables based on the provided ProxySpec.
 // If the provided ProxySpec is nil, this environment variables are not set.
 func SetProxyEnv(proxy *ecv1beta1.ProxySpec) {
@@ -166,7 +230,9 @@ func NoProxyHasLocalIP(noProxy string, localIP string) (bool, error) {
 	return foundLocal, nil
 }
 
-func CheckProxyConfigForLocalIP(proxy *ecv1beta1.ProxySpec, networkInterface string, lookup NetworkLookup) (bool, string, error) {
+// CheckProxyConfigForLocalIP checks that the no-proxy list covers the addresses of the node for
+// the IP families of the pod CIDRs. It returns the first address not covered.
+func CheckProxyConfigForLocalIP(proxy *ecv1beta1.ProxySpec, podCIDR string, networkInterface string, lookup NetworkLookup) (bool, string, error) {
 	if proxy == nil {
 		return true, "", nil // no proxy is fine
 	}
@@ -174,11 +240,16 @@ func CheckProxyConfigForLocalIP(proxy *ecv1beta1.ProxySpec, networkInterface str
 		return true, "", nil // no proxy is fine
 	}
 
-	ipnet, err := GetNetworkIPNet(networkInterface, lookup)
+	ipnets, err := nodeIPNets(podCIDR, networkInterface, lookup)
 	if err != nil {
 		return false, "", fmt.Errorf("failed to get default IPNet: %w", err)
 	}
 
-	ok, err := NoProxyHasLocalIP(proxy.NoProxy, ipnet.IP.String())
-	return ok, ipnet.IP.String(), err
+	for _, ipnet := range ipnets {
+		ok, err := NoProxyHasLocalIP(proxy.NoProxy, ipnet.IP.String())
+		if err != nil || !ok {
+			return ok, ipnet.IP.String(), err
+		}
+	}
+	return true, ipnets[0].IP.String(), nil
 }
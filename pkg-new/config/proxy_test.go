@@ -0,0 +1,99 @@
+package config
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNetworkLookup struct{}
+
+func (m *mockNetworkLookup) FirstValidIPNet(networkInterface string) (*net.IPNet, error) {
+	_, ipnet, _ := net.ParseCIDR("192.168.1.0/24")
+	return ipnet, nil
+}
+
+func (m *mockNetworkLookup) FirstValidIPv6Net(networkInterface string) (*net.IPNet, error) {
+	_, ipnet, _ := net.ParseCIDR("fd00:1::/64")
+	return ipnet, nil
+}
+
+// interfaceNetworkLookup returns the networks of the interfaces by name.
+type interfaceNetworkLookup map[string]string
+
+func (m interfaceNetworkLookup) FirstValidIPNet(networkInterface string) (*net.IPNet, error) {
+	ip, ipnet, err := net.ParseCIDR(m[networkInterface])
+	if err != nil {
+		return nil, err
+	}
+	ipnet.IP = ip
+	return ipnet, nil
+}
+
+func (m interfaceNetworkLookup) FirstValidIPv6Net(networkInterface string) (*net.IPNet, error) {
+	return m.FirstValidIPNet(networkInterface + "-v6")
+}
+
+func TestBuildProxySpec(t *testing.T) {
+	// the environment holds the proxy being replaced
+	t.Setenv("http_proxy", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("no_proxy", "")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("HTTP_PROXY", "http://old-proxy:3128")
+	t.Setenv("NO_PROXY", "old.example.com")
+
+	proxy, err := BuildProxySpec("", "https://proxy:3128", "internal.example.com", "10.244.0.0/17", "10.244.128.0/17", "eth0", "", &mockNetworkLookup{})
+	require.NoError(t, err)
+	require.NotNil(t, proxy)
+	assert.Empty(t, proxy.HTTPProxy)
+	assert.Equal(t, "https://proxy:3128", proxy.HTTPSProxy)
+	assert.Equal(t, "internal.example.com", proxy.ProvidedNoProxy)
+	assert.Contains(t, proxy.NoProxy, "localhost")
+	assert.Contains(t, proxy.NoProxy, "10.244.0.0/17,10.244.128.0/17,internal.example.com")
+	assert.Contains(t, proxy.NoProxy, "192.168.1.0/24")
+	assert.NotContains(t, proxy.NoProxy, "old.example.com")
+
+	proxy, err = BuildProxySpec("", "", "", "10.244.0.0/17", "10.244.128.0/17", "eth0", "", &mockNetworkLookup{})
+	require.NoError(t, err)
+	assert.Nil(t, proxy)
+
+	// GetProxySpec falls back to the environment
+	proxy, err = GetProxySpec("", "", "", "10.244.0.0/17", "10.244.128.0/17", "eth0", "", &mockNetworkLookup{})
+	require.NoError(t, err)
+	require.NotNil(t, proxy)
+	assert.Equal(t, "http://old-proxy:3128", proxy.HTTPProxy)
+	assert.Equal(t, "old.example.com", proxy.ProvidedNoProxy)
+}
+
+func TestBuildProxySpec_ControlPlaneInterface(t *testing.T) {
+	lookup := interfaceNetworkLookup{
+		"eth0": "192.168.1.10/24",
+		"eth1": "10.10.0.10/16",
+	}
+
+	proxy, err := BuildProxySpec("http://proxy:3128", "", "", "10.244.0.0/17", "10.244.128.0/17", "eth0", "eth1", lookup)
+	require.NoError(t, err)
+	require.NotNil(t, proxy)
+	assert.Contains(t, proxy.NoProxy, "192.168.1.0/24")
+	assert.Contains(t, proxy.NoProxy, "10.10.0.0/16")
+
+	// a network already covered by the no-proxy list is not added again
+	proxy, err = BuildProxySpec("http://proxy:3128", "", "10.10.0.0/16", "10.244.0.0/17", "10.244.128.0/17", "eth0", "eth1", lookup)
+	require.NoError(t, err)
+	require.NotNil(t, proxy)
+	assert.Equal(t, 1, strings.Count(proxy.NoProxy, "10.10.0.0/16"))
+
+	// the control plane uses the network interface
+	proxy, err = BuildProxySpec("http://proxy:3128", "", "", "10.244.0.0/17", "10.244.128.0/17", "eth0", "eth0", lookup)
+	require.NoError(t, err)
+	require.NotNil(t, proxy)
+	assert.NotContains(t, proxy.NoProxy, "10.10.0.0/16")
+
+	_, err = BuildProxySpec("http://proxy:3128", "", "", "10.244.0.0/17", "10.244.128.0/17", "eth0", "eth2", lookup)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "control plane interface eth2")
+}
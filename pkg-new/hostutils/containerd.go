@@ -56,7 +56,7 @@ func useContainerdV3Schema() bool {
 // The drop-in schema depends on the embedded k0s/containerd version.
 func (h *HostUtils) AddInsecureRegistry(registry string) error {
 	parentDir := runtimeconfig.K0sContainerdConfigPath
-	if err := os.MkdirAll(parentDir, 0755); err != nil {
+	if err := h.fs.MkdirAll(parentDir, 0755); err != nil {
 		return fmt.Errorf("failed to ensure containerd directory exists: %w", err)
 	}
 
@@ -65,7 +65,7 @@ func (h *HostUtils) AddInsecureRegistry(registry string) error {
 	}
 
 	contents := fmt.Sprintf(registryConfigTemplateV2, registry)
-	if err := os.WriteFile(filepath.Join(parentDir, "embedded-registry.toml"), []byte(contents), 0644); err != nil {
+	if err := h.fs.WriteFile(filepath.Join(parentDir, "embedded-registry.toml"), []byte(contents), 0644); err != nil {
 		return fmt.Errorf("failed to write embedded-registry.toml: %w", err)
 	}
 	return nil
@@ -75,16 +75,16 @@ func (h *HostUtils) AddInsecureRegistry(registry string) error {
 // config_path drop-in plus a hosts.toml carrying skip_verify for the registry.
 func (h *HostUtils) addInsecureRegistryV3(registry string) error {
 	dropIn := fmt.Sprintf(registryConfigTemplateV3, runtimeconfig.K0sContainerdCertsDir)
-	if err := os.WriteFile(filepath.Join(runtimeconfig.K0sContainerdConfigPath, "embedded-registry.toml"), []byte(dropIn), 0644); err != nil {
+	if err := h.fs.WriteFile(filepath.Join(runtimeconfig.K0sContainerdConfigPath, "embedded-registry.toml"), []byte(dropIn), 0644); err != nil {
 		return fmt.Errorf("failed to write embedded-registry.toml: %w", err)
 	}
 
 	hostDir := filepath.Join(runtimeconfig.K0sContainerdCertsDir, registry)
-	if err := os.MkdirAll(hostDir, 0755); err != nil {
+	if err := h.fs.MkdirAll(hostDir, 0755); err != nil {
 		return fmt.Errorf("failed to ensure containerd certs.d directory exists: %w", err)
 	}
 	hostsToml := fmt.Sprintf(hostsTomlTemplateV3, registry, registry)
-	if err := os.WriteFile(filepath.Join(hostDir, "hosts.toml"), []byte(hostsToml), 0644); err != nil {
+	if err := h.fs.WriteFile(filepath.Join(hostDir, "hosts.toml"), []byte(hostsToml), 0644); err != nil {
 		return fmt.Errorf("failed to write hosts.toml: %w", err)
 	}
 	return nil
@@ -109,7 +109,7 @@ func (h *HostUtils) MigrateContainerdConfigToV3(isAirgap bool) error {
 		// Online installs don't use the in-cluster registry; the drop-in only
 		// blocks k0s 1.36 from starting, so remove it.
 		// Previous EC installers always created this file.
-		if err := os.Remove(path); err != nil {
+		if err := h.fs.Remove(path); err != nil {
 			if os.IsNotExist(err) {
 				return nil
 			}
@@ -0,0 +1,38 @@
+package hostutils
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// ConfigureDNSUpstreams writes the resolv.conf the kubelet is started with when DNS upstreams
+// are configured, CoreDNS then forwards to them instead of the nameservers of the host.
+func (h *HostUtils) ConfigureDNSUpstreams(rc runtimeconfig.RuntimeConfig) error {
+	if len(rc.DNSUpstreams()) == 0 {
+		return nil
+	}
+
+	file := ResolvConfFile(rc)
+	h.logger.Debugf("writing dns upstreams to %s", file.Path)
+	if err := h.fs.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+		return fmt.Errorf("create resolv.conf directory: %w", err)
+	}
+	if err := h.fs.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+		return fmt.Errorf("write resolv.conf: %w", err)
+	}
+	return nil
+}
+
+// resolvConfContent returns a resolv.conf with only the nameservers. The search domains of the
+// host are left out so pods only search the cluster domains.
+func resolvConfContent(nameservers []string) string {
+	var sb strings.Builder
+	sb.WriteString("# Generated by embedded-cluster from the DNS upstreams.\n")
+	for _, nameserver := range nameservers {
+		fmt.Fprintf(&sb, "nameserver %s\n", nameserver)
+	}
+	return sb.String()
+}
@@ -11,7 +11,24 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/support"
 )
 
-func (h *HostUtils) MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, airgapBundle string) error {
+// Materializer writes the files the host utils don't generate: the runtime config, the assets
+// embedded in the installer, the support bundle spec and the airgap bundle contents. Dry run
+// replaces it to record the files instead of writing them.
+type Materializer interface {
+	WriteRuntimeConfig(rc runtimeconfig.RuntimeConfig) error
+	MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, airgapBundle string) error
+}
+
+var _ Materializer = (*hostMaterializer)(nil)
+
+// hostMaterializer writes the files to the host.
+type hostMaterializer struct{}
+
+func (hostMaterializer) WriteRuntimeConfig(rc runtimeconfig.RuntimeConfig) error {
+	return rc.WriteToDisk()
+}
+
+func (hostMaterializer) MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, airgapBundle string) error {
 	materializer := goods.NewMaterializer(rc)
 	if err := materializer.Materialize(); err != nil {
 		return fmt.Errorf("materialize binaries: %w", err)
@@ -37,3 +54,7 @@ func (h *HostUtils) MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRele
 
 	return nil
 }
+
+func (h *HostUtils) MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, airgapBundle string) error {
+	return h.materializer.MaterializeFiles(rc, channelRelease, airgapBundle)
+}
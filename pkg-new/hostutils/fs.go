@@ -0,0 +1,40 @@
+package hostutils
+
+import (
+	"os"
+)
+
+// FileSystem is where the host utils write the configuration of the host. Dry run replaces it
+// to record the files instead of writing them.
+type FileSystem interface {
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	Symlink(oldname, newname string) error
+	Chmod(name string, mode os.FileMode) error
+}
+
+var _ FileSystem = (*hostFileSystem)(nil)
+
+// hostFileSystem writes to the file system of the host.
+type hostFileSystem struct{}
+
+func (hostFileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (hostFileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (hostFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (hostFileSystem) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+func (hostFileSystem) Chmod(name string, mode os.FileMode) error {
+	return os.Chmod(name, mode)
+}
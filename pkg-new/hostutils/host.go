@@ -7,7 +7,9 @@ import (
 var _ HostUtilsInterface = (*HostUtils)(nil)
 
 type HostUtils struct {
-	logger logrus.FieldLogger
+	logger       logrus.FieldLogger
+	fs           FileSystem
+	materializer Materializer
 }
 
 type HostUtilsOption func(*HostUtils)
@@ -18,9 +20,26 @@ func WithLogger(logger logrus.FieldLogger) HostUtilsOption {
 	}
 }
 
+// WithFileSystem sets the file system the host configuration is written to.
+func WithFileSystem(fs FileSystem) HostUtilsOption {
+	return func(h *HostUtils) {
+		h.fs = fs
+	}
+}
+
+// WithMaterializer sets the materializer writing the runtime config and the files shipped with
+// the installer.
+func WithMaterializer(materializer Materializer) HostUtilsOption {
+	return func(h *HostUtils) {
+		h.materializer = materializer
+	}
+}
+
 func New(opts ...HostUtilsOption) *HostUtils {
 	h := &HostUtils{
-		logger: logrus.StandardLogger(),
+		logger:       logrus.StandardLogger(),
+		fs:           hostFileSystem{},
+		materializer: hostMaterializer{},
 	}
 
 	for _, opt := range opts {
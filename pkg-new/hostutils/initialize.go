@@ -3,9 +3,9 @@ package hostutils
 import (
 	"context"
 	"fmt"
-	"os"
 	"path/filepath"
 
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 )
@@ -13,16 +13,18 @@ import (
 type InitForInstallOptions struct {
 	License      []byte
 	AirgapBundle string
+	// NTPServers are set as the servers of the time synchronization service when not empty.
+	NTPServers []string
 }
 
 func (h *HostUtils) ConfigureHost(ctx context.Context, rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, opts InitForInstallOptions) error {
 	h.logger.Debugf("writing runtime config to disk")
-	if err := rc.WriteToDisk(); err != nil {
+	if err := h.materializer.WriteRuntimeConfig(rc); err != nil {
 		return fmt.Errorf("write runtime config to disk: %w", err)
 	}
 
 	h.logger.Debugf("setting permissions on %s", rc.EmbeddedClusterHomeDirectory())
-	if err := os.Chmod(rc.EmbeddedClusterHomeDirectory(), 0755); err != nil {
+	if err := h.fs.Chmod(rc.EmbeddedClusterHomeDirectory(), 0755); err != nil {
 		// don't fail as there are cases where we can't change the permissions (bind mounts, selinux, etc...),
 		// and we handle and surface those errors to the user later (host preflights, checking exec errors, etc...)
 		h.logger.Debugf("unable to chmod embedded-cluster home dir: %s", err)
@@ -35,7 +37,7 @@ func (h *HostUtils) ConfigureHost(ctx context.Context, rc runtimeconfig.RuntimeC
 
 	if opts.License != nil {
 		h.logger.Debugf("write license file to %s", rc.EmbeddedClusterHomeDirectory())
-		if err := os.WriteFile(filepath.Join(rc.EmbeddedClusterHomeDirectory(), "license.yaml"), opts.License, 0400); err != nil {
+		if err := h.fs.WriteFile(filepath.Join(rc.EmbeddedClusterHomeDirectory(), "license.yaml"), opts.License, 0400); err != nil {
 			h.logger.Warnf("unable to write license file to %s: %v", rc.EmbeddedClusterHomeDirectory(), err)
 		}
 	}
@@ -66,9 +68,21 @@ func (h *HostUtils) ConfigureHost(ctx context.Context, rc runtimeconfig.RuntimeC
 	}
 
 	h.logger.Debugf("configuring firewalld")
-	if err := h.ConfigureFirewalld(ctx, rc.PodCIDR(), rc.ServiceCIDR()); err != nil {
+	if err := h.ConfigureFirewalld(ctx, netutils.JoinCIDRs(rc.PodCIDR(), rc.IPv6PodCIDR()), netutils.JoinCIDRs(rc.ServiceCIDR(), rc.IPv6ServiceCIDR())); err != nil {
 		h.logger.Debugf("unable to configure firewalld: %v", err)
 	}
 
+	h.logger.Debugf("configuring dns upstreams")
+	if err := h.ConfigureDNSUpstreams(rc); err != nil {
+		return fmt.Errorf("configure dns upstreams: %w", err)
+	}
+
+	if len(opts.NTPServers) > 0 {
+		h.logger.Debugf("configuring time synchronization")
+		if err := h.ConfigureTimeSync(ctx, opts.NTPServers); err != nil {
+			return fmt.Errorf("configure time synchronization: %w", err)
+		}
+	}
+
 	return nil
 }
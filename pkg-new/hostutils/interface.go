@@ -24,6 +24,8 @@ type HostUtilsInterface interface {
 	ConfigureKernelModules() error
 	ConfigureNetworkManager(ctx context.Context, rc runtimeconfig.RuntimeConfig) error
 	ConfigureFirewalld(ctx context.Context, podNetwork, serviceNetwork string) error
+	ConfigureTimeSync(ctx context.Context, servers []string) error
+	ConfigureDNSUpstreams(rc runtimeconfig.RuntimeConfig) error
 	ResetFirewalld(ctx context.Context) error
 	MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, airgapBundle string) error
 	CreateSystemdUnitFiles(ctx context.Context, logger logrus.FieldLogger, rc runtimeconfig.RuntimeConfig, hostname string, isWorker bool) error
@@ -57,6 +59,14 @@ func ConfigureFirewalld(ctx context.Context, podNetwork, serviceNetwork string)
 	return h.ConfigureFirewalld(ctx, podNetwork, serviceNetwork)
 }
 
+func ConfigureTimeSync(ctx context.Context, servers []string) error {
+	return h.ConfigureTimeSync(ctx, servers)
+}
+
+func ConfigureDNSUpstreams(rc runtimeconfig.RuntimeConfig) error {
+	return h.ConfigureDNSUpstreams(rc)
+}
+
 func ResetFirewalld(ctx context.Context) error {
 	return h.ResetFirewalld(ctx)
 }
@@ -58,6 +58,18 @@ func (m *MockHostUtils) ConfigureFirewalld(ctx context.Context, podNetwork, serv
 	return args.Error(0)
 }
 
+// ConfigureTimeSync mocks the ConfigureTimeSync method
+func (m *MockHostUtils) ConfigureTimeSync(ctx context.Context, servers []string) error {
+	args := m.Called(ctx, servers)
+	return args.Error(0)
+}
+
+// ConfigureDNSUpstreams mocks the ConfigureDNSUpstreams method
+func (m *MockHostUtils) ConfigureDNSUpstreams(rc runtimeconfig.RuntimeConfig) error {
+	args := m.Called(rc)
+	return args.Error(0)
+}
+
 // ResetFirewalld mocks the ResetFirewalld method
 func (m *MockHostUtils) ResetFirewalld(ctx context.Context) error {
 	args := m.Called(ctx)
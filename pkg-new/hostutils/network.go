@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/replicatedhq/embedded-cluster/cmd/installer/goods"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
@@ -31,8 +32,11 @@ func (h *HostUtils) ConfigureNetworkManager(ctx context.Context, rc runtimeconfi
 	}
 
 	logrus.Debugf("creating NetworkManager config file")
-	materializer := goods.NewMaterializer(rc)
-	if err := materializer.CalicoNetworkManagerConfig(); err != nil {
+	content, err := goods.CalicoNetworkManagerConfig()
+	if err != nil {
+		return fmt.Errorf("read configuration: %w", err)
+	}
+	if err := h.fs.WriteFile(goods.CalicoNetworkManagerConfigPath, content, 0644); err != nil {
 		return fmt.Errorf("materialize configuration: %w", err)
 	}
 
@@ -45,7 +49,8 @@ func (h *HostUtils) ConfigureNetworkManager(ctx context.Context, rc runtimeconfi
 
 // ConfigureFirewalld configures firewalld for the cluster. It adds the ec-net zone for pod and
 // service communication with default target ACCEPT, and opens the necessary ports in the default
-// zone for k0s and k8s components on the host network.
+// zone for k0s and k8s components on the host network. The networks of both families of a
+// dual-stack cluster are separated by a comma.
 func (h *HostUtils) ConfigureFirewalld(ctx context.Context, podNetwork, serviceNetwork string) error {
 	isActive, err := firewalld.IsFirewalldActive(ctx)
 	if err != nil {
@@ -135,14 +140,18 @@ func ensureFirewalldECNetZone(ctx context.Context, podNetwork, serviceNetwork st
 		return fmt.Errorf("set target to ACCEPT: %w", err)
 	}
 
-	err = firewalld.AddSourceToZone(ctx, podNetwork, opts...)
-	if err != nil {
-		return fmt.Errorf("add pod network source: %w", err)
+	for _, network := range strings.Split(podNetwork, ",") {
+		err = firewalld.AddSourceToZone(ctx, network, opts...)
+		if err != nil {
+			return fmt.Errorf("add pod network source: %w", err)
+		}
 	}
 
-	err = firewalld.AddSourceToZone(ctx, serviceNetwork, opts...)
-	if err != nil {
-		return fmt.Errorf("add service network source: %w", err)
+	for _, network := range strings.Split(serviceNetwork, ",") {
+		err = firewalld.AddSourceToZone(ctx, network, opts...)
+		if err != nil {
+			return fmt.Errorf("add service network source: %w", err)
+		}
 	}
 
 	// Add the calico interfaces
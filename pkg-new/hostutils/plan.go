@@ -0,0 +1,209 @@
+package hostutils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/goods"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// HostFile is a file written to the host while it is configured for the cluster. The functions
+// returning them allow planning the changes to the host without touching it.
+type HostFile struct {
+	Path string
+	// Content is left empty when it can only be known once the host is configured.
+	Content string
+	// LinkTarget is set when the file is a symlink.
+	LinkTarget string
+}
+
+// SysctlConfigFiles returns the sysctl config files written by ConfigureSysctl. The content of
+// the dynamic config depends on the values currently set on the host.
+func SysctlConfigFiles() []HostFile {
+	return []HostFile{
+		{Path: sysctlConfigPath, Content: string(embeddedClusterSysctlConf)},
+		{Path: dynamicSysctlConfigPath},
+	}
+}
+
+// SysctlSettings returns the sysctl values set by ConfigureSysctl, by key. The dynamic values
+// are only set when the value on the host is out of bounds, so they are the minimum or maximum
+// accepted.
+func SysctlSettings() map[string]string {
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(embeddedClusterSysctlConf))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		settings[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	for _, constraint := range dynamicSysctlConstraints {
+		settings[constraint.key] = strconv.FormatInt(constraint.value, 10)
+	}
+	return settings
+}
+
+// SysctlChanges returns the sysctl values ConfigureSysctl changes on this host, by key. Keys that
+// can't be read, like the ones of kernel modules that are not loaded yet, are changed.
+func SysctlChanges() map[string]string {
+	return sysctlChanges(getCurrentSysctlValue)
+}
+
+func sysctlChanges(getter sysctlValueGetter) map[string]string {
+	changes := map[string]string{}
+	dynamic := map[string]bool{}
+	for _, constraint := range dynamicSysctlConstraints {
+		dynamic[constraint.key] = true
+		if current, err := getter(constraint.key); err != nil || constraint.needsUpdate(current) {
+			changes[constraint.key] = strconv.FormatInt(constraint.value, 10)
+		}
+	}
+	for key, value := range SysctlSettings() {
+		if dynamic[key] {
+			continue
+		}
+		if current, err := getter(key); err == nil && strconv.FormatInt(current, 10) == value {
+			continue
+		}
+		changes[key] = value
+	}
+	return changes
+}
+
+// KernelModulesConfigFile returns the kernel modules config file written by
+// ConfigureKernelModules.
+func KernelModulesConfigFile() HostFile {
+	return HostFile{Path: modulesLoadConfigPath, Content: string(embeddedClusterModulesConf)}
+}
+
+// KernelModules returns the kernel modules loaded by ConfigureKernelModules when they are
+// available on the host kernel.
+func KernelModules() []string {
+	modules := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(embeddedClusterModulesConf))
+	for scanner.Scan() {
+		module := strings.TrimSpace(scanner.Text())
+		if module == "" || strings.HasPrefix(module, "#") {
+			continue
+		}
+		modules = append(modules, module)
+	}
+	return modules
+}
+
+// KernelModulesToLoad returns the kernel modules ConfigureKernelModules loads on this host, the
+// ones available on the host kernel that are not loaded yet.
+func KernelModulesToLoad() []string {
+	return kernelModulesToLoad(func(module string) bool {
+		_, err := os.Stat(filepath.Join("/sys/module", module))
+		return err == nil
+	})
+}
+
+func kernelModulesToLoad(loaded func(module string) bool) []string {
+	modules := []string{}
+	for _, module := range KernelModules() {
+		if loaded(module) || !_moduleExistsFunc(module) {
+			continue
+		}
+		modules = append(modules, module)
+	}
+	return modules
+}
+
+// SystemdUnitFiles returns the systemd unit files and drop-ins written by
+// CreateSystemdUnitFiles.
+func SystemdUnitFiles(rc runtimeconfig.RuntimeConfig, hostname string, isWorker bool) ([]HostFile, error) {
+	src := k0sSystemdUnitFileName(isWorker)
+
+	files := []HostFile{}
+	if proxy := rc.ProxySpec(); proxy != nil {
+		files = append(files, K0sProxyDropInFile(proxy, isWorker))
+	}
+	if hostname != "" {
+		files = append(files, HostFile{
+			Path:    filepath.Join(fmt.Sprintf("%s.d", src), "autopilot-hostname.conf"),
+			Content: autopilotConfigContent(hostname),
+		})
+	}
+	files = append(files, HostFile{Path: systemdUnitFileName(), LinkTarget: src})
+
+	unit, err := goods.LocalArtifactMirrorUnit()
+	if err != nil {
+		return nil, fmt.Errorf("read local artifact mirror unit: %w", err)
+	}
+	files = append(files,
+		HostFile{Path: goods.LocalArtifactMirrorUnitFilePath, Content: string(unit)},
+		LocalArtifactMirrorDropInFile(rc),
+	)
+	if proxy := rc.ProxySpec(); proxy != nil {
+		files = append(files, LocalArtifactMirrorProxyDropInFile(proxy))
+	}
+
+	return files, nil
+}
+
+// K0sProxyDropInFile returns the drop-in setting the proxy of the k0s service. Its content is
+// empty when proxy is nil, the drop-in is then removed from the host.
+func K0sProxyDropInFile(proxy *ecv1beta1.ProxySpec, isWorker bool) HostFile {
+	return HostFile{
+		Path:    filepath.Join(fmt.Sprintf("%s.d", k0sSystemdUnitFileName(isWorker)), systemd.ProxyDropInFileName),
+		Content: proxyDropInContent(proxy),
+	}
+}
+
+// LocalArtifactMirrorProxyDropInFile returns the drop-in setting the proxy of the local
+// artifact mirror service. Its content is empty when proxy is nil, the drop-in is then removed
+// from the host.
+func LocalArtifactMirrorProxyDropInFile(proxy *ecv1beta1.ProxySpec) HostFile {
+	return HostFile{
+		Path:    systemd.DropInFilePath("local-artifact-mirror.service", systemd.ProxyDropInFileName),
+		Content: proxyDropInContent(proxy),
+	}
+}
+
+func proxyDropInContent(proxy *ecv1beta1.ProxySpec) string {
+	if proxy == nil {
+		return ""
+	}
+	return systemd.ProxyDropInContent(proxy.HTTPProxy, proxy.HTTPSProxy, proxy.NoProxy)
+}
+
+// LocalArtifactMirrorDropInFile returns the drop-in file written by
+// WriteLocalArtifactMirrorDropInFile.
+func LocalArtifactMirrorDropInFile(rc runtimeconfig.RuntimeConfig) HostFile {
+	return HostFile{
+		Path:    systemd.DropInFilePath("local-artifact-mirror.service", "embedded-cluster.conf"),
+		Content: localArtifactMirrorDropInContent(rc),
+	}
+}
+
+// TimeSyncConfig returns the file ConfigureTimeSync writes to set the NTP servers and the
+// commands it runs, they depend on the time synchronization service installed on the host.
+func TimeSyncConfig(servers []string) (HostFile, [][]string, error) {
+	change, err := planTimeSync(servers)
+	if err != nil {
+		return HostFile{}, nil, err
+	}
+	return change.file, change.commands, nil
+}
+
+// ResolvConfFile returns the resolv.conf file written by ConfigureDNSUpstreams, the kubelet
+// hands it to CoreDNS in place of the resolv.conf of the host.
+func ResolvConfFile(rc runtimeconfig.RuntimeConfig) HostFile {
+	return HostFile{Path: rc.PathToResolvConf(), Content: resolvConfContent(rc.DNSUpstreams())}
+}
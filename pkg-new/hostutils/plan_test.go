@@ -0,0 +1,146 @@
+package hostutils
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_KernelModules(t *testing.T) {
+	modules := KernelModules()
+	require.NotEmpty(t, modules)
+	for _, module := range modules {
+		assert.NotEmpty(t, module)
+		assert.NotContains(t, module, "#")
+	}
+}
+
+func Test_SysctlSettings(t *testing.T) {
+	settings := SysctlSettings()
+	assert.Equal(t, "1", settings["net.ipv4.ip_forward"])
+	assert.Equal(t, "1", settings["net.bridge.bridge-nf-call-iptables"])
+	assert.Equal(t, "0", settings["net.ipv4.conf.all.arp_filter"])
+	assert.Equal(t, "2", settings["net.ipv4.conf.all.rp_filter"])
+	assert.Equal(t, "1024", settings["fs.inotify.max_user_instances"])
+	for key := range settings {
+		assert.NotContains(t, key, "#")
+		assert.NotContains(t, key, " ")
+	}
+}
+
+func Test_sysctlChanges(t *testing.T) {
+	current := map[string]int64{
+		"fs.inotify.max_user_instances": 128,
+		"fs.inotify.max_user_watches":   524288,
+		"net.ipv4.ip_forward":           0,
+	}
+	getter := func(key string) (int64, error) {
+		if value, ok := current[key]; ok {
+			return value, nil
+		}
+		if key == "net.bridge.bridge-nf-call-iptables" || key == "net.bridge.bridge-nf-call-ip6tables" {
+			return 0, errors.New("no such file or directory")
+		}
+		settings := SysctlSettings()
+		value, err := strconv.ParseInt(settings[key], 10, 64)
+		require.NoError(t, err)
+		return value, nil
+	}
+
+	assert.Equal(t, map[string]string{
+		// raised to the minimum, the watches are already above it
+		"fs.inotify.max_user_instances": "1024",
+		"net.ipv4.ip_forward":           "1",
+		// the keys of br_netfilter don't exist before it is loaded
+		"net.bridge.bridge-nf-call-iptables":  "1",
+		"net.bridge.bridge-nf-call-ip6tables": "1",
+	}, sysctlChanges(getter))
+}
+
+func Test_kernelModulesToLoad(t *testing.T) {
+	origModuleExists := _moduleExistsFunc
+	_moduleExistsFunc = func(module string) bool { return module != "nft_compat" }
+	t.Cleanup(func() { _moduleExistsFunc = origModuleExists })
+
+	loaded := func(module string) bool { return module == "overlay" || module == "nf_tables" }
+	assert.Equal(t, []string{"ip_tables", "br_netfilter", "nf_conntrack"}, kernelModulesToLoad(loaded))
+}
+
+func Test_ProxyDropInFiles(t *testing.T) {
+	proxy := &ecv1beta1.ProxySpec{HTTPProxy: "http://proxy:3128", NoProxy: "localhost"}
+
+	file := K0sProxyDropInFile(proxy, false)
+	assert.Equal(t, "/etc/systemd/system/k0scontroller.service.d/http-proxy.conf", file.Path)
+	assert.Contains(t, file.Content, `Environment="HTTP_PROXY=http://proxy:3128"`)
+	assert.Contains(t, file.Content, `Environment="NO_PROXY=localhost"`)
+
+	file = LocalArtifactMirrorProxyDropInFile(proxy)
+	assert.Equal(t, "/etc/systemd/system/local-artifact-mirror.service.d/http-proxy.conf", file.Path)
+	assert.Contains(t, file.Content, `Environment="HTTP_PROXY=http://proxy:3128"`)
+
+	file = K0sProxyDropInFile(nil, true)
+	assert.Equal(t, "/etc/systemd/system/k0sworker.service.d/http-proxy.conf", file.Path)
+	assert.Empty(t, file.Content)
+}
+
+func Test_SystemdUnitFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxy     *ecv1beta1.ProxySpec
+		hostname  string
+		isWorker  bool
+		wantPaths []string
+	}{
+		{
+			name: "controller without proxy or hostname",
+			wantPaths: []string{
+				systemdUnitFileName(),
+				"/etc/systemd/system/local-artifact-mirror.service",
+				"/etc/systemd/system/local-artifact-mirror.service.d/embedded-cluster.conf",
+			},
+		},
+		{
+			name:     "worker with proxy and hostname",
+			proxy:    &ecv1beta1.ProxySpec{HTTPProxy: "http://proxy:3128"},
+			hostname: "node1",
+			isWorker: true,
+			wantPaths: []string{
+				"/etc/systemd/system/k0sworker.service.d/http-proxy.conf",
+				"/etc/systemd/system/k0sworker.service.d/autopilot-hostname.conf",
+				systemdUnitFileName(),
+				"/etc/systemd/system/local-artifact-mirror.service",
+				"/etc/systemd/system/local-artifact-mirror.service.d/embedded-cluster.conf",
+				"/etc/systemd/system/local-artifact-mirror.service.d/http-proxy.conf",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := runtimeconfig.New(nil)
+			rc.SetProxySpec(tt.proxy)
+
+			files, err := SystemdUnitFiles(rc, tt.hostname, tt.isWorker)
+			require.NoError(t, err)
+
+			paths := []string{}
+			for _, file := range files {
+				paths = append(paths, file.Path)
+			}
+			assert.Equal(t, tt.wantPaths, paths)
+
+			for _, file := range files {
+				if file.Path == systemdUnitFileName() {
+					assert.Equal(t, k0sSystemdUnitFileName(tt.isWorker), file.LinkTarget)
+					continue
+				}
+				assert.NotEmpty(t, file.Content, file.Path)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package hostutils
 import (
 	"os/exec"
 
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 )
 
@@ -23,10 +24,8 @@ func (h *HostUtils) ConfigureSELinuxFcontext(rc runtimeconfig.RuntimeConfig) err
 		"bin_t",
 		rc.EmbeddedClusterBinsSubDir() + "(/.*)?",
 	}
-	out, err := exec.Command("semanage", args...).CombinedOutput()
-	if err != nil {
+	if _, err := helpers.RunCommand("semanage", args...); err != nil {
 		h.logger.Debugf("unable to set contexts on binary directory: %v", err)
-		h.logger.Debugln(string(out))
 	}
 
 	return nil
@@ -40,10 +39,8 @@ func (h *HostUtils) RestoreSELinuxContext(rc runtimeconfig.RuntimeConfig) error
 	}
 
 	h.logger.Debugf("relabeling embedded-cluster data directory with restorecon")
-	out, err := exec.Command("restorecon", "-RvF", rc.EmbeddedClusterHomeDirectory()).CombinedOutput()
-	if err != nil {
+	if _, err := helpers.RunCommand("restorecon", "-RvF", rc.EmbeddedClusterHomeDirectory()); err != nil {
 		h.logger.Debugf("unable to run restorecon: %v", err)
-		h.logger.Debugln(string(out))
 	}
 
 	return nil
@@ -1,8 +1,6 @@
 package hostutils
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	_ "embed"
 	"fmt"
@@ -66,6 +64,17 @@ type sysctlConstraint struct {
 	operator sysctlOperator
 }
 
+// needsUpdate returns true if the current value is out of the bounds of the constraint.
+func (c sysctlConstraint) needsUpdate(current int64) bool {
+	switch c.operator {
+	case sysctlOperatorMin:
+		return current < c.value
+	case sysctlOperatorMax:
+		return current > c.value
+	}
+	return false
+}
+
 type sysctlValueGetter func(key string) (int64, error)
 
 // ConfigureSysctl writes the sysctl config files for the embedded cluster and reloads the sysctl configuration.
@@ -76,11 +85,11 @@ func (h *HostUtils) ConfigureSysctl() error {
 		return fmt.Errorf("find sysctl binary: %w", err)
 	}
 
-	if err := sysctlConfig(); err != nil {
+	if err := sysctlConfig(h.fs); err != nil {
 		return fmt.Errorf("materialize sysctl config: %w", err)
 	}
 
-	if err := dynamicSysctlConfig(); err != nil {
+	if err := dynamicSysctlConfig(h.fs); err != nil {
 		return fmt.Errorf("materialize dynamic sysctl config: %w", err)
 	}
 
@@ -91,11 +100,11 @@ func (h *HostUtils) ConfigureSysctl() error {
 }
 
 // sysctlConfig writes the embedded sysctl config to the /etc/sysctl.d directory.
-func sysctlConfig() error {
-	if err := os.MkdirAll(filepath.Dir(sysctlConfigPath), 0755); err != nil {
+func sysctlConfig(fs FileSystem) error {
+	if err := fs.MkdirAll(filepath.Dir(sysctlConfigPath), 0755); err != nil {
 		return fmt.Errorf("create directory: %w", err)
 	}
-	if err := os.WriteFile(sysctlConfigPath, embeddedClusterSysctlConf, 0644); err != nil {
+	if err := fs.WriteFile(sysctlConfigPath, embeddedClusterSysctlConf, 0644); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 	return nil
@@ -103,14 +112,14 @@ func sysctlConfig() error {
 
 // dynamicSysctlConfig generates a dynamic sysctl config file based on current system values
 // and our constraints.
-func dynamicSysctlConfig() error {
-	return generateDynamicSysctlConfig(getCurrentSysctlValue, dynamicSysctlConfigPath)
+func dynamicSysctlConfig(fs FileSystem) error {
+	return generateDynamicSysctlConfig(fs, getCurrentSysctlValue, dynamicSysctlConfigPath)
 }
 
 // generateDynamicSysctlConfig is the testable version of dynamicSysctlConfig that accepts
 // a custom sysctl value getter and config path.
-func generateDynamicSysctlConfig(getter sysctlValueGetter, configPath string) error {
-	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+func generateDynamicSysctlConfig(fs FileSystem, getter sysctlValueGetter, configPath string) error {
+	if err := fs.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("create directory: %w", err)
 	}
 
@@ -124,20 +133,12 @@ func generateDynamicSysctlConfig(getter sysctlValueGetter, configPath string) er
 			return fmt.Errorf("check current value for %s: %w", constraint.key, err)
 		}
 
-		needsUpdate := false
-		switch constraint.operator {
-		case sysctlOperatorMin:
-			needsUpdate = currentValue < constraint.value
-		case sysctlOperatorMax:
-			needsUpdate = currentValue > constraint.value
-		}
-
-		if needsUpdate {
+		if constraint.needsUpdate(currentValue) {
 			fmt.Fprintf(&config, "%s = %d\n", constraint.key, constraint.value)
 		}
 	}
 
-	if err := os.WriteFile(configPath, []byte(config.String()), 0644); err != nil {
+	if err := fs.WriteFile(configPath, []byte(config.String()), 0644); err != nil {
 		return fmt.Errorf("write dynamic config file: %w", err)
 	}
 	return nil
@@ -165,7 +166,7 @@ func (h *HostUtils) ConfigureKernelModules() error {
 		return fmt.Errorf("find modprobe binary: %w", err)
 	}
 
-	if err := kernelModulesConfig(); err != nil {
+	if err := kernelModulesConfig(h.fs); err != nil {
 		return fmt.Errorf("materialize kernel modules config: %w", err)
 	}
 
@@ -177,11 +178,11 @@ func (h *HostUtils) ConfigureKernelModules() error {
 
 // kernelModulesConfig writes the embedded kernel modules config to the /etc/modules-load.d
 // directory.
-func kernelModulesConfig() error {
-	if err := os.MkdirAll(filepath.Dir(modulesLoadConfigPath), 0755); err != nil {
+func kernelModulesConfig(fs FileSystem) error {
+	if err := fs.MkdirAll(filepath.Dir(modulesLoadConfigPath), 0755); err != nil {
 		return fmt.Errorf("create directory: %w", err)
 	}
-	if err := os.WriteFile(modulesLoadConfigPath, embeddedClusterModulesConf, 0644); err != nil {
+	if err := fs.WriteFile(modulesLoadConfigPath, embeddedClusterModulesConf, 0644); err != nil {
 		return fmt.Errorf("write file: %w", err)
 	}
 	return nil
@@ -191,12 +192,7 @@ func kernelModulesConfig() error {
 // the config file and calling modprobe for each one. Modules that are not available on the host
 // kernel are skipped gracefully.
 func ensureKernelModulesLoaded(logger logrus.FieldLogger) (finalErr error) {
-	scanner := bufio.NewScanner(bytes.NewReader(embeddedClusterModulesConf))
-	for scanner.Scan() {
-		module := strings.TrimSpace(scanner.Text())
-		if module == "" || strings.HasPrefix(module, "#") {
-			continue
-		}
+	for _, module := range KernelModules() {
 		if !_moduleExistsFunc(module) {
 			logger.Debugf("Module %s not available on this kernel, skipping", module)
 			continue
@@ -225,26 +221,23 @@ var _moduleExistsFunc = func(module string) bool {
 func (h *HostUtils) CreateSystemdUnitFiles(ctx context.Context, logger logrus.FieldLogger, rc runtimeconfig.RuntimeConfig, hostname string, isWorker bool) error {
 	dst := systemdUnitFileName()
 	if _, err := os.Lstat(dst); err == nil {
-		if err := os.Remove(dst); err != nil {
+		if err := h.fs.Remove(dst); err != nil {
 			return err
 		}
 	}
-	src := fmt.Sprintf("%s/k0scontroller.service", systemdConfigPath)
-	if isWorker {
-		src = fmt.Sprintf("%s/k0sworker.service", systemdConfigPath)
-	}
+	src := k0sSystemdUnitFileName(isWorker)
 	if proxy := rc.ProxySpec(); proxy != nil {
-		if err := ensureProxyConfig(fmt.Sprintf("%s.d", src), proxy.HTTPProxy, proxy.HTTPSProxy, proxy.NoProxy); err != nil {
+		if err := ensureProxyConfig(h.fs, fmt.Sprintf("%s.d", src), proxy.HTTPProxy, proxy.HTTPSProxy, proxy.NoProxy); err != nil {
 			return fmt.Errorf("unable to create proxy config: %w", err)
 		}
 	}
 	if hostname != "" {
-		if err := ensureAutopilotConfig(fmt.Sprintf("%s.d", src), hostname); err != nil {
+		if err := ensureAutopilotConfig(h.fs, fmt.Sprintf("%s.d", src), hostname); err != nil {
 			return fmt.Errorf("unable to create autopilot hostname config: %w", err)
 		}
 	}
 	logger.Debugf("linking %s to %s", src, dst)
-	if err := os.Symlink(src, dst); err != nil {
+	if err := h.fs.Symlink(src, dst); err != nil {
 		return fmt.Errorf("failed to create symlink: %w", err)
 	}
 
@@ -261,21 +254,25 @@ func systemdUnitFileName() string {
 	return fmt.Sprintf("%s/%s.service", systemdConfigPath, runtimeconfig.AppSlug())
 }
 
+// k0sSystemdUnitFileName returns the path to the systemd unit file k0s creates when installed.
+func k0sSystemdUnitFileName(isWorker bool) string {
+	if isWorker {
+		return fmt.Sprintf("%s/k0sworker.service", systemdConfigPath)
+	}
+	return fmt.Sprintf("%s/k0scontroller.service", systemdConfigPath)
+}
+
 // ensureProxyConfig creates a new http-proxy.conf configuration file. The file is saved in the
 // systemd directory (/etc/systemd/system/k0s{controller,worker}.service.d/).
-func ensureProxyConfig(servicePath string, httpProxy string, httpsProxy string, noProxy string) error {
+func ensureProxyConfig(fs FileSystem, servicePath string, httpProxy string, httpsProxy string, noProxy string) error {
 	// create the directory
-	if err := os.MkdirAll(servicePath, 0755); err != nil {
+	if err := fs.MkdirAll(servicePath, 0755); err != nil {
 		return fmt.Errorf("unable to create directory: %w", err)
 	}
 
 	// create and write the file
-	content := fmt.Sprintf(`[Service]
-Environment="HTTP_PROXY=%s"
-Environment="HTTPS_PROXY=%s"
-Environment="NO_PROXY=%s"`, httpProxy, httpsProxy, noProxy)
-
-	err := os.WriteFile(filepath.Join(servicePath, "http-proxy.conf"), []byte(content), 0644)
+	content := systemd.ProxyDropInContent(httpProxy, httpsProxy, noProxy)
+	err := fs.WriteFile(filepath.Join(servicePath, systemd.ProxyDropInFileName), []byte(content), 0644)
 	if err != nil {
 		return fmt.Errorf("unable to create and write proxy file: %w", err)
 	}
@@ -287,13 +284,21 @@ Environment="NO_PROXY=%s"`, httpProxy, httpsProxy, noProxy)
 // service is responsible for serving on localhost, through http, all files that are used
 // during a cluster upgrade.
 func (h *HostUtils) installAndEnableLocalArtifactMirror(ctx context.Context, logger logrus.FieldLogger, rc runtimeconfig.RuntimeConfig) error {
-	materializer := goods.NewMaterializer(rc)
-	if err := materializer.LocalArtifactMirrorUnitFile(); err != nil {
+	unit, err := goods.LocalArtifactMirrorUnit()
+	if err != nil {
+		return fmt.Errorf("failed to read artifact mirror unit: %w", err)
+	}
+	if err := h.fs.WriteFile(goods.LocalArtifactMirrorUnitFilePath, unit, 0644); err != nil {
 		return fmt.Errorf("failed to materialize artifact mirror unit: %w", err)
 	}
 	if err := h.WriteLocalArtifactMirrorDropInFile(rc); err != nil {
 		return fmt.Errorf("failed to write local artifact mirror environment file: %w", err)
 	}
+	if proxy := rc.ProxySpec(); proxy != nil {
+		if err := h.writeHostFile(LocalArtifactMirrorProxyDropInFile(proxy)); err != nil {
+			return fmt.Errorf("failed to write local artifact mirror proxy file: %w", err)
+		}
+	}
 	if _, err := helpers.RunCommand("systemctl", "daemon-reload"); err != nil {
 		return fmt.Errorf("unable to get reload systemctl daemon: %w", err)
 	}
@@ -352,30 +357,46 @@ ExecStart=%s serve
 )
 
 func (h *HostUtils) WriteLocalArtifactMirrorDropInFile(rc runtimeconfig.RuntimeConfig) error {
-	contents := fmt.Sprintf(
+	if err := h.writeHostFile(LocalArtifactMirrorDropInFile(rc)); err != nil {
+		return fmt.Errorf("write drop-in file: %w", err)
+	}
+	return nil
+}
+
+// writeHostFile writes the file to the host, creating its directory.
+func (h *HostUtils) writeHostFile(file HostFile) error {
+	if err := h.fs.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	if err := h.fs.WriteFile(file.Path, []byte(file.Content), 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
+func localArtifactMirrorDropInContent(rc runtimeconfig.RuntimeConfig) string {
+	return fmt.Sprintf(
 		localArtifactMirrorDropInFileContents,
 		rc.LocalArtifactMirrorPort(),
 		rc.EmbeddedClusterHomeDirectory(),
 		rc.PathToEmbeddedClusterBinary("local-artifact-mirror"),
 	)
-	err := systemd.WriteDropInFile("local-artifact-mirror.service", "embedded-cluster.conf", []byte(contents))
-	if err != nil {
-		return fmt.Errorf("write drop-in file: %w", err)
-	}
-	return nil
+}
+
+func autopilotConfigContent(hostname string) string {
+	return fmt.Sprintf(`[Service]
+Environment="AUTOPILOT_HOSTNAME=%s"`, hostname)
 }
 
 // ensureAutopilotConfig creates a new autopilot-hostname.conf configuration file. The file is saved in the
 // systemd directory (/etc/systemd/system/k0s{controller,worker}.service.d/).
-func ensureAutopilotConfig(servicePath string, hostname string) error {
-	if err := os.MkdirAll(servicePath, 0755); err != nil {
+func ensureAutopilotConfig(fs FileSystem, servicePath string, hostname string) error {
+	if err := fs.MkdirAll(servicePath, 0755); err != nil {
 		return fmt.Errorf("unable to create directory: %w", err)
 	}
 
-	content := fmt.Sprintf(`[Service]
-Environment="AUTOPILOT_HOSTNAME=%s"`, hostname)
-
-	err := os.WriteFile(filepath.Join(servicePath, "autopilot-hostname.conf"), []byte(content), 0644)
+	content := autopilotConfigContent(hostname)
+	err := fs.WriteFile(filepath.Join(servicePath, "autopilot-hostname.conf"), []byte(content), 0644)
 	if err != nil {
 		return fmt.Errorf("unable to create and write autopilot hostname file: %w", err)
 	}
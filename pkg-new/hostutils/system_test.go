@@ -33,7 +33,7 @@ func TestSysctlConfig(t *testing.T) {
 	defer os.RemoveAll(dstdir)
 
 	sysctlConfigPath = filepath.Join(dstdir, "sysctl.conf")
-	err = sysctlConfig()
+	err = sysctlConfig(hostFileSystem{})
 	assert.NoError(t, err)
 
 	// check that the file exists.
@@ -117,7 +117,7 @@ func TestDynamicSysctlConfig(t *testing.T) {
 				return value, nil
 			}
 
-			err := generateDynamicSysctlConfig(mockGetter, configPath)
+			err := generateDynamicSysctlConfig(hostFileSystem{}, mockGetter, configPath)
 			require.NoError(t, err)
 
 			// Read generated file
@@ -223,7 +223,7 @@ func Test_ensureProxyConfig(t *testing.T) {
 			tempDir := t.TempDir()
 			servicePath := filepath.Join(tempDir, "k0scontroller.service.d")
 
-			err := ensureProxyConfig(servicePath, tt.httpProxy, tt.httpsProxy, tt.noProxy)
+			err := ensureProxyConfig(hostFileSystem{}, servicePath, tt.httpProxy, tt.httpsProxy, tt.noProxy)
 			if tt.expectError {
 				assert.Error(t, err)
 				return
@@ -283,7 +283,7 @@ func Test_ensureAutopilotConfig(t *testing.T) {
 			tempDir := t.TempDir()
 			servicePath := filepath.Join(tempDir, "k0scontroller.service.d")
 
-			err := ensureAutopilotConfig(servicePath, tt.hostname)
+			err := ensureAutopilotConfig(hostFileSystem{}, servicePath, tt.hostname)
 			if tt.expectError {
 				assert.Error(t, err)
 				return
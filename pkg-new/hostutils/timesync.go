@@ -0,0 +1,159 @@
+package hostutils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+)
+
+const (
+	timeSyncBlockBegin = "# BEGIN embedded-cluster ntp servers"
+	timeSyncBlockEnd   = "# END embedded-cluster ntp servers"
+)
+
+// chronyConfigs are the main chrony config files and the name of the chrony unit, the paths
+// and the unit differ between the RHEL and the Debian families.
+var chronyConfigs = []struct {
+	path string
+	unit string
+}{
+	{path: "/etc/chrony.conf", unit: "chronyd"},
+	{path: "/etc/chrony/chrony.conf", unit: "chrony"},
+}
+
+// timesyncdBinaryPaths are the paths systemd-timesyncd is installed at.
+var timesyncdBinaryPaths = []string{"/usr/lib/systemd/systemd-timesyncd", "/lib/systemd/systemd-timesyncd"}
+
+// timesyncdDropInPath is the path to the systemd-timesyncd config file setting the NTP servers.
+var timesyncdDropInPath = "/etc/systemd/timesyncd.conf.d/99-embedded-cluster.conf"
+
+// timeSyncWait is how long to wait for the clock to synchronize once the servers are set.
+var timeSyncWait = time.Minute
+
+// timeSyncChange is the change ConfigureTimeSync makes to the host: the config file written and
+// the commands run to apply it.
+type timeSyncChange struct {
+	file     HostFile
+	commands [][]string
+}
+
+// ConfigureTimeSync configures the time synchronization service of the host to use the NTP
+// servers. chrony is configured when it is installed, systemd-timesyncd otherwise. The clock is
+// given a short while to synchronize, the host preflights report it if it does not.
+func (h *HostUtils) ConfigureTimeSync(ctx context.Context, servers []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	change, err := planTimeSync(servers)
+	if err != nil {
+		return err
+	}
+
+	h.logger.Debugf("writing time synchronization config to %s", change.file.Path)
+	if err := h.fs.MkdirAll(filepath.Dir(change.file.Path), 0755); err != nil {
+		return fmt.Errorf("create time synchronization config directory: %w", err)
+	}
+	if err := h.fs.WriteFile(change.file.Path, []byte(change.file.Content), 0644); err != nil {
+		return fmt.Errorf("write time synchronization config: %w", err)
+	}
+	for _, command := range change.commands {
+		if _, err := helpers.RunCommand(command[0], command[1:]...); err != nil {
+			return fmt.Errorf("run %s: %w", strings.Join(command, " "), err)
+		}
+	}
+
+	h.logger.Debugf("waiting for the clock to synchronize")
+	if !waitForClockSync(ctx, timeSyncWait) {
+		h.logger.Warnf("The clock did not synchronize with %s within %s.", strings.Join(servers, ", "), timeSyncWait)
+	}
+	return nil
+}
+
+// planTimeSync returns the change that sets the NTP servers of the time synchronization service
+// installed on the host.
+func planTimeSync(servers []string) (*timeSyncChange, error) {
+	for _, chrony := range chronyConfigs {
+		content, err := os.ReadFile(chrony.path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("read chrony config: %w", err)
+		}
+
+		lines := []string{}
+		for _, server := range servers {
+			lines = append(lines, fmt.Sprintf("server %s iburst", server))
+		}
+		return &timeSyncChange{
+			file: HostFile{Path: chrony.path, Content: replaceTimeSyncBlock(string(content), lines)},
+			commands: [][]string{
+				{"systemctl", "enable", chrony.unit},
+				{"systemctl", "restart", chrony.unit},
+			},
+		}, nil
+	}
+
+	if !anyFileExists(timesyncdBinaryPaths) {
+		return nil, fmt.Errorf("neither chrony nor systemd-timesyncd is installed")
+	}
+	return &timeSyncChange{
+		file: HostFile{Path: timesyncdDropInPath, Content: fmt.Sprintf("[Time]\nNTP=%s\n", strings.Join(servers, " "))},
+		commands: [][]string{
+			{"timedatectl", "set-ntp", "true"},
+			{"systemctl", "restart", "systemd-timesyncd"},
+		},
+	}, nil
+}
+
+// replaceTimeSyncBlock returns the config with the block of lines managed by the installer set
+// to lines. The block is added at the end of the config the first time.
+func replaceTimeSyncBlock(config string, lines []string) string {
+	block := strings.Join(append(append([]string{timeSyncBlockBegin}, lines...), timeSyncBlockEnd), "\n") + "\n"
+
+	start := strings.Index(config, timeSyncBlockBegin)
+	end := strings.Index(config, timeSyncBlockEnd)
+	if start >= 0 && end > start {
+		rest := strings.TrimPrefix(config[end+len(timeSyncBlockEnd):], "\n")
+		return config[:start] + block + rest
+	}
+
+	if config != "" && !strings.HasSuffix(config, "\n") {
+		config += "\n"
+	}
+	return config + block
+}
+
+// waitForClockSync waits until the kernel reports the clock as synchronized, as shown by
+// timedatectl, and returns false if it is not within the timeout.
+func waitForClockSync(ctx context.Context, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		out, err := helpers.RunCommand("timedatectl", "show", "-p", "NTPSynchronized", "--value")
+		if err == nil && strings.TrimSpace(out) == "yes" {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func anyFileExists(paths []string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
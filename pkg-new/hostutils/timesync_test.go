@@ -0,0 +1,81 @@
+package hostutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceTimeSyncBlock(t *testing.T) {
+	lines := []string{"server 10.0.0.1 iburst", "server 10.0.0.2 iburst"}
+	block := "# BEGIN embedded-cluster ntp servers\nserver 10.0.0.1 iburst\nserver 10.0.0.2 iburst\n# END embedded-cluster ntp servers\n"
+
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "empty config",
+			config: "",
+			want:   block,
+		},
+		{
+			name:   "block added at the end",
+			config: "pool 2.pool.ntp.org iburst\nmakestep 1.0 3",
+			want:   "pool 2.pool.ntp.org iburst\nmakestep 1.0 3\n" + block,
+		},
+		{
+			name:   "block replaced",
+			config: "pool 2.pool.ntp.org iburst\n# BEGIN embedded-cluster ntp servers\nserver 192.168.0.1 iburst\n# END embedded-cluster ntp servers\nmakestep 1.0 3\n",
+			want:   "pool 2.pool.ntp.org iburst\n" + block + "makestep 1.0 3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, replaceTimeSyncBlock(tt.config, lines))
+		})
+	}
+}
+
+func TestPlanTimeSync(t *testing.T) {
+	origChrony, origTimesyncd, origDropIn := chronyConfigs, timesyncdBinaryPaths, timesyncdDropInPath
+	defer func() {
+		chronyConfigs, timesyncdBinaryPaths, timesyncdDropInPath = origChrony, origTimesyncd, origDropIn
+	}()
+
+	dir := t.TempDir()
+	chronyPath := filepath.Join(dir, "chrony.conf")
+	timesyncdPath := filepath.Join(dir, "systemd-timesyncd")
+	chronyConfigs = []struct {
+		path string
+		unit string
+	}{{path: chronyPath, unit: "chronyd"}}
+	timesyncdBinaryPaths = []string{timesyncdPath}
+	timesyncdDropInPath = filepath.Join(dir, "timesyncd.conf.d", "99-embedded-cluster.conf")
+
+	servers := []string{"10.0.0.1", "10.0.0.2"}
+
+	// nothing installed
+	_, err := planTimeSync(servers)
+	require.ErrorContains(t, err, "neither chrony nor systemd-timesyncd is installed")
+
+	// systemd-timesyncd installed
+	require.NoError(t, os.WriteFile(timesyncdPath, nil, 0755))
+	change, err := planTimeSync(servers)
+	require.NoError(t, err)
+	assert.Equal(t, timesyncdDropInPath, change.file.Path)
+	assert.Equal(t, "[Time]\nNTP=10.0.0.1 10.0.0.2\n", change.file.Content)
+	assert.Equal(t, [][]string{{"timedatectl", "set-ntp", "true"}, {"systemctl", "restart", "systemd-timesyncd"}}, change.commands)
+
+	// chrony is preferred
+	require.NoError(t, os.WriteFile(chronyPath, []byte("pool 2.pool.ntp.org iburst\n"), 0644))
+	change, err = planTimeSync(servers)
+	require.NoError(t, err)
+	assert.Equal(t, chronyPath, change.file.Path)
+	assert.Contains(t, change.file.Content, "pool 2.pool.ntp.org iburst\n# BEGIN embedded-cluster ntp servers\nserver 10.0.0.1 iburst\n")
+	assert.Equal(t, [][]string{{"systemctl", "enable", "chronyd"}, {"systemctl", "restart", "chronyd"}}, change.commands)
+}
@@ -0,0 +1,61 @@
+package installconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ParseFile reads, parses and validates the InstallConfig file at the given path. Relative
+// paths in the file are resolved against the directory holding it.
+func ParseFile(path string) (*InstallConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read install config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("construct path for install config file: %w", err)
+	}
+	cfg.resolvePaths(filepath.Dir(absPath))
+
+	return cfg, nil
+}
+
+// Parse parses and validates an InstallConfig document. Unknown fields are rejected so typos
+// don't go unnoticed.
+func Parse(data []byte) (*InstallConfig, error) {
+	var cfg InstallConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, fmt.Errorf("unmarshal install config: %w", err)
+	}
+
+	if err := Validate(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid install config: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+func (c *InstallConfig) resolvePaths(baseDir string) {
+	for _, p := range []*string{
+		&c.Spec.License,
+		&c.Spec.AirgapBundle,
+		&c.Spec.ConfigValues,
+		&c.Spec.DataDir,
+		&c.Spec.AdminConsole.TLSCert,
+		&c.Spec.AdminConsole.TLSKey,
+	} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(baseDir, *p)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package installconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *InstallConfig
+		wantErr string
+	}{
+		{
+			name: "valid config",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: InstallConfig
+spec:
+  license: /tmp/license.yaml
+  dataDir: /opt/app
+  adminConsole:
+    port: 30001
+    password: password
+  network:
+    cidr: 10.0.0.0/16
+  proxy:
+    httpProxy: http://proxy:3128
+    noProxy: 10.0.0.0/8
+`,
+			want: &InstallConfig{
+				APIVersion: APIVersion,
+				Kind:       Kind,
+				Spec: InstallConfigSpec{
+					License: "/tmp/license.yaml",
+					DataDir: "/opt/app",
+					AdminConsole: AdminConsoleSpec{
+						Port:     30001,
+						Password: "password",
+					},
+					Network: NetworkSpec{
+						CIDR: "10.0.0.0/16",
+					},
+					Proxy: ProxySpec{
+						HTTPProxy: "http://proxy:3128",
+						NoProxy:   "10.0.0.0/8",
+					},
+				},
+			},
+		},
+		{
+			name: "unknown field",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: InstallConfig
+spec:
+  licence: /tmp/license.yaml
+`,
+			wantErr: "unmarshal install config",
+		},
+		{
+			name: "unsupported version",
+			data: `apiVersion: embeddedcluster.replicated.com/v1alpha1
+kind: InstallConfig
+`,
+			wantErr: "apiVersion: Unsupported value",
+		},
+		{
+			name:    "not yaml",
+			data:    `{{`,
+			wantErr: "unmarshal install config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "install.yaml")
+
+	data := `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: InstallConfig
+spec:
+  license: license.yaml
+  airgapBundle: /bundles/app.airgap
+  adminConsole:
+    tlsCert: certs/tls.crt
+    tlsKey: certs/tls.key
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	got, err := ParseFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, "license.yaml"), got.Spec.License)
+	assert.Equal(t, "/bundles/app.airgap", got.Spec.AirgapBundle)
+	assert.Equal(t, filepath.Join(dir, "certs/tls.crt"), got.Spec.AdminConsole.TLSCert)
+	assert.Equal(t, filepath.Join(dir, "certs/tls.key"), got.Spec.AdminConsole.TLSKey)
+
+	_, err = ParseFile(filepath.Join(dir, "missing.yaml"))
+	require.ErrorContains(t, err, "read install config file")
+}
@@ -0,0 +1,74 @@
+// Package installconfig implements the InstallConfig document, a versioned YAML answers file
+// that holds the values otherwise passed to the install command as flags.
+package installconfig
+
+const (
+	// APIVersion is the only supported apiVersion of the InstallConfig document.
+	APIVersion = "embeddedcluster.replicated.com/v1beta1"
+	// Kind is the kind of the InstallConfig document.
+	Kind = "InstallConfig"
+)
+
+// InstallConfig holds the answers used to install a cluster without passing them as flags.
+type InstallConfig struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       InstallConfigSpec `json:"spec"`
+}
+
+// InstallConfigSpec holds the install answers. Relative paths are resolved against the
+// directory holding the InstallConfig file.
+type InstallConfigSpec struct {
+	// License is the path to the license file.
+	License string `json:"license,omitempty"`
+	// AirgapBundle is the path to the air gap bundle.
+	AirgapBundle string `json:"airgapBundle,omitempty"`
+	// ConfigValues is the path to the config values to use when installing.
+	ConfigValues string `json:"configValues,omitempty"`
+	// DataDir is the path to the data directory.
+	DataDir string `json:"dataDir,omitempty"`
+	// LocalArtifactMirrorPort is the port on which the Local Artifact Mirror is served.
+	LocalArtifactMirrorPort int `json:"localArtifactMirrorPort,omitempty"`
+	// IgnoreHostPreflights allows bypassing host preflight failures.
+	IgnoreHostPreflights bool `json:"ignoreHostPreflights,omitempty"`
+	// IgnoreAppPreflights allows bypassing app preflight failures.
+	IgnoreAppPreflights bool `json:"ignoreAppPreflights,omitempty"`
+
+	AdminConsole AdminConsoleSpec `json:"adminConsole,omitempty"`
+	Network      NetworkSpec      `json:"network,omitempty"`
+	Proxy        ProxySpec        `json:"proxy,omitempty"`
+}
+
+// AdminConsoleSpec holds the Admin Console answers.
+type AdminConsoleSpec struct {
+	Port     int    `json:"port,omitempty"`
+	Password string `json:"password,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	// Address is the IP address or the hostname the Admin Console is advertised on.
+	Address string `json:"address,omitempty"`
+	// TLSCert and TLSKey are the paths to the TLS certificate and key for the Admin Console.
+	TLSCert string `json:"tlsCert,omitempty"`
+	TLSKey  string `json:"tlsKey,omitempty"`
+}
+
+// NetworkSpec holds the network answers. CIDR is split into the pod and service ranges and
+// can't be combined with PodCIDR or ServiceCIDR. IPv6CIDR is split into the IPv6 ranges of a
+// dual-stack cluster.
+type NetworkSpec struct {
+	Interface   string `json:"interface,omitempty"`
+	CIDR        string `json:"cidr,omitempty"`
+	PodCIDR     string `json:"podCIDR,omitempty"`
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+	IPv6CIDR    string `json:"ipv6CIDR,omitempty"`
+	// ControlPlaneInterface and PodNetworkInterface split the traffic of Interface between
+	// the Kubernetes API and etcd on one side and the pods on the other.
+	ControlPlaneInterface string `json:"controlPlaneInterface,omitempty"`
+	PodNetworkInterface   string `json:"podNetworkInterface,omitempty"`
+}
+
+// ProxySpec holds the proxy answers.
+type ProxySpec struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	NoProxy    string `json:"noProxy,omitempty"`
+}
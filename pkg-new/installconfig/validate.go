@@ -0,0 +1,146 @@
+package installconfig
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate validates the InstallConfig. The returned error aggregates every problem found,
+// each one prefixed with the path of the offending field.
+func Validate(cfg *InstallConfig) error {
+	var errs field.ErrorList
+
+	if cfg.APIVersion == "" {
+		errs = append(errs, field.Required(field.NewPath("apiVersion"), ""))
+	} else if cfg.APIVersion != APIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), cfg.APIVersion, []string{APIVersion}))
+	}
+
+	if cfg.Kind == "" {
+		errs = append(errs, field.Required(field.NewPath("kind"), ""))
+	} else if cfg.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), cfg.Kind, []string{Kind}))
+	}
+
+	errs = append(errs, validateSpec(cfg.Spec, field.NewPath("spec"))...)
+
+	return errs.ToAggregate()
+}
+
+func validateSpec(spec InstallConfigSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validatePort(spec.LocalArtifactMirrorPort, path.Child("localArtifactMirrorPort"))...)
+	errs = append(errs, validateAdminConsole(spec.AdminConsole, path.Child("adminConsole"))...)
+	errs = append(errs, validateNetwork(spec.Network, path.Child("network"))...)
+	errs = append(errs, validateProxy(spec.Proxy, path.Child("proxy"))...)
+
+	if spec.LocalArtifactMirrorPort != 0 && spec.LocalArtifactMirrorPort == spec.AdminConsole.Port {
+		errs = append(errs, field.Duplicate(path.Child("localArtifactMirrorPort"), spec.LocalArtifactMirrorPort))
+	}
+
+	return errs
+}
+
+func validateAdminConsole(spec AdminConsoleSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validatePort(spec.Port, path.Child("port"))...)
+
+	if spec.Address != "" {
+		if err := netutils.ValidateHostAddress(spec.Address); err != nil {
+			errs = append(errs, field.Invalid(path.Child("address"), spec.Address, err.Error()))
+		}
+	}
+
+	if spec.TLSCert != "" && spec.TLSKey == "" {
+		errs = append(errs, field.Required(path.Child("tlsKey"), "must be set together with tlsCert"))
+	}
+	if spec.TLSKey != "" && spec.TLSCert == "" {
+		errs = append(errs, field.Required(path.Child("tlsCert"), "must be set together with tlsKey"))
+	}
+
+	return errs
+}
+
+func validateNetwork(spec NetworkSpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.CIDR != "" {
+		validate := func(cidr string) error { return netutils.ValidateCIDR(cidr, 16, true) }
+		if netutils.IsIPv6CIDR(spec.CIDR) {
+			validate = netutils.ValidateIPv6CIDR
+		}
+		if err := validate(spec.CIDR); err != nil {
+			errs = append(errs, field.Invalid(path.Child("cidr"), spec.CIDR, err.Error()))
+		}
+		if spec.PodCIDR != "" || spec.ServiceCIDR != "" {
+			errs = append(errs, field.Forbidden(path.Child("cidr"), "cannot be used together with podCIDR or serviceCIDR"))
+		}
+	}
+
+	if spec.PodCIDR != "" {
+		if _, _, err := net.ParseCIDR(spec.PodCIDR); err != nil {
+			errs = append(errs, field.Invalid(path.Child("podCIDR"), spec.PodCIDR, err.Error()))
+		}
+	}
+	if spec.ServiceCIDR != "" {
+		if _, _, err := net.ParseCIDR(spec.ServiceCIDR); err != nil {
+			errs = append(errs, field.Invalid(path.Child("serviceCIDR"), spec.ServiceCIDR, err.Error()))
+		}
+	}
+
+	if spec.IPv6CIDR != "" {
+		if err := netutils.ValidateIPv6CIDR(spec.IPv6CIDR); err != nil {
+			errs = append(errs, field.Invalid(path.Child("ipv6CIDR"), spec.IPv6CIDR, err.Error()))
+		}
+		if netutils.IsIPv6CIDR(spec.CIDR) || netutils.IsIPv6CIDR(spec.PodCIDR) {
+			errs = append(errs, field.Forbidden(path.Child("ipv6CIDR"), "cannot be used together with IPv6 CIDRs, they install an IPv6 only cluster"))
+		}
+	}
+
+	if spec.PodNetworkInterface != "" {
+		if _, err := regexp.Compile(spec.PodNetworkInterface); err != nil {
+			errs = append(errs, field.Invalid(path.Child("podNetworkInterface"), spec.PodNetworkInterface, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+func validateProxy(spec ProxySpec, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+
+	errs = append(errs, validateProxyURL(spec.HTTPProxy, path.Child("httpProxy"))...)
+	errs = append(errs, validateProxyURL(spec.HTTPSProxy, path.Child("httpsProxy"))...)
+
+	return errs
+}
+
+func validateProxyURL(value string, path *field.Path) field.ErrorList {
+	if value == "" {
+		return nil
+	}
+
+	// proxy urls may hold credentials so the value is left out of the error
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, "must be a URL with a scheme and a host, e.g. http://proxy.example.com:3128")}
+	}
+
+	return nil
+}
+
+func validatePort(port int, path *field.Path) field.ErrorList {
+	if port == 0 {
+		return nil
+	}
+	if port < 1 || port > 65535 {
+		return field.ErrorList{field.Invalid(path, port, "must be between 1 and 65535")}
+	}
+	return nil
+}
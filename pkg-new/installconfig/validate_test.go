@@ -0,0 +1,143 @@
+package installconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     InstallConfigSpec
+		wantErrs []string
+	}{
+		{
+			name: "empty spec",
+			spec: InstallConfigSpec{},
+		},
+		{
+			name: "port out of range",
+			spec: InstallConfigSpec{
+				LocalArtifactMirrorPort: 70000,
+				AdminConsole:            AdminConsoleSpec{Port: -1},
+			},
+			wantErrs: []string{
+				"spec.localArtifactMirrorPort: Invalid value: 70000",
+				"spec.adminConsole.port: Invalid value: -1",
+			},
+		},
+		{
+			name: "same port for admin console and local artifact mirror",
+			spec: InstallConfigSpec{
+				LocalArtifactMirrorPort: 30000,
+				AdminConsole:            AdminConsoleSpec{Port: 30000},
+			},
+			wantErrs: []string{"spec.localArtifactMirrorPort: Duplicate value: 30000"},
+		},
+		{
+			name: "tls cert without key",
+			spec: InstallConfigSpec{
+				AdminConsole: AdminConsoleSpec{TLSCert: "tls.crt"},
+			},
+			wantErrs: []string{"spec.adminConsole.tlsKey: Required value"},
+		},
+		{
+			name: "admin console address with a port",
+			spec: InstallConfigSpec{
+				AdminConsole: AdminConsoleSpec{Address: "console.example.com:30000"},
+			},
+			wantErrs: []string{"spec.adminConsole.address: Invalid value"},
+		},
+		{
+			name: "separate interfaces",
+			spec: InstallConfigSpec{
+				AdminConsole: AdminConsoleSpec{Address: "console.example.com"},
+				Network:      NetworkSpec{ControlPlaneInterface: "eth0", PodNetworkInterface: "eth1|ens.*"},
+			},
+		},
+		{
+			name: "invalid pod network interface expression",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{PodNetworkInterface: "eth("},
+			},
+			wantErrs: []string{"spec.network.podNetworkInterface: Invalid value"},
+		},
+		{
+			name: "cidr combined with pod cidr",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{CIDR: "10.0.0.0/16", PodCIDR: "10.1.0.0/16"},
+			},
+			wantErrs: []string{"spec.network.cidr: Forbidden"},
+		},
+		{
+			name: "invalid cidrs",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{PodCIDR: "10.1.0.0", ServiceCIDR: "nope"},
+			},
+			wantErrs: []string{
+				"spec.network.podCIDR: Invalid value",
+				"spec.network.serviceCIDR: Invalid value",
+			},
+		},
+		{
+			name: "ipv6 only cidr",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{CIDR: "fd00:10::/48"},
+			},
+		},
+		{
+			name: "dual-stack cidrs",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{CIDR: "10.0.0.0/16", IPv6CIDR: "fd00:10::/48"},
+			},
+		},
+		{
+			name: "ipv6 cidr with an ipv6 only cidr",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{CIDR: "fd00:10::/48", IPv6CIDR: "fd00:20::/48"},
+			},
+			wantErrs: []string{"spec.network.ipv6CIDR: Forbidden"},
+		},
+		{
+			name: "ipv6 cidr outside the unique local range",
+			spec: InstallConfigSpec{
+				Network: NetworkSpec{IPv6CIDR: "2001:db8::/48"},
+			},
+			wantErrs: []string{"spec.network.ipv6CIDR: Invalid value"},
+		},
+		{
+			name: "proxy without scheme does not leak credentials",
+			spec: InstallConfigSpec{
+				Proxy: ProxySpec{HTTPSProxy: "user:secret@proxy"},
+			},
+			wantErrs: []string{"spec.proxy.httpsProxy: Invalid value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&InstallConfig{APIVersion: APIVersion, Kind: Kind, Spec: tt.spec})
+			if len(tt.wantErrs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErrs {
+				assert.Contains(t, err.Error(), want)
+			}
+			assert.NotContains(t, err.Error(), "secret")
+		})
+	}
+}
+
+func TestValidate_TypeMeta(t *testing.T) {
+	err := Validate(&InstallConfig{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "apiVersion: Required value")
+	assert.Contains(t, err.Error(), "kind: Required value")
+
+	err = Validate(&InstallConfig{APIVersion: APIVersion, Kind: "Config"})
+	require.ErrorContains(t, err, `kind: Unsupported value: "Config"`)
+}
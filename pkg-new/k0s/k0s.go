@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
@@ -86,11 +87,11 @@ func (k *K0s) Install(rc runtimeconfig.RuntimeConfig, hostname string) error {
 		return fmt.Errorf("unable to move k0s binary: %w", err)
 	}
 
-	nodeIP, err := netutils.FirstValidAddress(rc.NetworkInterface())
+	nodeIPs, err := netutils.NodeAddresses(rc.NetworkInterface(), netutils.ClusterIPFamily(rc.PodCIDR(), rc.IPv6PodCIDR()))
 	if err != nil {
 		return fmt.Errorf("unable to find first valid address: %w", err)
 	}
-	flags, err := config.InstallFlags(rc, nodeIP, hostname)
+	flags, err := config.InstallFlags(rc, strings.Join(nodeIPs, ","), hostname)
 	if err != nil {
 		return fmt.Errorf("unable to get install flags: %w", err)
 	}
@@ -126,12 +127,13 @@ func (k *K0s) NewK0sConfig(networkInterface string, isAirgap bool, podCIDR strin
 	domains := domains.GetDomains(embCfgSpec, release.GetChannelRelease())
 	cfg := config.RenderK0sConfig(domains.ProxyRegistryDomain)
 
-	address, err := netutils.FirstValidAddress(networkInterface)
+	// the api and etcd listen on the ipv6 address of the node in ipv6 only clusters
+	addresses, err := netutils.NodeAddresses(networkInterface, netutils.ClusterIPFamily(podCIDR, ""))
 	if err != nil {
 		return nil, fmt.Errorf("unable to find first valid address: %w", err)
 	}
-	cfg.Spec.API.Address = address
-	cfg.Spec.Storage.Etcd.PeerAddress = address
+	cfg.Spec.API.Address = addresses[0]
+	cfg.Spec.Storage.Etcd.PeerAddress = addresses[0]
 
 	cfg.Spec.Network.PodCIDR = podCIDR
 	cfg.Spec.Network.ServiceCIDR = serviceCIDR
@@ -0,0 +1,66 @@
+package nodeinventory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ParseFile reads, parses and validates the NodeInventory file at the given path. Relative
+// paths in the file are resolved against the directory holding it. Nodes listed with
+// controllerRoleName, the name of the controller role in the release, join the control plane.
+func ParseFile(path string, controllerRoleName string) (*NodeInventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read node inventory file: %w", err)
+	}
+
+	inv, err := Parse(data, controllerRoleName)
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("construct path for node inventory file: %w", err)
+	}
+	inv.resolvePaths(filepath.Dir(absPath))
+
+	return inv, nil
+}
+
+// Parse parses and validates a NodeInventory document. Unknown fields are rejected so typos
+// don't go unnoticed.
+func Parse(data []byte, controllerRoleName string) (*NodeInventory, error) {
+	inv := NodeInventory{controllerRoleName: controllerRoleName}
+	if err := yaml.UnmarshalStrict(data, &inv); err != nil {
+		return nil, fmt.Errorf("unmarshal node inventory: %w", err)
+	}
+
+	if err := Validate(&inv); err != nil {
+		return nil, fmt.Errorf("invalid node inventory: %w", err)
+	}
+
+	return &inv, nil
+}
+
+func (i *NodeInventory) resolvePaths(baseDir string) {
+	specs := []*SSHSpec{&i.Spec.SSH}
+	for _, node := range i.Spec.Nodes {
+		if node.SSH != nil {
+			specs = append(specs, node.SSH)
+		}
+	}
+
+	for _, spec := range specs {
+		for _, p := range []*string{&spec.PrivateKeyPath, &spec.KnownHostsPath} {
+			// paths relative to the home directory are expanded when connecting
+			if *p != "" && !filepath.IsAbs(*p) && !strings.HasPrefix(*p, "~") {
+				*p = filepath.Join(baseDir, *p)
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package nodeinventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name               string
+		data               string
+		controllerRoleName string
+		want               *NodeInventory
+		wantErr            string
+	}{
+		{
+			name: "valid inventory",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: NodeInventory
+spec:
+  ssh:
+    user: ubuntu
+    privateKeyPath: /home/ubuntu/.ssh/id_ed25519
+  joinTokens:
+    worker: abcdef
+  nodes:
+  - address: 10.0.0.2
+    role: controller
+  - address: 10.0.0.3
+    role: worker
+    ssh:
+      port: 2222
+`,
+			want: &NodeInventory{
+				APIVersion:         APIVersion,
+				Kind:               Kind,
+				controllerRoleName: RoleController,
+				Spec: NodeInventorySpec{
+					SSH: SSHSpec{
+						User:           "ubuntu",
+						PrivateKeyPath: "/home/ubuntu/.ssh/id_ed25519",
+					},
+					JoinTokens: map[string]string{"worker": "abcdef"},
+					Nodes: []Node{
+						{Address: "10.0.0.2", Role: RoleController},
+						{Address: "10.0.0.3", Role: "worker", SSH: &SSHSpec{Port: 2222}},
+					},
+				},
+			},
+		},
+		{
+			name: "renamed controller role",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: NodeInventory
+spec:
+  nodes:
+  - address: 10.0.0.2
+    role: management
+`,
+			controllerRoleName: "management",
+			want: &NodeInventory{
+				APIVersion:         APIVersion,
+				Kind:               Kind,
+				controllerRoleName: "management",
+				Spec: NodeInventorySpec{
+					Nodes: []Node{{Address: "10.0.0.2", Role: "management"}},
+				},
+			},
+		},
+		{
+			name: "controller role renamed in the release",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: NodeInventory
+spec:
+  nodes:
+  - address: 10.0.0.2
+    role: controller
+`,
+			controllerRoleName: "management",
+			want: &NodeInventory{
+				APIVersion:         APIVersion,
+				Kind:               Kind,
+				controllerRoleName: "management",
+				Spec: NodeInventorySpec{
+					Nodes: []Node{{Address: "10.0.0.2", Role: "controller"}},
+				},
+			},
+		},
+		{
+			name: "unknown field",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: NodeInventory
+spec:
+  hosts:
+  - address: 10.0.0.2
+`,
+			wantErr: "unmarshal node inventory",
+		},
+		{
+			name: "wrong kind",
+			data: `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: InstallConfig
+spec:
+  nodes:
+  - address: 10.0.0.2
+    role: controller
+`,
+			wantErr: "kind: Unsupported value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controllerRoleName := tt.controllerRoleName
+			if controllerRoleName == "" {
+				controllerRoleName = RoleController
+			}
+			got, err := Parse([]byte(tt.data), controllerRoleName)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nodes.yaml")
+
+	data := `apiVersion: embeddedcluster.replicated.com/v1beta1
+kind: NodeInventory
+spec:
+  ssh:
+    privateKeyPath: keys/id_rsa
+    knownHostsPath: ~/.ssh/known_hosts
+  nodes:
+  - address: 10.0.0.2
+    role: controller
+    ssh:
+      privateKeyPath: /keys/controller
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+
+	got, err := ParseFile(path, RoleController)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(dir, "keys/id_rsa"), got.Spec.SSH.PrivateKeyPath)
+	assert.Equal(t, "~/.ssh/known_hosts", got.Spec.SSH.KnownHostsPath)
+	assert.Equal(t, "/keys/controller", got.Spec.Nodes[0].SSH.PrivateKeyPath)
+
+	_, err = ParseFile(filepath.Join(dir, "missing.yaml"), RoleController)
+	require.ErrorContains(t, err, "read node inventory file")
+}
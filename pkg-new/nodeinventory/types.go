@@ -0,0 +1,160 @@
+// Package nodeinventory implements the NodeInventory document, a versioned YAML file listing
+// the hosts to join to a cluster over SSH and the role each of them joins with.
+package nodeinventory
+
+const (
+	// APIVersion is the only supported apiVersion of the NodeInventory document.
+	APIVersion = "embeddedcluster.replicated.com/v1beta1"
+	// Kind is the kind of the NodeInventory document.
+	Kind = "NodeInventory"
+
+	// RoleController is the default name of the role of nodes joining the control plane. The
+	// vendor can rename it, see ParseFile. The join command for controllers is fetched from the
+	// cluster, all other roles need a join token unless the cluster is being installed.
+	RoleController = "controller"
+
+	// DefaultSSHUser, DefaultSSHPort and DefaultSSHPrivateKeyPath are used for nodes that don't
+	// set their own SSH settings when the inventory doesn't set them either.
+	DefaultSSHUser           = "root"
+	DefaultSSHPort           = 22
+	DefaultSSHPrivateKeyPath = "~/.ssh/id_rsa"
+)
+
+// NodeInventory lists the nodes to join to the cluster.
+type NodeInventory struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       NodeInventorySpec `json:"spec"`
+
+	// controllerRoleName is the name of the controller role in the release, RoleController
+	// when empty.
+	controllerRoleName string
+}
+
+// NodeInventorySpec holds the nodes and the settings shared by all of them. Relative paths are
+// resolved against the directory holding the NodeInventory file.
+type NodeInventorySpec struct {
+	// SSH holds the SSH settings used for nodes that don't set their own.
+	SSH SSHSpec `json:"ssh,omitempty"`
+	// JoinTokens maps a role to the join token generated for it in the Admin Console. Tokens
+	// are required for every role other than controller when joining nodes to an existing
+	// cluster, an install generates them once the cluster is up.
+	JoinTokens map[string]string `json:"joinTokens,omitempty"`
+	// NoHA disables high availability when the third controller joins.
+	NoHA bool `json:"noHA,omitempty"`
+	// NetworkInterface is the network interface the nodes use for the cluster.
+	NetworkInterface string `json:"networkInterface,omitempty"`
+	// ControlPlaneInterface is the network interface the Kubernetes API and etcd of the
+	// controllers advertise their address on, when it is not NetworkInterface.
+	ControlPlaneInterface string `json:"controlPlaneInterface,omitempty"`
+	// NTPServers are configured as the time synchronization servers of the nodes before they
+	// join.
+	NTPServers []string `json:"ntpServers,omitempty"`
+	// Nodes are the nodes to join. Controllers join first, one at a time, in the order they
+	// are listed.
+	Nodes []Node `json:"nodes"`
+}
+
+// SSHSpec holds the settings used to connect to a node.
+type SSHSpec struct {
+	User string `json:"user,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// PrivateKeyPath is the path to the private key used to authenticate.
+	PrivateKeyPath string `json:"privateKeyPath,omitempty"`
+	// KnownHostsPath is the path to the known_hosts file used to verify the host keys. It
+	// defaults to ~/.ssh/known_hosts.
+	KnownHostsPath string `json:"knownHostsPath,omitempty"`
+	// InsecureIgnoreHostKey disables host key verification.
+	InsecureIgnoreHostKey bool `json:"insecureIgnoreHostKey,omitempty"`
+}
+
+// Node is a host to join to the cluster.
+type Node struct {
+	// Address is the hostname or IP address used to reach the node over SSH.
+	Address string `json:"address"`
+	// Role is the role the node joins with.
+	Role string `json:"role"`
+	// SSH overrides the SSH settings of the inventory for this node.
+	SSH *SSHSpec `json:"ssh,omitempty"`
+}
+
+// ControllerRoleName returns the name nodes joining the control plane are listed with.
+func (i *NodeInventory) ControllerRoleName() string {
+	if i.controllerRoleName == "" {
+		return RoleController
+	}
+	return i.controllerRoleName
+}
+
+// IsController returns true if the node joins the control plane.
+func (i *NodeInventory) IsController(node Node) bool {
+	return node.Role == i.ControllerRoleName()
+}
+
+// Roles returns the roles nodes other than the controllers join with, once each, in the order
+// they are first listed.
+func (i *NodeInventory) Roles() []string {
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, node := range i.Spec.Nodes {
+		if i.IsController(node) || seen[node.Role] {
+			continue
+		}
+		seen[node.Role] = true
+		roles = append(roles, node.Role)
+	}
+	return roles
+}
+
+// SSHSpec returns the SSH settings for the node, merging its own settings on top of the
+// inventory ones and filling in the defaults.
+func (i *NodeInventory) SSHSpec(node Node) SSHSpec {
+	spec := i.Spec.SSH
+	if node.SSH != nil {
+		if node.SSH.User != "" {
+			spec.User = node.SSH.User
+		}
+		if node.SSH.Port != 0 {
+			spec.Port = node.SSH.Port
+		}
+		if node.SSH.PrivateKeyPath != "" {
+			spec.PrivateKeyPath = node.SSH.PrivateKeyPath
+		}
+		if node.SSH.KnownHostsPath != "" {
+			spec.KnownHostsPath = node.SSH.KnownHostsPath
+		}
+		if node.SSH.InsecureIgnoreHostKey {
+			spec.InsecureIgnoreHostKey = true
+		}
+	}
+
+	if spec.User == "" {
+		spec.User = DefaultSSHUser
+	}
+	if spec.Port == 0 {
+		spec.Port = DefaultSSHPort
+	}
+	if spec.PrivateKeyPath == "" {
+		spec.PrivateKeyPath = DefaultSSHPrivateKeyPath
+	}
+
+	return spec
+}
+
+// JoinOrder returns the nodes in the order they must join: controllers first, as the control
+// plane must be in place before workers join, followed by the other nodes. Nodes keep the
+// order they are listed in within each group.
+func (i *NodeInventory) JoinOrder() []Node {
+	nodes := make([]Node, 0, len(i.Spec.Nodes))
+	for _, node := range i.Spec.Nodes {
+		if i.IsController(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	for _, node := range i.Spec.Nodes {
+		if !i.IsController(node) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
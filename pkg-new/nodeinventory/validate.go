@@ -0,0 +1,85 @@
+package nodeinventory
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate validates the NodeInventory. The returned error aggregates every problem found,
+// each one prefixed with the path of the offending field.
+func Validate(inv *NodeInventory) error {
+	var errs field.ErrorList
+
+	if inv.APIVersion == "" {
+		errs = append(errs, field.Required(field.NewPath("apiVersion"), ""))
+	} else if inv.APIVersion != APIVersion {
+		errs = append(errs, field.NotSupported(field.NewPath("apiVersion"), inv.APIVersion, []string{APIVersion}))
+	}
+
+	if inv.Kind == "" {
+		errs = append(errs, field.Required(field.NewPath("kind"), ""))
+	} else if inv.Kind != Kind {
+		errs = append(errs, field.NotSupported(field.NewPath("kind"), inv.Kind, []string{Kind}))
+	}
+
+	errs = append(errs, validateSpec(inv, field.NewPath("spec"))...)
+
+	return errs.ToAggregate()
+}
+
+func validateSpec(inv *NodeInventory, path *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	spec := inv.Spec
+
+	errs = append(errs, validateSSH(spec.SSH, path.Child("ssh"))...)
+
+	if len(spec.Nodes) == 0 {
+		errs = append(errs, field.Required(path.Child("nodes"), "at least one node must be listed"))
+	}
+
+	addresses := map[string]bool{}
+	for i, node := range spec.Nodes {
+		nodePath := path.Child("nodes").Index(i)
+
+		if node.Address == "" {
+			errs = append(errs, field.Required(nodePath.Child("address"), ""))
+		} else if addresses[node.Address] {
+			errs = append(errs, field.Duplicate(nodePath.Child("address"), node.Address))
+		}
+		addresses[node.Address] = true
+
+		if node.Role == "" {
+			errs = append(errs, field.Required(nodePath.Child("role"), ""))
+		}
+
+		if node.SSH != nil {
+			errs = append(errs, validateSSH(*node.SSH, nodePath.Child("ssh"))...)
+		}
+	}
+
+	return errs
+}
+
+// ValidateJoinTokens validates that the NodeInventory has a join token for the role of every
+// node other than the controllers, as required to join them to an existing cluster. An install
+// generates the tokens once the cluster is up instead.
+func ValidateJoinTokens(inv *NodeInventory) error {
+	var errs field.ErrorList
+
+	path := field.NewPath("spec", "joinTokens")
+	for _, role := range inv.Roles() {
+		if inv.Spec.JoinTokens[role] == "" {
+			errs = append(errs, field.Required(path.Key(role), fmt.Sprintf("a join token is required for every role other than %s", inv.ControllerRoleName())))
+		}
+	}
+
+	return errs.ToAggregate()
+}
+
+func validateSSH(spec SSHSpec, path *field.Path) field.ErrorList {
+	if spec.Port < 0 || spec.Port > 65535 {
+		return field.ErrorList{field.Invalid(path.Child("port"), spec.Port, "must be between 1 and 65535")}
+	}
+	return nil
+}
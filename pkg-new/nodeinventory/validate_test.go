@@ -0,0 +1,168 @@
+package nodeinventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     NodeInventorySpec
+		wantErrs []string
+	}{
+		{
+			name: "controllers only",
+			spec: NodeInventorySpec{
+				Nodes: []Node{
+					{Address: "10.0.0.2", Role: RoleController},
+					{Address: "10.0.0.3", Role: RoleController},
+				},
+			},
+		},
+		{
+			name:     "no nodes",
+			spec:     NodeInventorySpec{},
+			wantErrs: []string{"spec.nodes: Required value"},
+		},
+		{
+			name: "missing address and role",
+			spec: NodeInventorySpec{
+				Nodes: []Node{{}},
+			},
+			wantErrs: []string{
+				"spec.nodes[0].address: Required value",
+				"spec.nodes[0].role: Required value",
+			},
+		},
+		{
+			name: "duplicate address",
+			spec: NodeInventorySpec{
+				Nodes: []Node{
+					{Address: "10.0.0.2", Role: RoleController},
+					{Address: "10.0.0.2", Role: RoleController},
+				},
+			},
+			wantErrs: []string{`spec.nodes[1].address: Duplicate value: "10.0.0.2"`},
+		},
+		{
+			name: "install inventory without join tokens",
+			spec: NodeInventorySpec{
+				Nodes: []Node{
+					{Address: "10.0.0.2", Role: RoleController},
+					{Address: "10.0.0.3", Role: "worker"},
+					{Address: "10.0.0.4", Role: "gpu"},
+				},
+			},
+		},
+		{
+			name: "invalid ssh port",
+			spec: NodeInventorySpec{
+				SSH: SSHSpec{Port: 70000},
+				Nodes: []Node{
+					{Address: "10.0.0.2", Role: RoleController, SSH: &SSHSpec{Port: -1}},
+				},
+			},
+			wantErrs: []string{
+				"spec.ssh.port: Invalid value: 70000",
+				"spec.nodes[0].ssh.port: Invalid value: -1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&NodeInventory{APIVersion: APIVersion, Kind: Kind, Spec: tt.spec})
+			if len(tt.wantErrs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErrs {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestValidateJoinTokens(t *testing.T) {
+	inv := &NodeInventory{
+		Spec: NodeInventorySpec{
+			JoinTokens: map[string]string{"gpu": "abcdef"},
+			Nodes: []Node{
+				{Address: "10.0.0.1", Role: RoleController},
+				{Address: "10.0.0.2", Role: "gpu"},
+				{Address: "10.0.0.3", Role: "worker"},
+				{Address: "10.0.0.4", Role: "worker"},
+			},
+		},
+	}
+
+	err := ValidateJoinTokens(inv)
+	require.Error(t, err)
+	assert.Equal(t, "spec.joinTokens[worker]: Required value: a join token is required for every role other than controller", err.Error())
+
+	inv.Spec.JoinTokens["worker"] = "ghijkl"
+	require.NoError(t, ValidateJoinTokens(inv))
+
+	// nodes listed as controller are not controllers when the release renames the role
+	inv = &NodeInventory{
+		controllerRoleName: "management",
+		Spec: NodeInventorySpec{
+			Nodes: []Node{{Address: "10.0.0.2", Role: RoleController}},
+		},
+	}
+	require.ErrorContains(t, ValidateJoinTokens(inv), "a join token is required for every role other than management")
+}
+
+func TestNodeInventory_Roles(t *testing.T) {
+	inv := &NodeInventory{
+		Spec: NodeInventorySpec{
+			Nodes: []Node{
+				{Address: "worker-1", Role: "worker"},
+				{Address: "controller-1", Role: RoleController},
+				{Address: "gpu-1", Role: "gpu"},
+				{Address: "worker-2", Role: "worker"},
+			},
+		},
+	}
+	assert.Equal(t, []string{"worker", "gpu"}, inv.Roles())
+}
+
+func TestNodeInventory_SSHSpec(t *testing.T) {
+	inv := &NodeInventory{
+		Spec: NodeInventorySpec{
+			SSH: SSHSpec{User: "ubuntu", PrivateKeyPath: "/keys/default"},
+		},
+	}
+
+	got := inv.SSHSpec(Node{Address: "10.0.0.2"})
+	assert.Equal(t, SSHSpec{User: "ubuntu", Port: DefaultSSHPort, PrivateKeyPath: "/keys/default"}, got)
+
+	got = inv.SSHSpec(Node{Address: "10.0.0.3", SSH: &SSHSpec{Port: 2222, PrivateKeyPath: "/keys/node", InsecureIgnoreHostKey: true}})
+	assert.Equal(t, SSHSpec{User: "ubuntu", Port: 2222, PrivateKeyPath: "/keys/node", InsecureIgnoreHostKey: true}, got)
+
+	got = (&NodeInventory{}).SSHSpec(Node{Address: "10.0.0.4"})
+	assert.Equal(t, SSHSpec{User: DefaultSSHUser, Port: DefaultSSHPort, PrivateKeyPath: DefaultSSHPrivateKeyPath}, got)
+}
+
+func TestNodeInventory_JoinOrder(t *testing.T) {
+	inv := &NodeInventory{
+		Spec: NodeInventorySpec{
+			Nodes: []Node{
+				{Address: "worker-1", Role: "worker"},
+				{Address: "controller-1", Role: RoleController},
+				{Address: "gpu-1", Role: "gpu"},
+				{Address: "controller-2", Role: RoleController},
+			},
+		},
+	}
+
+	var got []string
+	for _, node := range inv.JoinOrder() {
+		got = append(got, node.Address)
+	}
+	assert.Equal(t, []string{"controller-1", "controller-2", "worker-1", "gpu-1"}, got)
+}
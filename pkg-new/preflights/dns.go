@@ -0,0 +1,308 @@
+package preflights
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// systemdResolvedStub is the address of the stub resolver of systemd-resolved.
+	systemdResolvedStub = "127.0.0.53"
+	// maxDNSNameservers is the number of nameservers the kubelet passes on to the pods.
+	maxDNSNameservers = 3
+	// maxHostSearchDomains is the number of search domains of the host that still fit in the six
+	// search domains musl and glibc before 2.26 read, once the three cluster domains are added.
+	maxHostSearchDomains = 3
+)
+
+var (
+	// hostResolvConfPath is the resolv.conf of the host.
+	hostResolvConfPath = "/etc/resolv.conf"
+	// systemdResolvedConfPath lists the nameservers systemd-resolved forwards to. The kubelet
+	// is given this file when the host resolv.conf points at the systemd-resolved stub.
+	systemdResolvedConfPath = "/run/systemd/resolve/resolv.conf"
+	// dnsQueryTimeout is how long a nameserver is given to answer.
+	dnsQueryTimeout = 3 * time.Second
+	// queryDNS is used during tests to mock queryNameserver.
+	queryDNS = queryNameserver
+)
+
+// DNSDiagnostics describes the DNS configuration CoreDNS will inherit. The host preflights
+// check that it does not loop back to the host and that its nameservers answer.
+type DNSDiagnostics struct {
+	// Upstreams are the nameservers set with --dns-upstream. The resolv.conf of the host is
+	// diagnosed when empty.
+	Upstreams []string
+}
+
+// DNSDiagnosticsResult holds the DNS configuration CoreDNS will inherit and how its
+// nameservers answered.
+type DNSDiagnosticsResult struct {
+	// ResolvConf is the path of the resolv.conf the nameservers and search domains were read
+	// from, empty when the upstreams were set.
+	ResolvConf    string
+	Nameservers   []string
+	SearchDomains []string
+	// StubLoop is set when the nameservers are loopback addresses, CoreDNS would then forward
+	// the queries to itself.
+	StubLoop bool
+	// Answers are the results of querying the nameservers the kubelet passes on.
+	Answers []DNSAnswer
+}
+
+// DNSAnswer is the result of querying a nameserver.
+type DNSAnswer struct {
+	Nameserver string
+	// Error is empty when the nameserver answered.
+	Error string
+}
+
+// resolvConf holds the parts of a resolv.conf the diagnostics look at.
+type resolvConf struct {
+	nameservers   []string
+	searchDomains []string
+}
+
+// RunDNSDiagnostics collects the DNS configuration CoreDNS will inherit, queries its
+// nameservers and returns the results of the checks.
+func RunDNSDiagnostics(ctx context.Context, opts DNSDiagnostics) *apitypes.PreflightsOutput {
+	out := &apitypes.PreflightsOutput{}
+	result, err := collectDNSDiagnostics(ctx, opts)
+	if err != nil {
+		out.Warn = append(out.Warn, apitypes.PreflightsRecord{
+			Title:   "DNS Upstream Resolvers",
+			Message: fmt.Sprintf("Unable to diagnose the DNS configuration: %v", err),
+		})
+		return out
+	}
+	analyzeDNSDiagnostics(out, result)
+	return out
+}
+
+// collectDNSDiagnostics reads the resolv.conf the kubelet hands to CoreDNS and queries the
+// nameservers in it.
+func collectDNSDiagnostics(ctx context.Context, opts DNSDiagnostics) (*DNSDiagnosticsResult, error) {
+	result := &DNSDiagnosticsResult{}
+	if len(opts.Upstreams) > 0 {
+		result.Nameservers = opts.Upstreams
+	} else {
+		conf, err := readResolvConf(hostResolvConfPath)
+		if err != nil {
+			return nil, err
+		}
+		result.ResolvConf = hostResolvConfPath
+		if isOnlySystemdResolvedStub(conf.nameservers) {
+			if resolved, err := readResolvConf(systemdResolvedConfPath); err == nil {
+				conf = resolved
+				result.ResolvConf = systemdResolvedConfPath
+			}
+		}
+		result.Nameservers = conf.nameservers
+		result.SearchDomains = conf.searchDomains
+	}
+
+	for _, nameserver := range result.Nameservers {
+		if addr, err := netip.ParseAddr(nameserver); err == nil && addr.IsLoopback() {
+			result.StubLoop = true
+		}
+	}
+	if result.StubLoop {
+		return result, nil
+	}
+
+	nameservers := result.Nameservers
+	if len(nameservers) > maxDNSNameservers {
+		nameservers = nameservers[:maxDNSNameservers]
+	}
+	for _, nameserver := range nameservers {
+		answer := DNSAnswer{Nameserver: nameserver}
+		if err := queryDNS(ctx, net.JoinHostPort(nameserver, "53")); err != nil {
+			answer.Error = err.Error()
+		}
+		result.Answers = append(result.Answers, answer)
+	}
+	return result, nil
+}
+
+// readResolvConf reads the nameservers and search domains of a resolv.conf. Later search or
+// domain lines replace the earlier ones, as the resolver does.
+func readResolvConf(path string) (*resolvConf, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	conf := &resolvConf{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], ";") {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			conf.nameservers = append(conf.nameservers, fields[1])
+		case "search", "domain":
+			conf.searchDomains = fields[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return conf, nil
+}
+
+func isOnlySystemdResolvedStub(nameservers []string) bool {
+	return len(nameservers) == 1 && nameservers[0] == systemdResolvedStub
+}
+
+// queryNameserver asks the nameserver at addr for the nameservers of the root zone, a query
+// every recursive resolver answers. SERVFAIL counts as an answer since resolvers in air gap
+// networks cannot resolve the root zone but still answer for the internal zones.
+func queryNameserver(ctx context.Context, addr string) error {
+	var idb [2]byte
+	if _, err := rand.Read(idb[:]); err != nil {
+		return err
+	}
+	id := binary.BigEndian.Uint16(idb[:])
+
+	query, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName("."),
+			Type:  dnsmessage.TypeNS,
+			Class: dnsmessage.ClassINET,
+		}},
+	}).Pack()
+	if err != nil {
+		return fmt.Errorf("pack query: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return err
+	}
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return fmt.Errorf("no answer within %s", dnsQueryTimeout)
+			}
+			return err
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil || header.ID != id || !header.Response {
+			// not the answer to the query, keep waiting
+			continue
+		}
+		switch header.RCode {
+		case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError, dnsmessage.RCodeServerFailure:
+			return nil
+		case dnsmessage.RCodeRefused:
+			return fmt.Errorf("refused the query")
+		default:
+			return fmt.Errorf("answered with %s", strings.TrimPrefix(header.RCode.String(), "RCode"))
+		}
+	}
+}
+
+// analyzeDNSDiagnostics adds the results of the DNS checks to the output.
+func analyzeDNSDiagnostics(out *apitypes.PreflightsOutput, result *DNSDiagnosticsResult) {
+	source := "the DNS upstreams"
+	if result.ResolvConf != "" {
+		source = result.ResolvConf
+	}
+
+	if result.StubLoop {
+		message := fmt.Sprintf(
+			"The nameservers in %s (%s) are loopback addresses. CoreDNS would forward queries to itself and loop.",
+			source, strings.Join(result.Nameservers, ", "),
+		)
+		if isOnlySystemdResolvedStub(result.Nameservers) {
+			message += fmt.Sprintf(" systemd-resolved is not running, start it so %s lists the nameservers it forwards to, or use --dns-upstream.", systemdResolvedConfPath)
+		} else {
+			message += " Use --dns-upstream to set the nameservers CoreDNS forwards to."
+		}
+		out.Fail = append(out.Fail, apitypes.PreflightsRecord{Title: "DNS Resolver Loop", Message: message})
+	} else if len(result.Nameservers) > 0 {
+		out.Pass = append(out.Pass, apitypes.PreflightsRecord{
+			Title:   "DNS Resolver Loop",
+			Message: fmt.Sprintf("CoreDNS will forward to the nameservers in %s, none of them are loopback addresses.", source),
+		})
+	}
+
+	limits := []string{}
+	if len(result.Nameservers) > maxDNSNameservers {
+		limits = append(limits, fmt.Sprintf(
+			"%s lists %d nameservers, only the first %d are used by CoreDNS and the pods.",
+			source, len(result.Nameservers), maxDNSNameservers,
+		))
+	}
+	if len(result.SearchDomains) > maxHostSearchDomains {
+		limits = append(limits, fmt.Sprintf(
+			"%s lists %d search domains. Along with the 3 cluster domains pods search more than 6 domains, which some resolvers ignore, and every lookup of an external name is tried in each of them. Remove search domains from %s or use --dns-upstream.",
+			source, len(result.SearchDomains), source,
+		))
+	}
+	if len(limits) > 0 {
+		out.Warn = append(out.Warn, apitypes.PreflightsRecord{Title: "DNS Resolver Limits", Message: strings.Join(limits, " ")})
+	} else if len(result.Nameservers) > 0 {
+		out.Pass = append(out.Pass, apitypes.PreflightsRecord{
+			Title:   "DNS Resolver Limits",
+			Message: fmt.Sprintf("The nameservers and search domains in %s are within the limits of the pods.", source),
+		})
+	}
+
+	if len(result.Answers) == 0 {
+		return
+	}
+	answered, failures := []string{}, []string{}
+	for _, answer := range result.Answers {
+		if answer.Error == "" {
+			answered = append(answered, answer.Nameserver)
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %s", answer.Nameserver, answer.Error))
+		}
+	}
+	switch {
+	case len(failures) == 0:
+		out.Pass = append(out.Pass, apitypes.PreflightsRecord{
+			Title:   "DNS Upstream Resolvers",
+			Message: fmt.Sprintf("The nameservers CoreDNS will forward to answered: %s.", strings.Join(answered, ", ")),
+		})
+	case len(answered) == 0:
+		out.Fail = append(out.Fail, apitypes.PreflightsRecord{
+			Title:   "DNS Upstream Resolvers",
+			Message: fmt.Sprintf("None of the nameservers CoreDNS will forward to answered (%s). Cluster DNS will not resolve external names. Allow DNS traffic to the nameservers or use --dns-upstream.", strings.Join(failures, "; ")),
+		})
+	default:
+		out.Warn = append(out.Warn, apitypes.PreflightsRecord{
+			Title:   "DNS Upstream Resolvers",
+			Message: fmt.Sprintf("Some of the nameservers CoreDNS will forward to did not answer (%s). The lookups CoreDNS forwards to them fail.", strings.Join(failures, "; ")),
+		})
+	}
+}
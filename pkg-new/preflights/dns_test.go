@@ -0,0 +1,260 @@
+package preflights
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestReadResolvConf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	require.NoError(t, os.WriteFile(path, []byte(`# generated
+; comment
+nameserver 10.0.0.2
+nameserver 2001:db8::53
+search old.example.com
+options ndots:2
+search a.example.com b.example.com
+`), 0644))
+
+	conf, err := readResolvConf(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.2", "2001:db8::53"}, conf.nameservers)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, conf.searchDomains)
+
+	_, err = readResolvConf(filepath.Join(t.TempDir(), "missing"))
+	require.ErrorContains(t, err, "open")
+}
+
+func TestCollectDNSDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	stub := filepath.Join(dir, "stub-resolv.conf")
+	require.NoError(t, os.WriteFile(stub, []byte("nameserver 127.0.0.53\nsearch example.com\n"), 0644))
+	resolved := filepath.Join(dir, "resolved-resolv.conf")
+	require.NoError(t, os.WriteFile(resolved, []byte("nameserver 10.0.0.2\nnameserver 10.0.0.3\nsearch example.com\n"), 0644))
+	plain := filepath.Join(dir, "resolv.conf")
+	require.NoError(t, os.WriteFile(plain, []byte("nameserver 10.0.0.4\nnameserver 10.0.0.5\nnameserver 10.0.0.6\nnameserver 10.0.0.7\n"), 0644))
+
+	tests := []struct {
+		name         string
+		opts         DNSDiagnostics
+		hostConf     string
+		resolvedConf string
+		want         *DNSDiagnosticsResult
+	}{
+		{
+			name:         "systemd-resolved stub",
+			hostConf:     stub,
+			resolvedConf: resolved,
+			want: &DNSDiagnosticsResult{
+				ResolvConf:    resolved,
+				Nameservers:   []string{"10.0.0.2", "10.0.0.3"},
+				SearchDomains: []string{"example.com"},
+				Answers: []DNSAnswer{
+					{Nameserver: "10.0.0.2"},
+					{Nameserver: "10.0.0.3", Error: "no answer within 3s"},
+				},
+			},
+		},
+		{
+			name:         "systemd-resolved stub without systemd-resolved",
+			hostConf:     stub,
+			resolvedConf: filepath.Join(dir, "missing"),
+			want: &DNSDiagnosticsResult{
+				ResolvConf:    stub,
+				Nameservers:   []string{"127.0.0.53"},
+				SearchDomains: []string{"example.com"},
+				StubLoop:      true,
+			},
+		},
+		{
+			name:     "only the first three nameservers are queried",
+			hostConf: plain,
+			want: &DNSDiagnosticsResult{
+				ResolvConf:  plain,
+				Nameservers: []string{"10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"},
+				Answers: []DNSAnswer{
+					{Nameserver: "10.0.0.4"},
+					{Nameserver: "10.0.0.5"},
+					{Nameserver: "10.0.0.6"},
+				},
+			},
+		},
+		{
+			name:     "dns upstreams",
+			opts:     DNSDiagnostics{Upstreams: []string{"10.0.0.2"}},
+			hostConf: filepath.Join(dir, "missing"),
+			want: &DNSDiagnosticsResult{
+				Nameservers: []string{"10.0.0.2"},
+				Answers:     []DNSAnswer{{Nameserver: "10.0.0.2"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldHost, oldResolved, oldQuery := hostResolvConfPath, systemdResolvedConfPath, queryDNS
+			t.Cleanup(func() {
+				hostResolvConfPath, systemdResolvedConfPath, queryDNS = oldHost, oldResolved, oldQuery
+			})
+			hostResolvConfPath, systemdResolvedConfPath = tt.hostConf, tt.resolvedConf
+			queryDNS = func(ctx context.Context, addr string) error {
+				if addr == "10.0.0.3:53" {
+					return fmt.Errorf("no answer within 3s")
+				}
+				return nil
+			}
+
+			got, err := collectDNSDiagnostics(context.Background(), tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQueryNameserver(t *testing.T) {
+	tests := []struct {
+		name    string
+		rcode   dnsmessage.RCode
+		silent  bool
+		wantErr string
+	}{
+		{name: "answer", rcode: dnsmessage.RCodeSuccess},
+		{name: "server failure", rcode: dnsmessage.RCodeServerFailure},
+		{name: "refused", rcode: dnsmessage.RCodeRefused, wantErr: "refused the query"},
+		{name: "not implemented", rcode: dnsmessage.RCodeNotImplemented, wantErr: "answered with NotImplemented"},
+		{name: "no answer", silent: true, wantErr: "no answer within 200ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := dnsQueryTimeout
+			t.Cleanup(func() { dnsQueryTimeout = old })
+			dnsQueryTimeout = 200 * time.Millisecond
+
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+			go serveDNS(conn, tt.rcode, tt.silent)
+
+			err = queryNameserver(context.Background(), conn.LocalAddr().String())
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// serveDNS answers the first query received on conn with the rcode, first sending an answer
+// with another id that must be ignored.
+func serveDNS(conn net.PacketConn, rcode dnsmessage.RCode, silent bool) {
+	buf := make([]byte, 512)
+	n, addr, err := conn.ReadFrom(buf)
+	if err != nil || silent {
+		return
+	}
+	var parser dnsmessage.Parser
+	header, err := parser.Start(buf[:n])
+	if err != nil {
+		return
+	}
+	for _, id := range []uint16{header.ID + 1, header.ID} {
+		resp, err := (&dnsmessage.Message{Header: dnsmessage.Header{ID: id, Response: true, RCode: rcode}}).Pack()
+		if err != nil {
+			return
+		}
+		_, _ = conn.WriteTo(resp, addr)
+	}
+}
+
+func TestAnalyzeDNSDiagnostics(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *DNSDiagnosticsResult
+		wantPass []string
+		wantWarn []string
+		wantFail []string
+	}{
+		{
+			name: "healthy",
+			result: &DNSDiagnosticsResult{
+				ResolvConf:    "/etc/resolv.conf",
+				Nameservers:   []string{"10.0.0.2"},
+				SearchDomains: []string{"example.com"},
+				Answers:       []DNSAnswer{{Nameserver: "10.0.0.2"}},
+			},
+			wantPass: []string{"DNS Resolver Loop", "DNS Resolver Limits", "DNS Upstream Resolvers"},
+		},
+		{
+			name: "stub loop",
+			result: &DNSDiagnosticsResult{
+				ResolvConf:  "/etc/resolv.conf",
+				Nameservers: []string{"127.0.0.53"},
+				StubLoop:    true,
+			},
+			wantPass: []string{"DNS Resolver Limits"},
+			wantFail: []string{"DNS Resolver Loop"},
+		},
+		{
+			name: "search domain blowup and some upstreams not answering",
+			result: &DNSDiagnosticsResult{
+				ResolvConf:    "/etc/resolv.conf",
+				Nameservers:   []string{"10.0.0.2", "10.0.0.3"},
+				SearchDomains: []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"},
+				Answers:       []DNSAnswer{{Nameserver: "10.0.0.2"}, {Nameserver: "10.0.0.3", Error: "refused the query"}},
+			},
+			wantPass: []string{"DNS Resolver Loop"},
+			wantWarn: []string{"DNS Resolver Limits", "DNS Upstream Resolvers"},
+		},
+		{
+			name: "no upstream answering",
+			result: &DNSDiagnosticsResult{
+				Nameservers: []string{"10.0.0.2"},
+				Answers:     []DNSAnswer{{Nameserver: "10.0.0.2", Error: "no answer within 3s"}},
+			},
+			wantPass: []string{"DNS Resolver Loop", "DNS Resolver Limits"},
+			wantFail: []string{"DNS Upstream Resolvers"},
+		},
+		{
+			name:   "no nameservers",
+			result: &DNSDiagnosticsResult{ResolvConf: "/etc/resolv.conf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &apitypes.PreflightsOutput{}
+			analyzeDNSDiagnostics(out, tt.result)
+			assert.Equal(t, tt.wantPass, recordTitles(out.Pass))
+			assert.Equal(t, tt.wantWarn, recordTitles(out.Warn))
+			assert.Equal(t, tt.wantFail, recordTitles(out.Fail))
+		})
+	}
+}
+
+func TestAnalyzeDNSDiagnosticsMessages(t *testing.T) {
+	out := &apitypes.PreflightsOutput{}
+	analyzeDNSDiagnostics(out, &DNSDiagnosticsResult{
+		ResolvConf:  "/etc/resolv.conf",
+		Nameservers: []string{"127.0.0.53"},
+		StubLoop:    true,
+	})
+	require.Len(t, out.Fail, 1)
+	assert.Equal(t, "The nameservers in /etc/resolv.conf (127.0.0.53) are loopback addresses. CoreDNS would forward queries to itself and loop. systemd-resolved is not running, start it so /run/systemd/resolve/resolv.conf lists the nameservers it forwards to, or use --dns-upstream.", out.Fail[0].Message)
+
+	out = &apitypes.PreflightsOutput{}
+	analyzeDNSDiagnostics(out, &DNSDiagnosticsResult{
+		Nameservers: []string{"10.0.0.2", "10.0.0.3"},
+		Answers:     []DNSAnswer{{Nameserver: "10.0.0.2"}, {Nameserver: "10.0.0.3", Error: "refused the query"}},
+	})
+	require.Len(t, out.Warn, 1)
+	assert.Equal(t, "Some of the nameservers CoreDNS will forward to did not answer (10.0.0.3: refused the query). The lookups CoreDNS forwards to them fail.", out.Warn[0].Message)
+}
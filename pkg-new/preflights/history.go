@@ -0,0 +1,241 @@
+package preflights
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+)
+
+const (
+	// HistoryDirName is the name of the directory, in the support directory, holding the
+	// results of every host preflights run.
+	HistoryDirName = "host-preflight-history"
+
+	// historyLimit is the number of runs kept in the history, the oldest ones are removed.
+	historyLimit = 50
+
+	// historyTimeFormat has a fixed width fraction of a second so the IDs sort in the order the
+	// runs were made.
+	historyTimeFormat = "20060102T150405.000000000Z"
+)
+
+// Check states compared between runs. A check that was not run is absent.
+const (
+	CheckStatePass   = "pass"
+	CheckStateWarn   = "warn"
+	CheckStateFail   = "fail"
+	CheckStateAbsent = "absent"
+)
+
+// HistoryRun is the result of a host preflights run kept in the history.
+type HistoryRun struct {
+	// ID identifies the run in the history, it sorts in the order the runs were made.
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Source is the command that ran the host preflights, e.g. install or join.
+	Source string                     `json:"source"`
+	Output *apitypes.PreflightsOutput `json:"output"`
+}
+
+// CheckChange is a check whose state differs between two runs.
+type CheckChange struct {
+	Title  string `json:"title"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Message is the message of the check in the later run, or in the earlier one if the check
+	// was not run anymore.
+	Message string `json:"message,omitempty"`
+}
+
+// SaveToHistory adds the output to the history in dir and removes the oldest runs past the
+// history limit. It returns the run that was added.
+func SaveToHistory(o *apitypes.PreflightsOutput, dir string, source string, now time.Time) (*HistoryRun, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create history directory: %w", err)
+	}
+
+	now = now.UTC()
+	run := &HistoryRun{
+		CreatedAt: now,
+		Source:    source,
+		Output:    o,
+	}
+	// a run made at the same time as another one, as far as the clock can tell, gets the next
+	// free ID so neither is overwritten
+	for at := now; ; at = at.Add(time.Nanosecond) {
+		run.ID = fmt.Sprintf("%s-%s", at.Format(historyTimeFormat), source)
+		created, err := writeHistoryRun(dir, run)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			break
+		}
+	}
+
+	if err := pruneHistory(dir, historyLimit); err != nil {
+		return nil, fmt.Errorf("prune history: %w", err)
+	}
+	return run, nil
+}
+
+// writeHistoryRun writes the run to a file named after its ID, unless the file already exists.
+// It returns whether the file was created.
+func writeHistoryRun(dir string, run *HistoryRun) (bool, error) {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("marshal history run: %w", err)
+	}
+	path := filepath.Join(dir, run.ID+".json")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("create history run %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return false, fmt.Errorf("write history run to %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// historyFiles returns the names of the runs in the history, oldest first.
+func historyFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read history directory: %w", err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func pruneHistory(dir string, limit int) error {
+	names, err := historyFiles(dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > limit {
+		if err := os.Remove(filepath.Join(dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// ListHistory returns the runs in the history, oldest first.
+func ListHistory(dir string) ([]HistoryRun, error) {
+	names, err := historyFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	runs := []HistoryRun{}
+	for _, name := range names {
+		run, err := readHistoryRun(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, *run)
+	}
+	return runs, nil
+}
+
+func readHistoryRun(path string) (*HistoryRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read history run: %w", err)
+	}
+	var run HistoryRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("decode history run %s: %w", path, err)
+	}
+	return &run, nil
+}
+
+// LoadOutputFile reads the host preflights output in a file. The file can be a run from the
+// history, a report written by "preflights run" or the results written by the installer.
+func LoadOutputFile(path string) (*apitypes.PreflightsOutput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	// history runs and reports hold the output in the output field
+	var wrapped struct {
+		Output *apitypes.PreflightsOutput `json:"output"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	if wrapped.Output != nil {
+		return wrapped.Output, nil
+	}
+
+	var output apitypes.PreflightsOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return &output, nil
+}
+
+// DiffOutputs returns the checks whose state differs between the two outputs, sorted by title.
+func DiffOutputs(before, after *apitypes.PreflightsOutput) []CheckChange {
+	beforeStates, beforeMessages := checkStates(before)
+	afterStates, afterMessages := checkStates(after)
+
+	changes := []CheckChange{}
+	for title, state := range afterStates {
+		previous, ok := beforeStates[title]
+		if !ok {
+			previous = CheckStateAbsent
+		}
+		if previous != state {
+			changes = append(changes, CheckChange{Title: title, Before: previous, After: state, Message: afterMessages[title]})
+		}
+	}
+	for title, state := range beforeStates {
+		if _, ok := afterStates[title]; !ok {
+			changes = append(changes, CheckChange{Title: title, Before: state, After: CheckStateAbsent, Message: beforeMessages[title]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Title < changes[j].Title })
+	return changes
+}
+
+// checkStates returns the state and the message of every check in the output, by title. When
+// the same title is reported more than once the worst state wins.
+func checkStates(o *apitypes.PreflightsOutput) (map[string]string, map[string]string) {
+	states, messages := map[string]string{}, map[string]string{}
+	if o == nil {
+		return states, messages
+	}
+	set := func(records []apitypes.PreflightsRecord, state string) {
+		for _, record := range records {
+			if _, ok := states[record.Title]; ok {
+				continue
+			}
+			states[record.Title] = state
+			messages[record.Title] = record.Message
+		}
+	}
+	set(o.Fail, CheckStateFail)
+	set(o.Warn, CheckStateWarn)
+	set(o.Pass, CheckStatePass)
+	return states, messages
+}
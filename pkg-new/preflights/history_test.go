@@ -0,0 +1,145 @@
+package preflights
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveToHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), HistoryDirName)
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	output := &apitypes.PreflightsOutput{Fail: []apitypes.PreflightsRecord{{Title: "IP forwarding", Message: "disabled"}}}
+	run, err := SaveToHistory(output, dir, "install", start)
+	require.NoError(t, err)
+	assert.Equal(t, "20240101T120000.000000000Z-install", run.ID)
+
+	for i := 1; i <= historyLimit; i++ {
+		_, err := SaveToHistory(&apitypes.PreflightsOutput{}, dir, "preflights-run", start.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	runs, err := ListHistory(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, historyLimit)
+	// the oldest run was removed
+	assert.Equal(t, "20240101T120100.000000000Z-preflights-run", runs[0].ID)
+	assert.Equal(t, fmt.Sprintf("%s-preflights-run", start.Add(historyLimit*time.Minute).Format(historyTimeFormat)), runs[len(runs)-1].ID)
+}
+
+func TestSaveToHistorySameTime(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), HistoryDirName)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := SaveToHistory(&apitypes.PreflightsOutput{Fail: []apitypes.PreflightsRecord{{Title: "IP forwarding"}}}, dir, "install", now)
+	require.NoError(t, err)
+	second, err := SaveToHistory(&apitypes.PreflightsOutput{Pass: []apitypes.PreflightsRecord{{Title: "IP forwarding"}}}, dir, "install", now)
+	require.NoError(t, err)
+	// a run in the same second is told apart by the fraction of the second
+	third, err := SaveToHistory(&apitypes.PreflightsOutput{}, dir, "install", now.Add(time.Millisecond))
+	require.NoError(t, err)
+
+	assert.Equal(t, "20240101T120000.000000000Z-install", first.ID)
+	assert.Equal(t, "20240101T120000.000000001Z-install", second.ID)
+	assert.Equal(t, "20240101T120000.001000000Z-install", third.ID)
+
+	runs, err := ListHistory(dir)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, []string{first.ID, second.ID, third.ID}, []string{runs[0].ID, runs[1].ID, runs[2].ID})
+	// neither run was overwritten
+	assert.Len(t, runs[0].Output.Fail, 1)
+	assert.Len(t, runs[1].Output.Pass, 1)
+}
+
+func TestListHistoryMissingDir(t *testing.T) {
+	runs, err := ListHistory(filepath.Join(t.TempDir(), "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestLoadOutputFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "history run",
+			content: `{"id":"20240101T120000Z-install","output":{"fail":[{"title":"Memory"}]}}`,
+			want:    []string{"Memory"},
+		},
+		{
+			name:    "report",
+			content: `{"metadata":{"hostname":"node1"},"output":{"fail":[{"title":"CPU"}]}}`,
+			want:    []string{"CPU"},
+		},
+		{
+			name:    "installer results",
+			content: `{"pass":[],"fail":[{"title":"System Clock"}]}`,
+			want:    []string{"System Clock"},
+		},
+		{
+			name:    "invalid",
+			content: `not json`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, fmt.Sprintf("%d.json", i))
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0644))
+
+			output, err := LoadOutputFile(path)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			titles := []string{}
+			for _, record := range output.Fail {
+				titles = append(titles, record.Title)
+			}
+			assert.Equal(t, tt.want, titles)
+		})
+	}
+}
+
+func TestDiffOutputs(t *testing.T) {
+	before := &apitypes.PreflightsOutput{
+		Pass: []apitypes.PreflightsRecord{{Title: "Memory", Message: "enough memory"}, {Title: "CPU"}},
+		Warn: []apitypes.PreflightsRecord{{Title: "System Clock", Message: "not synchronized"}},
+		Fail: []apitypes.PreflightsRecord{{Title: "IP forwarding", Message: "disabled"}, {Title: "Kube API Server Port Availability"}},
+	}
+	after := &apitypes.PreflightsOutput{
+		Pass: []apitypes.PreflightsRecord{{Title: "IP forwarding", Message: "enabled"}, {Title: "CPU"}, {Title: "System Clock", Message: "synchronized"}},
+		Fail: []apitypes.PreflightsRecord{{Title: "Memory", Message: "not enough memory"}, {Title: "Kube API Server Port Availability"}},
+		Warn: []apitypes.PreflightsRecord{{Title: "Data Dir Symlink Check", Message: "symlink"}},
+	}
+
+	changes := DiffOutputs(before, after)
+
+	assert.Equal(t, []CheckChange{
+		{Title: "Data Dir Symlink Check", Before: CheckStateAbsent, After: CheckStateWarn, Message: "symlink"},
+		{Title: "IP forwarding", Before: CheckStateFail, After: CheckStatePass, Message: "enabled"},
+		{Title: "Memory", Before: CheckStatePass, After: CheckStateFail, Message: "not enough memory"},
+		{Title: "System Clock", Before: CheckStateWarn, After: CheckStatePass, Message: "synchronized"},
+	}, changes)
+
+	// checks that are not run anymore
+	changes = DiffOutputs(before, &apitypes.PreflightsOutput{})
+	require.Len(t, changes, 5)
+	assert.Equal(t, CheckChange{Title: "CPU", Before: CheckStatePass, After: CheckStateAbsent}, changes[0])
+
+	assert.Empty(t, DiffOutputs(before, before))
+}
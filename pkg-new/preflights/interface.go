@@ -23,6 +23,17 @@ type RunOptions struct {
 	PreflightBinaryPath string
 	ProxySpec           *ecv1beta1.ProxySpec
 	ExtraPaths          []string
+	// StorageBenchmarks are run after the host preflights and their results added to the output.
+	StorageBenchmarks []StorageBenchmark
+	// DNSDiagnostics is run after the host preflights when set and its results added to the
+	// output.
+	DNSDiagnostics *DNSDiagnostics
+	// IPv6RouteCheck is run after the host preflights when set and its results added to the
+	// output.
+	IPv6RouteCheck *IPv6RouteCheck
+	// AdditionalResults holds the results of checks the caller ran itself, they are added to
+	// the output.
+	AdditionalResults *apitypes.PreflightsOutput
 }
 
 type PreflightRunnerInterface interface {
@@ -0,0 +1,144 @@
+package preflights
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+)
+
+var (
+	// ipv6RoutePath is the IPv6 routing table of the host.
+	ipv6RoutePath = "/proc/net/ipv6_route"
+	// calicoInterfacePrefixes are the prefixes of the interfaces Calico creates, their routes
+	// belong to the cluster once it is installed.
+	calicoInterfacePrefixes = []string{"cali", "vxlan-v6.calico", "vxlan.calico"}
+)
+
+// IPv6RouteCheck describes the IPv6 ranges of the cluster. The host preflights check that no
+// route of the host already leads into them.
+type IPv6RouteCheck struct {
+	// CIDRs are the IPv6 pod and service ranges of the cluster.
+	CIDRs []string
+}
+
+// ipv6Route is a route of the IPv6 routing table.
+type ipv6Route struct {
+	prefix    netip.Prefix
+	device    string
+	isDefault bool
+}
+
+// RunIPv6RouteCheck reads the IPv6 routing table of the host and returns the results of
+// checking it against the IPv6 ranges of the cluster.
+func RunIPv6RouteCheck(opts IPv6RouteCheck) *apitypes.PreflightsOutput {
+	const title = "IPv6 Route Conflicts"
+
+	out := &apitypes.PreflightsOutput{}
+	routes, err := readIPv6Routes(ipv6RoutePath)
+	if err != nil {
+		out.Warn = append(out.Warn, apitypes.PreflightsRecord{
+			Title:   title,
+			Message: fmt.Sprintf("Unable to read the IPv6 routing table: %v", err),
+		})
+		return out
+	}
+
+	conflicts := ipv6RouteConflicts(opts.CIDRs, routes)
+	if len(conflicts) > 0 {
+		out.Fail = append(out.Fail, apitypes.PreflightsRecord{
+			Title: title,
+			Message: fmt.Sprintf(
+				"The IPv6 ranges of the cluster overlap with routes of this host: %s. Traffic to the pods and services would be routed off the host. Choose IPv6 ranges that are not routed on this network.",
+				strings.Join(conflicts, "; "),
+			),
+		})
+		return out
+	}
+	out.Pass = append(out.Pass, apitypes.PreflightsRecord{
+		Title:   title,
+		Message: fmt.Sprintf("No route of this host overlaps with the IPv6 ranges of the cluster (%s).", strings.Join(opts.CIDRs, ", ")),
+	})
+	return out
+}
+
+// ipv6RouteConflicts returns the routes that overlap with the cidrs. Default routes, the
+// routes of the addresses local to the host and the routes of Calico are not conflicts.
+func ipv6RouteConflicts(cidrs []string, routes []ipv6Route) []string {
+	conflicts := []string{}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			continue
+		}
+		for _, route := range routes {
+			if route.isDefault || isLocalIPv6Route(route) || isCalicoInterface(route.device) {
+				continue
+			}
+			if prefix.Overlaps(route.prefix) {
+				conflicts = append(conflicts, fmt.Sprintf("%s overlaps with %s via %s", cidr, route.prefix, route.device))
+			}
+		}
+	}
+	return conflicts
+}
+
+func isLocalIPv6Route(route ipv6Route) bool {
+	addr := route.prefix.Addr()
+	return addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsMulticast() || route.device == "lo"
+}
+
+func isCalicoInterface(device string) bool {
+	for _, prefix := range calicoInterfacePrefixes {
+		if strings.HasPrefix(device, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readIPv6Routes reads the routes of the main routing table in the format of
+// /proc/net/ipv6_route: the destination, its prefix length, the source, its prefix length,
+// the next hop, the metric, the reference and use counts, the flags and the device.
+func readIPv6Routes(path string) ([]ipv6Route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	routes := []ipv6Route{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		dest, err := hex.DecodeString(fields[0])
+		if err != nil || len(dest) != 16 {
+			continue
+		}
+		bits, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil {
+			continue
+		}
+		prefix, err := netip.AddrFrom16([16]byte(dest)).Prefix(int(bits))
+		if err != nil {
+			continue
+		}
+		routes = append(routes, ipv6Route{
+			prefix:    prefix,
+			device:    fields[9],
+			isDefault: bits == 0,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return routes, nil
+}
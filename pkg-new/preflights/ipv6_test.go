@@ -0,0 +1,91 @@
+package preflights
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIPv6Routes = `00000000000000000000000000000000 00 00000000000000000000000000000000 00 fe800000000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+fd000001000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+fd000020000000000000000000000000 30 00000000000000000000000000000000 00 fd000001000000000000000000000001 00000400 00000001 00000000 00000003     eth0
+fd000010000000000000000000000000 7a 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000001 00000000 00000001 vxlan-v6.calico
+fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0
+00000000000000000000000000000001 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000002 00000000 80200001       lo
+`
+
+func TestReadIPv6Routes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipv6_route")
+	require.NoError(t, os.WriteFile(path, []byte(testIPv6Routes), 0644))
+
+	routes, err := readIPv6Routes(path)
+	require.NoError(t, err)
+	require.Len(t, routes, 6)
+	assert.True(t, routes[0].isDefault)
+	assert.Equal(t, "fd00:1::/64", routes[1].prefix.String())
+	assert.Equal(t, "eth0", routes[1].device)
+	assert.Equal(t, "fd00:20::/48", routes[2].prefix.String())
+	assert.Equal(t, "vxlan-v6.calico", routes[3].device)
+}
+
+func TestRunIPv6RouteCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipv6_route")
+	require.NoError(t, os.WriteFile(path, []byte(testIPv6Routes), 0644))
+
+	origPath := ipv6RoutePath
+	ipv6RoutePath = path
+	t.Cleanup(func() { ipv6RoutePath = origPath })
+
+	tests := []struct {
+		name     string
+		cidrs    []string
+		wantPass bool
+		wantMsg  string
+	}{
+		{
+			name:     "no overlap",
+			cidrs:    []string{"fd00:30::/49", "fd00:30:0:8000::/108"},
+			wantPass: true,
+		},
+		{
+			name:     "calico routes are not conflicts",
+			cidrs:    []string{"fd00:10::/49"},
+			wantPass: true,
+		},
+		{
+			name:    "overlaps with a routed network",
+			cidrs:   []string{"fd00:20::/49", "fd00:20:0:8000::/108"},
+			wantMsg: "fd00:20::/49 overlaps with fd00:20::/48 via eth0; fd00:20:0:8000::/108 overlaps with fd00:20::/48 via eth0",
+		},
+		{
+			name:    "contains the network of the node",
+			cidrs:   []string{"fd00:1::/48"},
+			wantMsg: "fd00:1::/48 overlaps with fd00:1::/64 via eth0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := RunIPv6RouteCheck(IPv6RouteCheck{CIDRs: tt.cidrs})
+			if tt.wantPass {
+				assert.Equal(t, []string{"IPv6 Route Conflicts"}, recordTitles(out.Pass))
+				assert.Empty(t, out.Fail)
+				return
+			}
+			require.Len(t, out.Fail, 1)
+			assert.Contains(t, out.Fail[0].Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestRunIPv6RouteCheckMissingRoutes(t *testing.T) {
+	origPath := ipv6RoutePath
+	ipv6RoutePath = filepath.Join(t.TempDir(), "missing")
+	t.Cleanup(func() { ipv6RoutePath = origPath })
+
+	out := RunIPv6RouteCheck(IPv6RouteCheck{CIDRs: []string{"fd00:30::/49"}})
+	require.Len(t, out.Warn, 1)
+	assert.Contains(t, out.Warn[0].Message, "Unable to read the IPv6 routing table")
+}
@@ -55,8 +55,8 @@ func SaveToDisk(o *apitypes.PreflightsOutput, path string) error {
 		return fmt.Errorf("unable to marshal preflight results: %w", err)
 	}
 
-	// If we ever want to store multiple preflight results
-	// we can add a timestamp to the filename.
+	// Only the latest results are kept here, every run is also kept
+	// in the history, see SaveToHistory.
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("unable to write preflight results to %s: %w", path, err)
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
 	"github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
 )
 
@@ -42,6 +43,13 @@ type PrepareHostPreflightOptions struct {
 	WorkerAirgapStorageSpace          string
 	DisableFilesystemPerformanceCheck bool
 	K8sVersion                        string
+	// IPv6PodCIDR and IPv6ServiceCIDR are the IPv6 ranges of a dual-stack cluster.
+	IPv6PodCIDR     string
+	IPv6ServiceCIDR string
+	// VendorHostPreflights holds the templated host preflight specs shipped in the release.
+	VendorHostPreflights [][]byte
+	// VendorVariables are available to the templated host preflights as .Vendor.
+	VendorVariables map[string]string
 }
 
 // k8sVersionRequiresCgroupV2 checks if a given k0s version requires cgroup v2.
@@ -90,6 +98,9 @@ func PrepareHostPreflights(ctx context.Context, opts PrepareHostPreflightOptions
 		WorkerAirgapStorageSpace:          opts.WorkerAirgapStorageSpace,
 		DisableFilesystemPerformanceCheck: opts.DisableFilesystemPerformanceCheck,
 		RequiresCgroupV2:                  requiresCgroupV2,
+		IPv6:                              netutils.IsIPv6CIDR(opts.PodCIDR) || opts.IPv6PodCIDR != "",
+		IPv6Only:                          netutils.IsIPv6CIDR(opts.PodCIDR),
+		Vendor:                            opts.VendorVariables,
 	}.WithCIDRData(opts.PodCIDR, opts.ServiceCIDR, opts.GlobalCIDR)
 
 	if err != nil {
@@ -108,7 +119,12 @@ func PrepareHostPreflights(ctx context.Context, opts PrepareHostPreflightOptions
 		return nil, fmt.Errorf("get cluster host preflights: %w", err)
 	}
 
-	for _, h := range chpfs {
+	vhpfs, err := GetVendorHostPreflights(ctx, opts.VendorHostPreflights, data)
+	if err != nil {
+		return nil, fmt.Errorf("get vendor host preflights: %w", err)
+	}
+
+	for _, h := range append(chpfs, vhpfs...) {
 		hpf.Collectors = append(hpf.Collectors, h.Spec.Collectors...)
 		hpf.Analyzers = append(hpf.Analyzers, h.Spec.Analyzers...)
 	}
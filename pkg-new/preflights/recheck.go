@@ -0,0 +1,55 @@
+package preflights
+
+import (
+	"fmt"
+	"os"
+
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+)
+
+// RecheckSpecFileName is the name of the file, in the support directory, holding the host
+// preflights the operator periodically runs on the node once it is part of the cluster.
+const RecheckSpecFileName = "host-preflight-spec.yaml"
+
+// kubernetesRemnantsCollectorName is the name of the collector looking for container runtimes and
+// remnants of other Kubernetes installations, the cluster leaves the same ones once it runs.
+const kubernetesRemnantsCollectorName = "kubernetes-remnants"
+
+// RecheckHostPreflightSpec returns the host preflights that still apply once the node runs the
+// cluster. Port and CIDR availability checks are left out as the cluster itself uses them, and
+// so are the checks for remnants of other Kubernetes installations.
+func RecheckHostPreflightSpec(spec *troubleshootv1beta2.HostPreflightSpec) *troubleshootv1beta2.HostPreflightSpec {
+	recheck := &troubleshootv1beta2.HostPreflightSpec{}
+	for _, c := range spec.Collectors {
+		if c == nil || c.TCPPortStatus != nil || c.SubnetAvailable != nil {
+			continue
+		}
+		if c.HostRun != nil && c.HostRun.CollectorName == kubernetesRemnantsCollectorName {
+			continue
+		}
+		recheck.Collectors = append(recheck.Collectors, c)
+	}
+	for _, a := range spec.Analyzers {
+		if a == nil || a.TCPPortStatus != nil || a.SubnetAvailable != nil {
+			continue
+		}
+		if a.TextAnalyze != nil && a.TextAnalyze.FileName == fmt.Sprintf("host-collectors/run-host/%s.txt", kubernetesRemnantsCollectorName) {
+			continue
+		}
+		recheck.Analyzers = append(recheck.Analyzers, a)
+	}
+	return recheck
+}
+
+// SaveRecheckSpecToDisk writes the host preflights that still apply once the node runs the
+// cluster to path, so the operator can run them again later on.
+func SaveRecheckSpecToDisk(spec *troubleshootv1beta2.HostPreflightSpec, path string) error {
+	data, err := serializeHostSpec(RecheckHostPreflightSpec(spec))
+	if err != nil {
+		return fmt.Errorf("marshal host preflight spec: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write host preflight spec to %s: %w", path, err)
+	}
+	return nil
+}
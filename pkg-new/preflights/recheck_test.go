@@ -0,0 +1,37 @@
+package preflights
+
+import (
+	"testing"
+
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecheckHostPreflightSpec(t *testing.T) {
+	spec := &troubleshootv1beta2.HostPreflightSpec{
+		Collectors: []*troubleshootv1beta2.HostCollect{
+			{Memory: &troubleshootv1beta2.Memory{}},
+			{TCPPortStatus: &troubleshootv1beta2.TCPPortStatus{Port: 6443}},
+			{SubnetAvailable: &troubleshootv1beta2.SubnetAvailable{CIDRRangeAlloc: "10.244.0.0/16"}},
+			{HostRun: &troubleshootv1beta2.HostRun{HostCollectorMeta: troubleshootv1beta2.HostCollectorMeta{CollectorName: "kubernetes-remnants"}}},
+		},
+		Analyzers: []*troubleshootv1beta2.HostAnalyze{
+			{Memory: &troubleshootv1beta2.MemoryAnalyze{AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "Memory"}}},
+			{TCPPortStatus: &troubleshootv1beta2.TCPPortStatusAnalyze{AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "Kube API Server Port Availability"}}},
+			{SubnetAvailable: &troubleshootv1beta2.SubnetAvailableAnalyze{AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "Pod CIDR Availability"}}},
+			{TextAnalyze: &troubleshootv1beta2.TextAnalyze{AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "CNI Configuration"}, FileName: "host-collectors/run-host/kubernetes-remnants.txt"}},
+		},
+	}
+
+	recheck := RecheckHostPreflightSpec(spec)
+
+	require.Len(t, recheck.Collectors, 1)
+	assert.NotNil(t, recheck.Collectors[0].Memory)
+	require.Len(t, recheck.Analyzers, 1)
+	assert.Equal(t, "Memory", recheck.Analyzers[0].Memory.CheckName)
+
+	// the spec is not modified
+	assert.Len(t, spec.Collectors, 4)
+	assert.Len(t, spec.Analyzers, 4)
+}
@@ -0,0 +1,170 @@
+package preflights
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+)
+
+// RemediationKind is the kind of change made to the host to fix a host preflight.
+type RemediationKind string
+
+const (
+	RemediationKindSysctl       RemediationKind = "sysctl"
+	RemediationKindKernelModule RemediationKind = "kernel-module"
+)
+
+// sysctlRemediations maps the host preflights fixed by setting a sysctl to its key. The values
+// are the ones set by the installer.
+var sysctlRemediations = map[string]string{
+	"ARP Filter default value for newly created interfaces":             "net.ipv4.conf.default.arp_filter",
+	"ARP Filter value for all interfaces":                               "net.ipv4.conf.all.arp_filter",
+	"ARP Ignore default value for newly created interfaces":             "net.ipv4.conf.default.arp_ignore",
+	"ARP Ignore value for all interfaces":                               "net.ipv4.conf.all.arp_ignore",
+	"Reverse Path Filtering default value for newly created interfaces": "net.ipv4.conf.default.rp_filter",
+	"Reverse Path Filtering value for all interfaces":                   "net.ipv4.conf.all.rp_filter",
+	"IP forwarding":                                            "net.ipv4.ip_forward",
+	"IP forwarding for all interfaces":                         "net.ipv4.conf.all.forwarding",
+	"IP forwarding default value for newly created interfaces": "net.ipv4.conf.default.forwarding",
+	"Bridge netfilter call iptables":                           "net.bridge.bridge-nf-call-iptables",
+	"Maximum number of inotify instances per user":             "fs.inotify.max_user_instances",
+	"Maximum number of inotify watches per user":               "fs.inotify.max_user_watches",
+}
+
+// kernelModuleRemediations maps the host preflights fixed by loading a kernel module to the
+// module.
+var kernelModuleRemediations = map[string]string{
+	"Overlay kernel module":      "overlay",
+	"BR Netfilter kernel module": "br_netfilter",
+	"NF Conntrack kernel module": "nf_conntrack",
+}
+
+// Remediation is a change to the host that fixes a failing host preflight. The installer makes
+// the same change when it configures the host, so making it ahead of time is safe.
+type Remediation struct {
+	// Check is the title of the host preflight fixed by the change. It is empty for the changes
+	// made along with the fixes.
+	Check string          `json:"check,omitempty"`
+	Kind  RemediationKind `json:"kind"`
+	// Name is the sysctl key or the kernel module name.
+	Name    string `json:"name"`
+	Current string `json:"current,omitempty"`
+	Desired string `json:"desired"`
+}
+
+// String describes the change.
+func (r Remediation) String() string {
+	switch r.Kind {
+	case RemediationKindKernelModule:
+		return fmt.Sprintf("load kernel module %s", r.Name)
+	default:
+		current := r.Current
+		if current == "" {
+			current = "unset"
+		}
+		return fmt.Sprintf("sysctl %s: %s -> %s", r.Name, current, r.Desired)
+	}
+}
+
+// PlanRemediations returns the changes that fix the failing host preflights the installer knows
+// how to fix, given the sysctl values and the kernel modules it changes on the host. The installer
+// sets all of its sysctl values and loads all of its kernel modules at once, so the other changes
+// made along with the fixes are returned after them. Failing host preflights that can't be fixed
+// are left out.
+func PlanRemediations(output *apitypes.PreflightsOutput, sysctlChanges map[string]string, modulesToLoad []string) []Remediation {
+	return planRemediations(output, sysctlChanges, modulesToLoad, readSysctl)
+}
+
+func planRemediations(output *apitypes.PreflightsOutput, sysctlChanges map[string]string, modulesToLoad []string, read func(key string) (string, error)) []Remediation {
+	if output == nil {
+		return nil
+	}
+
+	planned := map[string]bool{}
+	sysctl := func(check, key string) Remediation {
+		planned[key] = true
+		// keys of modules that are not loaded don't exist yet
+		current, _ := read(key)
+		return Remediation{
+			Check:   check,
+			Kind:    RemediationKindSysctl,
+			Name:    key,
+			Current: current,
+			Desired: sysctlChanges[key],
+		}
+	}
+	module := func(check, name string) Remediation {
+		planned[name] = true
+		return Remediation{
+			Check:   check,
+			Kind:    RemediationKindKernelModule,
+			Name:    name,
+			Desired: "loaded",
+		}
+	}
+
+	remediations := []Remediation{}
+	for _, record := range output.Fail {
+		if key, ok := sysctlRemediations[record.Title]; ok {
+			if _, ok := sysctlChanges[key]; ok {
+				remediations = append(remediations, sysctl(record.Title, key))
+			}
+			continue
+		}
+		if name, ok := kernelModuleRemediations[record.Title]; ok && slices.Contains(modulesToLoad, name) {
+			remediations = append(remediations, module(record.Title, name))
+		}
+	}
+
+	if NeedsSysctl(remediations) {
+		keys := slices.Sorted(maps.Keys(sysctlChanges))
+		for _, key := range keys {
+			if !planned[key] {
+				remediations = append(remediations, sysctl("", key))
+			}
+		}
+	}
+	if NeedsKernelModules(remediations) {
+		for _, name := range modulesToLoad {
+			if !planned[name] {
+				remediations = append(remediations, module("", name))
+			}
+		}
+	}
+	return remediations
+}
+
+// NeedsKernelModules returns true if the remediations need the kernel modules to be loaded,
+// either to load one of them or to set a sysctl that only exists once they are loaded.
+func NeedsKernelModules(remediations []Remediation) bool {
+	for _, r := range remediations {
+		if r.Kind == RemediationKindKernelModule || strings.HasPrefix(r.Name, "net.bridge.") {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsSysctl returns true if any of the remediations sets a sysctl.
+func NeedsSysctl(remediations []Remediation) bool {
+	for _, r := range remediations {
+		if r.Kind == RemediationKindSysctl {
+			return true
+		}
+	}
+	return false
+}
+
+// readSysctl returns the current value of a sysctl.
+func readSysctl(key string) (string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc/sys", strings.ReplaceAll(key, ".", "/")))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
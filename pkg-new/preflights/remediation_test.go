@@ -0,0 +1,115 @@
+package preflights
+
+import (
+	"errors"
+	"testing"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_planRemediations(t *testing.T) {
+	changes := map[string]string{
+		"net.ipv4.ip_forward":                "1",
+		"net.bridge.bridge-nf-call-iptables": "1",
+		"fs.inotify.max_user_instances":      "1024",
+		"net.ipv4.conf.all.rp_filter":        "2",
+	}
+	modules := []string{"br_netfilter", "nf_conntrack"}
+	current := map[string]string{
+		"net.ipv4.ip_forward":           "0",
+		"fs.inotify.max_user_instances": "128",
+		"net.ipv4.conf.all.rp_filter":   "1",
+	}
+	read := func(key string) (string, error) {
+		if value, ok := current[key]; ok {
+			return value, nil
+		}
+		return "", errors.New("no such file or directory")
+	}
+
+	output := &apitypes.PreflightsOutput{
+		Fail: []apitypes.PreflightsRecord{
+			{Title: "IP forwarding"},
+			{Title: "Bridge netfilter call iptables"},
+			{Title: "Maximum number of inotify instances per user"},
+			{Title: "BR Netfilter kernel module"},
+			{Title: "Memory"},
+			// the installer does not change this key on the host
+			{Title: "ARP Filter value for all interfaces"},
+		},
+		Warn: []apitypes.PreflightsRecord{
+			{Title: "Overlay kernel module"},
+		},
+	}
+
+	got := planRemediations(output, changes, modules, read)
+	assert.Equal(t, []Remediation{
+		{Check: "IP forwarding", Kind: RemediationKindSysctl, Name: "net.ipv4.ip_forward", Current: "0", Desired: "1"},
+		{Check: "Bridge netfilter call iptables", Kind: RemediationKindSysctl, Name: "net.bridge.bridge-nf-call-iptables", Desired: "1"},
+		{Check: "Maximum number of inotify instances per user", Kind: RemediationKindSysctl, Name: "fs.inotify.max_user_instances", Current: "128", Desired: "1024"},
+		{Check: "BR Netfilter kernel module", Kind: RemediationKindKernelModule, Name: "br_netfilter", Desired: "loaded"},
+		// made along with the fixes
+		{Kind: RemediationKindSysctl, Name: "net.ipv4.conf.all.rp_filter", Current: "1", Desired: "2"},
+		{Kind: RemediationKindKernelModule, Name: "nf_conntrack", Desired: "loaded"},
+	}, got)
+
+	// loading the modules does not set the sysctl values
+	got = planRemediations(&apitypes.PreflightsOutput{
+		Fail: []apitypes.PreflightsRecord{{Title: "NF Conntrack kernel module"}},
+	}, changes, modules, read)
+	assert.Equal(t, []Remediation{
+		{Check: "NF Conntrack kernel module", Kind: RemediationKindKernelModule, Name: "nf_conntrack", Desired: "loaded"},
+		{Kind: RemediationKindKernelModule, Name: "br_netfilter", Desired: "loaded"},
+	}, got)
+
+	// a module that is not available can't be loaded
+	assert.Empty(t, planRemediations(&apitypes.PreflightsOutput{
+		Fail: []apitypes.PreflightsRecord{{Title: "Overlay kernel module"}},
+	}, changes, modules, read))
+
+	assert.Empty(t, planRemediations(nil, changes, modules, read))
+	assert.Empty(t, planRemediations(&apitypes.PreflightsOutput{}, changes, modules, read))
+}
+
+func Test_RemediationString(t *testing.T) {
+	assert.Equal(t, "sysctl net.ipv4.ip_forward: 0 -> 1", Remediation{Kind: RemediationKindSysctl, Name: "net.ipv4.ip_forward", Current: "0", Desired: "1"}.String())
+	assert.Equal(t, "sysctl net.bridge.bridge-nf-call-iptables: unset -> 1", Remediation{Kind: RemediationKindSysctl, Name: "net.bridge.bridge-nf-call-iptables", Desired: "1"}.String())
+	assert.Equal(t, "load kernel module overlay", Remediation{Kind: RemediationKindKernelModule, Name: "overlay", Desired: "loaded"}.String())
+}
+
+func Test_NeedsKernelModules(t *testing.T) {
+	tests := []struct {
+		name         string
+		remediations []Remediation
+		wantModules  bool
+		wantSysctl   bool
+	}{
+		{
+			name:         "none",
+			remediations: nil,
+		},
+		{
+			name:         "ip forwarding only",
+			remediations: []Remediation{{Kind: RemediationKindSysctl, Name: "net.ipv4.ip_forward"}},
+			wantSysctl:   true,
+		},
+		{
+			name:         "bridge sysctl needs br_netfilter",
+			remediations: []Remediation{{Kind: RemediationKindSysctl, Name: "net.bridge.bridge-nf-call-iptables"}},
+			wantModules:  true,
+			wantSysctl:   true,
+		},
+		{
+			name:         "kernel module",
+			remediations: []Remediation{{Kind: RemediationKindKernelModule, Name: "overlay"}},
+			wantModules:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantModules, NeedsKernelModules(tt.remediations))
+			assert.Equal(t, tt.wantSysctl, NeedsSysctl(tt.remediations))
+		})
+	}
+}
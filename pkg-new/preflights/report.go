@@ -0,0 +1,305 @@
+package preflights
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+)
+
+const (
+	// ReportSignatureAlgorithm is the algorithm used to sign host preflight reports.
+	ReportSignatureAlgorithm = "ed25519"
+)
+
+var (
+	ErrReportSignatureMissing  = fmt.Errorf("report is not signed")
+	ErrReportKeyRequired       = fmt.Errorf("a key is required to sign or verify a report")
+	ErrReportSignatureInvalid  = fmt.Errorf("report signature is invalid")
+	ErrReportPublicKeyMismatch = fmt.Errorf("report was not signed with the expected key")
+)
+
+// Report holds the result of running the host preflights against a target configuration,
+// ahead of the installation.
+type Report struct {
+	Metadata ReportMetadata             `json:"metadata"`
+	Target   ReportTarget               `json:"target"`
+	Output   *apitypes.PreflightsOutput `json:"output"`
+	// Remediations holds the changes made to the host before the host preflights were run
+	// for the last time.
+	Remediations []Remediation    `json:"remediations,omitempty"`
+	Signature    *ReportSignature `json:"signature,omitempty"`
+}
+
+// ReportMetadata describes the host and the binary that produced the report.
+type ReportMetadata struct {
+	Hostname  string    `json:"hostname"`
+	CreatedAt time.Time `json:"createdAt"`
+	AppSlug   string    `json:"appSlug"`
+	Version   string    `json:"version"`
+}
+
+// ReportTarget is the configuration the host was evaluated against.
+type ReportTarget struct {
+	DataDir                 string               `json:"dataDir"`
+	NetworkInterface        string               `json:"networkInterface"`
+	NodeIP                  string               `json:"nodeIP"`
+	GlobalCIDR              string               `json:"globalCIDR,omitempty"`
+	PodCIDR                 string               `json:"podCIDR"`
+	ServiceCIDR             string               `json:"serviceCIDR"`
+	AdminConsolePort        int                  `json:"adminConsolePort"`
+	LocalArtifactMirrorPort int                  `json:"localArtifactMirrorPort"`
+	Proxy                   *ecv1beta1.ProxySpec `json:"proxy,omitempty"`
+	IsAirgap                bool                 `json:"isAirgap"`
+}
+
+// ReportSignature signs the SHA-256 digest of the report without its signature.
+type ReportSignature struct {
+	Algorithm string `json:"algorithm"`
+	Digest    string `json:"digest"`
+	PublicKey []byte `json:"publicKey"`
+	Signature []byte `json:"signature"`
+}
+
+// Sign signs the report with the provided key. The public key is embedded in the signature, but
+// only the owner of the key vouches for the report: whoever modifies it can sign it again with
+// another key.
+func (r *Report) Sign(key ed25519.PrivateKey) error {
+	if key == nil {
+		return ErrReportKeyRequired
+	}
+
+	digest, err := r.digest()
+	if err != nil {
+		return err
+	}
+
+	r.Signature = &ReportSignature{
+		Algorithm: ReportSignatureAlgorithm,
+		Digest:    hex.EncodeToString(digest),
+		PublicKey: key.Public().(ed25519.PublicKey),
+		Signature: ed25519.Sign(key, digest),
+	}
+	return nil
+}
+
+// Verify checks the report was signed with the provided public key and not modified since. The
+// public key embedded in the signature is not trusted, anyone can sign a modified report.
+func (r *Report) Verify(publicKey ed25519.PublicKey) error {
+	if publicKey == nil {
+		return ErrReportKeyRequired
+	}
+	if r.Signature == nil {
+		return ErrReportSignatureMissing
+	}
+	if r.Signature.Algorithm != ReportSignatureAlgorithm {
+		return fmt.Errorf("unsupported signature algorithm %q", r.Signature.Algorithm)
+	}
+	if len(r.Signature.PublicKey) != ed25519.PublicKeySize {
+		return ErrReportSignatureInvalid
+	}
+	if !publicKey.Equal(ed25519.PublicKey(r.Signature.PublicKey)) {
+		return ErrReportPublicKeyMismatch
+	}
+
+	digest, err := r.digest()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(digest) != r.Signature.Digest {
+		return ErrReportSignatureInvalid
+	}
+	if !ed25519.Verify(r.Signature.PublicKey, digest, r.Signature.Signature) {
+		return ErrReportSignatureInvalid
+	}
+	return nil
+}
+
+// digest returns the SHA-256 digest of the report without its signature.
+func (r *Report) digest() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = nil
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("marshal report: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// SaveReportJSON writes the report as JSON to the provided path.
+func SaveReportJSON(r *Report, path string) error {
+	buf := bytes.NewBuffer(nil)
+	if err := writeReportJSON(r, buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write preflights report to %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeReportJSON(r *Report, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("unable to marshal preflights report: %w", err)
+	}
+	return nil
+}
+
+// SaveReportHTML writes the report as a standalone HTML page to the provided path.
+func SaveReportHTML(r *Report, path string) error {
+	buf := bytes.NewBuffer(nil)
+	if err := WriteReportHTML(r, buf); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write preflights report to %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteReportHTML renders the report as a standalone HTML page.
+func WriteReportHTML(r *Report, w io.Writer) error {
+	if err := reportHTMLTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("unable to render preflights report: %w", err)
+	}
+	return nil
+}
+
+// ReportFromReader reads a JSON report from the provided reader.
+func ReportFromReader(from io.Reader) (*Report, error) {
+	report := &Report{}
+	if err := json.NewDecoder(from).Decode(report); err != nil {
+		return nil, fmt.Errorf("unable to decode preflights report: %w", err)
+	}
+	return report, nil
+}
+
+// ParseReportSigningKey parses a PEM encoded PKCS #8 ed25519 private key, as generated by
+// "openssl genpkey -algorithm ed25519".
+func ParseReportSigningKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an %s key", ReportSignatureAlgorithm)
+	}
+	return edKey, nil
+}
+
+// ParseReportPublicKey parses a PEM encoded PKIX ed25519 public key, as generated by
+// "openssl pkey -pubout".
+func ParseReportPublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not an %s key", ReportSignatureAlgorithm)
+	}
+	return edKey, nil
+}
+
+var reportHTMLTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Host preflights report for {{ .Metadata.Hostname }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+.fail { color: #b00020; font-weight: bold; }
+.warn { color: #a15c00; font-weight: bold; }
+.pass { color: #1b7f3b; font-weight: bold; }
+code { word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>Host preflights report for {{ .Metadata.Hostname }}</h1>
+<p>{{ .Metadata.AppSlug }} {{ .Metadata.Version }}, created at {{ .Metadata.CreatedAt.Format "2006-01-02 15:04:05 MST" }}</p>
+{{- with .Output }}
+<p>{{ len .Fail }} failed, {{ len .Warn }} warned, {{ len .Pass }} passed.</p>
+{{- end }}
+
+<h2>Target configuration</h2>
+<table>
+<tr><th>Data directory</th><td>{{ .Target.DataDir }}</td></tr>
+<tr><th>Network interface</th><td>{{ .Target.NetworkInterface }} ({{ .Target.NodeIP }})</td></tr>
+{{- if .Target.GlobalCIDR }}
+<tr><th>CIDR</th><td>{{ .Target.GlobalCIDR }}</td></tr>
+{{- end }}
+<tr><th>Pod CIDR</th><td>{{ .Target.PodCIDR }}</td></tr>
+<tr><th>Service CIDR</th><td>{{ .Target.ServiceCIDR }}</td></tr>
+<tr><th>Admin Console port</th><td>{{ .Target.AdminConsolePort }}</td></tr>
+<tr><th>Local Artifact Mirror port</th><td>{{ .Target.LocalArtifactMirrorPort }}</td></tr>
+{{- with .Target.Proxy }}
+<tr><th>HTTP proxy</th><td>{{ .HTTPProxy }}</td></tr>
+<tr><th>HTTPS proxy</th><td>{{ .HTTPSProxy }}</td></tr>
+<tr><th>No proxy</th><td>{{ .ProvidedNoProxy }}</td></tr>
+{{- end }}
+<tr><th>Air gap</th><td>{{ .Target.IsAirgap }}</td></tr>
+</table>
+
+<h2>Results</h2>
+<table>
+<tr><th>Result</th><th>Check</th><th>Message</th></tr>
+{{- with .Output }}
+{{- range .Fail }}
+<tr><td class="fail">Fail</td><td>{{ .Title }}</td><td>{{ .Message }}</td></tr>
+{{- end }}
+{{- range .Warn }}
+<tr><td class="warn">Warn</td><td>{{ .Title }}</td><td>{{ .Message }}</td></tr>
+{{- end }}
+{{- range .Pass }}
+<tr><td class="pass">Pass</td><td>{{ .Title }}</td><td>{{ .Message }}</td></tr>
+{{- end }}
+{{- end }}
+</table>
+
+{{- with .Remediations }}
+<h2>Changes made to the host</h2>
+<table>
+<tr><th>Check</th><th>Change</th></tr>
+{{- range . }}
+<tr><td>{{ or .Check "(made along with the fixes)" }}</td><td>{{ .String }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+{{- with .Signature }}
+<h2>Signature</h2>
+<p>The JSON version of this report is signed. Verify it before relying on these results.</p>
+<table>
+<tr><th>Algorithm</th><td>{{ .Algorithm }}</td></tr>
+<tr><th>SHA-256 digest</th><td><code>{{ .Digest }}</code></td></tr>
+</table>
+{{- end }}
+</body>
+</html>
+`))
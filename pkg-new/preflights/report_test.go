@@ -0,0 +1,119 @@
+package preflights
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() *Report {
+	return &Report{
+		Metadata: ReportMetadata{
+			Hostname:  "node-1",
+			CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+			AppSlug:   "my-app",
+			Version:   "v2.0.0",
+		},
+		Target: ReportTarget{
+			DataDir:     "/var/lib/embedded-cluster",
+			PodCIDR:     "10.244.0.0/17",
+			ServiceCIDR: "10.244.128.0/17",
+		},
+		Output: &apitypes.PreflightsOutput{
+			Pass: []apitypes.PreflightsRecord{{Title: "CPU", Message: "Enough CPU"}},
+			Fail: []apitypes.PreflightsRecord{{Title: "Memory", Message: "<script>alert(1)</script>"}},
+		},
+	}
+}
+
+func TestReport_SignAndVerify(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	report := testReport()
+	assert.ErrorIs(t, report.Verify(pub), ErrReportSignatureMissing)
+
+	require.NoError(t, report.Sign(key))
+	assert.NoError(t, report.Verify(pub))
+	assert.ErrorIs(t, report.Verify(otherPub), ErrReportPublicKeyMismatch)
+
+	// the signature survives a round trip through json
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, writeReportJSON(report, buf))
+	decoded, err := ReportFromReader(buf)
+	require.NoError(t, err)
+	assert.NoError(t, decoded.Verify(pub))
+
+	// any change to the results is detected
+	decoded.Output.Fail = nil
+	assert.ErrorIs(t, decoded.Verify(pub), ErrReportSignatureInvalid)
+}
+
+func TestReport_KeyRequired(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	report := testReport()
+	assert.ErrorIs(t, report.Sign(nil), ErrReportKeyRequired)
+	assert.Nil(t, report.Signature)
+
+	// a report signed again with another key after being modified is only rejected when the
+	// expected key is checked
+	require.NoError(t, report.Sign(key))
+	report.Target.DataDir = "/opt"
+	otherPub, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	require.NoError(t, report.Sign(otherKey))
+	assert.ErrorIs(t, report.Verify(nil), ErrReportKeyRequired)
+	assert.ErrorIs(t, report.Verify(pub), ErrReportPublicKeyMismatch)
+	assert.NoError(t, report.Verify(otherPub))
+}
+
+func TestParseReportKeys(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+
+	parsedKey, err := ParseReportSigningKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	require.NoError(t, err)
+	assert.True(t, key.Equal(parsedKey))
+
+	parsedPub, err := ParseReportPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	require.NoError(t, err)
+	assert.True(t, pub.Equal(parsedPub))
+
+	_, err = ParseReportSigningKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestWriteReportHTML(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	report := testReport()
+	require.NoError(t, report.Sign(key))
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, WriteReportHTML(report, buf))
+
+	html := buf.String()
+	assert.Contains(t, html, "Host preflights report for node-1")
+	assert.Contains(t, html, "1 failed, 0 warned, 1 passed.")
+	assert.Contains(t, html, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, html, "<script>")
+	assert.Contains(t, html, report.Signature.Digest)
+}
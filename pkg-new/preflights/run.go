@@ -28,7 +28,33 @@ func (p *PreflightRunner) RunHostPreflights(ctx context.Context, spec *troublesh
 		return nil, "", fmt.Errorf("marshal host preflight spec: %w", err)
 	}
 
-	return p.runPreflights(ctx, specYAML, opts)
+	out, stderr, err := p.runPreflights(ctx, specYAML, opts)
+	if out != nil {
+		markStrictHostPreflights(out, spec)
+	}
+	if err == nil {
+		if len(opts.StorageBenchmarks) > 0 {
+			appendOutput(out, RunStorageBenchmarks(ctx, opts.StorageBenchmarks))
+		}
+		if opts.DNSDiagnostics != nil {
+			appendOutput(out, RunDNSDiagnostics(ctx, *opts.DNSDiagnostics))
+		}
+		if opts.IPv6RouteCheck != nil {
+			appendOutput(out, RunIPv6RouteCheck(*opts.IPv6RouteCheck))
+		}
+		appendOutput(out, opts.AdditionalResults)
+	}
+	return out, stderr, err
+}
+
+// appendOutput adds the results in other to out.
+func appendOutput(out, other *apitypes.PreflightsOutput) {
+	if other == nil {
+		return
+	}
+	out.Pass = append(out.Pass, other.Pass...)
+	out.Warn = append(out.Warn, other.Warn...)
+	out.Fail = append(out.Fail, other.Fail...)
 }
 
 // RunAppPreflights runs the provided app preflight spec locally.
@@ -117,6 +143,49 @@ func saveSpecToTempFile(specYAML []byte) (string, error) {
 	return tmpfile.Name(), nil
 }
 
+// markStrictHostPreflights marks the results of the host analyzers set as strict in the spec.
+// The preflight binary only reports strict for app preflights so host results are matched to
+// their analyzer by title.
+func markStrictHostPreflights(out *apitypes.PreflightsOutput, spec *troubleshootv1beta2.HostPreflightSpec) {
+	titles := strictHostAnalyzerTitles(spec)
+	if len(titles) == 0 {
+		return
+	}
+	for _, records := range [][]apitypes.PreflightsRecord{out.Pass, out.Warn, out.Fail} {
+		for i := range records {
+			if titles[records[i].Title] {
+				records[i].Strict = true
+			}
+		}
+	}
+}
+
+// strictHostAnalyzerTitles returns the check names of the host analyzers that are strict and
+// not excluded.
+func strictHostAnalyzerTitles(spec *troubleshootv1beta2.HostPreflightSpec) map[string]bool {
+	titles := map[string]bool{}
+	for _, analyzer := range spec.Analyzers {
+		data, err := json.Marshal(analyzer)
+		if err != nil {
+			continue
+		}
+		// every host analyzer embeds the analyzer meta, only one of them is set
+		analyzers := map[string]troubleshootv1beta2.AnalyzeMeta{}
+		if err := json.Unmarshal(data, &analyzers); err != nil {
+			continue
+		}
+		for _, meta := range analyzers {
+			if meta.Exclude.BoolOrDefaultFalse() || !meta.Strict.BoolOrDefaultFalse() {
+				continue
+			}
+			if meta.CheckName != "" {
+				titles[meta.CheckName] = true
+			}
+		}
+	}
+	return titles
+}
+
 func dedup[T any](objs []T) []T {
 	seen := make(map[string]bool)
 	out := []T{}
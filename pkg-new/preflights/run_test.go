@@ -5,8 +5,11 @@ import (
 	"strings"
 	"testing"
 
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	troubleshootv1beta2 "github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	"github.com/replicatedhq/troubleshoot/pkg/multitype"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -269,3 +272,47 @@ func Test_preparePreflightEnv(t *testing.T) {
 		})
 	}
 }
+
+func Test_markStrictHostPreflights(t *testing.T) {
+	spec := &troubleshootv1beta2.HostPreflightSpec{
+		Analyzers: []*troubleshootv1beta2.HostAnalyze{
+			{
+				DiskUsage: &troubleshootv1beta2.DiskUsageAnalyze{
+					AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "App Disk", Strict: multitype.FromBool(true)},
+				},
+			},
+			{
+				CPU: &troubleshootv1beta2.CPUAnalyze{
+					AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "AVX2 Support", Strict: multitype.FromString("true")},
+				},
+			},
+			{
+				Memory: &troubleshootv1beta2.MemoryAnalyze{
+					AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{CheckName: "Memory"},
+				},
+			},
+			{
+				TCPPortStatus: &troubleshootv1beta2.TCPPortStatusAnalyze{
+					AnalyzeMeta: troubleshootv1beta2.AnalyzeMeta{
+						CheckName: "Excluded Port",
+						Strict:    multitype.FromBool(true),
+						Exclude:   multitype.FromBool(true),
+					},
+				},
+			},
+		},
+	}
+	out := &apitypes.PreflightsOutput{
+		Pass: []apitypes.PreflightsRecord{{Title: "Memory"}},
+		Warn: []apitypes.PreflightsRecord{{Title: "Excluded Port"}},
+		Fail: []apitypes.PreflightsRecord{{Title: "App Disk"}, {Title: "AVX2 Support"}},
+	}
+
+	markStrictHostPreflights(out, spec)
+
+	assert.False(t, out.Pass[0].Strict)
+	assert.False(t, out.Warn[0].Strict)
+	assert.True(t, out.Fail[0].Strict)
+	assert.True(t, out.Fail[1].Strict)
+	assert.True(t, out.HasStrictFailures())
+}
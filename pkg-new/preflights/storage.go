@@ -0,0 +1,347 @@
+package preflights
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+)
+
+// StorageBenchmark is a benchmark of the storage of a directory run along with the host
+// preflights. The troubleshoot filesystem performance collector only measures write latency so
+// throughput and IOPS are measured here.
+type StorageBenchmark struct {
+	// Name names the directory in the titles of the checks, e.g. "Data Directory".
+	Name       string
+	Directory  string
+	Thresholds ecv1beta1.StoragePerformance
+}
+
+// StorageBenchmarkResult holds the performance measured by a storage benchmark.
+type StorageBenchmarkResult struct {
+	SequentialWriteMBps float64
+	RandomWriteIOPS     float64
+	FsyncLatencyP50     time.Duration
+	FsyncLatencyP99     time.Duration
+}
+
+// storageBenchmarkParams sizes the storage benchmark. Every phase stops after its runtime even
+// when it did not complete its operations, so slow storage does not hold the installation.
+type storageBenchmarkParams struct {
+	sequentialSize  int64
+	sequentialBlock int
+	randomOps       int
+	fsyncOps        int
+	fsyncSize       int
+	runtime         time.Duration
+}
+
+var defaultStorageBenchmarkParams = storageBenchmarkParams{
+	sequentialSize:  256 << 20,
+	sequentialBlock: 1 << 20,
+	randomOps:       2000,
+	fsyncOps:        1000,
+	// the size of an etcd write-ahead log entry, as in the etcd write latency check
+	fsyncSize: 2300,
+	runtime:   10 * time.Second,
+}
+
+const randomBlockSize = 4096
+
+// RunStorageBenchmarks benchmarks the storage of the directories and returns the results of the
+// checks against their thresholds. Directories on the same filesystem are only benchmarked once.
+func RunStorageBenchmarks(ctx context.Context, benchmarks []StorageBenchmark) *apitypes.PreflightsOutput {
+	out := &apitypes.PreflightsOutput{}
+	results := map[uint64]*StorageBenchmarkResult{}
+	for _, benchmark := range benchmarks {
+		dev, devErr := filesystemID(benchmark.Directory)
+		result, ok := results[dev]
+		if devErr != nil || !ok {
+			var err error
+			result, err = runStorageBenchmark(ctx, benchmark.Directory, defaultStorageBenchmarkParams)
+			if err != nil {
+				out.Fail = append(out.Fail, apitypes.PreflightsRecord{
+					Title:   fmt.Sprintf("%s Storage Performance", benchmark.Name),
+					Message: fmt.Sprintf("Unable to benchmark the storage of %s: %v", benchmark.Directory, err),
+					Strict:  benchmark.Thresholds.Strict,
+				})
+				continue
+			}
+			if devErr == nil {
+				results[dev] = result
+			}
+		}
+		analyzeStorageBenchmark(out, benchmark, result)
+	}
+	return out
+}
+
+// filesystemID returns the ID of the device holding the directory, or its closest existing
+// parent when it does not exist yet.
+func filesystemID(dir string) (uint64, error) {
+	existing, err := nearestExistingDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(existing)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("unable to get the device of %s", existing)
+	}
+	return uint64(stat.Dev), nil
+}
+
+// nearestExistingDir returns the directory, or its closest existing parent when it does not
+// exist yet. The directory is created on the filesystem of that parent.
+func nearestExistingDir(dir string) (string, error) {
+	for {
+		_, err := os.Stat(dir)
+		if err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if !os.IsNotExist(err) || parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
+
+// runStorageBenchmark measures the sequential write throughput, the random 4k synchronous write
+// IOPS and the latency of small writes followed by an fsync in a temporary directory under dir.
+// A missing dir is not created, the benchmark runs under its closest existing parent instead,
+// on the same filesystem.
+func runStorageBenchmark(ctx context.Context, dir string, params storageBenchmarkParams) (*StorageBenchmarkResult, error) {
+	existing, err := nearestExistingDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("find directory: %w", err)
+	}
+	tmpdir, err := os.MkdirTemp(existing, ".storage-benchmark-")
+	if err != nil {
+		return nil, fmt.Errorf("create benchmark directory: %w", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	result := &StorageBenchmarkResult{}
+
+	path := filepath.Join(tmpdir, "sequential")
+	written, mbps, err := benchmarkSequentialWrite(ctx, path, params)
+	if err != nil {
+		return nil, fmt.Errorf("sequential write: %w", err)
+	}
+	result.SequentialWriteMBps = mbps
+
+	// the random writes land in the file written sequentially so no block is allocated
+	result.RandomWriteIOPS, err = benchmarkRandomWrite(ctx, path, written, params)
+	if err != nil {
+		return nil, fmt.Errorf("random write: %w", err)
+	}
+
+	latencies, err := benchmarkFsync(ctx, filepath.Join(tmpdir, "fsync"), params)
+	if err != nil {
+		return nil, fmt.Errorf("fsync: %w", err)
+	}
+	result.FsyncLatencyP50 = percentile(latencies, 50)
+	result.FsyncLatencyP99 = percentile(latencies, 99)
+
+	return result, nil
+}
+
+// benchmarkSequentialWrite writes the file in large blocks and syncs it, it returns the number
+// of bytes written and the throughput in megabytes per second.
+func benchmarkSequentialWrite(ctx context.Context, path string, params storageBenchmarkParams) (int64, float64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	block := make([]byte, params.sequentialBlock)
+	if _, err := rand.Read(block); err != nil {
+		return 0, 0, err
+	}
+
+	var written int64
+	start := time.Now()
+	for written < params.sequentialSize && time.Since(start) < params.runtime {
+		if err := ctx.Err(); err != nil {
+			return 0, 0, err
+		}
+		n, err := f.Write(block)
+		if err != nil {
+			return 0, 0, err
+		}
+		written += int64(n)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, 0, err
+	}
+	elapsed := time.Since(start)
+
+	return written, float64(written) / 1e6 / elapsed.Seconds(), nil
+}
+
+// benchmarkRandomWrite writes 4k blocks at random offsets of the file with synchronous writes,
+// one at a time, and returns the number of writes per second.
+func benchmarkRandomWrite(ctx context.Context, path string, size int64, params storageBenchmarkParams) (float64, error) {
+	blocks := size / randomBlockSize
+	if blocks == 0 {
+		return 0, fmt.Errorf("file is smaller than a block")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_SYNC, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	block := make([]byte, randomBlockSize)
+	if _, err := rand.Read(block); err != nil {
+		return 0, err
+	}
+
+	ops := 0
+	start := time.Now()
+	for ops < params.randomOps && time.Since(start) < params.runtime {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(blocks))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := f.WriteAt(block, n.Int64()*randomBlockSize); err != nil {
+			return 0, err
+		}
+		ops++
+	}
+
+	return float64(ops) / time.Since(start).Seconds(), nil
+}
+
+// benchmarkFsync appends small writes to the file, each followed by an fsync, and returns the
+// latency of every write sorted from the fastest to the slowest.
+func benchmarkFsync(ctx context.Context, path string, params storageBenchmarkParams) ([]time.Duration, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data := make([]byte, params.fsyncSize)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+
+	latencies := []time.Duration{}
+	start := time.Now()
+	for len(latencies) < params.fsyncOps && time.Since(start) < params.runtime {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		opStart := time.Now()
+		if _, err := f.Write(data); err != nil {
+			return nil, err
+		}
+		if err := f.Sync(); err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, time.Since(opStart))
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies, nil
+}
+
+// percentile returns the pth percentile of the sorted latencies, using the nearest rank.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// analyzeStorageBenchmark adds the results of the checks set in the thresholds of the benchmark
+// to the output.
+func analyzeStorageBenchmark(out *apitypes.PreflightsOutput, benchmark StorageBenchmark, result *StorageBenchmarkResult) {
+	thresholds := benchmark.Thresholds
+	add := func(title string, pass bool, message string) {
+		record := apitypes.PreflightsRecord{
+			Title:   fmt.Sprintf("%s %s", benchmark.Name, title),
+			Message: message,
+			Strict:  thresholds.Strict,
+		}
+		if pass {
+			out.Pass = append(out.Pass, record)
+		} else {
+			out.Fail = append(out.Fail, record)
+		}
+	}
+
+	if thresholds.MinSequentialWriteMBps > 0 {
+		pass := result.SequentialWriteMBps >= float64(thresholds.MinSequentialWriteMBps)
+		message := fmt.Sprintf(
+			"Sequential writes to %s reached %.0f MB/s, at least %d MB/s is required.",
+			benchmark.Directory, result.SequentialWriteMBps, thresholds.MinSequentialWriteMBps,
+		)
+		if !pass {
+			message += fmt.Sprintf(" Use faster storage for %s.", benchmark.Directory)
+		}
+		add("Sequential Write Throughput", pass, message)
+	}
+
+	if thresholds.MinRandomWriteIOPS > 0 {
+		pass := result.RandomWriteIOPS >= float64(thresholds.MinRandomWriteIOPS)
+		message := fmt.Sprintf(
+			"Random 4k synchronous writes to %s reached %.0f IOPS, at least %d IOPS is required.",
+			benchmark.Directory, result.RandomWriteIOPS, thresholds.MinRandomWriteIOPS,
+		)
+		if !pass {
+			message += fmt.Sprintf(" Use faster storage for %s.", benchmark.Directory)
+		}
+		add("Random Write IOPS", pass, message)
+	}
+
+	if thresholds.MaxFsyncLatencyP50 != nil || thresholds.MaxFsyncLatencyP99 != nil {
+		pass := true
+		required := ""
+		if limit := thresholds.MaxFsyncLatencyP50; limit != nil {
+			pass = pass && result.FsyncLatencyP50 <= limit.Duration
+			required = fmt.Sprintf("p50 at most %s", limit.Duration)
+		}
+		if limit := thresholds.MaxFsyncLatencyP99; limit != nil {
+			pass = pass && result.FsyncLatencyP99 <= limit.Duration
+			if required != "" {
+				required += " and "
+			}
+			required += fmt.Sprintf("p99 at most %s", limit.Duration)
+		}
+		message := fmt.Sprintf(
+			"Writes followed by an fsync to %s took p50 %s and p99 %s, %s is required.",
+			benchmark.Directory,
+			result.FsyncLatencyP50.Round(time.Microsecond),
+			result.FsyncLatencyP99.Round(time.Microsecond),
+			required,
+		)
+		if !pass {
+			message += fmt.Sprintf(" Use faster storage for %s.", benchmark.Directory)
+		}
+		add("Fsync Latency", pass, message)
+	}
+}
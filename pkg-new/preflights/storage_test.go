@@ -0,0 +1,145 @@
+package preflights
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRunStorageBenchmark(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "embedded-cluster", "openebs-local")
+
+	result, err := runStorageBenchmark(context.Background(), dir, storageBenchmarkParams{
+		sequentialSize:  1 << 20,
+		sequentialBlock: 64 << 10,
+		randomOps:       10,
+		fsyncOps:        10,
+		fsyncSize:       2300,
+		runtime:         5 * time.Second,
+	})
+	require.NoError(t, err)
+	assert.Greater(t, result.SequentialWriteMBps, 0.0)
+	assert.Greater(t, result.RandomWriteIOPS, 0.0)
+	assert.Greater(t, result.FsyncLatencyP50, time.Duration(0))
+	assert.LessOrEqual(t, result.FsyncLatencyP50, result.FsyncLatencyP99)
+
+	// the missing directory was not created and the benchmark files were removed from its parent
+	assert.NoDirExists(t, filepath.Join(parent, "embedded-cluster"))
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 1*time.Millisecond, percentile(latencies, 0))
+	assert.Equal(t, time.Duration(0), percentile(nil, 99))
+}
+
+func TestAnalyzeStorageBenchmark(t *testing.T) {
+	result := &StorageBenchmarkResult{
+		SequentialWriteMBps: 150,
+		RandomWriteIOPS:     800,
+		FsyncLatencyP50:     2 * time.Millisecond,
+		FsyncLatencyP99:     12 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name       string
+		thresholds ecv1beta1.StoragePerformance
+		wantPass   []string
+		wantFail   []string
+		wantStrict bool
+	}{
+		{
+			name: "no thresholds",
+		},
+		{
+			name: "all thresholds met",
+			thresholds: ecv1beta1.StoragePerformance{
+				MinSequentialWriteMBps: 100,
+				MinRandomWriteIOPS:     500,
+				MaxFsyncLatencyP50:     &metav1.Duration{Duration: 5 * time.Millisecond},
+				MaxFsyncLatencyP99:     &metav1.Duration{Duration: 20 * time.Millisecond},
+			},
+			wantPass: []string{
+				"Data Directory Sequential Write Throughput",
+				"Data Directory Random Write IOPS",
+				"Data Directory Fsync Latency",
+			},
+		},
+		{
+			name: "strict thresholds not met",
+			thresholds: ecv1beta1.StoragePerformance{
+				MinSequentialWriteMBps: 200,
+				MinRandomWriteIOPS:     500,
+				MaxFsyncLatencyP50:     &metav1.Duration{Duration: 5 * time.Millisecond},
+				MaxFsyncLatencyP99:     &metav1.Duration{Duration: 10 * time.Millisecond},
+				Strict:                 true,
+			},
+			wantPass:   []string{"Data Directory Random Write IOPS"},
+			wantFail:   []string{"Data Directory Sequential Write Throughput", "Data Directory Fsync Latency"},
+			wantStrict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &apitypes.PreflightsOutput{}
+			analyzeStorageBenchmark(out, StorageBenchmark{
+				Name:       "Data Directory",
+				Directory:  "/var/lib/embedded-cluster",
+				Thresholds: tt.thresholds,
+			}, result)
+
+			assert.Equal(t, tt.wantPass, recordTitles(out.Pass))
+			assert.Equal(t, tt.wantFail, recordTitles(out.Fail))
+			assert.Equal(t, tt.wantStrict, out.HasStrictFailures())
+		})
+	}
+}
+
+func TestAnalyzeStorageBenchmarkMessages(t *testing.T) {
+	out := &apitypes.PreflightsOutput{}
+	analyzeStorageBenchmark(out, StorageBenchmark{
+		Name:      "OpenEBS Data Directory",
+		Directory: "/var/lib/embedded-cluster/openebs-local",
+		Thresholds: ecv1beta1.StoragePerformance{
+			MinSequentialWriteMBps: 200,
+			MaxFsyncLatencyP99:     &metav1.Duration{Duration: 10 * time.Millisecond},
+		},
+	}, &StorageBenchmarkResult{
+		SequentialWriteMBps: 80,
+		FsyncLatencyP50:     time.Millisecond,
+		FsyncLatencyP99:     4 * time.Millisecond,
+	})
+
+	require.Len(t, out.Fail, 1)
+	assert.Equal(t, "Sequential writes to /var/lib/embedded-cluster/openebs-local reached 80 MB/s, at least 200 MB/s is required. Use faster storage for /var/lib/embedded-cluster/openebs-local.", out.Fail[0].Message)
+	require.Len(t, out.Pass, 1)
+	assert.Equal(t, "Writes followed by an fsync to /var/lib/embedded-cluster/openebs-local took p50 1ms and p99 4ms, p99 at most 10ms is required.", out.Pass[0].Message)
+}
+
+func recordTitles(records []apitypes.PreflightsRecord) []string {
+	if len(records) == 0 {
+		return nil
+	}
+	titles := []string{}
+	for _, record := range records {
+		titles = append(titles, record.Title)
+	}
+	return titles
+}
@@ -55,6 +55,37 @@ func GetClusterHostPreflights(ctx context.Context, mode apitypes.Mode, data type
 	return kinds.HostPreflightsV1Beta2, nil
 }
 
+// GetVendorHostPreflights renders the host preflight specs shipped by the vendor with the same
+// data as the cluster host preflights. A reference to a vendor variable that is not set is an
+// error so typos don't silently render an empty value.
+func GetVendorHostPreflights(ctx context.Context, specs [][]byte, data types.HostPreflightTemplateData) ([]v1beta2.HostPreflight, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	rendered := []string{}
+	for i, spec := range specs {
+		tmpl, err := template.New("vendor-preflight").Option("missingkey=error").Parse(string(spec))
+		if err != nil {
+			return nil, fmt.Errorf("parse vendor host preflight %d: %w", i, err)
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, fmt.Errorf("render vendor host preflight %d: %w", i, err)
+		}
+		rendered = append(rendered, buf.String())
+	}
+
+	kinds, err := loader.LoadSpecs(ctx, loader.LoadOptions{
+		RawSpecs: rendered,
+		Strict:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load vendor host preflight specs: %w", err)
+	}
+	return kinds.HostPreflightsV1Beta2, nil
+}
+
 func renderHostPreflightTemplate(spec string, data types.HostPreflightTemplateData) (string, error) {
 	tmpl, err := template.New("preflight").Parse(spec)
 	if err != nil {
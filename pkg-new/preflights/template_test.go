@@ -9,6 +9,7 @@ import (
 
 	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/preflights/types"
+	analyzer "github.com/replicatedhq/troubleshoot/pkg/analyze"
 	"github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
 	"github.com/replicatedhq/troubleshoot/pkg/multitype"
 	"github.com/stretchr/testify/require"
@@ -700,6 +701,208 @@ func TestTemplateNetfilterBackendCollector(t *testing.T) {
 	req.True(foundAnalyzer, "expected Netfilter backend textAnalyze analyzer")
 }
 
+func TestTemplateTimeSyncServiceAnalyzer(t *testing.T) {
+	for _, isUI := range []bool{false, true} {
+		req := require.New(t)
+		tl := types.HostPreflightTemplateData{IsUI: isUI}
+		hpfc, err := GetClusterHostPreflights(context.Background(), apitypes.ModeInstall, tl)
+		req.NoError(err)
+
+		commonSpec := hpfc[0].Spec
+
+		var foundCollector bool
+		for _, c := range commonSpec.Collectors {
+			if c.HostRun != nil && c.HostRun.CollectorName == "time-sync-service" {
+				foundCollector = true
+				break
+			}
+		}
+		req.True(foundCollector, "expected time-sync-service run collector")
+
+		var foundAnalyzer bool
+		for _, a := range commonSpec.Analyzers {
+			if a.TextAnalyze != nil && a.TextAnalyze.CheckName == "Time Synchronization Service" {
+				foundAnalyzer = true
+				req.Equal("host-collectors/run-host/time-sync-service.txt", a.TextAnalyze.FileName)
+				req.Len(a.TextAnalyze.Outcomes, 3)
+				req.Equal("Service == none", a.TextAnalyze.Outcomes[0].Warn.When)
+				if isUI {
+					req.NotContains(a.TextAnalyze.Outcomes[0].Warn.Message, "--ntp-servers")
+				} else {
+					req.Contains(a.TextAnalyze.Outcomes[0].Warn.Message, "ntpd, or use --ntp-servers")
+				}
+				req.Equal("{{ .Service }} is active and has synchronized the clock", a.TextAnalyze.Outcomes[2].Pass.Message)
+				break
+			}
+		}
+		req.True(foundAnalyzer, "expected Time Synchronization Service textAnalyze analyzer")
+	}
+}
+
+func TestTemplateKubernetesRemnantsAnalyzers(t *testing.T) {
+	req := require.New(t)
+	hpfc, err := GetClusterHostPreflights(context.Background(), apitypes.ModeInstall, types.HostPreflightTemplateData{})
+	req.NoError(err)
+
+	installSpec := hpfc[1].Spec
+
+	var foundCollector bool
+	for _, c := range installSpec.Collectors {
+		if c.HostRun != nil && c.HostRun.CollectorName == "kubernetes-remnants" {
+			foundCollector = true
+			req.Equal("sh", c.HostRun.Command)
+			break
+		}
+	}
+	req.True(foundCollector, "expected kubernetes-remnants run collector")
+
+	analyzers := []*v1beta2.HostAnalyze{}
+	for _, a := range installSpec.Analyzers {
+		if a.TextAnalyze != nil && a.TextAnalyze.FileName == "host-collectors/run-host/kubernetes-remnants.txt" {
+			analyzers = append(analyzers, a)
+		}
+	}
+	req.Len(analyzers, 5)
+
+	tests := []struct {
+		name   string
+		output string
+		want   map[string]string
+	}{
+		{
+			name: "clean host",
+			output: `docker=inactive containerd=inactive crio=inactive podman=none
+kubelet=none
+cni-configs=none
+kubernetes-firewall-rules=0
+kurl=none
+`,
+			want: map[string]string{
+				"kURL Installation":         "pass",
+				"Container Runtime":         "pass",
+				"Kubelet":                   "pass",
+				"CNI Configuration":         "pass",
+				"Kubernetes Firewall Rules": "pass",
+			},
+		},
+		{
+			name: "host running kurl with docker",
+			output: `docker=active containerd=active crio=inactive podman=none
+kubelet=active
+cni-configs=10-weave.conflist
+kubernetes-firewall-rules=312
+kurl=running
+`,
+			want: map[string]string{
+				"kURL Installation":         "fail",
+				"Container Runtime":         "fail",
+				"Kubelet":                   "fail",
+				"CNI Configuration":         "fail",
+				"Kubernetes Firewall Rules": "warn",
+			},
+		},
+		{
+			name: "leftovers of a removed distribution",
+			output: `docker=inactive containerd=inactive crio=inactive podman=running
+kubelet=leftover
+cni-configs=10-calico.conflist,calico-kubeconfig
+kubernetes-firewall-rules=0
+kurl=leftover
+`,
+			want: map[string]string{
+				"kURL Installation":         "warn",
+				"Container Runtime":         "warn",
+				"Kubelet":                   "warn",
+				"CNI Configuration":         "fail",
+				"Kubernetes Firewall Rules": "pass",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getFile := func(string) ([]byte, error) { return []byte(tt.output), nil }
+			findFiles := func(path string, _ []string) (map[string][]byte, error) {
+				return map[string][]byte{path: []byte(tt.output)}, nil
+			}
+			got := map[string]string{}
+			for _, a := range analyzers {
+				for _, result := range analyzer.HostAnalyze(context.Background(), a, getFile, findFiles) {
+					switch {
+					case result.IsPass:
+						got[result.Title] = "pass"
+					case result.IsWarn:
+						got[result.Title] = "warn"
+					case result.IsFail:
+						got[result.Title] = "fail"
+					}
+				}
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTemplateIPv6Analyzers(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        types.HostPreflightTemplateData
+		excludeIPv4 string
+		excludeIPv6 string
+	}{
+		{
+			name:        "ipv4",
+			data:        types.HostPreflightTemplateData{},
+			excludeIPv4: "false",
+			excludeIPv6: "true",
+		},
+		{
+			name:        "dual-stack",
+			data:        types.HostPreflightTemplateData{IPv6: true},
+			excludeIPv4: "false",
+			excludeIPv6: "false",
+		},
+		{
+			name:        "ipv6 only",
+			data:        types.HostPreflightTemplateData{IPv6: true, IPv6Only: true},
+			excludeIPv4: "true",
+			excludeIPv6: "false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			hpfc, err := GetClusterHostPreflights(context.Background(), apitypes.ModeInstall, tt.data)
+			req.NoError(err)
+
+			excludes := map[string]string{}
+			for _, a := range append(hpfc[0].Spec.Analyzers, hpfc[1].Spec.Analyzers...) {
+				switch {
+				case a.IPV4Interfaces != nil:
+					excludes[a.IPV4Interfaces.CheckName] = a.IPV4Interfaces.Exclude.StrVal
+				case a.TextAnalyze != nil && a.TextAnalyze.Exclude != nil:
+					excludes[a.TextAnalyze.CheckName] = a.TextAnalyze.Exclude.StrVal
+				case a.Sysctl != nil && a.Sysctl.Exclude != nil:
+					excludes[a.Sysctl.CheckName] = a.Sysctl.Exclude.StrVal
+				case a.SubnetAvailable != nil:
+					excludes[a.SubnetAvailable.CheckName] = a.SubnetAvailable.Exclude.StrVal
+				case a.NetworkNamespaceConnectivity != nil:
+					excludes["Network Namespace Connectivity"] = a.NetworkNamespaceConnectivity.Exclude.StrVal
+				}
+			}
+
+			req.Equal(tt.excludeIPv4, excludes["IPv4 Interface"])
+			req.Equal(tt.excludeIPv4, excludes["Default Route"])
+			req.Equal(tt.excludeIPv4, excludes["Network Namespace Connectivity"])
+			req.Equal("true", excludes["Pod CIDR Availability"], "the pod cidr is not set")
+			req.Equal(tt.excludeIPv6, excludes["IPv6 Default Route"])
+			req.Equal(tt.excludeIPv6, excludes["IPv6 Interface"])
+			req.Equal(tt.excludeIPv6, excludes["IPv6 enabled for all interfaces"])
+			req.Equal(tt.excludeIPv6, excludes["IPv6 forwarding for all interfaces"])
+			req.Equal(tt.excludeIPv6, excludes["Bridge netfilter call ip6tables"])
+		})
+	}
+}
+
 func TestTemplateCgroupV2Analyzer(t *testing.T) {
 	req := require.New(t)
 	tl := types.HostPreflightTemplateData{RequiresCgroupV2: true}
@@ -740,6 +943,71 @@ func TestTemplateCgroupV2AnalyzerExcluded(t *testing.T) {
 	req.True(foundAnalyzer, "expected Cgroup Version jsonCompare analyzer to exist with exclude when RequiresCgroupV2 is false")
 }
 
+func TestGetVendorHostPreflights(t *testing.T) {
+	spec := []byte(`apiVersion: troubleshoot.sh/v1beta2
+kind: HostPreflight
+metadata:
+  name: vendor
+spec:
+  collectors:
+    - diskUsage:
+        collectorName: app-disk
+        path: '{{ .Vendor.appDir }}'
+  analyzers:
+    - diskUsage:
+        checkName: App Disk
+        collectorName: app-disk
+        strict: true
+        outcomes:
+          - fail:
+              when: 'total < {{ .Vendor.appDiskSize }}'
+              message: '{{ .Vendor.appDir }} must be at least {{ .Vendor.appDiskSize }}'
+          - pass:
+              message: '{{ .Vendor.appDir }} is large enough'
+    - cpu:
+        checkName: CPU Architecture
+        outcomes:
+          - pass:
+              when: 'machineArch == {{ .SystemArchitecture }}'
+              message: Supported architecture
+`)
+
+	t.Run("renders the template data and vendor variables", func(t *testing.T) {
+		req := require.New(t)
+		data := types.HostPreflightTemplateData{
+			SystemArchitecture: "amd64",
+			Vendor:             map[string]string{"appDir": "/var/lib/app", "appDiskSize": "100Gi"},
+		}
+		hpfs, err := GetVendorHostPreflights(context.Background(), [][]byte{spec}, data)
+		req.NoError(err)
+		req.Len(hpfs, 1)
+
+		hpf := hpfs[0].Spec
+		req.Len(hpf.Collectors, 1)
+		req.Equal("/var/lib/app", hpf.Collectors[0].DiskUsage.Path)
+		req.Len(hpf.Analyzers, 2)
+		req.Equal("total < 100Gi", hpf.Analyzers[0].DiskUsage.Outcomes[0].Fail.When)
+		req.True(hpf.Analyzers[0].DiskUsage.Strict.BoolOrDefaultFalse())
+		req.Equal("machineArch == amd64", hpf.Analyzers[1].CPU.Outcomes[0].Pass.When)
+	})
+
+	t.Run("fails on a missing vendor variable", func(t *testing.T) {
+		req := require.New(t)
+		data := types.HostPreflightTemplateData{
+			Vendor: map[string]string{"appDir": "/var/lib/app"},
+		}
+		_, err := GetVendorHostPreflights(context.Background(), [][]byte{spec}, data)
+		req.ErrorContains(err, "appDiskSize")
+	})
+
+	t.Run("no vendor specs", func(t *testing.T) {
+		req := require.New(t)
+		hpfs, err := GetVendorHostPreflights(context.Background(), nil, types.HostPreflightTemplateData{})
+		req.NoError(err)
+		req.Empty(hpfs)
+	})
+}
+
 func TestCalculateAirgapStorageSpace(t *testing.T) {
 	embeddedAssetsSize := int64(1024 * 1024 * 1024)
 
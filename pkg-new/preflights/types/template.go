@@ -40,6 +40,12 @@ type HostPreflightTemplateData struct {
 	WorkerAirgapStorageSpace          string
 	DisableFilesystemPerformanceCheck bool
 	RequiresCgroupV2                  bool
+	// IPv6 is set when the cluster uses IPv6, either IPv6 only or dual-stack.
+	IPv6 bool
+	// IPv6Only is set when the cluster uses IPv6 only, the IPv4 checks are then skipped.
+	IPv6Only bool
+	// Vendor holds the variables set by the vendor in the Embedded Cluster config.
+	Vendor map[string]string
 }
 
 // WithCIDRData sets the respective CIDR properties in the HostPreflightTemplateData struct based on the provided CIDR strings
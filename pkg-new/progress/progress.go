@@ -0,0 +1,142 @@
+// Package progress implements the machine-readable progress stream written by the installer
+// when running with --output=json. Every event is written as a single line of JSON so the
+// stream can be consumed by tools wrapping the binary.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// EventType is the kind of thing an event reports on.
+type EventType string
+
+const (
+	// EventTypePhase reports on a phase of the command, e.g. initializing the host.
+	EventTypePhase EventType = "phase"
+	// EventTypeAddOn reports on the installation of an addon.
+	EventTypeAddOn EventType = "addon"
+	// EventTypePreflight reports the result of a single preflight check.
+	EventTypePreflight EventType = "preflight"
+	// EventTypeNode reports on a node being joined to the cluster over SSH.
+	EventTypeNode EventType = "node"
+	// EventTypeResult reports the final outcome of the command. It is always the last event.
+	EventTypeResult EventType = "result"
+)
+
+// Status is the status reported by an event.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+
+	// StatusPass, StatusWarn and StatusFail are used for preflight events only.
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Event is a single entry in the progress stream.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	Type    EventType `json:"type"`
+	Name    string    `json:"name,omitempty"`
+	Status  Status    `json:"status"`
+	Message string    `json:"message,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Writer writes events to an io.Writer as newline-delimited JSON. It is safe for concurrent use.
+type Writer struct {
+	mu      sync.Mutex
+	out     io.Writer
+	command string
+	now     func() time.Time
+}
+
+// NewWriter returns a Writer that tags every event with the given command name.
+func NewWriter(out io.Writer, command string) *Writer {
+	return &Writer{
+		out:     out,
+		command: command,
+		now:     time.Now,
+	}
+}
+
+// Emit writes the event to the stream. The time and command are set by the Writer.
+func (w *Writer) Emit(ev Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ev.Time = w.now().UTC()
+	ev.Command = w.command
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return
+	}
+	_, _ = w.out.Write(append(data, '\n'))
+}
+
+var std *Writer
+
+// SetWriter sets the Writer used by the package level functions. Passing nil disables the
+// progress stream, which is the default.
+func SetWriter(w *Writer) {
+	std = w
+}
+
+// Enabled returns true if the progress stream is enabled.
+func Enabled() bool {
+	return std != nil
+}
+
+// Emit writes the event to the progress stream, if enabled.
+func Emit(ev Event) {
+	if std == nil {
+		return
+	}
+	std.Emit(ev)
+}
+
+// Phase reports the status of a phase of the command.
+func Phase(name string, status Status, err error) {
+	Emit(Event{Type: EventTypePhase, Name: name, Status: status, Error: errorString(err)})
+}
+
+// AddOn reports the status of an addon installation.
+func AddOn(name string, status Status, message string) {
+	Emit(Event{Type: EventTypeAddOn, Name: name, Status: status, Message: message})
+}
+
+// Preflight reports the result of a single preflight check.
+func Preflight(title string, status Status, message string) {
+	Emit(Event{Type: EventTypePreflight, Name: title, Status: status, Message: message})
+}
+
+// Node reports the status of a node being joined to the cluster.
+func Node(address string, status Status, message string, err error) {
+	Emit(Event{Type: EventTypeNode, Name: address, Status: status, Message: message, Error: errorString(err)})
+}
+
+// Result reports the final outcome of the command.
+func Result(err error) {
+	status := StatusSucceeded
+	if err != nil {
+		status = StatusFailed
+	}
+	Emit(Event{Type: EventTypeResult, Status: status, Error: errorString(err)})
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,80 @@
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter_Emit(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		emit func()
+		want []Event
+	}{
+		{
+			name: "disabled stream writes nothing",
+			emit: func() {
+				SetWriter(nil)
+				Phase("initialize", StatusRunning, nil)
+				Result(nil)
+			},
+			want: nil,
+		},
+		{
+			name: "phase, addon and preflight events",
+			emit: func() {
+				Phase("initialize", StatusRunning, nil)
+				Phase("initialize", StatusFailed, errors.New("boom"))
+				AddOn("OpenEBS", StatusSucceeded, "Installed")
+				Preflight("Disk Space", StatusWarn, "low disk space")
+			},
+			want: []Event{
+				{Time: now, Command: "install", Type: EventTypePhase, Name: "initialize", Status: StatusRunning},
+				{Time: now, Command: "install", Type: EventTypePhase, Name: "initialize", Status: StatusFailed, Error: "boom"},
+				{Time: now, Command: "install", Type: EventTypeAddOn, Name: "OpenEBS", Status: StatusSucceeded, Message: "Installed"},
+				{Time: now, Command: "install", Type: EventTypePreflight, Name: "Disk Space", Status: StatusWarn, Message: "low disk space"},
+			},
+		},
+		{
+			name: "result events",
+			emit: func() {
+				Result(nil)
+				Result(errors.New("install failed"))
+			},
+			want: []Event{
+				{Time: now, Command: "install", Type: EventTypeResult, Status: StatusSucceeded},
+				{Time: now, Command: "install", Type: EventTypeResult, Status: StatusFailed, Error: "install failed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			w := NewWriter(buf, "install")
+			w.now = func() time.Time { return now }
+			SetWriter(w)
+			t.Cleanup(func() { SetWriter(nil) })
+
+			tt.emit()
+
+			var got []Event
+			scanner := bufio.NewScanner(buf)
+			for scanner.Scan() {
+				var ev Event
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev), "each line must be a json document")
+				got = append(got, ev)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package sshutils implements the SSH client used to run the installer on remote hosts.
+package sshutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// DefaultKnownHostsPath is the known_hosts file used when the config doesn't set one.
+const DefaultKnownHostsPath = "~/.ssh/known_hosts"
+
+// Config holds the settings used to connect to a host.
+type Config struct {
+	Address        string
+	Port           int
+	User           string
+	PrivateKeyPath string
+	KnownHostsPath string
+	// InsecureIgnoreHostKey disables host key verification.
+	InsecureIgnoreHostKey bool
+	// Timeout is the time allowed to establish the connection.
+	Timeout time.Duration
+}
+
+// Client runs commands and copies files on a remote host.
+type Client interface {
+	// Run runs the command on the remote host, writing its output to stdout and stderr. The
+	// command is interpreted by the remote user's shell.
+	Run(ctx context.Context, cmd string, stdout, stderr io.Writer) error
+	// Upload writes the content of src to dst on the remote host with the given mode.
+	Upload(ctx context.Context, src io.Reader, dst string, mode os.FileMode) error
+	// Close closes the connection.
+	Close() error
+}
+
+// DialFunc connects to a host. It exists so the connection can be swapped out in tests.
+type DialFunc func(ctx context.Context, cfg Config) (Client, error)
+
+var _ DialFunc = Dial
+
+type client struct {
+	conn *ssh.Client
+}
+
+// Dial connects to the host described by cfg, authenticating with its private key.
+func Dial(ctx context.Context, cfg Config) (Client, error) {
+	clientConfig, err := clientConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := net.JoinHostPort(cfg.Address, strconv.Itoa(cfg.Port))
+	dialer := net.Dialer{Timeout: clientConfig.Timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, clientConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s: %w", addr, err)
+	}
+
+	return &client{conn: ssh.NewClient(sshConn, chans, reqs)}, nil
+}
+
+func clientConfig(cfg Config) (*ssh.ClientConfig, error) {
+	keyPath, err := ExpandHome(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", keyPath, err)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !cfg.InsecureIgnoreHostKey {
+		knownHostsPath := cfg.KnownHostsPath
+		if knownHostsPath == "" {
+			knownHostsPath = DefaultKnownHostsPath
+		}
+		knownHostsPath, err = ExpandHome(knownHostsPath)
+		if err != nil {
+			return nil, err
+		}
+		hostKeyCallback, err = knownhosts.New(knownHostsPath)
+		if err != nil {
+			return nil, fmt.Errorf("load known hosts: %w", err)
+		}
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &ssh.ClientConfig{
+		User:            cfg.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         timeout,
+	}, nil
+}
+
+func (c *client) Run(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	return c.run(ctx, cmd, nil, stdout, stderr)
+}
+
+func (c *client) Upload(ctx context.Context, src io.Reader, dst string, mode os.FileMode) error {
+	// the file is written next to its destination and moved in place once complete so an
+	// interrupted upload doesn't leave a truncated file behind
+	tmp := dst + ".tmp"
+	cmd := fmt.Sprintf("cat > %s && chmod %o %s && mv -f %s %s", Quote(tmp), mode.Perm(), Quote(tmp), Quote(tmp), Quote(dst))
+
+	stderr := &strings.Builder{}
+	if err := c.run(ctx, cmd, src, io.Discard, stderr); err != nil {
+		return fmt.Errorf("upload %s: %w: %s", dst, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (c *client) run(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		return fmt.Errorf("open session: %w", err)
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	if err := session.Start(cmd); err != nil {
+		return fmt.Errorf("start command: %w", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("command exited with status %d", exitErr.ExitStatus())
+		}
+		return err
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGTERM)
+		return ctx.Err()
+	}
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
+
+// ExpandHome replaces a leading ~ in path with the home directory of the current user.
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// Quote quotes s so it is passed as a single word to a POSIX shell.
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
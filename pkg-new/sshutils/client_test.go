@@ -0,0 +1,46 @@
+package sshutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "/tmp/app", want: `'/tmp/app'`},
+		{name: "spaces", in: "a b", want: `'a b'`},
+		{name: "single quote", in: "it's", want: `'it'\''s'`},
+		{name: "empty", in: "", want: `''`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Quote(tt.in))
+		})
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	got, err := ExpandHome("~/.ssh/id_rsa")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".ssh/id_rsa"), got)
+
+	got, err = ExpandHome("/keys/id_rsa")
+	require.NoError(t, err)
+	assert.Equal(t, "/keys/id_rsa", got)
+
+	got, err = ExpandHome("~other/id_rsa")
+	require.NoError(t, err)
+	assert.Equal(t, "~other/id_rsa", got)
+}
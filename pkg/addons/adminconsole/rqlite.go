@@ -0,0 +1,176 @@
+package adminconsole
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	rqliteSecretName = "kotsadm-rqlite"
+	// rqliteRemainingPod is the rqlite replica kept when the statefulset is scaled down.
+	rqliteRemainingPod = "kotsadm-rqlite-0"
+)
+
+// rqliteAPIPort is the port of the http api of the rqlite pods.
+var rqliteAPIPort = 4001
+
+// RemoveRqlitePeers removes every node but the first replica from the rqlite cluster of the
+// admin console. It must run before rqlite is scaled down to a single replica: the nodes deleted
+// by the scale down would otherwise remain voters of the cluster, and the remaining replica would
+// lose the quorum it needs to elect itself leader.
+func RemoveRqlitePeers(ctx context.Context, kcli client.Client, namespace string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	api, err := newRqliteAPI(ctx, kcli, namespace)
+	if err != nil {
+		return err
+	}
+
+	self, err := api.nodeID(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "get node id of %s", rqliteRemainingPod)
+	}
+	ids, err := api.nodeIDs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list rqlite nodes")
+	}
+
+	for _, id := range ids {
+		if id == self {
+			continue
+		}
+		if err := api.removeNode(ctx, id); err != nil {
+			return errors.Wrapf(err, "remove rqlite node %s", id)
+		}
+	}
+
+	return nil
+}
+
+// rqliteAPI calls the http api of the rqlite replica kept when scaling down.
+type rqliteAPI struct {
+	address string
+	user    *url.Userinfo
+}
+
+func newRqliteAPI(ctx context.Context, kcli client.Client, namespace string) (*rqliteAPI, error) {
+	pod := &corev1.Pod{}
+	if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: rqliteRemainingPod}, pod); err != nil {
+		return nil, errors.Wrapf(err, "get pod %s", rqliteRemainingPod)
+	}
+	if pod.Status.PodIP == "" {
+		return nil, errors.Errorf("pod %s has no ip address", rqliteRemainingPod)
+	}
+
+	// the uri the admin console connects to rqlite with holds the credentials of the api
+	secret := &corev1.Secret{}
+	if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: rqliteSecretName}, secret); err != nil {
+		return nil, errors.Wrapf(err, "get secret %s", rqliteSecretName)
+	}
+	uri, err := url.Parse(string(secret.Data["uri"]))
+	if err != nil {
+		return nil, errors.Wrap(err, "parse rqlite uri")
+	}
+
+	return &rqliteAPI{
+		address: fmt.Sprintf("http://%s:%d", pod.Status.PodIP, rqliteAPIPort),
+		user:    uri.User,
+	}, nil
+}
+
+// nodeID returns the id of the node in the rqlite cluster.
+func (r *rqliteAPI) nodeID(ctx context.Context) (string, error) {
+	var status struct {
+		Store struct {
+			NodeID string `json:"node_id"`
+		} `json:"store"`
+	}
+	if err := r.do(ctx, http.MethodGet, "/status", nil, &status); err != nil {
+		return "", err
+	}
+	if status.Store.NodeID == "" {
+		return "", errors.New("node id not found in status")
+	}
+	return status.Store.NodeID, nil
+}
+
+// nodeIDs returns the ids of the nodes of the rqlite cluster. Depending on the version of
+// rqlite, the nodes are returned as a map by id or as a list.
+func (r *rqliteAPI) nodeIDs(ctx context.Context) ([]string, error) {
+	var raw json.RawMessage
+	if err := r.do(ctx, http.MethodGet, "/nodes", nil, &raw); err != nil {
+		return nil, err
+	}
+
+	type node struct {
+		ID string `json:"id"`
+	}
+	var list struct {
+		Nodes []node `json:"nodes"`
+	}
+	if err := json.Unmarshal(raw, &list); err == nil && len(list.Nodes) > 0 {
+		ids := []string{}
+		for _, n := range list.Nodes {
+			ids = append(ids, n.ID)
+		}
+		return ids, nil
+	}
+
+	byID := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &byID); err != nil {
+		return nil, errors.Wrap(err, "decode nodes")
+	}
+	ids := []string{}
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// removeNode removes the node from the rqlite cluster. The request is forwarded to the leader
+// by rqlite.
+func (r *rqliteAPI) removeNode(ctx context.Context, id string) error {
+	body, err := json.Marshal(map[string]string{"id": id})
+	if err != nil {
+		return errors.Wrap(err, "marshal request")
+	}
+	return r.do(ctx, http.MethodDelete, "/remove", body, nil)
+}
+
+func (r *rqliteAPI) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.address+path, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	if r.user != nil {
+		password, _ := r.user.Password()
+		req.SetBasicAuth(r.user.Username(), password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s %s: unexpected status code: %d", method, path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "decode response")
+	}
+	return nil
+}
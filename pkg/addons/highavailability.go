@@ -12,6 +12,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	registrymigrate "github.com/replicatedhq/embedded-cluster/pkg/addons/registry/migrate"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
@@ -38,6 +39,16 @@ type EnableHAOptions struct {
 	KotsadmNamespace   string
 }
 
+// removeRqlitePeers removes the rqlite replicas other than the first from the rqlite cluster.
+var removeRqlitePeers = adminconsole.RemoveRqlitePeers
+
+// DisableHAOptions are the options used to disable high availability. RegistryNode is the
+// node the registry data is moved back to in airgap installations.
+type DisableHAOptions struct {
+	EnableHAOptions
+	RegistryNode string
+}
+
 // CanEnableHA checks if high availability can be enabled in the cluster.
 func (a *AddOns) CanEnableHA(ctx context.Context) (bool, string, error) {
 	in, err := kubeutils.GetLatestInstallation(ctx, a.kcli)
@@ -130,6 +141,138 @@ func (a *AddOns) EnableHA(ctx context.Context, opts EnableHAOptions, spinner *sp
 	return nil
 }
 
+// ReconcileHA re-applies high availability to the registry, when its data was already migrated,
+// and to the admin console. This is needed after a controller is replaced so the replicas are
+// spread across the current set of controllers again.
+func (a *AddOns) ReconcileHA(ctx context.Context, opts EnableHAOptions, spinner *spinner.MessageWriter) error {
+	if opts.IsAirgap {
+		isHA, err := registry.IsRegistryHA(ctx, a.kcli)
+		if err != nil {
+			return errors.Wrap(err, "check if registry is highly available")
+		}
+		if isHA {
+			logrus.Debugf("Reconciling high availability for the registry")
+			spinner.Infof("Reconciling high availability for the registry")
+			if err := a.ensureSeaweedfs(ctx, opts); err != nil {
+				return errors.Wrap(err, "ensure seaweedfs")
+			}
+			if err := a.enableRegistryHA(ctx, opts); err != nil {
+				return errors.Wrap(err, "enable registry high availability")
+			}
+		}
+	}
+
+	logrus.Debugf("Reconciling high availability for the Admin Console")
+	spinner.Infof("Reconciling high availability for the Admin Console")
+	if err := a.EnableAdminConsoleHA(ctx, opts); err != nil {
+		return errors.Wrap(err, "enable admin console high availability")
+	}
+
+	logrus.Debugf("High availability reconciled!")
+	spinner.Infof("High availability reconciled!")
+	return nil
+}
+
+// CanDisableHA checks if high availability can be disabled in the cluster.
+func (a *AddOns) CanDisableHA(ctx context.Context) (bool, string, error) {
+	in, err := kubeutils.GetLatestInstallation(ctx, a.kcli)
+	if err != nil {
+		return false, "", errors.Wrap(err, "get latest installation")
+	}
+	if !in.Spec.HighAvailability {
+		return false, "not enabled", nil
+	}
+
+	if err := a.kcli.Get(ctx, client.ObjectKey{Name: constants.EcRestoreStateCMName, Namespace: "embedded-cluster"}, &corev1.ConfigMap{}); err == nil {
+		return false, "a restore is in progress", nil
+	} else if client.IgnoreNotFound(err) != nil {
+		return false, "", errors.Wrap(err, "get restore state configmap")
+	}
+
+	return true, "", nil
+}
+
+// DisableHA disables high availability. In airgap installations the registry data is moved
+// back from seaweedfs to the registry PVC and seaweedfs is removed.
+func (a *AddOns) DisableHA(ctx context.Context, opts DisableHAOptions, spinner *spinner.MessageWriter) error {
+	if opts.IsAirgap {
+		logrus.Debugf("Disabling high availability")
+		spinner.Infof("Disabling high availability")
+
+		onPVC, err := registry.IsRegistryOnPVC(ctx, a.kcli)
+		if err != nil {
+			return errors.Wrap(err, "check if registry data has been migrated")
+		} else if !onPVC {
+			logrus.Debugf("Scaling registry to 0 replicas")
+			// if the migration fails, we need to scale the registry back up, still on seaweedfs
+			defer a.maybeScaleRegistryBackOnFailure()
+			err := a.scaleRegistryDown(ctx)
+			if err != nil {
+				return errors.Wrap(err, "scale registry to 0 replicas")
+			}
+
+			logrus.Debugf("Migrating registry data back to the registry volume")
+			spinner.Infof("Migrating registry data back to the registry volume")
+			err = a.migrateRegistryDataToPVC(ctx, opts, spinner)
+			if err != nil {
+				return errors.Wrap(err, "migrate registry data")
+			}
+			logrus.Debugf("Data migration complete!")
+
+			logrus.Debugf("Disabling high availability for the registry")
+			spinner.Infof("Disabling high availability for the registry")
+			err = a.disableRegistryHA(ctx, opts.EnableHAOptions)
+			if err != nil {
+				return errors.Wrap(err, "disable registry high availability")
+			}
+			logrus.Debugf("Registry high availability disabled!")
+		}
+	}
+
+	kotsadmNamespace, err := runtimeconfig.KotsadmNamespace(ctx, a.kcli)
+	if err != nil {
+		return errors.Wrap(err, "get kotsadm namespace")
+	}
+
+	// the rqlite replicas removed by the scale down must leave the cluster first, otherwise the
+	// remaining replica cannot reach a quorum
+	logrus.Debugf("Removing rqlite peers")
+	spinner.Infof("Removing the Admin Console database peers")
+	if err := removeRqlitePeers(ctx, a.kcli, kotsadmNamespace); err != nil {
+		return errors.Wrap(err, "remove rqlite peers")
+	}
+
+	logrus.Debugf("Updating the Admin Console to disable high availability")
+	spinner.Infof("Updating the Admin Console to disable high availability")
+	if err := a.upgradeAdminConsoleHA(ctx, opts.EnableHAOptions, false); err != nil {
+		return errors.Wrap(err, "disable admin console high availability")
+	}
+	logrus.Debugf("Admin console high availability disabled!")
+
+	if opts.IsAirgap {
+		logrus.Debugf("Removing seaweedfs")
+		spinner.Infof("Removing SeaweedFS")
+		if err := a.removeSeaweedfs(ctx); err != nil {
+			return errors.Wrap(err, "remove seaweedfs")
+		}
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, a.kcli)
+	if err != nil {
+		return errors.Wrap(err, "get latest installation")
+	}
+
+	if err := kubeutils.UpdateInstallation(ctx, a.kcli, in, func(in *ecv1beta1.Installation) {
+		in.Spec.HighAvailability = false
+	}); err != nil {
+		return errors.Wrap(err, "update installation")
+	}
+
+	logrus.Debugf("High availability disabled!")
+	spinner.Infof("High availability disabled!")
+	return nil
+}
+
 // maybeScaleRegistryBackOnFailure scales the registry back to 1 replica if the migration failed
 // (the registry is at 0 replicas).
 func (a *AddOns) maybeScaleRegistryBackOnFailure() {
@@ -208,6 +351,60 @@ func (a *AddOns) migrateRegistryData(ctx context.Context, cfgspec *ecv1beta1.Con
 	return nil
 }
 
+// migrateRegistryDataToPVC runs the registry data migration back to the registry PVC, whose
+// volume is created on the registry node.
+func (a *AddOns) migrateRegistryDataToPVC(ctx context.Context, opts DisableHAOptions, writer *spinner.MessageWriter) error {
+	r := &registry.Registry{
+		ServiceCIDR: opts.ServiceCIDR,
+		IsHA:        false,
+	}
+	pvc, err := r.PersistentVolumeClaim(a.addOnOverrides(r, opts.EmbeddedConfigSpec, opts.EndUserConfigSpec))
+	if err != nil {
+		return errors.Wrap(err, "get registry pvc")
+	}
+
+	operatorImage, err := getOperatorImage()
+	if err != nil {
+		return errors.Wrap(err, "get operator image")
+	}
+	if a.domains.ProxyRegistryDomain != "" {
+		operatorImage = strings.Replace(operatorImage, "proxy.replicated.com", a.domains.ProxyRegistryDomain, 1)
+	}
+
+	progressCh, errCh, err := registrymigrate.RunDataMigrationToPVC(ctx, a.kcli, a.kclient, operatorImage, opts.RegistryNode, pvc)
+	if err != nil {
+		return errors.Wrap(err, "run registry data migration")
+	}
+	if err := a.waitForPodAndLogProgress(writer, progressCh, errCh); err != nil {
+		return errors.Wrap(err, "registry data migration failed")
+	}
+
+	return nil
+}
+
+// removeSeaweedfs uninstalls seaweedfs and removes its namespace. The data stored on the hosts
+// is left in place and removed when the nodes are reset.
+func (a *AddOns) removeSeaweedfs(ctx context.Context) error {
+	sw := &seaweedfs.SeaweedFS{}
+	err := a.hcli.Uninstall(ctx, helm.UninstallOptions{
+		ReleaseName:    sw.ReleaseName(),
+		Namespace:      sw.Namespace(),
+		Wait:           true,
+		IgnoreNotFound: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm uninstall")
+	}
+
+	ns := &corev1.Namespace{}
+	ns.Name = sw.Namespace()
+	if err := a.kcli.Delete(ctx, ns); client.IgnoreNotFound(err) != nil {
+		return errors.Wrap(err, "delete namespace")
+	}
+
+	return nil
+}
+
 // ensureSeaweedfs ensures that seaweedfs is installed.
 func (a *AddOns) ensureSeaweedfs(ctx context.Context, opts EnableHAOptions) error {
 	// TODO (@salah): add support for end user overrides
@@ -238,13 +435,33 @@ func (a *AddOns) enableRegistryHA(ctx context.Context, opts EnableHAOptions) err
 	return nil
 }
 
+// disableRegistryHA scales the registry deployment back to a single replica storing its data in
+// the registry PVC.
+func (a *AddOns) disableRegistryHA(ctx context.Context, opts EnableHAOptions) error {
+	r := &registry.Registry{
+		ServiceCIDR: opts.ServiceCIDR,
+		IsHA:        false,
+	}
+	if err := r.Upgrade(ctx, a.logf, a.kcli, a.mcli, a.hcli, a.domains, a.addOnOverrides(r, opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)); err != nil {
+		return errors.Wrap(err, "upgrade registry")
+	}
+
+	return nil
+}
+
 // EnableAdminConsoleHA enables high availability for the admin console.
 func (a *AddOns) EnableAdminConsoleHA(ctx context.Context, opts EnableHAOptions) error {
+	return a.upgradeAdminConsoleHA(ctx, opts, true)
+}
+
+// upgradeAdminConsoleHA upgrades the admin console with high availability enabled or disabled
+// and waits for rqlite to be ready.
+func (a *AddOns) upgradeAdminConsoleHA(ctx context.Context, opts EnableHAOptions, isHA bool) error {
 	// TODO (@salah): add support for end user overrides
 	ac := &adminconsole.AdminConsole{
 		ClusterID:          opts.ClusterID,
 		IsAirgap:           opts.IsAirgap,
-		IsHA:               true,
+		IsHA:               isHA,
 		Proxy:              opts.ProxySpec,
 		ServiceCIDR:        opts.ServiceCIDR,
 		IsMultiNodeEnabled: opts.IsMultiNodeEnabled,
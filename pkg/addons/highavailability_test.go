@@ -6,8 +6,12 @@ import (
 
 	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg-new/constants"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
 	v12 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -113,3 +117,110 @@ func Test_canEnableHA(t *testing.T) {
 		})
 	}
 }
+
+func Test_canDisableHA(t *testing.T) {
+	scheme := scheme.Scheme
+	v1beta1.AddToScheme(scheme)
+	tests := []struct {
+		name       string
+		kcli       client.Client
+		want       bool
+		wantReason string
+	}{
+		{
+			name: "high availability is enabled",
+			kcli: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&v1beta1.Installation{
+					ObjectMeta: v1.ObjectMeta{Name: "test-installation"},
+					Spec:       v1beta1.InstallationSpec{HighAvailability: true},
+				},
+			).Build(),
+			want: true,
+		},
+		{
+			name: "high availability is not enabled",
+			kcli: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&v1beta1.Installation{
+					ObjectMeta: v1.ObjectMeta{Name: "test-installation"},
+					Spec:       v1beta1.InstallationSpec{HighAvailability: false},
+				},
+			).Build(),
+			want:       false,
+			wantReason: "not enabled",
+		},
+		{
+			name: "high availability is enabled but a restore is in progress",
+			kcli: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&v1beta1.Installation{
+					ObjectMeta: v1.ObjectMeta{Name: "test-installation"},
+					Spec:       v1beta1.InstallationSpec{HighAvailability: true},
+				},
+				&v12.ConfigMap{
+					ObjectMeta: v1.ObjectMeta{Name: constants.EcRestoreStateCMName, Namespace: "embedded-cluster"},
+				},
+			).Build(),
+			want:       false,
+			wantReason: "a restore is in progress",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addOns := New(WithKubernetesClient(tt.kcli))
+			got, reason, err := addOns.CanDisableHA(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantReason, reason)
+		})
+	}
+}
+
+func Test_disableHA_removesRqlitePeersBeforeScalingDown(t *testing.T) {
+	scheme := scheme.Scheme
+	v1beta1.AddToScheme(scheme)
+
+	kcli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1beta1.Installation{
+			ObjectMeta: v1.ObjectMeta{Name: "test-installation"},
+			Spec:       v1beta1.InstallationSpec{HighAvailability: true},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: v1.ObjectMeta{Name: "kotsadm-rqlite", Namespace: "kotsadm"},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 1},
+		},
+	).Build()
+
+	steps := []string{}
+
+	prev := removeRqlitePeers
+	t.Cleanup(func() { removeRqlitePeers = prev })
+	removeRqlitePeers = func(ctx context.Context, kcli client.Client, namespace string) error {
+		assert.Equal(t, "kotsadm", namespace)
+		steps = append(steps, "remove rqlite peers")
+		return nil
+	}
+
+	hcli := &helm.MockClient{}
+	hcli.On("ReleaseExists", mock.Anything, mock.Anything, "admin-console").Return(true, nil)
+	hcli.On("Upgrade", mock.Anything, mock.MatchedBy(func(opts helm.UpgradeOptions) bool {
+		return opts.ReleaseName == "admin-console" && opts.Values["isHA"] == false
+	})).Run(func(args mock.Arguments) {
+		steps = append(steps, "scale admin console down")
+	}).Return(&helm.ReleaseInfo{}, nil)
+
+	loading := spinner.Start(
+		spinner.WithWriter(func(format string, args ...any) (int, error) { return 0, nil }),
+		spinner.WithTTY(false),
+	)
+	defer loading.Close()
+
+	addOns := New(WithKubernetesClient(kcli), WithHelmClient(hcli))
+	err := addOns.DisableHA(context.Background(), DisableHAOptions{}, loading)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"remove rqlite peers", "scale admin console down"}, steps)
+	hcli.AssertExpectations(t)
+
+	in := &v1beta1.Installation{}
+	require.NoError(t, kcli.Get(context.Background(), client.ObjectKey{Name: "test-installation"}, in))
+	assert.False(t, in.Spec.HighAvailability)
+}
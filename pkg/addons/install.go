@@ -2,6 +2,7 @@ package addons
 
 import (
 	"context"
+	"slices"
 
 	"github.com/pkg/errors"
 	apitypes "github.com/replicatedhq/embedded-cluster/api/types"
@@ -12,6 +13,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 )
 
@@ -38,6 +40,12 @@ type InstallOptions struct {
 	K0sDataDir              string
 	OpenEBSDataDir          string
 	ServiceCIDR             string
+
+	// Resume indicates that a previous install was interrupted. Addons listed in
+	// InstalledAddOns are skipped and any release left behind by the other addons is removed
+	// before they are installed again.
+	Resume          bool
+	InstalledAddOns []string
 }
 
 type KubernetesInstallOptions struct {
@@ -60,6 +68,16 @@ func (a *AddOns) Install(ctx context.Context, opts InstallOptions) error {
 	addons := GetAddOnsForInstall(opts)
 
 	for _, addon := range addons {
+		if opts.Resume {
+			if slices.Contains(opts.InstalledAddOns, addon.Name()) {
+				a.logf("%s is already installed, skipping", addon.Name())
+				continue
+			}
+			if err := a.removeLeftoverRelease(ctx, addon); err != nil {
+				return errors.Wrapf(err, "remove leftover %s release", addon.Name())
+			}
+		}
+
 		a.sendProgress(addon.Name(), apitypes.StateRunning, "Installing")
 
 		overrides := a.addOnOverrides(addon, opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
@@ -75,6 +93,30 @@ func (a *AddOns) Install(ctx context.Context, opts InstallOptions) error {
 	return nil
 }
 
+// removeLeftoverRelease uninstalls the release of an addon that failed to install during an
+// interrupted install so it can be installed again from scratch.
+func (a *AddOns) removeLeftoverRelease(ctx context.Context, addon types.AddOn) error {
+	exists, err := a.hcli.ReleaseExists(ctx, addon.Namespace(), addon.ReleaseName())
+	if err != nil {
+		return errors.Wrap(err, "check if release exists")
+	}
+	if !exists {
+		return nil
+	}
+
+	a.logf("Removing leftover %s release", addon.Name())
+	err = a.hcli.Uninstall(ctx, helm.UninstallOptions{
+		ReleaseName:    addon.ReleaseName(),
+		Namespace:      addon.Namespace(),
+		Wait:           true,
+		IgnoreNotFound: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm uninstall")
+	}
+	return nil
+}
+
 func (a *AddOns) InstallKubernetes(ctx context.Context, opts KubernetesInstallOptions) error {
 	addons := GetAddOnsForKubernetesInstall(opts)
 
@@ -25,6 +25,14 @@ type AddOnsInterface interface {
 	EnableHA(ctx context.Context, opts EnableHAOptions, spinner *spinner.MessageWriter) error
 	// EnableAdminConsoleHA enables high availability for the admin console
 	EnableAdminConsoleHA(ctx context.Context, opts EnableHAOptions) error
+	// CanDisableHA checks if high availability can be disabled in the cluster
+	CanDisableHA(context.Context) (bool, string, error)
+	// DisableHA disables high availability for the cluster
+	DisableHA(ctx context.Context, opts DisableHAOptions, spinner *spinner.MessageWriter) error
+	// ReconcileHA re-applies high availability to the registry and the admin console
+	ReconcileHA(ctx context.Context, opts EnableHAOptions, spinner *spinner.MessageWriter) error
+	// ReconcileProxy upgrades the addons that use the proxy of the cluster with the proxy in opts
+	ReconcileProxy(ctx context.Context, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, opts UpgradeOptions) error
 }
 
 var _ AddOnsInterface = (*AddOns)(nil)
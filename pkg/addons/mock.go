@@ -45,3 +45,27 @@ func (m *MockAddOns) EnableAdminConsoleHA(ctx context.Context, opts EnableHAOpti
 	args := m.Called(ctx, opts)
 	return args.Error(0)
 }
+
+// ReconcileHA mocks the ReconcileHA method
+func (m *MockAddOns) ReconcileHA(ctx context.Context, opts EnableHAOptions, spinner *spinner.MessageWriter) error {
+	args := m.Called(ctx, opts, spinner)
+	return args.Error(0)
+}
+
+// ReconcileProxy mocks the ReconcileProxy method
+func (m *MockAddOns) ReconcileProxy(ctx context.Context, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, opts UpgradeOptions) error {
+	args := m.Called(ctx, in, meta, opts)
+	return args.Error(0)
+}
+
+// CanDisableHA mocks the CanDisableHA method
+func (m *MockAddOns) CanDisableHA(ctx context.Context) (bool, string, error) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.String(1), args.Error(2)
+}
+
+// DisableHA mocks the DisableHA method
+func (m *MockAddOns) DisableHA(ctx context.Context, opts DisableHAOptions, spinner *spinner.MessageWriter) error {
+	args := m.Called(ctx, opts, spinner)
+	return args.Error(0)
+}
@@ -0,0 +1,52 @@
+package addons
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
+)
+
+// ReconcileProxy upgrades the addons whose values hold the proxy of the cluster so they pick up
+// the proxy in opts. Unlike Upgrade, the addons are upgraded even if the installation reports
+// them as upgraded already. Helm rolls out the workloads whose pods get a new environment.
+func (a *AddOns) ReconcileProxy(ctx context.Context, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, opts UpgradeOptions) error {
+	addons, err := a.getAddOnsForProxy(meta, opts)
+	if err != nil {
+		return errors.Wrap(err, "get addons for proxy")
+	}
+
+	for _, addon := range addons {
+		a.logf("Reconciling the proxy of addon %s", addon.Name())
+
+		overrides := a.addOnOverrides(addon, in.Spec.Config, opts.EndUserConfigSpec)
+
+		if err := addon.Upgrade(ctx, a.logf, a.kcli, a.mcli, a.hcli, a.domains, overrides); err != nil {
+			return errors.Wrapf(err, "addon %s", addon.Name())
+		}
+	}
+
+	return nil
+}
+
+// getAddOnsForProxy returns the addons of getAddOnsForUpgrade that use the proxy.
+func (a *AddOns) getAddOnsForProxy(meta *ectypes.ReleaseMetadata, opts UpgradeOptions) ([]types.AddOn, error) {
+	addons, err := a.getAddOnsForUpgrade(meta, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []types.AddOn{}
+	for _, addon := range addons {
+		switch addon.(type) {
+		case *embeddedclusteroperator.EmbeddedClusterOperator, *velero.Velero, *adminconsole.AdminConsole:
+			result = append(result, addon)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package addons
+
+import (
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_getAddOnsForProxy(t *testing.T) {
+	meta := &ectypes.ReleaseMetadata{
+		Configs: ecv1beta1.Helm{
+			Charts: []ecv1beta1.Chart{
+				{
+					Name:      "embedded-cluster-operator",
+					ChartName: "replicated/embedded-cluster-operator",
+					Version:   "1.22.0+k8s-1.30",
+				},
+			},
+		},
+		Images: []string{
+			"proxy.replicated.com/anonymous/replicated/embedded-cluster-operator-image:1.22.0-k8s-1.30-amd64@sha256:929b6cb42add383a69e3b26790c06320bd4eac0ecd60b509212c1864d69c6a88",
+			"proxy.replicated.com/anonymous/replicated/embedded-cluster-utils:latest-amd64@sha256:f499ed26bd5899bc5a1ae14d9d13853d1fc615ae21bde86fe250960772fd2c70",
+		},
+	}
+	proxy := &ecv1beta1.ProxySpec{
+		HTTPProxy:  "http://proxy.example.com",
+		HTTPSProxy: "https://proxy.example.com",
+		NoProxy:    "localhost,127.0.0.1",
+	}
+
+	addons, err := New().getAddOnsForProxy(meta, UpgradeOptions{
+		IsAirgap:                true,
+		IsHA:                    true,
+		DisasterRecoveryEnabled: true,
+		ProxySpec:               proxy,
+	})
+	require.NoError(t, err)
+	require.Len(t, addons, 3)
+
+	eco, ok := addons[0].(*embeddedclusteroperator.EmbeddedClusterOperator)
+	require.True(t, ok, "first addon should be EmbeddedClusterOperator")
+	assert.Equal(t, proxy, eco.Proxy)
+
+	vel, ok := addons[1].(*velero.Velero)
+	require.True(t, ok, "second addon should be Velero")
+	assert.Equal(t, proxy, vel.Proxy)
+
+	ac, ok := addons[2].(*adminconsole.AdminConsole)
+	require.True(t, ok, "third addon should be AdminConsole")
+	assert.Equal(t, proxy, ac.Proxy)
+
+	addons, err = New().getAddOnsForProxy(meta, UpgradeOptions{})
+	require.NoError(t, err)
+	require.Len(t, addons, 2)
+}
@@ -3,6 +3,7 @@ package migrate
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -123,6 +124,111 @@ func RegistryData(ctx context.Context) error {
 	return nil
 }
 
+// RegistryDataToPVC runs a migration that copies the registry data in the seaweedfs s3 store to
+// disk in the registry PVC. It is the reverse of RegistryData.
+func RegistryDataToPVC(ctx context.Context) error {
+	slog.Info("Connecting to s3")
+
+	s3Client, err := getS3Client(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get s3 client")
+	}
+
+	slog.Info("Listing registry objects")
+
+	keys, err := listRegistryObjects(ctx, s3Client)
+	if err != nil {
+		return errors.Wrap(err, "list registry objects")
+	}
+
+	slog.Info("Running registry data migration")
+
+	for i, key := range keys {
+		path := filepath.Join("/", key)
+
+		// retry downloading the object 5 times with exponential backoff
+		var lasterr error
+		err = wait.ExponentialBackoffWithContext(ctx, wait.Backoff{
+			Duration: 1 * time.Second,
+			Factor:   2,
+			Steps:    5,
+		}, func(ctx context.Context) (bool, error) {
+			slog.Info("Downloading object", "path", key)
+
+			lasterr = downloadObject(ctx, s3Client, key, path)
+			if lasterr != nil {
+				slog.Error("Failed to download object", "path", key, "error", lasterr)
+			}
+			return lasterr == nil, nil
+		})
+		if err != nil {
+			if lasterr == nil {
+				lasterr = err
+			}
+			return fmt.Errorf("download object: %w", lasterr)
+		}
+
+		// NOTE: this is used by the cli to report progress
+		// DO NOT CHANGE THIS
+		slog.Info(
+			"Downloaded object",
+			append(
+				[]any{"path", key},
+				getProgressArgs(i+1, len(keys))...,
+			)...,
+		)
+	}
+
+	slog.Info("Registry data migration complete")
+
+	return nil
+}
+
+// listRegistryObjects returns the keys of all objects stored by the registry in the s3 bucket.
+func listRegistryObjects(ctx context.Context, s3Client *s3.Client) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: ptr.To(s3Bucket),
+		Prefix: ptr.To(s3RootDirectory + "/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, aws.ToString(obj.Key))
+		}
+	}
+	return keys, nil
+}
+
+func downloadObject(ctx context.Context, s3Client *s3.Client, key string, path string) error {
+	out, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: ptr.To(s3Bucket),
+		Key:    ptr.To(key),
+	})
+	if err != nil {
+		return fmt.Errorf("get object: %w", err)
+	}
+	defer out.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, out.Body); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
 func getS3Client(ctx context.Context) (*s3.Client, error) {
 	creds := credentials.NewStaticCredentialsProvider(os.Getenv("s3AccessKey"), os.Getenv("s3SecretKey"), "")
 	conf, err := config.LoadDefaultConfig(ctx,
@@ -26,8 +26,9 @@ import (
 const (
 	StatusConditionType = "RegistryMigrationStatus"
 
-	dataMigrationPodName = "registry-data-migration"
-	serviceAccountName   = "registry-data-migration-serviceaccount"
+	dataMigrationPodName      = "registry-data-migration"
+	dataMigrationToPVCPodName = "registry-data-migration-to-pvc"
+	serviceAccountName        = "registry-data-migration-serviceaccount"
 
 	// seaweedfsS3SecretName is the name of the secret containing the s3 credentials.
 	// This secret name is defined in the values-ha.yaml file in the release metadata.
@@ -55,28 +56,67 @@ func RunDataMigration(ctx context.Context, cli client.Client, kclient kubernetes
 	}
 
 	logrus.Debug("Ensuring data migration pod")
-	_, err = ensureDataMigrationPod(ctx, cli, image)
+	_, err = ensureDataMigrationPod(ctx, cli, newMigrationPod(image))
 	if err != nil {
 		return nil, nil, fmt.Errorf("ensure pod: %w", err)
 	}
 
+	startMonitoring(ctx, cli, kclient, dataMigrationPodName, progressCh, errCh)
+
+	return progressCh, errCh, nil
+}
+
+// RunDataMigrationToPVC should be called when transitioning from HA to non-HA airgapped
+// installations. This function creates a pod on the given node that downloads the registry data
+// from s3 into the registry PVC, which is created if it does not exist. The registry deployment
+// must be scaled down before calling this function.
+func RunDataMigrationToPVC(ctx context.Context, cli client.Client, kclient kubernetes.Interface, image string, nodeName string, pvc *corev1.PersistentVolumeClaim) (<-chan string, <-chan error, error) {
+	progressCh := make(chan string)
+	errCh := make(chan error, 1)
+
+	logrus.Debug("Ensuring migration service account")
+	err := ensureMigrationServiceAccount(ctx, cli)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ensure service account: %w", err)
+	}
+
+	logrus.Debug("Ensuring s3 access credentials secret")
+	err = ensureS3Secret(ctx, cli)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ensure s3 secret: %w", err)
+	}
+
+	logrus.Debug("Ensuring registry PVC")
+	err = ensureRegistryPVC(ctx, cli, pvc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ensure registry pvc: %w", err)
+	}
+
+	logrus.Debug("Ensuring data migration pod")
+	_, err = ensureDataMigrationPod(ctx, cli, newMigrationToPVCPod(image, nodeName, pvc.Name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("ensure pod: %w", err)
+	}
+
+	startMonitoring(ctx, cli, kclient, dataMigrationToPVCPodName, progressCh, errCh)
+
+	return progressCh, errCh, nil
+}
+
+func startMonitoring(ctx context.Context, cli client.Client, kclient kubernetes.Interface, podName string, progressCh chan<- string, errCh chan<- error) {
 	ctx, cancel := context.WithCancel(ctx)
 	go func() {
 		defer cancel()
 		logrus.Debug("Monitoring pod status")
 		defer logrus.Debug("Pod status monitor stopped")
-		monitorPodStatus(ctx, cli, errCh)
+		monitorPodStatus(ctx, cli, podName, errCh)
 	}()
 
-	go monitorPodProgress(ctx, kclient, progressCh)
-
-	return progressCh, errCh, nil
+	go monitorPodProgress(ctx, kclient, podName, progressCh)
 }
 
-func ensureDataMigrationPod(ctx context.Context, cli client.Client, image string) (*corev1.Pod, error) {
-	pod := newMigrationPod(image)
-
-	err := maybeDeleteDataMigrationPod(ctx, cli)
+func ensureDataMigrationPod(ctx context.Context, cli client.Client, pod *corev1.Pod) (*corev1.Pod, error) {
+	err := maybeDeleteDataMigrationPod(ctx, cli, pod.Name)
 	if err != nil {
 		return nil, fmt.Errorf("delete pod: %w", err)
 	}
@@ -89,11 +129,11 @@ func ensureDataMigrationPod(ctx context.Context, cli client.Client, image string
 	return pod, nil
 }
 
-func maybeDeleteDataMigrationPod(ctx context.Context, cli client.Client) error {
+func maybeDeleteDataMigrationPod(ctx context.Context, cli client.Client, podName string) error {
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: constants.RegistryNamespace,
-			Name:      dataMigrationPodName,
+			Name:      podName,
 		},
 	}
 
@@ -105,7 +145,7 @@ func maybeDeleteDataMigrationPod(ctx context.Context, cli client.Client) error {
 		return nil
 	}
 
-	err = kubeutils.WaitForPodDeleted(ctx, cli, constants.RegistryNamespace, dataMigrationPodName, &kubeutils.WaitOptions{
+	err = kubeutils.WaitForPodDeleted(ctx, cli, constants.RegistryNamespace, podName, &kubeutils.WaitOptions{
 		Backoff: &wait.Backoff{
 			Steps:    30,
 			Duration: 2 * time.Second,
@@ -120,7 +160,7 @@ func maybeDeleteDataMigrationPod(ctx context.Context, cli client.Client) error {
 	return nil
 }
 
-func monitorPodStatus(ctx context.Context, cli client.Client, errCh chan<- error) {
+func monitorPodStatus(ctx context.Context, cli client.Client, podName string, errCh chan<- error) {
 	defer close(errCh)
 
 	opts := &kubeutils.WaitOptions{
@@ -132,7 +172,7 @@ func monitorPodStatus(ctx context.Context, cli client.Client, errCh chan<- error
 			Jitter:   0.1,
 		},
 	}
-	pod, err := kubeutils.WaitForPodComplete(ctx, cli, constants.RegistryNamespace, dataMigrationPodName, opts)
+	pod, err := kubeutils.WaitForPodComplete(ctx, cli, constants.RegistryNamespace, podName, opts)
 	if err != nil {
 		errCh <- err
 	}
@@ -146,12 +186,12 @@ func monitorPodStatus(ctx context.Context, cli client.Client, errCh chan<- error
 	}
 }
 
-func monitorPodProgress(ctx context.Context, kclient kubernetes.Interface, progressCh chan<- string) {
+func monitorPodProgress(ctx context.Context, kclient kubernetes.Interface, podName string, progressCh chan<- string) {
 	defer close(progressCh)
 
 	for ctx.Err() == nil {
 		logrus.Debugf("Streaming registry data migration pod logs")
-		podLogs, err := streamPodLogs(ctx, kclient)
+		podLogs, err := streamPodLogs(ctx, kclient, podName)
 		if err != nil {
 			if ctx.Err() == nil {
 				logrus.Debugf("Failed to stream registry data migration pod logs: %v", err)
@@ -172,13 +212,13 @@ func monitorPodProgress(ctx context.Context, kclient kubernetes.Interface, progr
 	}
 }
 
-func streamPodLogs(ctx context.Context, kclient kubernetes.Interface) (io.ReadCloser, error) {
+func streamPodLogs(ctx context.Context, kclient kubernetes.Interface, podName string) (io.ReadCloser, error) {
 	logOpts := corev1.PodLogOptions{
 		Container: "migrate-registry-data",
 		Follow:    true,
 		TailLines: ptr.To(int64(100)),
 	}
-	podLogs, err := kclient.CoreV1().Pods(constants.RegistryNamespace).GetLogs(dataMigrationPodName, &logOpts).Stream(ctx)
+	podLogs, err := kclient.CoreV1().Pods(constants.RegistryNamespace).GetLogs(podName, &logOpts).Stream(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get logs: %w", err)
 	}
@@ -275,6 +315,31 @@ func newMigrationPod(image string) *corev1.Pod {
 	return pod
 }
 
+// newMigrationToPVCPod returns the pod that downloads the registry data from s3 into the
+// registry PVC. The pod is pinned to the node as the local volume is bound to the node of its
+// first consumer.
+func newMigrationToPVCPod(image string, nodeName string, pvcName string) *corev1.Pod {
+	pod := newMigrationPod(image)
+	pod.Name = dataMigrationToPVCPodName
+	pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName = pvcName
+	pod.Spec.NodeSelector = map[string]string{corev1.LabelHostname: nodeName}
+	pod.Spec.Containers[0].Args = []string{"migrate", "registry-data-to-pvc"}
+	pod.Labels = applyRegistryLabels(nil, dataMigrationToPVCPodName)
+	return pod
+}
+
+// ensureRegistryPVC creates the PVC used by the registry when it is not highly available, as
+// built by the registry addon. The PVC is removed by helm when the registry is made highly
+// available, so it is created here with the ownership metadata helm needs to adopt it once the
+// registry is scaled back.
+func ensureRegistryPVC(ctx context.Context, cli client.Client, pvc *corev1.PersistentVolumeClaim) error {
+	err := cli.Create(ctx, pvc)
+	if client.IgnoreAlreadyExists(err) != nil {
+		return fmt.Errorf("create pvc: %w", err)
+	}
+	return nil
+}
+
 func ensureMigrationServiceAccount(ctx context.Context, cli client.Client) error {
 	newRole := rbacv1.Role{
 		ObjectMeta: metav1.ObjectMeta{
@@ -39,3 +39,20 @@ func Test_getProgressFromLogLine(t *testing.T) {
 	}
 	buf.Reset()
 }
+
+func Test_newMigrationToPVCPod(t *testing.T) {
+	pod := newMigrationToPVCPod("operator:latest", "node-1", "registry-data")
+
+	if pod.Name != dataMigrationToPVCPodName {
+		t.Errorf("pod.Name = %v, want %v", pod.Name, dataMigrationToPVCPodName)
+	}
+	if got := pod.Spec.NodeSelector["kubernetes.io/hostname"]; got != "node-1" {
+		t.Errorf("pod.Spec.NodeSelector[kubernetes.io/hostname] = %v, want %v", got, "node-1")
+	}
+	if got := pod.Spec.Containers[0].Args; len(got) != 2 || got[1] != "registry-data-to-pvc" {
+		t.Errorf("pod.Spec.Containers[0].Args = %v, want [migrate registry-data-to-pvc]", got)
+	}
+	if got := pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName; got != "registry-data" {
+		t.Errorf("claim name = %v, want %v", got, "registry-data")
+	}
+}
@@ -88,3 +88,20 @@ func IsRegistryHA(ctx context.Context, kcli client.Client) (bool, error) {
 
 	return deploy.Spec.Replicas != nil && *deploy.Spec.Replicas > 1, nil
 }
+
+// IsRegistryOnPVC checks if the registry deployment stores its data in the registry PVC, as
+// opposed to the seaweedfs s3 store used when the registry is highly available.
+func IsRegistryOnPVC(ctx context.Context, kcli client.Client) (bool, error) {
+	deploy := appsv1.Deployment{}
+	err := kcli.Get(ctx, client.ObjectKey{Namespace: _namespace, Name: "registry"}, &deploy)
+	if err != nil {
+		return false, fmt.Errorf("get registry deployment: %w", err)
+	}
+
+	for _, volume := range deploy.Spec.Template.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == "registry" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -13,6 +13,9 @@ import (
 	"go.yaml.in/yaml/v3"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -124,3 +127,61 @@ func helmValuesHA() (map[string]interface{}, error) {
 
 	return hvHA, nil
 }
+
+// PersistentVolumeClaim returns the PVC the registry stores its data in when it is not highly
+// available, as rendered by its chart with the given overrides. It holds the ownership metadata
+// helm needs to adopt it, for when it is created outside of the chart.
+func (r *Registry) PersistentVolumeClaim(overrides []string) (*corev1.PersistentVolumeClaim, error) {
+	hv, err := helmValues()
+	if err != nil {
+		return nil, errors.Wrap(err, "get helm values")
+	}
+	for _, override := range overrides {
+		hv, err = helm.PatchValues(hv, override)
+		if err != nil {
+			return nil, errors.Wrap(err, "patch helm values")
+		}
+	}
+
+	values := map[string]string{}
+	for _, path := range []string{"fullnameOverride", "persistence.size", "persistence.storageClass", "persistence.accessMode"} {
+		v, err := helm.GetValue(hv, path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get %s", path)
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("%s is not a string", path)
+		}
+		values[path] = s
+	}
+
+	size, err := resource.ParseQuantity(values["persistence.size"])
+	if err != nil {
+		return nil, errors.Wrap(err, "parse persistence size")
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      values["fullnameOverride"],
+			Namespace: r.Namespace(),
+			Labels: map[string]string{
+				"app":                          "docker-registry",
+				"app.kubernetes.io/managed-by": "Helm",
+			},
+			Annotations: map[string]string{
+				"meta.helm.sh/release-name":      r.ReleaseName(),
+				"meta.helm.sh/release-namespace": r.Namespace(),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.PersistentVolumeAccessMode(values["persistence.accessMode"])},
+			StorageClassName: ptr.To(values["persistence.storageClass"]),
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,29 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRegistry_PersistentVolumeClaim(t *testing.T) {
+	r := &Registry{}
+
+	pvc, err := r.PersistentVolumeClaim(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "registry", pvc.Name)
+	assert.Equal(t, _namespace, pvc.Namespace)
+	assert.Equal(t, _releaseName, pvc.Annotations["meta.helm.sh/release-name"])
+	assert.Equal(t, []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}, pvc.Spec.AccessModes)
+	assert.Equal(t, "openebs-hostpath", *pvc.Spec.StorageClassName)
+	assert.Equal(t, resource.MustParse("10Gi"), pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+
+	// the size set by the vendor or end user overrides is used
+	pvc, err = r.PersistentVolumeClaim([]string{"persistence:\n  size: 50Gi\n"})
+	require.NoError(t, err)
+	assert.Equal(t, resource.MustParse("50Gi"), pvc.Spec.Resources.Requests[corev1.ResourceStorage])
+}
@@ -104,6 +104,29 @@ func ApplyHostK0sConfigOverrides(ctx context.Context, cfg *k0sv1beta1.ClusterCon
 	return nil
 }
 
+// EnableDualStack configures the cluster with the IPv6 pod and service CIDRs along with the IPv4
+// ones. k0s then configures Calico with an IPv6 pool and the pods get an address of each family.
+func EnableDualStack(cfg *k0sv1beta1.ClusterConfig, ipv6PodCIDR, ipv6ServiceCIDR string) {
+	if cfg.Spec.Network == nil {
+		cfg.Spec.Network = &k0sv1beta1.Network{}
+	}
+	cfg.Spec.Network.DualStack = k0sv1beta1.DualStack{
+		Enabled:         true,
+		IPv6PodCIDR:     ipv6PodCIDR,
+		IPv6ServiceCIDR: ipv6ServiceCIDR,
+	}
+}
+
+// SetCalicoInterface makes Calico autodetect the address of the nodes on the interfaces matching
+// the regular expression, for both address families. The interfaces carry the traffic between
+// the pods of different nodes.
+func SetCalicoInterface(cfg *k0sv1beta1.ClusterConfig, iface string) {
+	enableCalicoNetworkProvider(cfg)
+	method := fmt.Sprintf("interface=%s", iface)
+	cfg.Spec.Network.Calico.IPAutodetectionMethod = method
+	cfg.Spec.Network.Calico.IPv6AutodetectionMethod = method
+}
+
 // extractK0sConfigPatch extracts the k0s config portion of the provided patch.
 func extractK0sConfigPatch(raw string, respectImmutableFields bool) (string, error) {
 	type PatchBody struct {
@@ -195,6 +218,11 @@ func AdditionalInstallFlags(rc runtimeconfig.RuntimeConfig, nodeIP string, hostn
 	if hostname != "" {
 		kubeletExtraArgs = fmt.Sprintf("%s --hostname-override=%s", kubeletExtraArgs, hostname)
 	}
+	if len(rc.DNSUpstreams()) > 0 {
+		// CoreDNS runs with the default DNS policy so it forwards to the nameservers of the
+		// resolv.conf given to the kubelet.
+		kubeletExtraArgs = fmt.Sprintf("%s --resolv-conf=%s", kubeletExtraArgs, rc.PathToResolvConf())
+	}
 
 	return []string{
 		// NOTE: quotes are not supported in older systemd
@@ -260,6 +260,47 @@ spec:
 	}
 }
 
+func TestAdditionalInstallFlagsDNSUpstreams(t *testing.T) {
+	rc := runtimeconfig.New(nil)
+	rc.SetNetworkSpec(embeddedclusterv1beta1.NetworkSpec{DNSUpstreams: []string{"10.0.0.53", "10.0.1.53"}})
+
+	flags := AdditionalInstallFlags(rc, "192.168.1.10", "test-node")
+	assert.Equal(t, []string{
+		"--kubelet-extra-args", "--node-ip=192.168.1.10 --hostname-override=test-node --resolv-conf=" + rc.PathToResolvConf(),
+		"--data-dir", rc.EmbeddedClusterK0sSubDir(),
+	}, flags)
+}
+
+func TestAdditionalInstallFlagsDualStack(t *testing.T) {
+	rc := runtimeconfig.New(nil)
+
+	flags := AdditionalInstallFlags(rc, "192.168.1.10,fd00:1::10", "test-node")
+	assert.Equal(t, []string{
+		"--kubelet-extra-args", "--node-ip=192.168.1.10,fd00:1::10 --hostname-override=test-node",
+		"--data-dir", rc.EmbeddedClusterK0sSubDir(),
+	}, flags)
+}
+
+func TestEnableDualStack(t *testing.T) {
+	cfg := &k0sv1beta1.ClusterConfig{Spec: &k0sv1beta1.ClusterSpec{}}
+	EnableDualStack(cfg, "fd00:10::/49", "fd00:10:0:8000::/108")
+
+	require.NotNil(t, cfg.Spec.Network)
+	assert.True(t, cfg.Spec.Network.DualStack.Enabled)
+	assert.Equal(t, "fd00:10::/49", cfg.Spec.Network.DualStack.IPv6PodCIDR)
+	assert.Equal(t, "fd00:10:0:8000::/108", cfg.Spec.Network.DualStack.IPv6ServiceCIDR)
+}
+
+func TestSetCalicoInterface(t *testing.T) {
+	cfg := RenderK0sConfig("proxy.replicated.com")
+	SetCalicoInterface(cfg, "eth1")
+
+	require.NotNil(t, cfg.Spec.Network.Calico)
+	assert.Equal(t, "interface=eth1", cfg.Spec.Network.Calico.IPAutodetectionMethod)
+	assert.Equal(t, "interface=eth1", cfg.Spec.Network.Calico.IPv6AutodetectionMethod)
+	assert.Equal(t, "false", cfg.Spec.Network.Calico.EnvVars["FELIX_USAGEREPORTINGENABLED"])
+}
+
 func TestApplyHostK0sConfigOverrides(t *testing.T) {
 	origOutput := logrus.StandardLogger().Out
 	logrus.SetOutput(io.Discard)
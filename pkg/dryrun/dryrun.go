@@ -128,6 +128,11 @@ func Dump() error {
 	mu.Lock()
 	defer mu.Unlock()
 
+	// nothing to dump when planning
+	if drFile == "" {
+		return nil
+	}
+
 	dr.LogOutput = dr.LogBuffer.String()
 	dr.LogBuffer.Reset()
 
@@ -178,6 +183,27 @@ func RecordCommand(cmd string, args []string, env map[string]string) {
 	})
 }
 
+func RecordFile(file types.File) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dr.Files = append(dr.Files, file)
+}
+
+func RecordHelmRelease(release types.HelmRelease) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dr.HelmReleases = append(dr.HelmReleases, release)
+}
+
+func RecordImages(images []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dr.Images = append(dr.Images, images...)
+}
+
 func RecordMetric(title string, url string, payload []byte) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -0,0 +1,132 @@
+package dryrun
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/cmd/installer/goods"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/hostutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// NewHostUtils returns the host utils writing to a FileSystem and a Materializer that record
+// the files instead of writing them. The commands they run are recorded by the helpers.
+func NewHostUtils() *hostutils.HostUtils {
+	return hostutils.New(
+		hostutils.WithFileSystem(&FileSystem{}),
+		hostutils.WithMaterializer(&Materializer{}),
+	)
+}
+
+var _ hostutils.FileSystem = (*FileSystem)(nil)
+
+// FileSystem records the files the host utils write. Directories and permissions are not
+// recorded.
+type FileSystem struct{}
+
+// fileCategories are the categories of the files written by the host utils, by the prefix of
+// their path. Other files are recorded as materialized.
+var fileCategories = []struct {
+	prefix   string
+	category types.FileCategory
+}{
+	{"/etc/systemd/timesyncd.conf.d/", types.FileCategoryTimeSync},
+	{"/etc/chrony", types.FileCategoryTimeSync},
+	{"/etc/systemd/system/", types.FileCategorySystemd},
+	{"/etc/sysctl.d/", types.FileCategorySysctl},
+	{"/etc/modules-load.d/", types.FileCategoryKernelModules},
+	{"/etc/NetworkManager/", types.FileCategoryNetworkManager},
+	{"/etc/k0s/containerd", types.FileCategoryContainerd},
+}
+
+func (f *FileSystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	file := types.File{Category: types.FileCategoryMaterialized, Path: name}
+	for _, c := range fileCategories {
+		if strings.HasPrefix(name, c.prefix) {
+			file.Category = c.category
+			break
+		}
+	}
+	// the resolv.conf handed to the kubelet is written to the k0s data directory
+	if filepath.Base(name) == "resolv.conf" {
+		file.Category = types.FileCategoryK0s
+	}
+	// the content of materialized files, like the license, is left out of the plan
+	if file.Category != types.FileCategoryMaterialized {
+		file.Content = string(data)
+	}
+	RecordFile(file)
+	return nil
+}
+
+func (f *FileSystem) MkdirAll(path string, perm os.FileMode) error {
+	return nil
+}
+
+func (f *FileSystem) Remove(name string) error {
+	return nil
+}
+
+func (f *FileSystem) Symlink(oldname, newname string) error {
+	RecordFile(types.File{
+		Category:   types.FileCategorySystemd,
+		Path:       newname,
+		LinkTarget: oldname,
+	})
+	return nil
+}
+
+func (f *FileSystem) Chmod(name string, mode os.FileMode) error {
+	return nil
+}
+
+var _ hostutils.Materializer = (*Materializer)(nil)
+
+// Materializer records the runtime config and the files shipped with the installer instead of
+// writing them.
+type Materializer struct{}
+
+func (m *Materializer) WriteRuntimeConfig(rc runtimeconfig.RuntimeConfig) error {
+	RecordFile(types.File{
+		Category: types.FileCategoryMaterialized,
+		Path:     runtimeconfig.ECConfigPath,
+		Note:     "runtime config",
+	})
+	return nil
+}
+
+func (m *Materializer) MaterializeFiles(rc runtimeconfig.RuntimeConfig, channelRelease *release.ChannelRelease, airgapBundle string) error {
+	paths, err := goods.NewMaterializer(rc).MaterializedFiles()
+	if err != nil {
+		return fmt.Errorf("list materialized files: %w", err)
+	}
+	for _, path := range paths {
+		RecordFile(types.File{Category: types.FileCategoryMaterialized, Path: path})
+	}
+
+	RecordFile(types.File{
+		Category: types.FileCategoryMaterialized,
+		Path:     rc.PathToEmbeddedClusterSupportFile("host-support-bundle.yaml"),
+		Note:     "host support bundle spec",
+	})
+
+	if airgapBundle != "" {
+		RecordFile(types.File{
+			Category: types.FileCategoryMaterialized,
+			Path:     filepath.Join(rc.EmbeddedClusterK0sSubDir(), airgap.K0sImagePath),
+			Note:     fmt.Sprintf("images extracted from %s", airgapBundle),
+		})
+		RecordFile(types.File{
+			Category: types.FileCategoryMaterialized,
+			Path:     rc.EmbeddedClusterChartsSubDirNoCreate(),
+			Note:     fmt.Sprintf("charts extracted from %s", airgapBundle),
+		})
+	}
+
+	return nil
+}
@@ -65,6 +65,25 @@ func (c *Kotsadm) GetJoinToken(ctx context.Context, kotsAPIAddress, shortToken s
 	}
 }
 
+// SetGenerateJoinCommandResponse sets the response for the GenerateJoinCommand method, based on the provided roles.
+func (c *Kotsadm) SetGenerateJoinCommandResponse(roles []string, resp string, err error) {
+	mockErr := c.setResponse(resp, err, "GenerateJoinCommand", roles...)
+	if mockErr != nil {
+		panic(mockErr)
+	}
+}
+
+// GenerateJoinCommand issues a request to the kots api to generate the join command for a node
+// joining with the given roles.
+func (c *Kotsadm) GenerateJoinCommand(ctx context.Context, kotsadmAddress, authString string, roles []string) (string, error) {
+	key := strings.Join(append([]string{"GenerateJoinCommand"}, roles...), ":")
+	if handler, ok := c.mockHandlers[key]; ok {
+		return handler.resp.(string), handler.err
+	} else {
+		return "", fmt.Errorf("no response set for GenerateJoinCommand, roles: %s", strings.Join(roles, ","))
+	}
+}
+
 func (c *Kotsadm) SetGetK0sImagesFileResponse(kotsAPIAddress string, resp io.ReadCloser, err error) {
 	mockErr := c.setResponse(resp, err, "GetK0sImagesFile", kotsAPIAddress)
 	if mockErr != nil {
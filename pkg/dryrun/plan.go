@@ -0,0 +1,226 @@
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/hostutils"
+	"github.com/replicatedhq/embedded-cluster/pkg-new/k0s"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"helm.sh/helm/v3/pkg/repo"
+	chart "helm.sh/helm/v4/pkg/chart/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// InitPlan enables dry run to plan an install. Unlike Init, nothing is written to the host and
+// no output file is dumped: the files, commands and helm releases of the install are recorded
+// and retrieved with GetPlan.
+func InitPlan() {
+	Init("", &Client{
+		Systemd:       &Systemd{},
+		FirewalldUtil: &FirewalldUtil{},
+		HelmClient:    &PlanHelmClient{},
+	})
+	helpers.Set(&PlanHelpers{})
+	hostutils.Set(NewHostUtils())
+	k0s.Set(&PlanK0s{K0s: &K0s{k0s: new(k0s.K0s)}})
+	runtimeconfig.DisableDirCreation()
+}
+
+var _ helpers.HelpersInterface = (*PlanHelpers)(nil)
+
+// PlanHelpers records the commands that change the host. The commands that only read the host
+// run on it so the plan follows its state, and the waits for the services the install starts
+// return at once as nothing is started.
+type PlanHelpers struct {
+	host helpers.Helpers
+}
+
+// planHostReads matches the commands that only read the host.
+var planHostReads = regexp.MustCompile(`^sysctl -n `)
+
+// planServiceWaits are the commands polling the services the install starts, with the output
+// they return once the service is up.
+var planServiceWaits = map[string]string{
+	"systemctl status local-artifact-mirror":      "",
+	"timedatectl show -p NTPSynchronized --value": "yes\n",
+}
+
+func (h *PlanHelpers) RunCommandWithOptions(opts helpers.RunCommandOptions, bin string, args ...string) error {
+	full := strings.TrimSpace(filepath.Base(bin) + " " + strings.Join(args, " "))
+	if planHostReads.MatchString(full) {
+		return h.host.RunCommandWithOptions(opts, bin, args...)
+	}
+	if out, ok := planServiceWaits[full]; ok {
+		if opts.Stdout != nil {
+			_, _ = io.WriteString(opts.Stdout, out)
+		}
+		return nil
+	}
+	RecordCommand(bin, args, opts.Env)
+	return nil
+}
+
+func (h *PlanHelpers) RunCommand(bin string, args ...string) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	if err := h.RunCommandWithOptions(helpers.RunCommandOptions{Stdout: stdout}, bin, args...); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+func (h *PlanHelpers) IsSystemdServiceActive(ctx context.Context, svcname string) (bool, error) {
+	return new(systemd.DBus).IsActive(ctx, svcname)
+}
+
+// GetPlan returns everything recorded so far.
+func GetPlan() *types.Plan {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return &types.Plan{
+		Files:        slices.Clone(dr.Files),
+		Commands:     slices.Clone(dr.Commands),
+		HelmReleases: slices.Clone(dr.HelmReleases),
+		Images:       slices.Clone(dr.Images),
+	}
+}
+
+var _ k0s.K0sInterface = (*PlanK0s)(nil)
+
+// PlanK0s records the k0s config and the commands used to install k0s instead of installing it.
+type PlanK0s struct {
+	*K0s
+}
+
+func (c *PlanK0s) Install(rc runtimeconfig.RuntimeConfig, hostname string) error {
+	RecordFile(types.File{
+		Category: types.FileCategoryK0s,
+		Path:     runtimeconfig.K0sBinaryPath,
+		Note:     fmt.Sprintf("moved from %s", rc.PathToEmbeddedClusterBinary("k0s")),
+	})
+
+	nodeIPs, err := netutils.NodeAddresses(rc.NetworkInterface(), netutils.ClusterIPFamily(rc.PodCIDR(), rc.IPv6PodCIDR()))
+	if err != nil {
+		return fmt.Errorf("unable to find first valid address: %w", err)
+	}
+	flags, err := config.InstallFlags(rc, strings.Join(nodeIPs, ","), hostname)
+	if err != nil {
+		return fmt.Errorf("unable to get install flags: %w", err)
+	}
+	RecordCommand(runtimeconfig.K0sBinaryPath, flags, nil)
+	RecordCommand(runtimeconfig.K0sBinaryPath, []string{"start"}, nil)
+	return nil
+}
+
+func (c *PlanK0s) WriteK0sConfig(ctx context.Context, cfg *k0sv1beta1.ClusterConfig) error {
+	cfg.TypeMeta = metav1.TypeMeta{
+		APIVersion: k0sv1beta1.ClusterConfigAPIVersion,
+		Kind:       k0sv1beta1.ClusterConfigKind,
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to marshal config: %w", err)
+	}
+	RecordFile(types.File{
+		Category: types.FileCategoryK0s,
+		Path:     runtimeconfig.K0sConfigPath,
+		Content:  string(data),
+	})
+	return nil
+}
+
+var _ helm.Client = (*PlanHelmClient)(nil)
+
+// PlanHelmClient records the helm releases that would be installed with their values.
+type PlanHelmClient struct{}
+
+func (c *PlanHelmClient) Close() error {
+	return nil
+}
+
+func (c *PlanHelmClient) AddRepo(ctx context.Context, repo *repo.Entry) error {
+	return nil
+}
+
+func (c *PlanHelmClient) Latest(ctx context.Context, reponame, chart string) (string, error) {
+	return "", nil
+}
+
+func (c *PlanHelmClient) Pull(ctx context.Context, reponame, chart string, version string) (string, error) {
+	return "", nil
+}
+
+func (c *PlanHelmClient) PullByRef(ctx context.Context, ref string, version string) (string, error) {
+	return "", nil
+}
+
+func (c *PlanHelmClient) RegistryAuth(ctx context.Context, server, user, pass string) error {
+	return nil
+}
+
+func (c *PlanHelmClient) Push(ctx context.Context, path, dst string) error {
+	return nil
+}
+
+func (c *PlanHelmClient) GetChartMetadata(ctx context.Context, ref string, version string) (*chart.Metadata, error) {
+	return &chart.Metadata{Version: version}, nil
+}
+
+func (c *PlanHelmClient) ReleaseExists(ctx context.Context, namespace string, releaseName string) (bool, error) {
+	return false, nil
+}
+
+func (c *PlanHelmClient) Install(ctx context.Context, opts helm.InstallOptions) (*helm.ReleaseInfo, error) {
+	RecordHelmRelease(types.HelmRelease{
+		Name:      opts.ReleaseName,
+		Namespace: opts.Namespace,
+		Chart:     opts.ChartPath,
+		Version:   opts.ChartVersion,
+		Values:    opts.Values,
+	})
+	return &helm.ReleaseInfo{
+		Name:      opts.ReleaseName,
+		Namespace: opts.Namespace,
+		Chart:     opts.ChartPath,
+		Version:   opts.ChartVersion,
+	}, nil
+}
+
+func (c *PlanHelmClient) Upgrade(ctx context.Context, opts helm.UpgradeOptions) (*helm.ReleaseInfo, error) {
+	RecordHelmRelease(types.HelmRelease{
+		Name:      opts.ReleaseName,
+		Namespace: opts.Namespace,
+		Chart:     opts.ChartPath,
+		Version:   opts.ChartVersion,
+		Values:    opts.Values,
+	})
+	return &helm.ReleaseInfo{
+		Name:      opts.ReleaseName,
+		Namespace: opts.Namespace,
+		Chart:     opts.ChartPath,
+		Version:   opts.ChartVersion,
+	}, nil
+}
+
+func (c *PlanHelmClient) Uninstall(ctx context.Context, opts helm.UninstallOptions) error {
+	return nil
+}
+
+func (c *PlanHelmClient) Render(ctx context.Context, opts helm.InstallOptions) ([][]byte, error) {
+	return nil, nil
+}
@@ -32,6 +32,9 @@ type DryRun struct {
 	Metrics           []Metric                               `json:"metrics"`
 	HostPreflightSpec *troubleshootv1beta2.HostPreflightSpec `json:"hostPreflightSpec"`
 	AppPreflightSpec  *troubleshootv1beta2.PreflightSpec     `json:"appPreflightSpec"`
+	Files             []File                                 `json:"files,omitempty"`
+	HelmReleases      []HelmRelease                          `json:"helmReleases,omitempty"`
+	Images            []string                               `json:"images,omitempty"`
 
 	// These fields are set on marshal
 	OSEnv          map[string]string `json:"osEnv"`
@@ -60,6 +63,47 @@ type Command struct {
 	Env map[string]string `json:"env,omitempty"`
 }
 
+// Plan holds the changes an install would make to the host and the cluster.
+type Plan struct {
+	Files        []File        `json:"files"`
+	Commands     []Command     `json:"commands"`
+	HelmReleases []HelmRelease `json:"helmReleases"`
+	Images       []string      `json:"images"`
+}
+
+// File is a file written to the host. Only recorded when planning.
+type File struct {
+	Category FileCategory `json:"category"`
+	Path     string       `json:"path"`
+	// Content is empty for binaries and for files whose content is only known once written.
+	Content    string `json:"content,omitempty"`
+	LinkTarget string `json:"linkTarget,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// FileCategory groups the files written to the host by what they configure.
+type FileCategory string
+
+const (
+	FileCategoryMaterialized   FileCategory = "materialized"
+	FileCategorySystemd        FileCategory = "systemd"
+	FileCategorySysctl         FileCategory = "sysctl"
+	FileCategoryKernelModules  FileCategory = "kernel-modules"
+	FileCategoryNetworkManager FileCategory = "network-manager"
+	FileCategoryContainerd     FileCategory = "containerd"
+	FileCategoryK0s            FileCategory = "k0s"
+	FileCategoryTimeSync       FileCategory = "time-sync"
+)
+
+// HelmRelease is a helm release installed in the cluster. Only recorded when planning.
+type HelmRelease struct {
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace"`
+	Chart     string                 `json:"chart"`
+	Version   string                 `json:"version"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
 func (d *DryRun) MarshalJSON() ([]byte, error) {
 	k8sObjects, err := d.K8sObjectsFromClient()
 	if err != nil {
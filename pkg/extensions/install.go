@@ -52,3 +52,22 @@ func Install(ctx context.Context, hcli helm.Client, progressChan chan<- Extensio
 
 	return nil
 }
+
+// RemoveLeftovers uninstalls any extension release left behind by an interrupted install so
+// that Install can be run again.
+func RemoveLeftovers(ctx context.Context, hcli helm.Client) error {
+	for _, ext := range config.AdditionalCharts() {
+		exists, err := hcli.ReleaseExists(ctx, ext.TargetNS, ext.Name)
+		if err != nil {
+			return errors.Wrapf(err, "check if extension %s exists", ext.Name)
+		}
+		if !exists {
+			continue
+		}
+		if err := uninstall(ctx, hcli, ext); err != nil {
+			return errors.Wrapf(err, "uninstall extension %s", ext.Name)
+		}
+	}
+
+	return nil
+}
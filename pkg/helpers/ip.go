@@ -15,7 +15,7 @@ func GetLowerBandIP(cidr string, index int) (net.IP, error) {
 
 	ip := ipnet.IP.To4()
 	if ip == nil {
-		return nil, fmt.Errorf("only IPv4 is supported")
+		ip = ipnet.IP.To16()
 	}
 	ipInt := big.NewInt(0).SetBytes(ip)
 
@@ -39,5 +39,5 @@ func GetLowerBandIP(cidr string, index int) (net.IP, error) {
 		return nil, fmt.Errorf("index %d is out of the band range", index)
 	}
 
-	return net.IP(selectedIP.Bytes()), nil
+	return net.IP(selectedIP.FillBytes(make([]byte, len(ip)))), nil
 }
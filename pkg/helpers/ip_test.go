@@ -17,6 +17,8 @@ func TestGetLowerBandIP(t *testing.T) {
 		{"192.168.1.0/24", 7, "192.168.1.8"},
 		{"172.16.0.0/28", 0, "172.16.0.1"},
 		{"172.16.0.0/28", 14, "172.16.0.15"},
+		{"fd00:10:96::/108", 0, "fd00:10:96::1"},
+		{"fd00:10:96::/108", 9, "fd00:10:96::a"},
 	}
 	for _, tt := range validTests {
 		t.Run(tt.cidr, func(t *testing.T) {
@@ -38,6 +40,7 @@ func TestGetLowerBandIP(t *testing.T) {
 		{"10.96.0.0/24", 16},
 		{"192.168.1.0/24", 255},
 		{"172.16.0.0/28", 16},
+		{"fd00:10:96::/124", 16},
 	}
 	for _, tt := range invalidTests {
 		t.Run(tt.cidr, func(t *testing.T) {
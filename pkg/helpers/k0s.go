@@ -11,6 +11,10 @@ func NetworkSpecFromK0sConfig(k0sCfg *k0sv1beta1.ClusterConfig) ecv1beta1.Networ
 	if k0sCfg.Spec != nil && k0sCfg.Spec.Network != nil {
 		network.PodCIDR = k0sCfg.Spec.Network.PodCIDR
 		network.ServiceCIDR = k0sCfg.Spec.Network.ServiceCIDR
+		if k0sCfg.Spec.Network.DualStack.Enabled {
+			network.IPv6PodCIDR = k0sCfg.Spec.Network.DualStack.IPv6PodCIDR
+			network.IPv6ServiceCIDR = k0sCfg.Spec.Network.DualStack.IPv6ServiceCIDR
+		}
 	}
 
 	if k0sCfg.Spec.API != nil {
@@ -0,0 +1,15 @@
+package systemd
+
+import "fmt"
+
+// ProxyDropInFileName is the name of the drop-in setting the proxy of a unit.
+const ProxyDropInFileName = "http-proxy.conf"
+
+// ProxyDropInContent returns the content of the drop-in setting the proxy environment variables
+// of a unit.
+func ProxyDropInContent(httpProxy string, httpsProxy string, noProxy string) string {
+	return fmt.Sprintf(`[Service]
+Environment="HTTP_PROXY=%s"
+Environment="HTTPS_PROXY=%s"
+Environment="NO_PROXY=%s"`, httpProxy, httpsProxy, noProxy)
+}
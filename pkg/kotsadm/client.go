@@ -1,12 +1,14 @@
 package kotsadm
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/replicatedhq/embedded-cluster/kinds/types/join"
@@ -45,6 +47,43 @@ func (c *Client) GetJoinToken(ctx context.Context, kotsAPIAddress, shortToken st
 	return &command, nil
 }
 
+// GenerateJoinCommand issues a request to the kots api to generate the join command for a node
+// joining with the given roles. authString is the admin console auth string, it authorizes the
+// request as an admin of the cluster.
+func (c *Client) GenerateJoinCommand(ctx context.Context, kotsadmAddress, authString string, roles []string) (string, error) {
+	url := fmt.Sprintf("http://%s/api/v1/embedded-cluster/generate-node-join-command", kotsadmAddress)
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	body, err := json.Marshal(map[string][]string{"roles": roles})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authString)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to generate join command: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var command struct {
+		Command []string `json:"command"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&command); err != nil {
+		return "", fmt.Errorf("unable to decode response: %w", err)
+	}
+	return strings.Join(command.Command, " "), nil
+}
+
 // GetK0sImagesFile fetches the k0s images file from the KOTS API.
 // caller is responsible for closing the response body.
 func (c *Client) GetK0sImagesFile(ctx context.Context, kotsAPIAddress string) (io.ReadCloser, error) {
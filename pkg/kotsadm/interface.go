@@ -21,6 +21,7 @@ func Set(kotsadm ClientInterface) {
 
 type ClientInterface interface {
 	GetJoinToken(ctx context.Context, kotsAPIAddress, shortToken string) (*join.JoinCommandResponse, error)
+	GenerateJoinCommand(ctx context.Context, kotsadmAddress, authString string, roles []string) (string, error)
 	GetK0sImagesFile(ctx context.Context, kotsAPIAddress string) (io.ReadCloser, error)
 	GetECCharts(ctx context.Context, kotsAPIAddress string) (io.ReadCloser, error)
 }
@@ -33,6 +34,12 @@ func GetJoinToken(ctx context.Context, kotsAPIAddress, shortToken string) (*join
 	return _kotsadm.GetJoinToken(ctx, kotsAPIAddress, shortToken)
 }
 
+// GenerateJoinCommand is a helper function that issues a request to the kots api to generate the
+// join command for a node joining with the given roles.
+func GenerateJoinCommand(ctx context.Context, kotsadmAddress, authString string, roles []string) (string, error) {
+	return _kotsadm.GenerateJoinCommand(ctx, kotsadmAddress, authString, roles)
+}
+
 // GetK0sImagesFile is a helper function that fetches the k0s images file from the KOTS API.
 // caller is responsible for closing the response body.
 func GetK0sImagesFile(ctx context.Context, kotsAPIAddress string) (io.ReadCloser, error) {
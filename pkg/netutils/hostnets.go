@@ -0,0 +1,233 @@
+package netutils
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// routeTablePath is the IPv4 routing table of the host, can be overridden for testing.
+var routeTablePath = "/proc/net/route"
+
+// noInterface is the interface of the blackhole, unreachable and prohibit routes in the routing
+// table. They drop the traffic instead of reaching a network, Calico adds one for the block of
+// the pod cidr assigned to the host.
+const noInterface = "*"
+
+// clusterInterfacePrefixes are the prefixes of the interfaces Calico creates. Their routes
+// belong to the cluster when the installer runs again on the same host.
+var clusterInterfacePrefixes = []string{"cali", "vxlan.calico", "vxlan-v6.calico", "tunl0"}
+
+// interfaceKinds describe the interfaces other software creates, by prefix of their names.
+var interfaceKinds = []struct {
+	prefix string
+	kind   string
+}{
+	{"docker", "Docker bridge"},
+	{"br-", "Docker bridge"},
+	{"virbr", "libvirt bridge"},
+	{"lxdbr", "LXD bridge"},
+	{"cni", "container network"},
+	{"flannel", "container network"},
+	{"tun", "VPN"},
+	{"tap", "VPN"},
+	{"wg", "VPN"},
+	{"tailscale", "VPN"},
+	{"zt", "VPN"},
+	{"ppp", "VPN"},
+	{"ipsec", "VPN"},
+	{"vti", "VPN"},
+	{"nordlynx", "VPN"},
+}
+
+// HostNetwork is a network the host reaches, through a route or an address of one of its
+// interfaces.
+type HostNetwork struct {
+	Prefix netip.Prefix
+	// Interface is the interface the network is reached through.
+	Interface string
+	// Address is set when the network is the one of an address of the interface.
+	Address string
+}
+
+// String describes the network and how the host reaches it.
+func (n HostNetwork) String() string {
+	via := n.Interface
+	if kind := InterfaceKind(n.Interface); kind != "" {
+		via = fmt.Sprintf("%s (%s)", n.Interface, kind)
+	}
+	if n.Address != "" {
+		return fmt.Sprintf("the address %s of %s", n.Address, via)
+	}
+	return fmt.Sprintf("the route to %s via %s", n.Prefix, via)
+}
+
+// InterfaceKind returns what created the interface, a Docker bridge or a VPN for example. It
+// returns an empty string for the interfaces it does not recognize.
+func InterfaceKind(name string) string {
+	for _, k := range interfaceKinds {
+		if strings.HasPrefix(name, k.prefix) {
+			return k.kind
+		}
+	}
+	return ""
+}
+
+// ListHostNetworks returns the networks the host reaches through its IPv4 routes and the
+// addresses of its interfaces. Default routes, blackhole routes, loopback and link-local
+// networks and the networks of the cluster interfaces are left out.
+func ListHostNetworks() ([]HostNetwork, error) {
+	routes, err := readIPv4Routes(routeTablePath)
+	if err != nil {
+		return nil, err
+	}
+
+	ifs, err := DefaultNetworkInterfaceProvider.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("list network interfaces: %w", err)
+	}
+	networks := []HostNetwork{}
+	for _, i := range ifs {
+		addrs, err := i.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("list addresses of %s: %w", i.Name(), err)
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(ipnet.String())
+			if err != nil {
+				continue
+			}
+			networks = append(networks, HostNetwork{
+				Prefix:    prefix.Masked(),
+				Interface: i.Name(),
+				Address:   prefix.Addr().Unmap().String(),
+			})
+		}
+	}
+
+	// the routes of the networks of the addresses are left out, the addresses describe them
+	for _, route := range routes {
+		connected := false
+		for _, network := range networks {
+			if network.Prefix == route.Prefix && network.Interface == route.Interface {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			networks = append(networks, route)
+		}
+	}
+
+	result := []HostNetwork{}
+	for _, network := range networks {
+		if isIgnoredHostNetwork(network) {
+			continue
+		}
+		result = append(result, network)
+	}
+	return result, nil
+}
+
+func isIgnoredHostNetwork(network HostNetwork) bool {
+	addr := network.Prefix.Addr()
+	if network.Prefix.Bits() == 0 || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return true
+	}
+	if network.Interface == noInterface {
+		return true
+	}
+	for _, prefix := range clusterInterfacePrefixes {
+		if strings.HasPrefix(network.Interface, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CIDRConflicts returns the host networks that overlap with the cidr.
+func CIDRConflicts(cidr string, networks []HostNetwork) ([]HostNetwork, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("parse cidr: %w", err)
+	}
+	conflicts := []HostNetwork{}
+	for _, network := range networks {
+		if prefix.Overlaps(network.Prefix) {
+			conflicts = append(conflicts, network)
+		}
+	}
+	return conflicts, nil
+}
+
+// SelectFreeCIDR returns the first candidate cidr that does not overlap with the host networks.
+func SelectFreeCIDR(candidates []string, networks []HostNetwork) (string, error) {
+	for _, candidate := range candidates {
+		conflicts, err := CIDRConflicts(candidate, networks)
+		if err != nil {
+			return "", fmt.Errorf("candidate %s: %w", candidate, err)
+		}
+		if len(conflicts) == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("all candidate cidrs (%s) overlap with the networks of this host", strings.Join(candidates, ", "))
+}
+
+// readIPv4Routes reads the routes of the main routing table in the format of /proc/net/route.
+// The destination and the mask are hexadecimal numbers in the byte order of the host, little
+// endian on the architectures supported.
+func readIPv4Routes(path string) ([]HostNetwork, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	routes := []HostNetwork{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		dest, err := parseRouteAddr(fields[1])
+		if err != nil {
+			continue
+		}
+		mask, err := parseRouteAddr(fields[7])
+		if err != nil {
+			continue
+		}
+		ones := 0
+		for _, b := range mask.As4() {
+			ones += bits.OnesCount8(b)
+		}
+		prefix, err := dest.Prefix(ones)
+		if err != nil {
+			continue
+		}
+		routes = append(routes, HostNetwork{Prefix: prefix, Interface: fields[0]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return routes, nil
+}
+
+func parseRouteAddr(s string) (netip.Addr, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return netip.Addr{}, fmt.Errorf("invalid address %q", s)
+	}
+	return netip.AddrFrom4([4]byte{b[3], b[2], b[1], b[0]}), nil
+}
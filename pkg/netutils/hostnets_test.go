@@ -0,0 +1,165 @@
+package netutils
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRouteTable = `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0101A8C0	0003	0	0	100	00000000	0	0	0
+eth0	0001A8C0	00000000	0001	0	0	100	00FFFFFF	0	0	0
+tun0	0000F40A	00000000	0001	0	0	0	0000FFFF	0	0	0
+docker0	000011AC	00000000	0001	0	0	0	0000FFFF	0	0	0
+cali1234	0500F50A	00000000	0005	0	0	0	FFFFFFFF	0	0	0
+eth0	0000FEA9	00000000	0001	0	0	1000	0000FFFF	0	0	0
+*	0000F50A	00000000	0201	0	0	0	0000FFFF	0	0	0
+`
+
+func TestReadIPv4Routes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "route")
+	require.NoError(t, os.WriteFile(path, []byte(testRouteTable), 0644))
+
+	routes, err := readIPv4Routes(path)
+	require.NoError(t, err)
+	require.Len(t, routes, 7)
+	assert.Equal(t, "0.0.0.0/0", routes[0].Prefix.String())
+	assert.Equal(t, "192.168.1.0/24", routes[1].Prefix.String())
+	assert.Equal(t, HostNetwork{Prefix: netip.MustParsePrefix("10.244.0.0/16"), Interface: "tun0"}, routes[2])
+	assert.Equal(t, "172.17.0.0/16", routes[3].Prefix.String())
+	assert.Equal(t, "10.245.0.5/32", routes[4].Prefix.String())
+	assert.Equal(t, HostNetwork{Prefix: netip.MustParsePrefix("10.245.0.0/16"), Interface: "*"}, routes[6])
+}
+
+func TestListHostNetworks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "route")
+	require.NoError(t, os.WriteFile(path, []byte(testRouteTable), 0644))
+
+	originalPath := routeTablePath
+	originalProvider := DefaultNetworkInterfaceProvider
+	t.Cleanup(func() {
+		routeTablePath = originalPath
+		DefaultNetworkInterfaceProvider = originalProvider
+	})
+	routeTablePath = path
+	DefaultNetworkInterfaceProvider = &mockNetworkInterfaceProvider{
+		interfaces: []NetworkInterface{
+			&mockNetworkInterface{
+				name:  "lo",
+				flags: net.FlagUp | net.FlagLoopback,
+				addrs: []net.Addr{
+					&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+					&net.IPNet{IP: net.ParseIP("::1"), Mask: net.CIDRMask(128, 128)},
+				},
+			},
+			&mockNetworkInterface{
+				name:  "eth0",
+				flags: net.FlagUp,
+				addrs: []net.Addr{
+					&net.IPNet{IP: net.ParseIP("192.168.1.10").To4(), Mask: net.CIDRMask(24, 32)},
+					&net.IPNet{IP: net.ParseIP("fd00:1::10"), Mask: net.CIDRMask(64, 128)},
+					&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+				},
+			},
+			&mockNetworkInterface{
+				name:  "docker0",
+				flags: net.FlagUp,
+				addrs: []net.Addr{
+					&net.IPNet{IP: net.ParseIP("172.17.0.1").To4(), Mask: net.CIDRMask(16, 32)},
+				},
+			},
+		},
+	}
+
+	networks, err := ListHostNetworks()
+	require.NoError(t, err)
+	assert.Equal(t, []HostNetwork{
+		{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Interface: "eth0", Address: "192.168.1.10"},
+		{Prefix: netip.MustParsePrefix("fd00:1::/64"), Interface: "eth0", Address: "fd00:1::10"},
+		{Prefix: netip.MustParsePrefix("172.17.0.0/16"), Interface: "docker0", Address: "172.17.0.1"},
+		{Prefix: netip.MustParsePrefix("10.244.0.0/16"), Interface: "tun0"},
+	}, networks)
+}
+
+func TestCIDRConflicts(t *testing.T) {
+	networks := []HostNetwork{
+		{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Interface: "eth0", Address: "192.168.1.10"},
+		{Prefix: netip.MustParsePrefix("10.244.5.0/24"), Interface: "wg0"},
+		{Prefix: netip.MustParsePrefix("172.16.0.0/12"), Interface: "eth1"},
+	}
+
+	tests := []struct {
+		name string
+		cidr string
+		want []string
+	}{
+		{
+			name: "no conflict",
+			cidr: "10.245.0.0/16",
+			want: []string{},
+		},
+		{
+			name: "contains a vpn route",
+			cidr: "10.244.0.0/16",
+			want: []string{"the route to 10.244.5.0/24 via wg0 (VPN)"},
+		},
+		{
+			name: "inside a routed network",
+			cidr: "172.28.0.0/16",
+			want: []string{"the route to 172.16.0.0/12 via eth1"},
+		},
+		{
+			name: "contains the address of the host",
+			cidr: "192.168.0.0/16",
+			want: []string{"the address 192.168.1.10 of eth0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conflicts, err := CIDRConflicts(tt.cidr, networks)
+			require.NoError(t, err)
+			got := []string{}
+			for _, conflict := range conflicts {
+				got = append(got, conflict.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := CIDRConflicts("invalid", networks)
+	assert.Error(t, err)
+}
+
+func TestSelectFreeCIDR(t *testing.T) {
+	networks := []HostNetwork{
+		{Prefix: netip.MustParsePrefix("10.244.0.0/16"), Interface: "tun0"},
+		{Prefix: netip.MustParsePrefix("10.245.0.0/16"), Interface: "docker0", Address: "10.245.0.1"},
+	}
+
+	cidr, err := SelectFreeCIDR([]string{"10.244.0.0/16", "10.245.0.0/16", "10.246.0.0/16"}, networks)
+	require.NoError(t, err)
+	assert.Equal(t, "10.246.0.0/16", cidr)
+
+	cidr, err = SelectFreeCIDR([]string{"10.244.0.0/16"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "10.244.0.0/16", cidr)
+
+	_, err = SelectFreeCIDR([]string{"10.244.0.0/16", "10.245.0.0/16"}, networks)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all candidate cidrs (10.244.0.0/16, 10.245.0.0/16) overlap")
+}
+
+func TestInterfaceKind(t *testing.T) {
+	assert.Equal(t, "Docker bridge", InterfaceKind("docker0"))
+	assert.Equal(t, "Docker bridge", InterfaceKind("br-0a1b2c3d4e5f"))
+	assert.Equal(t, "VPN", InterfaceKind("tun0"))
+	assert.Equal(t, "VPN", InterfaceKind("wg0"))
+	assert.Equal(t, "VPN", InterfaceKind("tailscale0"))
+	assert.Equal(t, "", InterfaceKind("eth0"))
+	assert.Equal(t, "", InterfaceKind("ens192"))
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/replicatedhq/embedded-cluster/pkg-new/cloudutils"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // adapted from https://github.com/k0sproject/k0s/blob/v1.30.4%2Bk0s.0/internal/pkg/iface/iface.go#L61
@@ -18,7 +19,50 @@ func FirstValidAddress(networkInterface string) (string, error) {
 }
 
 func FirstValidIPNet(networkInterface string) (*net.IPNet, error) {
-	ifs, err := listValidInterfaces()
+	return firstValidIPNetOf(networkInterface, firstValidIPNet)
+}
+
+// FirstValidIPv6Address returns the first global IPv6 address of the network interface, or of
+// the first interface with one if networkInterface is empty.
+func FirstValidIPv6Address(networkInterface string) (string, error) {
+	ipnet, err := FirstValidIPv6Net(networkInterface)
+	if err != nil {
+		return "", fmt.Errorf("get ipv6 ipnet for interface %s: %w", networkInterface, err)
+	}
+	return ipnet.IP.String(), nil
+}
+
+// FirstValidIPv6Net returns the network of the first global IPv6 address of the network
+// interface, or of the first interface with one if networkInterface is empty. Link-local
+// addresses are skipped as they are not reachable from the other nodes.
+func FirstValidIPv6Net(networkInterface string) (*net.IPNet, error) {
+	return firstValidIPNetOf(networkInterface, firstValidIPv6Net)
+}
+
+// NodeAddresses returns the addresses of the node on the network interface for the IP family
+// of the cluster. Dual-stack clusters get the IPv4 address first, as the kubelet --node-ip
+// flag expects them.
+func NodeAddresses(networkInterface string, family IPFamily) ([]string, error) {
+	addresses := []string{}
+	if family != IPFamilyIPv6 {
+		address, err := FirstValidAddress(networkInterface)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	if family != IPFamilyIPv4 {
+		address, err := FirstValidIPv6Address(networkInterface)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+func firstValidIPNetOf(networkInterface string, find func(NetworkInterface) (*net.IPNet, error)) (*net.IPNet, error) {
+	ifs, err := listValidInterfaces(find)
 	if err != nil {
 		return nil, fmt.Errorf("list valid network interfaces: %w", err)
 	}
@@ -26,11 +70,11 @@ func FirstValidIPNet(networkInterface string) (*net.IPNet, error) {
 		return nil, fmt.Errorf("no valid network interfaces found on this machine")
 	}
 	if networkInterface == "" {
-		return firstValidIPNet(ifs[0])
+		return find(ifs[0])
 	}
 	for _, i := range ifs {
 		if i.Name() == networkInterface {
-			return firstValidIPNet(i)
+			return find(i)
 		}
 	}
 	var ifNames []string
@@ -43,7 +87,7 @@ func FirstValidIPNet(networkInterface string) (*net.IPNet, error) {
 // ListValidNetworkInterfaces returns a list of valid network interfaces that are up and not
 // loopback.
 func ListValidNetworkInterfaces() ([]NetworkInterface, error) {
-	ifs, err := listValidInterfaces()
+	ifs, err := listValidInterfaces(firstValidIPNet)
 	if err != nil {
 		return nil, err
 	}
@@ -61,15 +105,16 @@ func ListValidNetworkInterfaces() ([]NetworkInterface, error) {
 	return validIfs, nil
 }
 
-// listValidInterfaces returns a list of valid network interfaces for the node.
-func listValidInterfaces() ([]NetworkInterface, error) {
+// listValidInterfaces returns a list of valid network interfaces for the node, those find
+// returns an address for.
+func listValidInterfaces(find func(NetworkInterface) (*net.IPNet, error)) ([]NetworkInterface, error) {
 	ifs, err := DefaultNetworkInterfaceProvider.Interfaces()
 	if err != nil {
 		return nil, fmt.Errorf("list network interfaces: %w", err)
 	}
 	validIfs := []NetworkInterface{}
 	for _, i := range ifs {
-		if !isValidInterface(i) {
+		if !isValidInterface(i, find) {
 			continue
 		}
 		validIfs = append(validIfs, i)
@@ -77,7 +122,7 @@ func listValidInterfaces() ([]NetworkInterface, error) {
 	return validIfs, nil
 }
 
-func isValidInterface(i NetworkInterface) bool {
+func isValidInterface(i NetworkInterface, find func(NetworkInterface) (*net.IPNet, error)) bool {
 	switch {
 	case i.Name() == "vxlan.calico":
 		return false
@@ -90,11 +135,7 @@ func isValidInterface(i NetworkInterface) bool {
 	case strings.HasPrefix(i.Name(), "cali"):
 		return false
 	}
-	return hasValidIPNet(i)
-}
-
-func hasValidIPNet(i NetworkInterface) bool {
-	ipnet, err := firstValidIPNet(i)
+	ipnet, err := find(i)
 	return err == nil && ipnet != nil
 }
 
@@ -114,6 +155,19 @@ func firstValidIPNet(i NetworkInterface) (*net.IPNet, error) {
 	return nil, fmt.Errorf("could not find any non-local, non podnetwork ipv4 addresses")
 }
 
+func firstValidIPv6Net(i NetworkInterface) (*net.IPNet, error) {
+	addresses, err := i.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("get addresses: %w", err)
+	}
+	for _, a := range addresses {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() == nil && ipnet.IP.IsGlobalUnicast() {
+			return ipnet, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find any global ipv6 addresses")
+}
+
 func ListAllValidIPAddresses() ([]net.IP, error) {
 	ipAddresses := []net.IP{}
 
@@ -143,3 +197,15 @@ func ListAllValidIPAddresses() ([]net.IP, error) {
 
 	return ipAddresses, nil
 }
+
+// ValidateHostAddress checks that the address is an IP address or a hostname, without a scheme
+// or a port, as expected to build the URLs the hosts are reached on.
+func ValidateHostAddress(address string) error {
+	if net.ParseIP(address) != nil {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(strings.ToLower(address)); len(errs) > 0 {
+		return fmt.Errorf("%q is not an IP address or a hostname: %s", address, strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -295,6 +295,85 @@ func TestFirstValidIPNet(t *testing.T) {
 	}
 }
 
+func TestNodeAddresses(t *testing.T) {
+	originalProvider := DefaultNetworkInterfaceProvider
+	defer func() {
+		DefaultNetworkInterfaceProvider = originalProvider
+	}()
+
+	DefaultNetworkInterfaceProvider = &mockNetworkInterfaceProvider{
+		interfaces: []NetworkInterface{
+			&mockNetworkInterface{
+				name:  "eth0",
+				flags: net.FlagUp,
+				addrs: []net.Addr{
+					&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+					&net.IPNet{IP: net.ParseIP("192.168.1.100"), Mask: net.CIDRMask(24, 32)},
+					&net.IPNet{IP: net.ParseIP("fd00:1::100"), Mask: net.CIDRMask(64, 128)},
+				},
+			},
+			&mockNetworkInterface{
+				name:  "eth1",
+				flags: net.FlagUp,
+				addrs: []net.Addr{
+					&net.IPNet{IP: net.ParseIP("fd00:2::100"), Mask: net.CIDRMask(64, 128)},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		networkInterface  string
+		family            IPFamily
+		expectedAddresses []string
+		expectedError     bool
+	}{
+		{
+			name:              "ipv4",
+			networkInterface:  "eth0",
+			family:            IPFamilyIPv4,
+			expectedAddresses: []string{"192.168.1.100"},
+		},
+		{
+			name:              "ipv6 skips link-local addresses",
+			networkInterface:  "eth0",
+			family:            IPFamilyIPv6,
+			expectedAddresses: []string{"fd00:1::100"},
+		},
+		{
+			name:              "dual-stack returns the ipv4 address first",
+			networkInterface:  "eth0",
+			family:            IPFamilyDualStack,
+			expectedAddresses: []string{"192.168.1.100", "fd00:1::100"},
+		},
+		{
+			name:              "ipv6 only interface",
+			networkInterface:  "eth1",
+			family:            IPFamilyIPv6,
+			expectedAddresses: []string{"fd00:2::100"},
+		},
+		{
+			name:             "ipv6 only interface in a dual-stack cluster",
+			networkInterface: "eth1",
+			family:           IPFamilyDualStack,
+			expectedError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addresses, err := NodeAddresses(tt.networkInterface, tt.family)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAddresses, addresses)
+		})
+	}
+}
+
 func TestListValidNetworkInterfaces(t *testing.T) {
 	// Save original provider
 	originalProvider := DefaultNetworkInterfaceProvider
@@ -627,3 +706,12 @@ func TestListAllValidIPAddresses(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateHostAddress(t *testing.T) {
+	for _, address := range []string{"10.0.0.10", "fd00:1::10", "console.example.com", "Console.Example.com", "node-1"} {
+		assert.NoError(t, ValidateHostAddress(address), address)
+	}
+	for _, address := range []string{"", "https://console.example.com", "10.0.0.10:30000", "[fd00:1::10]"} {
+		assert.Error(t, ValidateHostAddress(address), address)
+	}
+}
@@ -8,8 +8,53 @@ import (
 	"github.com/apparentlymart/go-cidr/cidr"
 )
 
+// maxIPv6ServiceCIDRSize is the prefix length of the largest IPv6 service CIDR the API server
+// allocates service IPs from.
+const maxIPv6ServiceCIDRSize = 108
+
+// IPFamily is the IP family of the cluster network.
+type IPFamily string
+
+const (
+	IPFamilyIPv4      IPFamily = "IPv4"
+	IPFamilyIPv6      IPFamily = "IPv6"
+	IPFamilyDualStack IPFamily = "DualStack"
+)
+
+// ClusterIPFamily returns the IP family of a cluster from its pod CIDR and, for dual-stack
+// clusters, its IPv6 pod CIDR.
+func ClusterIPFamily(podCIDR, ipv6PodCIDR string) IPFamily {
+	switch {
+	case IsIPv6CIDR(podCIDR):
+		return IPFamilyIPv6
+	case ipv6PodCIDR != "":
+		return IPFamilyDualStack
+	default:
+		return IPFamilyIPv4
+	}
+}
+
+// IsIPv6CIDR returns true if the provided CIDR is an IPv6 CIDR.
+func IsIPv6CIDR(cidr string) bool {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	return err == nil && ipnet.IP.To4() == nil
+}
+
+// JoinCIDRs joins the non empty CIDRs with a comma, as the Kubernetes flags expect the CIDRs
+// of both families of a dual-stack cluster.
+func JoinCIDRs(cidrs ...string) string {
+	nonEmpty := []string{}
+	for _, cidr := range cidrs {
+		if cidr != "" {
+			nonEmpty = append(nonEmpty, cidr)
+		}
+	}
+	return strings.Join(nonEmpty, ",")
+}
+
 // SplitNetworkCIDR splits the provided network CIDR into two separated
-// subnets.
+// subnets. An IPv6 service subnet is shrunk to the first /108 of its half
+// as the API server does not allocate service IPs from larger ranges.
 func SplitNetworkCIDR(netaddr string) (string, string, error) {
 	_, ipnet, err := net.ParseCIDR(netaddr)
 	if err != nil {
@@ -26,6 +71,13 @@ func SplitNetworkCIDR(netaddr string) (string, string, error) {
 		return "", "", fmt.Errorf("unable to determine second cidr: %w", err)
 	}
 
+	if size, bits := svcnet.Mask.Size(); bits == 128 && size < maxIPv6ServiceCIDRSize {
+		svcnet, err = cidr.Subnet(svcnet, maxIPv6ServiceCIDRSize-size, 0)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to shrink second cidr: %w", err)
+		}
+	}
+
 	return podnet.String(), svcnet.String(), nil
 }
 
@@ -63,6 +115,41 @@ func ValidateCIDR(cidr string, notLessThan int, private bool) error {
 	return fmt.Errorf("The provided CIDR block (%s) is not in a private IP address range (%s)", cidr, strings.Join(privates, ", "))
 }
 
+// ValidateIPv6CIDR validates an IPv6 network CIDR. It must be a unique local
+// address range between /48 and /56, so that every node gets a /64 of the pod
+// half of it.
+func ValidateIPv6CIDR(cidr string) error {
+	_, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	if ipnet.IP.To4() != nil {
+		//nolint:staticcheck // ST1005
+		return fmt.Errorf("The provided CIDR block (%s) is not an IPv6 CIDR block", cidr)
+	}
+
+	if ipnet.String() != cidr {
+		//nolint:staticcheck // ST1005
+		return fmt.Errorf("The provided CIDR block (%s) is not valid, please use the canonical representation %s", cidr, ipnet.String())
+	}
+
+	if size, _ := ipnet.Mask.Size(); size > 56 {
+		//nolint:staticcheck // ST1005
+		return fmt.Errorf("The provided CIDR block (%s) is too small. It must be /56 or larger.", cidr)
+	} else if size < 48 {
+		//nolint:staticcheck // ST1005
+		return fmt.Errorf("The provided CIDR block (%s) is too large. It must be /48 or smaller.", cidr)
+	}
+
+	if _, ula, _ := net.ParseCIDR("fc00::/7"); !ula.Contains(ipnet.IP) {
+		//nolint:staticcheck // ST1005
+		return fmt.Errorf("The provided CIDR block (%s) is not in the unique local IPv6 address range (fc00::/7)", cidr)
+	}
+
+	return nil
+}
+
 // NetworksAreAdjacentAndSameSize returns true if the two provided CIDRs are
 // adjacent and have the same size. If this function returns true then the
 // second returned value is the CIDR that encompasses the two provided CIDRs.
@@ -26,6 +26,12 @@ func TestSplitNetworkCIDR(t *testing.T) {
 			expectedPodCIDR: "10.1.0.0/17",
 			expectedSvcCIDR: "10.1.128.0/17",
 		},
+		{
+			name:            "an ipv6 /48 cidr",
+			cidr:            "fd00:10::/48",
+			expectedPodCIDR: "fd00:10::/49",
+			expectedSvcCIDR: "fd00:10:0:8000::/108",
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
 			podnet, svcnet, err := SplitNetworkCIDR(tt.cidr)
@@ -99,6 +105,69 @@ func TestValidateCIDR(t *testing.T) {
 	}
 }
 
+func TestValidateIPv6CIDR(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		cidr string
+		err  string
+	}{
+		{
+			name: "valid /48 cidr",
+			cidr: "fd00:10::/48",
+		},
+		{
+			name: "valid /56 cidr",
+			cidr: "fd00:10:0:100::/56",
+		},
+		{
+			name: "an ipv4 cidr",
+			cidr: "10.0.0.0/16",
+			err:  "The provided CIDR block (10.0.0.0/16) is not an IPv6 CIDR block",
+		},
+		{
+			name: "small cidr",
+			cidr: "fd00:10::/64",
+			err:  "The provided CIDR block (fd00:10::/64) is too small. It must be /56 or larger.",
+		},
+		{
+			name: "large cidr",
+			cidr: "fd00::/40",
+			err:  "The provided CIDR block (fd00::/40) is too large. It must be /48 or smaller.",
+		},
+		{
+			name: "a global unicast cidr",
+			cidr: "2001:db8::/48",
+			err:  "The provided CIDR block (2001:db8::/48) is not in the unique local IPv6 address range (fc00::/7)",
+		},
+		{
+			name: "not a canonical cidr address",
+			cidr: "fd00:10::1/48",
+			err:  "The provided CIDR block (fd00:10::1/48) is not valid, please use the canonical representation fd00:10::/48",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateIPv6CIDR(tt.cidr)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestClusterIPFamily(t *testing.T) {
+	assert.Equal(t, IPFamilyIPv4, ClusterIPFamily("10.244.0.0/16", ""))
+	assert.Equal(t, IPFamilyDualStack, ClusterIPFamily("10.244.0.0/16", "fd00:10::/49"))
+	assert.Equal(t, IPFamilyIPv6, ClusterIPFamily("fd00:10::/49", ""))
+}
+
+func TestJoinCIDRs(t *testing.T) {
+	assert.Equal(t, "10.244.0.0/16,fd00:10::/49", JoinCIDRs("10.244.0.0/16", "fd00:10::/49"))
+	assert.Equal(t, "10.244.0.0/16", JoinCIDRs("10.244.0.0/16", ""))
+	assert.Equal(t, "", JoinCIDRs())
+}
+
 func TestNetworksAreAdjacentAndSameSize(t *testing.T) {
 	for _, tt := range []struct {
 		name     string
@@ -10,6 +10,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
@@ -29,6 +30,15 @@ var (
 	_releaseData *ReleaseData
 )
 
+// HostPreflightTemplateAnnotation opts a HostPreflight document in to being rendered with the
+// install data before it is loaded. The template actions of the other documents, like the ones
+// of troubleshoot in analyzer messages, are left as they are.
+const HostPreflightTemplateAnnotation = "embedded-cluster.replicated.com/template"
+
+// hostPreflightTemplateRegex matches the annotation set to true. The document can't be parsed
+// to read it, the template actions don't have to be valid YAML before they are rendered.
+var hostPreflightTemplateRegex = regexp.MustCompile(`(?m)^\s+` + regexp.QuoteMeta(HostPreflightTemplateAnnotation) + `:\s*["']?true["']?\s*$`)
+
 // ReleaseData holds the parsed data from a Kots Release.
 //
 // Note / TODO: Custom resources (like HelmChart CRs) must be templated before they are parsed
@@ -38,6 +48,7 @@ type ReleaseData struct {
 	Application           *kotsv1beta1.Application
 	AppConfig             *kotsv1beta1.Config
 	HostPreflights        *troubleshootv1beta2.HostPreflightSpec
+	RawHostPreflights     [][]byte
 	EmbeddedClusterConfig *ecv1beta1.Config
 	ChannelRelease        *ChannelRelease
 	VeleroBackup          *velerov1.Backup
@@ -66,6 +77,16 @@ func GetHostPreflights() *troubleshootv1beta2.HostPreflightSpec {
 	return _releaseData.HostPreflights
 }
 
+// GetRawHostPreflights returns the HostPreflight specs found in the release annotated with
+// HostPreflightTemplateAnnotation. They are only valid once rendered with the data of the
+// installation, so they are not part of GetHostPreflights. If none are found, returns an empty slice.
+func GetRawHostPreflights() [][]byte {
+	if _releaseData.RawHostPreflights == nil {
+		return [][]byte{}
+	}
+	return _releaseData.RawHostPreflights
+}
+
 // GetApplication reads and returns the kots application embedded as part of the
 // release. If no application is found, returns nil and no error. This function does
 // not unmarshal the application yaml.
@@ -394,6 +415,10 @@ func (r *ReleaseData) processYAMLDocument(content []byte, headerName string) err
 		if bytes.Contains(content, []byte("cluster.kurl.sh/v1beta1")) {
 			break
 		}
+		if hostPreflightTemplateRegex.Match(content) {
+			r.RawHostPreflights = append(r.RawHostPreflights, content)
+			break
+		}
 		hostPreflights, err := parseHostPreflights(content)
 		if err != nil {
 			return fmt.Errorf("failed to parse host preflights: %w", err)
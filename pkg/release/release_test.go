@@ -24,6 +24,9 @@ var (
 
 	//go:embed testdata/mixed-multi.yaml
 	mixedMultiData []byte
+
+	//go:embed testdata/host-preflight-templated.yaml
+	hostPreflightTemplatedData []byte
 )
 
 func Test_newReleaseDataFrom(t *testing.T) {
@@ -250,6 +253,24 @@ func TestParseMultiDocumentMixedYAML(t *testing.T) {
 	assert.Len(t, release.HostPreflights.Analyzers, 1)
 }
 
+func TestParseRawHostPreflights(t *testing.T) {
+	templated := generateReleaseTGZ(t, hostPreflightTemplatedData)
+
+	release, err := newReleaseDataFrom(templated)
+	require.NoError(t, err)
+
+	require.Len(t, release.RawHostPreflights, 1, "only the annotated host preflights are rendered")
+	assert.Contains(t, string(release.RawHostPreflights[0]), "path: '{{ .Vendor.appDir }}'")
+
+	// the template actions of the other host preflights are left to troubleshoot
+	require.NotNil(t, release.HostPreflights)
+	require.Len(t, release.HostPreflights.Analyzers, 1)
+	outcomes := release.HostPreflights.Analyzers[0].DiskUsage.Outcomes
+	require.Len(t, outcomes, 2)
+	assert.Equal(t, `{{ .Used }} of {{ .Capacity }} used on repl{{ ConfigOption "app_dir" }}`, outcomes[0].Fail.Message)
+	assert.Equal(t, "{{ .Used }} used", outcomes[1].Pass.Message)
+}
+
 func TestParseMultipleHelmChartCRsInSingleFile(t *testing.T) {
 	helmchartsMulti := generateReleaseTGZ(t, helmchartsMultiData)
 
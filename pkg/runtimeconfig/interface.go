@@ -24,6 +24,7 @@ type RuntimeConfig interface {
 	PathToEmbeddedClusterBinary(name string) string
 	PathToKubeConfig() string
 	PathToKubeletConfig() string
+	PathToResolvConf() string
 	EmbeddedClusterSupportSubDir() string
 	PathToEmbeddedClusterSupportFile(name string) string
 
@@ -35,10 +36,16 @@ type RuntimeConfig interface {
 	ManagerPort() int
 	ProxySpec() *ecv1beta1.ProxySpec
 	NetworkInterface() string
+	ControlPlaneInterface() string
+	PodNetworkInterface() string
+	AdminConsoleAddress() string
 	GlobalCIDR() string
 	PodCIDR() string
 	ServiceCIDR() string
+	IPv6PodCIDR() string
+	IPv6ServiceCIDR() string
 	NodePortRange() string
+	DNSUpstreams() []string
 	HostCABundlePath() string
 
 	SetDataDir(dataDir string)
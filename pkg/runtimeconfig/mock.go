@@ -104,6 +104,12 @@ func (m *MockRuntimeConfig) PathToKubeletConfig() string {
 	return args.String(0)
 }
 
+// PathToResolvConf mocks the PathToResolvConf method
+func (m *MockRuntimeConfig) PathToResolvConf() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 // EmbeddedClusterSupportSubDir mocks the EmbeddedClusterSupportSubDir method
 func (m *MockRuntimeConfig) EmbeddedClusterSupportSubDir() string {
 	args := m.Called()
@@ -170,18 +176,57 @@ func (m *MockRuntimeConfig) ServiceCIDR() string {
 	return args.String(0)
 }
 
+// IPv6PodCIDR returns the IPv6 pod CIDR of a dual-stack cluster.
+func (m *MockRuntimeConfig) IPv6PodCIDR() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+// IPv6ServiceCIDR returns the IPv6 service CIDR of a dual-stack cluster.
+func (m *MockRuntimeConfig) IPv6ServiceCIDR() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 // NetworkInterface returns the configured network interface or the default if not configured.
 func (m *MockRuntimeConfig) NetworkInterface() string {
 	args := m.Called()
 	return args.String(0)
 }
 
+// ControlPlaneInterface mocks the ControlPlaneInterface method
+func (m *MockRuntimeConfig) ControlPlaneInterface() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+// PodNetworkInterface mocks the PodNetworkInterface method
+func (m *MockRuntimeConfig) PodNetworkInterface() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+// AdminConsoleAddress mocks the AdminConsoleAddress method
+func (m *MockRuntimeConfig) AdminConsoleAddress() string {
+	args := m.Called()
+	return args.String(0)
+}
+
 // NodePortRange returns the configured node port range or the default if not configured.
 func (m *MockRuntimeConfig) NodePortRange() string {
 	args := m.Called()
 	return args.String(0)
 }
 
+// DNSUpstreams mocks the DNSUpstreams method
+func (m *MockRuntimeConfig) DNSUpstreams() []string {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).([]string)
+}
+
 // HostCABundlePath mocks the HostCABundlePath method
 func (m *MockRuntimeConfig) HostCABundlePath() string {
 	args := m.Called()
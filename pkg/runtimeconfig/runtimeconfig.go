@@ -180,6 +180,12 @@ func (rc *runtimeConfig) PathToKubeletConfig() string {
 	return filepath.Join(rc.EmbeddedClusterK0sSubDir(), "kubelet.conf")
 }
 
+// PathToResolvConf returns the path to the resolv.conf file the kubelet is given when DNS
+// upstreams are configured.
+func (rc *runtimeConfig) PathToResolvConf() string {
+	return filepath.Join(rc.EmbeddedClusterK0sSubDir(), "resolv.conf")
+}
+
 // EmbeddedClusterSupportSubDir returns the path to the directory where embedded-cluster
 // support files are stored. Things that are useful when providing end user support in
 // a running cluster should be stored into this directory.
@@ -292,11 +298,42 @@ func (rc *runtimeConfig) ServiceCIDR() string {
 	return rc.spec.Network.ServiceCIDR
 }
 
+// IPv6PodCIDR returns the IPv6 pod CIDR of a dual-stack cluster.
+func (rc *runtimeConfig) IPv6PodCIDR() string {
+	return rc.spec.Network.IPv6PodCIDR
+}
+
+// IPv6ServiceCIDR returns the IPv6 service CIDR of a dual-stack cluster.
+func (rc *runtimeConfig) IPv6ServiceCIDR() string {
+	return rc.spec.Network.IPv6ServiceCIDR
+}
+
 // NetworkInterface returns the configured network interface or the default if not configured.
 func (rc *runtimeConfig) NetworkInterface() string {
 	return rc.spec.Network.NetworkInterface
 }
 
+// ControlPlaneInterface returns the interface the Kubernetes API server and etcd advertise their
+// address on, the network interface if not configured.
+func (rc *runtimeConfig) ControlPlaneInterface() string {
+	if rc.spec.Network.ControlPlaneInterface == "" {
+		return rc.spec.Network.NetworkInterface
+	}
+	return rc.spec.Network.ControlPlaneInterface
+}
+
+// PodNetworkInterface returns the interface Calico autodetects the address of the nodes on,
+// empty when Calico autodetects the interface.
+func (rc *runtimeConfig) PodNetworkInterface() string {
+	return rc.spec.Network.PodNetworkInterface
+}
+
+// AdminConsoleAddress returns the address the admin console is advertised on, empty when it is
+// discovered.
+func (rc *runtimeConfig) AdminConsoleAddress() string {
+	return rc.spec.Network.AdminConsoleAddress
+}
+
 // NodePortRange returns the configured node port range or the default if not configured.
 func (rc *runtimeConfig) NodePortRange() string {
 	if rc.spec.Network.NodePortRange == "" {
@@ -305,6 +342,12 @@ func (rc *runtimeConfig) NodePortRange() string {
 	return rc.spec.Network.NodePortRange
 }
 
+// DNSUpstreams returns the configured DNS upstreams, empty when CoreDNS uses the nameservers of
+// the hosts.
+func (rc *runtimeConfig) DNSUpstreams() []string {
+	return rc.spec.Network.DNSUpstreams
+}
+
 // HostCABundlePath returns the path to the host CA bundle.
 func (rc *runtimeConfig) HostCABundlePath() string {
 	return rc.spec.HostCABundlePath
@@ -353,7 +396,19 @@ func (rc *runtimeConfig) GetKubernetesEnvSettings() *helmcli.EnvSettings {
 	return envSettings
 }
 
+// createDirs is disabled while an install is planned so nothing is written to the host.
+var createDirs = true
+
+// DisableDirCreation stops the runtime config from creating the directories it returns paths
+// to.
+func DisableDirCreation() {
+	createDirs = false
+}
+
 func mkdirAll(path string) error {
+	if !createDirs {
+		return nil
+	}
 	return os.MkdirAll(path, 0755)
 }
 
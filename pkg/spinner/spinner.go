@@ -12,7 +12,19 @@ import (
 
 var blocks = []string{"◐", "◓", "◑", "◒"}
 
-var hasTTY = isatty.IsTerminal(os.Stdout.Fd())
+var (
+	defaultPrintf WriteFn = fmt.Printf
+	hasTTY                = isatty.IsTerminal(os.Stdout.Fd())
+)
+
+// SetDefaultOutput sets the file spinners write to when no WriteFn is provided. It defaults to
+// stdout.
+func SetDefaultOutput(f *os.File) {
+	defaultPrintf = func(format string, args ...any) (int, error) {
+		return fmt.Fprintf(f, format, args...)
+	}
+	hasTTY = isatty.IsTerminal(f.Fd())
+}
 
 // WriteFn is a function that writes a formatted string.
 type WriteFn func(string, ...any) (int, error)
@@ -150,7 +162,7 @@ func Start(opts ...Option) *MessageWriter {
 	mw := &MessageWriter{
 		ch:     make(chan string, 1024),
 		end:    make(chan struct{}),
-		printf: fmt.Printf,
+		printf: defaultPrintf,
 		tty:    hasTTY,
 	}
 	for _, opt := range opts {